@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/ml"
+	"learning-go.adcon.dev/plot"
+)
+
+// generateKids makes a synthetic dataset where candies grow with age and a
+// third of the kids have a sweet tooth.
+func generateKids(n int, rng *rand.Rand) []Kid {
+	kids := make([]Kid, n)
+	for i := range kids {
+		age := 3 + rng.IntN(10)
+		candies := 2*age + int(rng.NormFloat64()*2)
+		if rng.IntN(3) == 0 {
+			candies += 15
+		}
+		kids[i] = Kid{Age: age, Candies: max(0, candies)}
+	}
+	return kids
+}
+
+func runKidsAnalyze(ctx *cli.Context) error {
+	fs := ctx.Flags
+	file := fs.String("file", "", "JSON file with kids (default: built-in sample)")
+	generate := fs.Int("generate", 0, "analyze this many synthetic kids instead of -file")
+	seed := fs.Uint64("seed", 1, "seed for data generation and k-means")
+	k := fs.Int("k", 0, "number of clusters (0 picks the best silhouette)")
+	maxK := fs.Int("maxk", 6, "largest k in the elbow report")
+	width := fs.Int("width", 60, "plot width in characters")
+	height := fs.Int("height", 16, "plot height in characters")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	if *k < 0 {
+		return fmt.Errorf("kids analyze: -k must be 0 or more, got %d", *k)
+	}
+	rng := rand.New(rand.NewPCG(*seed, *seed))
+	var kids []Kid
+	if *generate > 0 {
+		kids = generateKids(*generate, rng)
+	} else {
+		var err error
+		if kids, err = loadKids(*file); err != nil {
+			return err
+		}
+	}
+
+	ages := make([]float64, len(kids))
+	candies := make([]float64, len(kids))
+	points := make([][]float64, len(kids))
+	for i, kid := range kids {
+		ages[i], candies[i] = float64(kid.Age), float64(kid.Candies)
+		points[i] = []float64{ages[i], candies[i]}
+	}
+
+	fit, err := ml.FitLinear(ages, candies)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("OLS: candies = %.3f + %.3f·age, R² = %.4f\n", fit.Intercept, fit.Slope, fit.R2)
+	worst := 0
+	for i, r := range fit.Residuals {
+		if math.Abs(r) > math.Abs(fit.Residuals[worst]) {
+			worst = i
+		}
+	}
+	fmt.Printf("     largest residual %+.2f for %+v\n", fit.Residuals[worst], kids[worst])
+	line := plot.Series{Mark: '.'}
+	for c := range *width {
+		x := minOf(ages) + (maxOf(ages)-minOf(ages))*float64(c)/float64(*width-1)
+		line.X, line.Y = append(line.X, x), append(line.Y, fit.Predict(x))
+	}
+	if err := plot.Scatter(os.Stdout, *width, *height, "age", "candies", line, plot.Series{X: ages, Y: candies, Mark: '*'}); err != nil {
+		return err
+	}
+
+	// Age and candies have different scales, so cluster standardized values.
+	scaled, _, _ := ml.Standardize(points)
+	upper := min(*maxK, len(points))
+	runs, err := ml.Elbow(scaled, upper, 5, rng)
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nk   inertia     silhouette")
+	best, bestScore := 1, math.Inf(-1)
+	for i, run := range runs {
+		kk := i + 1
+		score := ml.Silhouette(scaled, run.Labels, kk)
+		fmt.Printf("%-3d %-11.3f %.3f\n", kk, run.Inertia, score)
+		if kk > 1 && score > bestScore {
+			best, bestScore = kk, score
+		}
+	}
+	if *k == 0 {
+		*k = best
+	}
+	var c ml.Clustering
+	if *k <= len(runs) {
+		c = runs[*k-1]
+	} else if c, err = ml.KMeans(scaled, *k, 100, rng); err != nil {
+		return err
+	}
+	fmt.Printf("\nk-means with k=%d (%d iterations):\n", *k, c.Iterations)
+	marks := []rune("ox+#@%&$")
+	series := make([]plot.Series, *k)
+	for i, label := range c.Labels {
+		s := &series[label]
+		s.Mark = marks[label%len(marks)]
+		s.X, s.Y = append(s.X, ages[i]), append(s.Y, candies[i])
+	}
+	for j, s := range series {
+		fmt.Printf("  %c cluster %d: %d kids\n", marks[j%len(marks)], j, len(s.X))
+	}
+	return plot.Scatter(os.Stdout, *width, *height, "age", "candies", series...)
+}
+
+func minOf(xs []float64) float64 {
+	m := math.Inf(1)
+	for _, x := range xs {
+		m = min(m, x)
+	}
+	return m
+}
+
+func maxOf(xs []float64) float64 {
+	m := math.Inf(-1)
+	for _, x := range xs {
+		m = max(m, x)
+	}
+	return m
+}
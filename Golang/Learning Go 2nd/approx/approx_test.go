@@ -0,0 +1,125 @@
+package approx
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func covers(edges []Edge, cover []int) bool {
+	for _, e := range edges {
+		if !slices.Contains(cover, e.U) && !slices.Contains(cover, e.V) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestVertexCover(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 200 {
+		n := 2 + rng.IntN(13)
+		edges := RandomGraph(n, rng.Float64(), rng)
+		exact, err := ExactVertexCover(n, edges)
+		if err != nil {
+			t.Fatal(err)
+		}
+		approx := VertexCover(n, edges)
+		if !covers(edges, exact) || !covers(edges, approx) {
+			t.Fatalf("n=%d edges %v: exact %v or approximate %v misses an edge", n, edges, exact, approx)
+		}
+		if len(approx) > 2*len(exact) {
+			t.Errorf("n=%d edges %v: cover of %d, optimum %d", n, edges, len(approx), len(exact))
+		}
+		if s := slices.Sorted(slices.Values(approx)); len(slices.Compact(s)) != len(approx) {
+			t.Errorf("cover %v repeats a vertex", approx)
+		}
+	}
+}
+
+func isTour(tour []int, n int) bool {
+	s := slices.Sorted(slices.Values(tour))
+	for i, v := range s {
+		if v != i {
+			return false
+		}
+	}
+	return len(s) == n
+}
+
+func TestTSPRatios(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	const eps = 1e-9
+	for range 100 {
+		n := 3 + rng.IntN(8)
+		d := EuclideanDist(RandomPoints(n, rng))
+		best, err := ExactTSP(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opt := TourLength(d, best)
+		tree, chris := TreeTour(d), Christofides(d)
+		improved := TwoOpt(d, chris)
+		for name, tour := range map[string][]int{"exact": best, "tree": tree, "Christofides": chris, "2-opt": improved} {
+			if !isTour(tour, n) {
+				t.Fatalf("%s tour %v does not visit %d cities once", name, tour, n)
+			}
+			if TourLength(d, tour) < opt-eps {
+				t.Errorf("%s tour of %g beats the optimum %g", name, TourLength(d, tour), opt)
+			}
+		}
+		if l := TourLength(d, tree); l > 2*opt+eps {
+			t.Errorf("n=%d: tree tour %g over twice the optimum %g", n, l, opt)
+		}
+		if l := TourLength(d, chris); l > 1.5*opt+eps {
+			t.Errorf("n=%d: Christofides %g over 1.5 times the optimum %g", n, l, opt)
+		}
+		if TourLength(d, improved) > TourLength(d, chris)+eps {
+			t.Errorf("n=%d: 2-opt lengthened the tour", n)
+		}
+	}
+}
+
+func TestGreedySetCover(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+	for range 100 {
+		universe := 1 + rng.IntN(30)
+		subsets, err := RandomSetCover(universe, 1+rng.IntN(12), 0.2, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		greedy, err := GreedySetCover(universe, subsets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exact, err := ExactSetCover(universe, subsets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, chosen := range map[string][]int{"greedy": greedy, "exact": exact} {
+			covered := make([]bool, universe)
+			for _, i := range chosen {
+				for _, x := range subsets[i] {
+					covered[x] = true
+				}
+			}
+			if i := slices.Index(covered, false); i >= 0 {
+				t.Fatalf("%s cover %v of %v misses %d", name, chosen, subsets, i)
+			}
+		}
+		// H(d) for the largest subset size d.
+		d, h := 0, 0.0
+		for _, s := range subsets {
+			d = max(d, len(s))
+		}
+		for i := 1; i <= d; i++ {
+			h += 1 / float64(i)
+		}
+		if float64(len(greedy)) > h*float64(len(exact)) {
+			t.Errorf("greedy took %d subsets, optimum %d, bound H(%d) = %.2f", len(greedy), len(exact), d, h)
+		}
+	}
+	if _, err := GreedySetCover(3, [][]int{{0}, {1}}); err == nil {
+		t.Error("GreedySetCover covered an element no subset holds")
+	}
+}
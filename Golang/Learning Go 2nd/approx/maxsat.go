@@ -0,0 +1,93 @@
+package approx
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Literal is a DIMACS-style literal: v+1 for variable v, -(v+1) for its
+// negation.
+type Literal int
+
+func (l Literal) variable() int {
+	if l < 0 {
+		return int(-l) - 1
+	}
+	return int(l) - 1
+}
+
+// Clause is a disjunction of three literals on distinct variables.
+type Clause [3]Literal
+
+// Satisfied counts the clauses made true by assign.
+func Satisfied(clauses []Clause, assign []bool) int {
+	n := 0
+	for _, c := range clauses {
+		for _, l := range c {
+			if assign[l.variable()] == (l > 0) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// RandomAssignment is the randomized MAX-3-SAT approximation from CLRS 35.4:
+// each clause is missed with probability 1/8, so a uniform assignment
+// satisfies 7/8 of the clauses in expectation. Keeping the best of trials
+// assignments only improves on that.
+func RandomAssignment(vars int, clauses []Clause, trials int, rng *rand.Rand) []bool {
+	var best []bool
+	bestCount := -1
+	for range max(1, trials) {
+		assign := make([]bool, vars)
+		for i := range assign {
+			assign[i] = rng.IntN(2) == 1
+		}
+		if c := Satisfied(clauses, assign); c > bestCount {
+			best, bestCount = assign, c
+		}
+	}
+	return best
+}
+
+// ExactMaxSAT tries all 2ⁿ assignments. It is limited to 24 variables.
+func ExactMaxSAT(vars int, clauses []Clause) ([]bool, error) {
+	if vars > 24 {
+		return nil, fmt.Errorf("approx: exact MAX-SAT over %d variables is too large", vars)
+	}
+	assign := make([]bool, vars)
+	best := make([]bool, vars)
+	bestCount := -1
+	for bitsSet := 0; bitsSet < 1<<vars; bitsSet++ {
+		for v := range assign {
+			assign[v] = bitsSet&(1<<v) != 0
+		}
+		if c := Satisfied(clauses, assign); c > bestCount {
+			bestCount = c
+			copy(best, assign)
+		}
+	}
+	return best, nil
+}
+
+// RandomClauses draws m clauses over vars variables, each on three distinct
+// variables with random signs, so vars must be at least 3.
+func RandomClauses(vars, m int, rng *rand.Rand) ([]Clause, error) {
+	if vars < 3 || m < 0 {
+		return nil, fmt.Errorf("approx: cannot draw %d three-variable clauses over %d variables", m, vars)
+	}
+	clauses := make([]Clause, m)
+	for i := range clauses {
+		picked := rng.Perm(vars)[:3]
+		for j, v := range picked {
+			l := Literal(v + 1)
+			if rng.IntN(2) == 0 {
+				l = -l
+			}
+			clauses[i][j] = l
+		}
+	}
+	return clauses, nil
+}
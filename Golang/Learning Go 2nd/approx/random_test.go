@@ -0,0 +1,45 @@
+package approx
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestRandomClauses(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 1))
+	for _, vars := range []int{-1, 0, 2} {
+		if _, err := RandomClauses(vars, 4, rng); err == nil {
+			t.Errorf("RandomClauses(%d, 4) accepted fewer than 3 variables", vars)
+		}
+	}
+	clauses, err := RandomClauses(3, 20, rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range clauses {
+		vs := map[Literal]bool{}
+		for _, l := range c {
+			vs[max(l, -l)] = true
+		}
+		if len(vs) != 3 {
+			t.Errorf("clause %v repeats a variable", c)
+		}
+	}
+}
+
+func TestRandomSetCover(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 1))
+	if _, err := RandomSetCover(5, 0, 0.2, rng); err == nil {
+		t.Error("RandomSetCover accepted no subsets for 5 elements")
+	}
+	if _, err := RandomSetCover(-1, 3, 0.2, rng); err == nil {
+		t.Error("RandomSetCover accepted a negative universe")
+	}
+	subsets, err := RandomSetCover(30, 1, 0, rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GreedySetCover(30, subsets); err != nil {
+		t.Errorf("a single subset with p = 0 does not cover: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package approx
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand/v2"
+)
+
+// GreedySetCover is CLRS GREEDY-SET-COVER: repeatedly take the subset that
+// covers the most uncovered elements of 0..universe-1. It returns the indexes
+// of the chosen subsets and is within H(max subset size) ≤ ln n + 1 of the
+// optimum. It fails when the subsets do not cover the universe.
+func GreedySetCover(universe int, subsets [][]int) ([]int, error) {
+	covered := make([]bool, universe)
+	left := universe
+	var chosen []int
+	for left > 0 {
+		best, gain := -1, 0
+		for i, s := range subsets {
+			g := 0
+			for _, x := range s {
+				if !covered[x] {
+					g++
+				}
+			}
+			if g > gain {
+				best, gain = i, g
+			}
+		}
+		if best < 0 {
+			return nil, fmt.Errorf("approx: %d elements cannot be covered", left)
+		}
+		for _, x := range subsets[best] {
+			if !covered[x] {
+				covered[x] = true
+				left--
+			}
+		}
+		chosen = append(chosen, best)
+	}
+	return chosen, nil
+}
+
+// ExactSetCover tries every combination of subsets, smallest first. It is
+// limited to 24 subsets and a universe of 64 elements.
+func ExactSetCover(universe int, subsets [][]int) ([]int, error) {
+	if len(subsets) > 24 || universe > 64 {
+		return nil, fmt.Errorf("approx: exact set cover of %d subsets over %d elements is too large", len(subsets), universe)
+	}
+	masks := make([]uint64, len(subsets))
+	for i, s := range subsets {
+		for _, x := range s {
+			masks[i] |= 1 << x
+		}
+	}
+	want := uint64(1)<<universe - 1
+	if universe == 64 {
+		want = ^uint64(0)
+	}
+	best, found := uint32(0), false
+	for combo := uint32(0); combo < 1<<len(subsets); combo++ {
+		if found && bits.OnesCount32(combo) >= bits.OnesCount32(best) {
+			continue
+		}
+		var got uint64
+		for i := range subsets {
+			if combo&(1<<i) != 0 {
+				got |= masks[i]
+			}
+		}
+		if got == want {
+			best, found = combo, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("approx: subsets do not cover the universe")
+	}
+	var chosen []int
+	for i := range subsets {
+		if best&(1<<i) != 0 {
+			chosen = append(chosen, i)
+		}
+	}
+	return chosen, nil
+}
+
+// RandomSetCover builds m random subsets over 0..universe-1 and makes sure
+// every element appears in at least one of them, so a non-empty universe
+// needs at least one subset.
+func RandomSetCover(universe, m int, p float64, rng *rand.Rand) ([][]int, error) {
+	if universe < 0 || m < 0 || universe > 0 && m == 0 {
+		return nil, fmt.Errorf("approx: cannot cover %d elements with %d subsets", universe, m)
+	}
+	subsets := make([][]int, m)
+	seen := make([]bool, universe)
+	for i := range subsets {
+		for x := range universe {
+			if rng.Float64() < p {
+				subsets[i] = append(subsets[i], x)
+				seen[x] = true
+			}
+		}
+	}
+	for x, ok := range seen {
+		if !ok {
+			i := rng.IntN(m)
+			subsets[i] = append(subsets[i], x)
+		}
+	}
+	return subsets, nil
+}
@@ -0,0 +1,316 @@
+package approx
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+)
+
+// Dist is a symmetric distance matrix that satisfies the triangle inequality.
+type Dist [][]float64
+
+// EuclideanDist builds the metric for points in the plane.
+func EuclideanDist(xs, ys []float64) Dist {
+	d := make(Dist, len(xs))
+	for i := range d {
+		d[i] = make([]float64, len(xs))
+		for j := range d[i] {
+			d[i][j] = math.Hypot(xs[i]-xs[j], ys[i]-ys[j])
+		}
+	}
+	return d
+}
+
+// RandomPoints scatters n points in the unit square.
+func RandomPoints(n int, rng *rand.Rand) (xs, ys []float64) {
+	xs, ys = make([]float64, n), make([]float64, n)
+	for i := range n {
+		xs[i], ys[i] = rng.Float64(), rng.Float64()
+	}
+	return xs, ys
+}
+
+// TourLength sums the edges of the closed tour.
+func TourLength(d Dist, tour []int) float64 {
+	total := 0.0
+	for i := range tour {
+		total += d[tour[i]][tour[(i+1)%len(tour)]]
+	}
+	return total
+}
+
+// mst returns the parent of every vertex in a minimum spanning tree rooted at
+// 0, built with Prim's algorithm on the dense matrix.
+func mst(d Dist) []int {
+	n := len(d)
+	parent := make([]int, n)
+	key := make([]float64, n)
+	done := make([]bool, n)
+	for i := range key {
+		key[i], parent[i] = math.Inf(1), -1
+	}
+	key[0] = 0
+	for range n {
+		u := -1
+		for v := range n {
+			if !done[v] && (u < 0 || key[v] < key[u]) {
+				u = v
+			}
+		}
+		done[u] = true
+		for v := range n {
+			if !done[v] && d[u][v] < key[v] {
+				key[v], parent[v] = d[u][v], u
+			}
+		}
+	}
+	return parent
+}
+
+// TreeTour is CLRS APPROX-TSP-TOUR: a preorder walk of a minimum spanning
+// tree. In a metric the tour costs at most twice the optimum.
+func TreeTour(d Dist) []int {
+	n := len(d)
+	if n == 0 {
+		return nil
+	}
+	children := make([][]int, n)
+	for v, p := range mst(d) {
+		if p >= 0 {
+			children[p] = append(children[p], v)
+		}
+	}
+	tour := make([]int, 0, n)
+	var walk func(int)
+	walk = func(u int) {
+		tour = append(tour, u)
+		for _, c := range children[u] {
+			walk(c)
+		}
+	}
+	walk(0)
+	return tour
+}
+
+// Christofides adds a minimum-weight perfect matching on the odd-degree MST
+// vertices, walks an Euler circuit of the union and shortcuts repeated
+// vertices. With an exact matching the tour is within 3/2 of the optimum;
+// matching is exact for up to 20 odd vertices and greedy beyond that, where
+// the bound no longer holds.
+func Christofides(d Dist) []int {
+	n := len(d)
+	if n < 3 {
+		return TreeTour(d)
+	}
+	adj := make([][]int, n)
+	for v, p := range mst(d) {
+		if p >= 0 {
+			adj[v] = append(adj[v], p)
+			adj[p] = append(adj[p], v)
+		}
+	}
+	var odd []int
+	for v := range n {
+		if len(adj[v])%2 == 1 {
+			odd = append(odd, v)
+		}
+	}
+	for _, e := range minMatching(d, odd) {
+		adj[e.U] = append(adj[e.U], e.V)
+		adj[e.V] = append(adj[e.V], e.U)
+	}
+
+	// Hierholzer's algorithm on the multigraph; used[] marks edge copies.
+	used := make([][]bool, n)
+	for v := range adj {
+		used[v] = make([]bool, len(adj[v]))
+	}
+	takeEdge := func(u int) (int, bool) {
+		for i, v := range adj[u] {
+			if used[u][i] {
+				continue
+			}
+			used[u][i] = true
+			for j, w := range adj[v] {
+				if w == u && !used[v][j] {
+					used[v][j] = true
+					break
+				}
+			}
+			return v, true
+		}
+		return 0, false
+	}
+	var circuit []int
+	stack := []int{0}
+	for len(stack) > 0 {
+		u := stack[len(stack)-1]
+		if v, ok := takeEdge(u); ok {
+			stack = append(stack, v)
+		} else {
+			circuit = append(circuit, u)
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	seen := make([]bool, n)
+	tour := make([]int, 0, n)
+	for _, v := range circuit {
+		if !seen[v] {
+			seen[v] = true
+			tour = append(tour, v)
+		}
+	}
+	return tour
+}
+
+// minMatching pairs up the given vertices with minimum total distance, using
+// a bitmask DP for up to 20 vertices and a greedy closest-pair rule above.
+func minMatching(d Dist, vs []int) []Edge {
+	k := len(vs)
+	if k == 0 {
+		return nil
+	}
+	if k > 20 {
+		return greedyMatching(d, vs)
+	}
+	full := 1<<k - 1
+	cost := make([]float64, 1<<k)
+	choice := make([]int, 1<<k)
+	for mask := 1; mask <= full; mask++ {
+		cost[mask] = math.Inf(1)
+		if bits.OnesCount(uint(mask))%2 == 1 {
+			continue
+		}
+		i := 0
+		for mask&(1<<i) == 0 {
+			i++
+		}
+		for j := i + 1; j < k; j++ {
+			if mask&(1<<j) == 0 {
+				continue
+			}
+			rest := mask &^ (1<<i | 1<<j)
+			if c := cost[rest] + d[vs[i]][vs[j]]; c < cost[mask] {
+				cost[mask], choice[mask] = c, j
+			}
+		}
+	}
+	var pairs []Edge
+	for mask := full; mask != 0; {
+		i := 0
+		for mask&(1<<i) == 0 {
+			i++
+		}
+		j := choice[mask]
+		pairs = append(pairs, Edge{vs[i], vs[j]})
+		mask &^= 1<<i | 1<<j
+	}
+	return pairs
+}
+
+func greedyMatching(d Dist, vs []int) []Edge {
+	left := append([]int(nil), vs...)
+	var pairs []Edge
+	for len(left) > 1 {
+		bi, bj := 0, 1
+		for i := range left {
+			for j := i + 1; j < len(left); j++ {
+				if d[left[i]][left[j]] < d[left[bi]][left[bj]] {
+					bi, bj = i, j
+				}
+			}
+		}
+		pairs = append(pairs, Edge{left[bi], left[bj]})
+		left = append(left[:bj], left[bj+1:]...)
+		left = append(left[:bi], left[bi+1:]...)
+	}
+	return pairs
+}
+
+// TwoOpt improves a tour in place by reversing segments while that shortens
+// it, and returns the tour for chaining.
+func TwoOpt(d Dist, tour []int) []int {
+	n := len(tour)
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < n-1; i++ {
+			for j := i + 2; j < n; j++ {
+				a, b := tour[i], tour[i+1]
+				c, e := tour[j], tour[(j+1)%n]
+				if a == e {
+					continue
+				}
+				if d[a][c]+d[b][e] < d[a][b]+d[c][e]-1e-12 {
+					for l, r := i+1, j; l < r; l, r = l+1, r-1 {
+						tour[l], tour[r] = tour[r], tour[l]
+					}
+					improved = true
+				}
+			}
+		}
+	}
+	return tour
+}
+
+// ExactTSP solves the tour exactly with the Held–Karp dynamic program in
+// O(2ⁿ·n²) time. It is limited to 16 cities.
+func ExactTSP(d Dist) ([]int, error) {
+	n := len(d)
+	if n > 16 {
+		return nil, fmt.Errorf("approx: exact TSP of %d cities is too large", n)
+	}
+	if n <= 2 {
+		tour := make([]int, n)
+		for i := range tour {
+			tour[i] = i
+		}
+		return tour, nil
+	}
+	// best[mask][v]: shortest path from 0 through mask ending at v; city 0 is
+	// implicit and never in mask.
+	size := 1 << (n - 1)
+	best := make([][]float64, size)
+	prev := make([][]int8, size)
+	for mask := range best {
+		best[mask] = make([]float64, n)
+		prev[mask] = make([]int8, n)
+		for v := range best[mask] {
+			best[mask][v] = math.Inf(1)
+		}
+	}
+	for v := 1; v < n; v++ {
+		best[1<<(v-1)][v] = d[0][v]
+	}
+	for mask := 1; mask < size; mask++ {
+		for v := 1; v < n; v++ {
+			cur := best[mask][v]
+			if math.IsInf(cur, 1) {
+				continue
+			}
+			for w := 1; w < n; w++ {
+				bit := 1 << (w - 1)
+				if mask&bit != 0 {
+					continue
+				}
+				if c := cur + d[v][w]; c < best[mask|bit][w] {
+					best[mask|bit][w], prev[mask|bit][w] = c, int8(v)
+				}
+			}
+		}
+	}
+	full := size - 1
+	last := 1
+	for v := 2; v < n; v++ {
+		if best[full][v]+d[v][0] < best[full][last]+d[last][0] {
+			last = v
+		}
+	}
+	tour := make([]int, n)
+	for i, mask, v := n-1, full, last; i > 0; i-- {
+		tour[i] = v
+		mask, v = mask&^(1<<(v-1)), int(prev[mask][v])
+	}
+	return tour, nil
+}
@@ -0,0 +1,74 @@
+// Package approx holds approximation algorithms for NP-hard problems (CLRS
+// Chapters 34 and 35), each next to an exact exponential-time solver for small
+// instances so the approximation ratio can be measured.
+package approx
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand/v2"
+)
+
+// Edge is an undirected edge between two vertices numbered from 0.
+type Edge struct{ U, V int }
+
+// VertexCover is CLRS APPROX-VERTEX-COVER: take both endpoints of any edge
+// not yet covered. The chosen edges form a matching, and every cover needs
+// one endpoint of each, so the result is at most twice the optimum.
+func VertexCover(n int, edges []Edge) []int {
+	in := make([]bool, n)
+	var cover []int
+	for _, e := range edges {
+		if in[e.U] || in[e.V] {
+			continue
+		}
+		in[e.U], in[e.V] = true, true
+		cover = append(cover, e.U, e.V)
+	}
+	return cover
+}
+
+// ExactVertexCover tries vertex subsets in order of size. It is limited to 30
+// vertices.
+func ExactVertexCover(n int, edges []Edge) ([]int, error) {
+	if n > 30 {
+		return nil, fmt.Errorf("approx: exact vertex cover of %d vertices is too large", n)
+	}
+	best := uint32(1)<<n - 1
+	for set := uint32(0); set < 1<<n; set++ {
+		if bits.OnesCount32(set) >= bits.OnesCount32(best) {
+			continue
+		}
+		covers := true
+		for _, e := range edges {
+			if set&(1<<e.U) == 0 && set&(1<<e.V) == 0 {
+				covers = false
+				break
+			}
+		}
+		if covers {
+			best = set
+		}
+	}
+	var cover []int
+	for v := range n {
+		if best&(1<<v) != 0 {
+			cover = append(cover, v)
+		}
+	}
+	return cover, nil
+}
+
+// RandomGraph returns a G(n, p) random graph with edges in random order.
+func RandomGraph(n int, p float64, rng *rand.Rand) []Edge {
+	var edges []Edge
+	for u := range n {
+		for v := u + 1; v < n; v++ {
+			if rng.Float64() < p {
+				edges = append(edges, Edge{u, v})
+			}
+		}
+	}
+	rng.Shuffle(len(edges), func(i, j int) { edges[i], edges[j] = edges[j], edges[i] })
+	return edges
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"learning-go.adcon.dev/approx"
+	"learning-go.adcon.dev/cli"
+)
+
+// ratioStats accumulates approximation ratios (approximate cost over optimum
+// for minimization, optimum over approximate for maximization).
+type ratioStats struct {
+	name  string
+	bound string
+	sum   float64
+	worst float64
+	n     int
+}
+
+func (s *ratioStats) add(r float64) {
+	s.sum += r
+	s.worst = max(s.worst, r)
+	s.n++
+}
+
+func (s *ratioStats) String() string {
+	if s.n == 0 { // every vertex cover instance can come out without edges
+		return fmt.Sprintf("%-26s %5d  %10s  %10s  %s", s.name, 0, "-", "-", s.bound)
+	}
+	return fmt.Sprintf("%-26s %5d  %10.4f  %10.4f  %s", s.name, s.n, s.sum/float64(s.n), s.worst, s.bound)
+}
+
+func runApprox(ctx *cli.Context) error {
+	fs := ctx.Flags
+	trials := fs.Int("trials", 50, "random instances per problem")
+	seed := fs.Uint64("seed", 1, "random seed")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	if *trials < 1 {
+		return fmt.Errorf("approx: -trials must be at least 1, got %d", *trials)
+	}
+	rng := rand.New(rand.NewPCG(*seed, *seed))
+
+	cover := &ratioStats{name: "vertex cover (2-approx)", bound: "≤ 2"}
+	tree := &ratioStats{name: "TSP MST preorder walk", bound: "≤ 2"}
+	chris := &ratioStats{name: "TSP Christofides", bound: "≤ 1.5"}
+	twoOpt := &ratioStats{name: "TSP Christofides + 2-opt", bound: "≤ 1.5"}
+	sets := &ratioStats{name: "greedy set cover", bound: "≤ H(max |S|)"}
+	sat := &ratioStats{name: "MAX-3-SAT random (1 try)", bound: "E[sat] ≥ 7/8·clauses"}
+	for range *trials {
+		n := 6 + rng.IntN(10)
+		edges := approx.RandomGraph(n, 0.3, rng)
+		exact, err := approx.ExactVertexCover(n, edges)
+		if err != nil {
+			return err
+		}
+		if len(exact) > 0 {
+			cover.add(float64(len(approx.VertexCover(n, edges))) / float64(len(exact)))
+		}
+
+		d := approx.EuclideanDist(approx.RandomPoints(5+rng.IntN(7), rng))
+		best, err := approx.ExactTSP(d)
+		if err != nil {
+			return err
+		}
+		opt := approx.TourLength(d, best)
+		tree.add(approx.TourLength(d, approx.TreeTour(d)) / opt)
+		c := approx.Christofides(d)
+		chris.add(approx.TourLength(d, c) / opt)
+		twoOpt.add(approx.TourLength(d, approx.TwoOpt(d, c)) / opt)
+
+		universe := 10 + rng.IntN(20)
+		subsets, err := approx.RandomSetCover(universe, 8+rng.IntN(8), 0.2, rng)
+		if err != nil {
+			return err
+		}
+		greedy, err := approx.GreedySetCover(universe, subsets)
+		if err != nil {
+			return err
+		}
+		exactSets, err := approx.ExactSetCover(universe, subsets)
+		if err != nil {
+			return err
+		}
+		sets.add(float64(len(greedy)) / float64(len(exactSets)))
+
+		vars := 6 + rng.IntN(10)
+		clauses, err := approx.RandomClauses(vars, 4*vars, rng)
+		if err != nil {
+			return err
+		}
+		exactSAT, err := approx.ExactMaxSAT(vars, clauses)
+		if err != nil {
+			return err
+		}
+		got := approx.Satisfied(clauses, approx.RandomAssignment(vars, clauses, 1, rng))
+		sat.add(float64(approx.Satisfied(clauses, exactSAT)) / float64(max(1, got)))
+	}
+
+	fmt.Printf("%-26s %5s  %10s  %10s  %s\n", "algorithm", "runs", "mean ratio", "worst", "bound")
+	for _, s := range []*ratioStats{cover, tree, chris, twoOpt, sets, sat} {
+		fmt.Println(s)
+	}
+	return nil
+}
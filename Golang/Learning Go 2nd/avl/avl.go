@@ -0,0 +1,253 @@
+// Package avl implements an ordered map as an AVL tree. Every node also
+// records the size of its subtree, which makes rank queries O(log n) like
+// lookups. It is the balanced-tree baseline the skip list is measured
+// against.
+package avl
+
+import (
+	"cmp"
+	"iter"
+)
+
+// Tree is an ordered map from K to V. It is not safe for concurrent use.
+type Tree[K, V any] struct {
+	cmp  func(a, b K) int
+	root *node[K, V]
+}
+
+type node[K, V any] struct {
+	key         K
+	value       V
+	left, right *node[K, V]
+	height      int8 // a leaf is 1; an AVL tree of height 127 cannot fit in memory
+	size        int
+}
+
+// New creates a tree ordered by compare.
+func New[K, V any](compare func(a, b K) int) *Tree[K, V] {
+	return &Tree[K, V]{cmp: compare}
+}
+
+// NewOrdered creates a tree over a naturally ordered key type.
+func NewOrdered[K cmp.Ordered, V any]() *Tree[K, V] {
+	return New[K, V](cmp.Compare[K])
+}
+
+func (t *Tree[K, V]) Len() int { return t.root.len() }
+
+func (n *node[K, V]) len() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *node[K, V]) h() int8 {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func (n *node[K, V]) fix() {
+	n.height = max(n.left.h(), n.right.h()) + 1
+	n.size = n.left.len() + n.right.len() + 1
+}
+
+func (n *node[K, V]) rotateRight() *node[K, V] {
+	l := n.left
+	n.left, l.right = l.right, n
+	n.fix()
+	l.fix()
+	return l
+}
+
+func (n *node[K, V]) rotateLeft() *node[K, V] {
+	r := n.right
+	n.right, r.left = r.left, n
+	n.fix()
+	r.fix()
+	return r
+}
+
+// balance restores the AVL invariant at n, whose subtrees differ in height
+// by at most two, and returns the new subtree root.
+func (n *node[K, V]) balance() *node[K, V] {
+	n.fix()
+	switch d := n.left.h() - n.right.h(); {
+	case d > 1:
+		if n.left.left.h() < n.left.right.h() {
+			n.left = n.left.rotateLeft()
+		}
+		return n.rotateRight()
+	case d < -1:
+		if n.right.right.h() < n.right.left.h() {
+			n.right = n.right.rotateRight()
+		}
+		return n.rotateLeft()
+	}
+	return n
+}
+
+// Put stores value under key and reports whether the key was new.
+func (t *Tree[K, V]) Put(key K, value V) bool {
+	var added bool
+	t.root = t.put(t.root, key, value, &added)
+	return added
+}
+
+func (t *Tree[K, V]) put(n *node[K, V], key K, value V, added *bool) *node[K, V] {
+	if n == nil {
+		*added = true
+		return &node[K, V]{key: key, value: value, height: 1, size: 1}
+	}
+	switch c := t.cmp(key, n.key); {
+	case c < 0:
+		n.left = t.put(n.left, key, value, added)
+	case c > 0:
+		n.right = t.put(n.right, key, value, added)
+	default:
+		n.value = value
+		return n
+	}
+	return n.balance()
+}
+
+func (t *Tree[K, V]) Get(key K) (V, bool) {
+	for n := t.root; n != nil; {
+		switch c := t.cmp(key, n.key); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return n.value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// Delete removes key and reports whether it was present.
+func (t *Tree[K, V]) Delete(key K) bool {
+	var removed bool
+	t.root = t.delete(t.root, key, &removed)
+	return removed
+}
+
+func (t *Tree[K, V]) delete(n *node[K, V], key K, removed *bool) *node[K, V] {
+	if n == nil {
+		return nil
+	}
+	switch c := t.cmp(key, n.key); {
+	case c < 0:
+		n.left = t.delete(n.left, key, removed)
+	case c > 0:
+		n.right = t.delete(n.right, key, removed)
+	default:
+		*removed = true
+		if n.left == nil {
+			return n.right
+		}
+		if n.right == nil {
+			return n.left
+		}
+		// Replace n with its successor, the smallest node on the right.
+		var succ *node[K, V]
+		n.right = deleteMin(n.right, &succ)
+		succ.left, succ.right = n.left, n.right
+		n = succ
+	}
+	return n.balance()
+}
+
+// deleteMin unlinks the smallest node under n into *smallest.
+func deleteMin[K, V any](n *node[K, V], smallest **node[K, V]) *node[K, V] {
+	if n.left == nil {
+		*smallest = n
+		return n.right
+	}
+	n.left = deleteMin(n.left, smallest)
+	return n.balance()
+}
+
+// Rank returns the number of keys strictly less than key.
+func (t *Tree[K, V]) Rank(key K) int {
+	rank := 0
+	for n := t.root; n != nil; {
+		if t.cmp(n.key, key) < 0 {
+			rank += n.left.len() + 1
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return rank
+}
+
+// At returns the i-th smallest entry, counting from 0.
+func (t *Tree[K, V]) At(i int) (K, V, bool) {
+	for n := t.root; n != nil; {
+		switch l := n.left.len(); {
+		case i < l:
+			n = n.left
+		case i > l:
+			i -= l + 1
+			n = n.right
+		default:
+			return n.key, n.value, true
+		}
+	}
+	var (
+		k K
+		v V
+	)
+	return k, v, false
+}
+
+// From yields the entries with key >= lo in order.
+func (t *Tree[K, V]) From(lo K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		// The stack holds the nodes >= lo whose right subtrees are still
+		// to be visited, smallest on top.
+		var stack []*node[K, V]
+		for n := t.root; n != nil; {
+			if t.cmp(n.key, lo) >= 0 {
+				stack = append(stack, n)
+				n = n.left
+			} else {
+				n = n.right
+			}
+		}
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !yield(n.key, n.value) {
+				return
+			}
+			for n = n.right; n != nil; n = n.left {
+				stack = append(stack, n)
+			}
+		}
+	}
+}
+
+// All yields every entry in order.
+func (t *Tree[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var stack []*node[K, V]
+		for n := t.root; n != nil; n = n.left {
+			stack = append(stack, n)
+		}
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !yield(n.key, n.value) {
+				return
+			}
+			for n = n.right; n != nil; n = n.left {
+				stack = append(stack, n)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package avl
+
+import (
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// TestAgainstMap runs random puts and deletes on a tree and a map side by
+// side, checking the AVL invariants and every query after each step.
+func TestAgainstMap(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 9))
+	tree := NewOrdered[int, int]()
+	want := map[int]int{}
+	for step := range 3000 {
+		k := rng.IntN(500)
+		if rng.IntN(3) == 0 {
+			_, ok := want[k]
+			if got := tree.Delete(k); got != ok {
+				t.Fatalf("step %d: Delete(%d) = %v, want %v", step, k, got, ok)
+			}
+			delete(want, k)
+		} else {
+			_, ok := want[k]
+			if got := tree.Put(k, step); got != !ok {
+				t.Fatalf("step %d: Put(%d) = %v, want %v", step, k, got, !ok)
+			}
+			want[k] = step
+		}
+		if err := check(tree.root); err != "" {
+			t.Fatalf("step %d: %s", step, err)
+		}
+	}
+
+	keys := slices.Sorted(maps.Keys(want))
+	if tree.Len() != len(keys) {
+		t.Fatalf("Len = %d, want %d", tree.Len(), len(keys))
+	}
+	var got []int
+	for k, v := range tree.All() {
+		if v != want[k] {
+			t.Errorf("All yields %d=%d, want %d", k, v, want[k])
+		}
+		got = append(got, k)
+	}
+	if !slices.Equal(got, keys) {
+		t.Fatalf("All yields %v, want %v", got, keys)
+	}
+	for i, k := range keys {
+		if r := tree.Rank(k); r != i {
+			t.Errorf("Rank(%d) = %d, want %d", k, r, i)
+		}
+		if ak, av, ok := tree.At(i); !ok || ak != k || av != want[k] {
+			t.Errorf("At(%d) = %d, %d, %v; want %d, %d", i, ak, av, ok, k, want[k])
+		}
+		if v, ok := tree.Get(k); !ok || v != want[k] {
+			t.Errorf("Get(%d) = %d, %v; want %d", k, v, ok, want[k])
+		}
+	}
+	for lo := -1; lo <= 501; lo += 50 {
+		var from []int
+		for k := range tree.From(lo) {
+			from = append(from, k)
+		}
+		i, _ := slices.BinarySearch(keys, lo)
+		if !slices.Equal(from, keys[i:]) {
+			t.Errorf("From(%d) yields %v, want %v", lo, from, keys[i:])
+		}
+	}
+	if _, _, ok := tree.At(len(keys)); ok {
+		t.Error("At past the end succeeded")
+	}
+}
+
+// check returns a description of the first broken invariant under n.
+func check[K, V any](n *node[K, V]) string {
+	if n == nil {
+		return ""
+	}
+	if err := check(n.left); err != "" {
+		return err
+	}
+	if err := check(n.right); err != "" {
+		return err
+	}
+	switch {
+	case n.height != max(n.left.h(), n.right.h())+1:
+		return "stale height"
+	case n.size != n.left.len()+n.right.len()+1:
+		return "stale size"
+	case n.left.h()-n.right.h() > 1 || n.right.h()-n.left.h() > 1:
+		return "unbalanced"
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/escape"
+)
+
+// runBench runs the ordered-map benchmarks in skiplist/bench_test.go, or with
+// -kv the kv.Store ones in kvbench_test.go, through go test -benchmem and
+// lists the results, so both have the one harness go test provides.
+func runBench(ctx *cli.Context) error {
+	fs := ctx.Flags
+	pattern := fs.String("bench", ".", "run the benchmarks matching this regexp, as go test -bench")
+	stores := fs.Bool("kv", false, "benchmark the kv.Store engines on Kid records instead of the ordered maps")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	path := "./skiplist"
+	if *stores {
+		path = "."
+	}
+	pkg, err := escape.Lookup(".", path)
+	if err != nil {
+		return err
+	}
+	benches, err := escape.RunBenchmarks(".", pkg, *pattern)
+	if err != nil {
+		return err
+	}
+	if len(benches) == 0 {
+		return fmt.Errorf("bench: no benchmark in %s matches %q", pkg.ImportPath, *pattern)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "benchmark\truns\tns/op\tB/op\tallocs/op\t")
+	for _, b := range benches {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%d\t%d\t\n", b.Name, b.N, b.NsPerOp, b.BytesPerOp, b.AllocsPerOp)
+	}
+	return tw.Flush()
+}
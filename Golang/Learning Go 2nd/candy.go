@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"learning-go.adcon.dev/lp"
+)
+
+// CandyPlan is how many candies each kid holds after a redistribution and how
+// many candies changed hands to get there.
+type CandyPlan struct {
+	Final []int
+	Moved int
+}
+
+// candyBounds returns the fair range for a kid: between minPerYear and
+// maxPerYear candies for every year of age.
+func candyBounds(k Kid, minPerYear, maxPerYear int) (int, int) {
+	return k.Age * minPerYear, k.Age * maxPerYear
+}
+
+// RedistributeCandies models the fair redistribution as a linear program:
+//
+//	minimize   sum(give_i)
+//	subject to sum(final_i) = sum(candies_i)
+//	           give_i + final_i >= candies_i
+//	           age_i*minPerYear <= final_i <= age_i*maxPerYear
+//
+// The constraint matrix is totally unimodular, so the simplex vertex is
+// integral and rounding only removes floating-point noise.
+func RedistributeCandies(kids []Kid, minPerYear, maxPerYear int) (CandyPlan, *lp.Solution, error) {
+	m := lp.NewModel(lp.Minimize)
+	total := 0
+	objective := lp.Expr{}
+	pool := lp.Expr{}
+	final := make([]lp.Var, len(kids))
+	for i, k := range kids {
+		total += k.Candies
+		final[i] = m.Var(fmt.Sprintf("final%d", i))
+		give := m.Var(fmt.Sprintf("give%d", i))
+		objective[give] = 1
+		pool[final[i]] = 1
+		lo, hi := candyBounds(k, minPerYear, maxPerYear)
+		for _, c := range []struct {
+			name string
+			expr lp.Expr
+			rel  lp.Relation
+			rhs  int
+		}{
+			{fmt.Sprintf("give%d", i), lp.Expr{give: 1, final[i]: 1}, lp.GreaterEq, k.Candies},
+			{fmt.Sprintf("min%d", i), lp.Expr{final[i]: 1}, lp.GreaterEq, lo},
+			{fmt.Sprintf("max%d", i), lp.Expr{final[i]: 1}, lp.LessEq, hi},
+		} {
+			if err := m.AddConstraint(c.name, c.expr, c.rel, float64(c.rhs)); err != nil {
+				return CandyPlan{}, nil, err
+			}
+		}
+	}
+	m.SetObjective(objective)
+	if err := m.AddConstraint("total", pool, lp.Equal, float64(total)); err != nil {
+		return CandyPlan{}, nil, err
+	}
+
+	sol, err := m.Solve()
+	if err != nil {
+		return CandyPlan{}, sol, err
+	}
+	plan := CandyPlan{Final: make([]int, len(kids))}
+	for i, k := range kids {
+		plan.Final[i] = int(math.Round(sol.Value(final[i])))
+		if d := k.Candies - plan.Final[i]; d > 0 {
+			plan.Moved += d
+		}
+	}
+	return plan, sol, nil
+}
+
+// bruteForceCandies tries every integral distribution inside the fair ranges.
+// It reports false when no distribution satisfies them.
+func bruteForceCandies(kids []Kid, minPerYear, maxPerYear int) (CandyPlan, bool) {
+	total := 0
+	for _, k := range kids {
+		total += k.Candies
+	}
+	best := CandyPlan{Moved: -1}
+	cur := make([]int, len(kids))
+	var try func(i, left, moved int)
+	try = func(i, left, moved int) {
+		if i == len(kids) {
+			if left == 0 && (best.Moved < 0 || moved < best.Moved) {
+				best = CandyPlan{Final: append([]int(nil), cur...), Moved: moved}
+			}
+			return
+		}
+		lo, hi := candyBounds(kids[i], minPerYear, maxPerYear)
+		for f := lo; f <= hi && f <= left; f++ {
+			cur[i] = f
+			try(i+1, left-f, moved+max(0, kids[i].Candies-f))
+		}
+	}
+	try(0, total, 0)
+	return best, best.Moved >= 0
+}
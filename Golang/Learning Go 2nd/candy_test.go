@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+
+	"learning-go.adcon.dev/lp"
+)
+
+func TestRedistributeCandiesMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 1))
+	for range 300 {
+		kids := make([]Kid, 2+rng.IntN(3))
+		for i := range kids {
+			kids[i] = Kid{Age: 1 + rng.IntN(6), Candies: rng.IntN(20)}
+		}
+		brute, feasible := bruteForceCandies(kids, 3, 4)
+		plan, _, err := RedistributeCandies(kids, 3, 4)
+		switch {
+		case !feasible:
+			if !errors.Is(err, lp.ErrInfeasible) {
+				t.Errorf("%+v: got %+v, %v; want lp.ErrInfeasible", kids, plan, err)
+			}
+		case err != nil:
+			t.Errorf("%+v: %v; brute force moved %d", kids, err, brute.Moved)
+		case plan.Moved != brute.Moved:
+			t.Errorf("%+v: LP moved %d (%v), brute force %d (%v)", kids, plan.Moved, plan.Final, brute.Moved, brute.Final)
+		}
+	}
+}
+
+func TestRedistributeCandiesSample(t *testing.T) {
+	kids, err := loadKids("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, _, err := RedistributeCandies(kids, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The 6-year-old has 15 candies but needs at least 18, and the
+	// 5-year-old can spare up to 5 of their 20.
+	if plan.Moved != 3 || plan.Final[1] != 18 {
+		t.Errorf("moved %d candies to reach %v, want 3 with 18 for the second kid", plan.Moved, plan.Final)
+	}
+}
@@ -0,0 +1,244 @@
+// Package carve recovers files from a raw disk image whose file system is
+// too damaged to mount. It looks for the signatures that start JPEG, PNG,
+// PDF, ZIP (including DOCX and other Office files) and MP3 files, follows
+// each format's internal structure to find where the file ends, and checks
+// the result before reporting it, so fragments that merely start like a
+// file are told apart from whole ones.
+//
+// Carving only works for files stored in one piece, which is most small
+// files on a disk that was not badly fragmented.
+package carve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"learning-go.adcon.dev/options"
+)
+
+// Kind is a file format the carver recognizes.
+type Kind int
+
+const (
+	JPEG Kind = iota
+	PNG
+	PDF
+	ZIP // also DOCX, XLSX, PPTX and ODF documents
+	MP3
+)
+
+var kindNames = [...]string{
+	JPEG: "jpeg",
+	PNG:  "png",
+	PDF:  "pdf",
+	ZIP:  "zip",
+	MP3:  "mp3",
+}
+
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// ParseKind is the inverse of Kind.String.
+func ParseKind(s string) (Kind, error) {
+	for k, name := range kindNames {
+		if name == s {
+			return Kind(k), nil
+		}
+	}
+	return 0, fmt.Errorf("carve: unknown kind %q (want one of %s)", s, strings.Join(kindNames[:], ", "))
+}
+
+// ErrTruncated means a candidate's structure ran past the end of the image
+// or Config.MaxSize.
+var ErrTruncated = errors.New("truncated")
+
+// Found is a candidate file. Err is nil when its structure and contents
+// checked out; otherwise Size covers what could be followed before the
+// problem, which may be nothing.
+type Found struct {
+	Kind   Kind
+	Ext    string // file name extension, such as "docx" for a ZIP
+	Offset int64
+	Size   int64
+	Err    error
+}
+
+// Valid reports whether the candidate passed validation.
+func (f Found) Valid() bool { return f.Err == nil }
+
+// MarshalJSON writes Kind by name and Err, if any, as its message.
+func (f Found) MarshalJSON() ([]byte, error) {
+	var msg string
+	if f.Err != nil {
+		msg = f.Err.Error()
+	}
+	return json.Marshal(struct {
+		Kind   string `json:"kind"`
+		Ext    string `json:"ext"`
+		Offset int64  `json:"offset"`
+		Size   int64  `json:"size"`
+		Err    string `json:"error,omitempty"`
+	}{f.Kind.String(), f.Ext, f.Offset, f.Size, msg})
+}
+
+// Config tunes Scan. Zero fields take the defaults in DefaultConfig.
+type Config struct {
+	Kinds   []string  `json:"kinds"`    // formats to look for, by Kind name; all when empty
+	MaxSize int64     `json:"max_size"` // largest file followed, in bytes
+	Log     io.Writer `json:"-"`        // if set, receives a line per valid file as it is found
+}
+
+// EnvPrefix starts the environment variables that set Config fields, as in
+// CARVE_MAX_SIZE.
+const EnvPrefix = "CARVE"
+
+// Option sets one Config field; see options.Apply.
+type Option = options.Option[Config]
+
+func WithKinds(kinds ...string) Option { return options.New[Config]("kinds", kinds) }
+func WithMaxSize(n int64) Option       { return options.New[Config]("max_size", n) }
+
+// DefaultConfig returns the settings used for zero Config fields.
+func DefaultConfig() Config {
+	return Config{Kinds: slices.Clone(kindNames[:]), MaxSize: 256 << 20}
+}
+
+// Resolve applies opts over c, fills in defaults and validates the result,
+// as Scan does.
+func (c Config) Resolve(opts ...Option) (Config, error) {
+	c, err := options.Apply(c, opts...)
+	if err != nil {
+		return c, fmt.Errorf("carve: %w", err)
+	}
+	d := DefaultConfig()
+	if len(c.Kinds) == 0 {
+		c.Kinds = d.Kinds
+	}
+	if c.MaxSize == 0 {
+		c.MaxSize = d.MaxSize
+	}
+	if c.MaxSize < 0 {
+		return c, fmt.Errorf("carve: max_size must be positive")
+	}
+	for _, name := range c.Kinds {
+		if _, err := ParseKind(name); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+const (
+	chunkSize = 1 << 20
+	// overlap is the most bytes a signature check looks at, so a signature
+	// across two chunks is still seen whole.
+	overlap = 16
+)
+
+// Scan looks through the size bytes of r for files of the configured kinds
+// and returns every candidate in offset order, valid or not. Once a valid
+// file is found the scan resumes after its end, so images embedded in a
+// document are not reported again. Scan only reads from r.
+//
+// A read error ends the scan. To carve a failing disk, copy it to an image
+// with a tool that skips bad sectors, such as ddrescue, and carve that.
+func Scan(r io.ReaderAt, size int64, cfg Config, opts ...Option) ([]Found, error) {
+	cfg, err := cfg.Resolve(opts...)
+	if err != nil {
+		return nil, err
+	}
+	// byLead lists the formats to try by the first byte of a candidate.
+	var byLead [256][]*format
+	for _, f := range formats {
+		if slices.Contains(cfg.Kinds, f.kind.String()) {
+			byLead[f.lead] = append(byLead[f.lead], f)
+		}
+	}
+
+	var found []Found
+	buf := make([]byte, chunkSize+overlap)
+	for off := int64(0); off < size; {
+		n, err := r.ReadAt(buf[:min(int64(len(buf)), size-off)], off)
+		if err != nil && err != io.EOF {
+			return found, fmt.Errorf("carve: read at %d: %w", off, err)
+		}
+		end := n
+		if off+int64(n) < size {
+			end = n - overlap
+		}
+		next := off + int64(end)
+	chunk:
+		for i := 0; i < end; i++ {
+			for _, f := range byLead[buf[i]] {
+				if !f.match(buf[i:n]) {
+					continue
+				}
+				c := carveAt(r, size, off+int64(i), f, cfg.MaxSize)
+				if c.Err == nil {
+					if cfg.Log != nil {
+						fmt.Fprintf(cfg.Log, "%12d  %-5s %d bytes\n", c.Offset, c.Ext, c.Size)
+					}
+					found = append(found, c)
+					next = c.Offset + c.Size
+					break chunk
+				}
+				if f.weak {
+					// A bare MP3 frame sync is two bytes; most matches
+					// are noise not worth reporting.
+					continue
+				}
+				found = append(found, c)
+			}
+		}
+		off = next
+	}
+	return found, nil
+}
+
+// carveAt follows a candidate of format f at off and validates it.
+func carveAt(r io.ReaderAt, size, off int64, f *format, maxSize int64) Found {
+	sr := io.NewSectionReader(r, off, min(maxSize, size-off))
+	n, ext, err := f.carve(sr)
+	if err == nil && f.check != nil {
+		err = f.check(io.NewSectionReader(sr, 0, n), n)
+	}
+	if ext == "" {
+		ext = f.ext
+	}
+	return Found{Kind: f.kind, Ext: ext, Offset: off, Size: n, Err: err}
+}
+
+// FileName is the name Extract gives a candidate: its offset in the image,
+// which keeps names unique and sorted like the disk.
+func (f Found) FileName() string {
+	return fmt.Sprintf("%012d.%s", f.Offset, f.Ext)
+}
+
+// Extract copies a candidate out of r into dir and returns the file's
+// path. It refuses to overwrite an existing file.
+func Extract(r io.ReaderAt, f Found, dir string) (string, error) {
+	name := filepath.Join(dir, f.FileName())
+	out, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("carve: %w", err)
+	}
+	_, err = io.Copy(out, io.NewSectionReader(r, f.Offset, f.Size))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name)
+		return "", fmt.Errorf("carve: %s: %w", name, err)
+	}
+	return name, nil
+}
@@ -0,0 +1,229 @@
+package carve
+
+import (
+	"bytes"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// scan carves image through a file in a temporary directory, the way the
+// command reads a disk image.
+func scan(t *testing.T, image []byte) []Found {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "disk.img")
+	if err := os.WriteFile(name, image, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	found, err := Scan(f, int64(len(image)), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+// samples are complete files of every kind.
+func samples(t *testing.T) map[string][]byte {
+	t.Helper()
+	jpg, pngData, err := sampleImages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zipOf(map[string]string{"notas.txt": "recordar respaldar el disco\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string][]byte{"jpg": jpg, "png": pngData, "pdf": samplePDF(), "zip": archive, "mp3": sampleMP3(true, 40)}
+}
+
+// TestSample scans the image WriteSample builds. Damaged files only need to
+// be found and rejected; how far the carver got into them does not matter.
+func TestSample(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "sample.img")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := WriteSample(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := map[int64]Found{}
+	for _, f := range scan(t, image) {
+		at[f.Offset] = f
+	}
+	for _, w := range want {
+		f, ok := at[w.Offset]
+		switch {
+		case !ok:
+			t.Errorf("%s at %d not found", w.Ext, w.Offset)
+		case f.Valid() != w.Valid():
+			t.Errorf("%s at %d: valid %v (%v), want %v", w.Ext, w.Offset, f.Valid(), f.Err, w.Valid())
+		case w.Valid() && (f.Size != w.Size || f.Ext != w.Ext):
+			t.Errorf("%s at %d: carved %d bytes as %s, want %d", w.Ext, w.Offset, f.Size, f.Ext, w.Size)
+		}
+		delete(at, w.Offset)
+	}
+	for _, f := range at {
+		t.Errorf("unexpected %s at %d: %v", f.Ext, f.Offset, f.Err)
+	}
+}
+
+// TestTruncated cuts each file short at the end of the image.
+func TestTruncated(t *testing.T) {
+	files := samples(t)
+	pdf := files["pdf"]
+	firstRevision := int64(bytes.Index(pdf, []byte("%%EOF")) + len("%%EOF\n"))
+	tests := []struct {
+		ext  string
+		cut  int
+		size int64 // of a valid shorter file, or 0 if the cut file must be rejected
+	}{
+		{"jpg", 3, 0},
+		{"jpg", len(files["jpg"]) / 2, 0},
+		{"jpg", len(files["jpg"]) - 1, 0},
+		{"png", 20, 0},
+		{"png", len(files["png"]) / 2, 0},
+		{"png", len(files["png"]) - 1, 0},
+		{"zip", len(files["zip"]) / 2, 0},
+		{"zip", len(files["zip"]) - 1, 0},
+		{"pdf", 100, 0},
+		// An incremental update cut off leaves the first revision, which is
+		// a PDF of its own.
+		{"pdf", len(pdf) - 10, firstRevision},
+		// MP3 has no end marker: the complete frames are the file.
+		{"mp3", len(files["mp3"]) - 200, int64(len(files["mp3"]) - 128 - 417)},
+	}
+	for _, tt := range tests {
+		found := scan(t, files[tt.ext][:tt.cut])
+		if len(found) == 0 || found[0].Offset != 0 {
+			t.Errorf("%s cut to %d bytes: not found (%v)", tt.ext, tt.cut, found)
+			continue
+		}
+		f := found[0]
+		switch {
+		case tt.size == 0 && f.Valid():
+			t.Errorf("%s cut to %d bytes: accepted as %d bytes", tt.ext, tt.cut, f.Size)
+		case tt.size != 0 && (!f.Valid() || f.Size != tt.size):
+			t.Errorf("%s cut to %d bytes: %d bytes, %v; want %d bytes", tt.ext, tt.cut, f.Size, f.Err, tt.size)
+		}
+		if f.Size > int64(tt.cut) {
+			t.Errorf("%s cut to %d bytes: size %d runs past the image", tt.ext, tt.cut, f.Size)
+		}
+	}
+}
+
+// TestBitFlippedPNG flips single bits past the signature, which every chunk
+// CRC must catch.
+func TestBitFlippedPNG(t *testing.T) {
+	good := samples(t)["png"]
+	rng := rand.New(rand.NewPCG(1, 22))
+	for range 50 {
+		bit := 8*8 + rng.IntN(8*(len(good)-8))
+		image := bytes.Clone(good)
+		image[bit/8] ^= 1 << (bit % 8)
+		found := scan(t, image)
+		if len(found) == 0 || found[0].Offset != 0 || found[0].Kind != PNG {
+			t.Errorf("bit %d flipped: PNG not found (%v)", bit, found)
+			continue
+		}
+		for _, f := range found {
+			if f.Valid() {
+				t.Errorf("bit %d flipped: valid %s of %d bytes at %d", bit, f.Ext, f.Size, f.Offset)
+			}
+		}
+	}
+}
+
+// TestSignatureAtEnd puts all or part of a signature in the last bytes of
+// the image.
+func TestSignatureAtEnd(t *testing.T) {
+	tests := []struct {
+		tail string
+		kind Kind // of the truncated candidate, or -1 for none
+	}{
+		{"\x89", -1},
+		{"\x89PNG\r\n\x1a", -1},
+		{"\x89PNG\r\n\x1a\n", PNG},
+		{"\xff\xd8\xff", JPEG},
+		{"PK\x03\x04", ZIP},
+		{"%PDF-1.", -1},
+		{"%PDF-1.7", PDF},
+		{"ID3", MP3},
+	}
+	for _, tt := range tests {
+		image := append(make([]byte, 1000), tt.tail...)
+		found := scan(t, image)
+		if tt.kind < 0 {
+			if len(found) > 0 {
+				t.Errorf("%q at the end: found %v", tt.tail, found)
+			}
+			continue
+		}
+		if len(found) != 1 {
+			t.Errorf("%q at the end: found %v, want one candidate", tt.tail, found)
+			continue
+		}
+		f := found[0]
+		if f.Kind != tt.kind || f.Offset != 1000 || f.Valid() {
+			t.Errorf("%q at the end: %s at %d, %v; want a rejected %s at 1000", tt.tail, f.Kind, f.Offset, f.Err, tt.kind)
+		}
+		if f.Offset+f.Size > int64(len(image)) {
+			t.Errorf("%q at the end: size %d runs past the image", tt.tail, f.Size)
+		}
+	}
+	// A truncated structure is reported as such.
+	found := scan(t, append(make([]byte, 1000), "\x89PNG\r\n\x1a\n\x00\x00"...))
+	if len(found) != 1 || !errors.Is(found[0].Err, ErrTruncated) {
+		t.Errorf("PNG cut in its first chunk: %v, want ErrTruncated", found)
+	}
+}
+
+// TestChunkBoundary places files so that their signature or their body
+// spans the end of Scan's first 1 MiB read, where the next read overlaps.
+func TestChunkBoundary(t *testing.T) {
+	files := samples(t)
+	broken := bytes.Clone(files["png"])
+	broken[len(broken)/2] ^= 1
+	tests := []struct {
+		name  string
+		data  []byte
+		off   int64
+		valid bool
+	}{
+		{"signature across the boundary", files["png"], chunkSize - 3, true},
+		{"signature in the overlap", files["jpg"], chunkSize - overlap + 1, true},
+		{"signature just before the overlap", files["zip"], chunkSize - overlap - 1, true},
+		{"signature at the boundary", files["pdf"], chunkSize, true},
+		{"body across the boundary", files["mp3"], chunkSize - int64(len(files["mp3"]))/2, true},
+		{"rejected file in the overlap", broken, chunkSize - 5, false},
+	}
+	for _, tt := range tests {
+		image := make([]byte, 2*chunkSize)
+		copy(image[tt.off:], tt.data)
+		found := scan(t, image)
+		if len(found) != 1 {
+			t.Errorf("%s: found %v, want one candidate at %d", tt.name, found, tt.off)
+			continue
+		}
+		f := found[0]
+		if f.Offset != tt.off || f.Valid() != tt.valid || tt.valid && f.Size != int64(len(tt.data)) {
+			t.Errorf("%s: %s at %d, %d bytes, %v; want %d bytes at %d", tt.name, f.Ext, f.Offset, f.Size, f.Err, len(tt.data), tt.off)
+		}
+	}
+}
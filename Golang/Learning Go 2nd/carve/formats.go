@@ -0,0 +1,468 @@
+package carve
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// format knows how to recognize, follow and check one kind of file.
+type format struct {
+	kind Kind
+	ext  string
+	lead byte // the first byte of every match
+	weak bool // matches are common by chance, so failed candidates are not reported
+	// match reports whether b, which holds up to overlap bytes or the rest
+	// of the image, starts like a file of this kind.
+	match func(b []byte) bool
+	// carve follows the structure from the start of r and returns the
+	// file's size, and a more specific extension if it has one.
+	carve func(r *io.SectionReader) (int64, string, error)
+	// check validates the contents of the carved file, if the structure
+	// alone is not enough.
+	check func(r io.ReaderAt, size int64) error
+}
+
+var formats = []*format{
+	{kind: JPEG, ext: "jpg", lead: 0xff, match: prefix("\xff\xd8\xff"), carve: carveJPEG, check: func(r io.ReaderAt, size int64) error {
+		_, err := jpeg.Decode(io.NewSectionReader(r, 0, size))
+		return err
+	}},
+	{kind: PNG, ext: "png", lead: 0x89, match: prefix("\x89PNG\r\n\x1a\n"), carve: carvePNG, check: func(r io.ReaderAt, size int64) error {
+		_, err := png.Decode(io.NewSectionReader(r, 0, size))
+		return err
+	}},
+	{kind: PDF, ext: "pdf", lead: '%', match: matchPDF, carve: carvePDF},
+	{kind: ZIP, ext: "zip", lead: 'P', match: prefix("PK\x03\x04"), carve: carveZIP},
+	{kind: MP3, ext: "mp3", lead: 'I', match: prefix("ID3"), carve: carveMP3},
+	{kind: MP3, ext: "mp3", lead: 0xff, weak: true, match: matchFrame, carve: carveMP3},
+}
+
+func prefix(sig string) func([]byte) bool {
+	return func(b []byte) bool { return bytes.HasPrefix(b, []byte(sig)) }
+}
+
+// cursor reads a candidate from its first byte and keeps count of where it
+// is. Running out of input is ErrTruncated.
+type cursor struct {
+	br  *bufio.Reader
+	pos int64
+}
+
+func newCursor(r io.Reader) *cursor { return &cursor{br: bufio.NewReader(r)} }
+
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
+
+func (c *cursor) readByte() (byte, error) {
+	b, err := c.br.ReadByte()
+	if err != nil {
+		return 0, truncated(err)
+	}
+	c.pos++
+	return b, nil
+}
+
+func (c *cursor) read(p []byte) error {
+	n, err := io.ReadFull(c.br, p)
+	c.pos += int64(n)
+	return truncated(err)
+}
+
+func (c *cursor) skip(n int64) error {
+	m, err := io.CopyN(io.Discard, c.br, n)
+	c.pos += m
+	return truncated(err)
+}
+
+// carveJPEG walks the marker segments up to the start of scan, then the
+// entropy-coded data, in which 0xFF is always followed by 0x00, a restart
+// marker or the next marker, up to the end-of-image marker.
+func carveJPEG(r *io.SectionReader) (int64, string, error) {
+	c := newCursor(r)
+	if err := c.skip(2); err != nil { // SOI
+		return c.pos, "", err
+	}
+	frame := false
+	var m byte
+	pending := false // m was read at the end of entropy-coded data
+	for {
+		if !pending {
+			b, err := c.readByte()
+			if err != nil {
+				return c.pos, "", err
+			}
+			if b != 0xff {
+				return c.pos, "", fmt.Errorf("jpeg: no marker at %d", c.pos-1)
+			}
+			if m, err = c.marker(); err != nil {
+				return c.pos, "", err
+			}
+		}
+		pending = false
+		switch {
+		case m == 0xd9: // EOI
+			if !frame {
+				return c.pos, "", errors.New("jpeg: no frame header")
+			}
+			return c.pos, "", nil
+		case m >= 0xd0 && m <= 0xd7, m == 0x01: // no payload
+			continue
+		case m == 0x00 || m == 0xd8:
+			return c.pos, "", fmt.Errorf("jpeg: bad marker %#x at %d", m, c.pos-2)
+		}
+		var l [2]byte
+		if err := c.read(l[:]); err != nil {
+			return c.pos, "", err
+		}
+		n := int64(binary.BigEndian.Uint16(l[:]))
+		if n < 2 {
+			return c.pos, "", fmt.Errorf("jpeg: bad segment length at %d", c.pos-2)
+		}
+		if err := c.skip(n - 2); err != nil {
+			return c.pos, "", err
+		}
+		if m >= 0xc0 && m <= 0xcf && m != 0xc4 && m != 0xc8 && m != 0xcc {
+			frame = true // SOFn
+		}
+		if m != 0xda { // SOS
+			continue
+		}
+		for !pending {
+			b, err := c.readByte()
+			if err != nil {
+				return c.pos, "", err
+			}
+			if b != 0xff {
+				continue
+			}
+			if m, err = c.marker(); err != nil {
+				return c.pos, "", err
+			}
+			pending = m != 0x00 && (m < 0xd0 || m > 0xd7)
+		}
+	}
+}
+
+// marker reads the byte after 0xFF, skipping fill bytes.
+func (c *cursor) marker() (byte, error) {
+	m, err := c.readByte()
+	for err == nil && m == 0xff {
+		m, err = c.readByte()
+	}
+	return m, err
+}
+
+// carvePNG reads chunks, checking each CRC, from IHDR to IEND.
+func carvePNG(r *io.SectionReader) (int64, string, error) {
+	c := newCursor(r)
+	if err := c.skip(8); err != nil {
+		return c.pos, "", err
+	}
+	crc := crc32.NewIEEE()
+	for first := true; ; first = false {
+		var hdr [8]byte
+		if err := c.read(hdr[:]); err != nil {
+			return c.pos, "", err
+		}
+		n := binary.BigEndian.Uint32(hdr[:4])
+		typ := string(hdr[4:])
+		if n > 1<<31-1 {
+			return c.pos, "", fmt.Errorf("png: bad length for chunk %q", typ)
+		}
+		if first && typ != "IHDR" {
+			return c.pos, "", fmt.Errorf("png: first chunk is %q, not IHDR", typ)
+		}
+		crc.Reset()
+		crc.Write(hdr[4:])
+		m, err := io.CopyN(crc, c.br, int64(n))
+		c.pos += m
+		if err != nil {
+			return c.pos, "", truncated(err)
+		}
+		var sum [4]byte
+		if err := c.read(sum[:]); err != nil {
+			return c.pos, "", err
+		}
+		if binary.BigEndian.Uint32(sum[:]) != crc.Sum32() {
+			return c.pos, "", fmt.Errorf("png: bad CRC in chunk %q", typ)
+		}
+		if typ == "IEND" {
+			return c.pos, "", nil
+		}
+	}
+}
+
+func matchPDF(b []byte) bool {
+	// %PDF-1.7 or %PDF-2.0
+	return len(b) >= 8 && bytes.HasPrefix(b, []byte("%PDF-")) && isDigit(b[5]) && b[6] == '.' && isDigit(b[7])
+}
+
+func isDigit(b byte) bool { return '0' <= b && b <= '9' }
+
+var (
+	startxref = regexp.MustCompile(`startxref\s+(\d+)\s*$`)
+	xrefAt    = regexp.MustCompile(`^(xref|\d+\s+\d+\s+obj)`)
+)
+
+// carvePDF ends a PDF at the last %%EOF, before any next PDF header, whose
+// startxref points at a cross-reference table or stream inside the file.
+// Files saved with incremental updates have one %%EOF per update, and the
+// last one is the end of the file.
+func carvePDF(r *io.SectionReader) (int64, string, error) {
+	limit := r.Size()
+	if err := find(r, 1, []byte("%PDF-"), func(off int64) bool {
+		limit = off
+		return false
+	}); err != nil {
+		return 0, "", err
+	}
+	var end int64
+	var last error = ErrTruncated
+	err := find(io.NewSectionReader(r, 0, limit), 0, []byte("%%EOF"), func(off int64) bool {
+		tail := make([]byte, min(off, 1024))
+		if _, err := r.ReadAt(tail, off-int64(len(tail))); err != nil {
+			last = err
+			return true
+		}
+		m := startxref.FindSubmatch(tail)
+		if m == nil {
+			last = fmt.Errorf("pdf: no startxref before %%%%EOF at %d", off)
+			return true
+		}
+		x, err := strconv.ParseInt(string(m[1]), 10, 64)
+		if err != nil || x >= off {
+			last = fmt.Errorf("pdf: startxref %s outside the file", m[1])
+			return true
+		}
+		head := make([]byte, 32)
+		n, _ := r.ReadAt(head, x)
+		if !xrefAt.Match(head[:n]) {
+			last = fmt.Errorf("pdf: no cross-reference at startxref %d", x)
+			return true
+		}
+		end, last = off+5, nil
+		return true
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	if end == 0 {
+		return 0, "", last
+	}
+	// Keep the line ending after %%EOF.
+	eol := make([]byte, 2)
+	n, _ := r.ReadAt(eol, end)
+	switch {
+	case n == 2 && string(eol) == "\r\n":
+		end += 2
+	case n >= 1 && (eol[0] == '\n' || eol[0] == '\r'):
+		end++
+	}
+	return end, "", nil
+}
+
+// carveZIP looks for the end of central directory record that points back
+// at a central directory ending right before it, which is where a ZIP file
+// written from its first byte ends. The archive is then opened and every
+// entry read, which checks their CRCs. Office documents are told apart by
+// the entries they contain.
+func carveZIP(r *io.SectionReader) (int64, string, error) {
+	var size int64
+	err := find(r, 4, []byte("PK\x05\x06"), func(off int64) bool {
+		var eocd [22]byte
+		if n, _ := r.ReadAt(eocd[:], off); n < len(eocd) {
+			return false
+		}
+		cdSize := int64(binary.LittleEndian.Uint32(eocd[12:]))
+		cdOffset := int64(binary.LittleEndian.Uint32(eocd[16:]))
+		comment := int64(binary.LittleEndian.Uint16(eocd[20:]))
+		if cdOffset+cdSize != off || off+22+comment > r.Size() {
+			return true // another archive's, or a stray signature
+		}
+		size = off + 22 + comment
+		return false
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	if size == 0 {
+		return 0, "", fmt.Errorf("zip: %w end of central directory", ErrTruncated)
+	}
+	zr, err := zip.NewReader(io.NewSectionReader(r, 0, size), size)
+	if err != nil {
+		return size, "", fmt.Errorf("zip: %w", err)
+	}
+	ext := "zip"
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			ext = "docx"
+		case "xl/workbook.xml":
+			ext = "xlsx"
+		case "ppt/presentation.xml":
+			ext = "pptx"
+		case "mimetype":
+			if mt, err := readSmall(f); err == nil {
+				switch string(mt) {
+				case "application/vnd.oasis.opendocument.text":
+					ext = "odt"
+				case "application/vnd.oasis.opendocument.spreadsheet":
+					ext = "ods"
+				}
+			}
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return size, ext, fmt.Errorf("zip: %s: %w", f.Name, err)
+		}
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		if err != nil {
+			return size, ext, fmt.Errorf("zip: %s: %w", f.Name, err)
+		}
+	}
+	return size, ext, nil
+}
+
+func readSmall(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, 128))
+}
+
+// find calls fn with the offset of each occurrence of sep in r at or after
+// from, in order, until fn returns false.
+func find(r *io.SectionReader, from int64, sep []byte, fn func(off int64) bool) error {
+	buf := make([]byte, 64<<10)
+	for off := from; off < r.Size(); {
+		n, err := r.ReadAt(buf, off)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		b := buf[:n]
+		for i := 0; ; {
+			j := bytes.Index(b[i:], sep)
+			if j < 0 {
+				break
+			}
+			if !fn(off + int64(i+j)) {
+				return nil
+			}
+			i += j + 1
+		}
+		if err == io.EOF {
+			return nil
+		}
+		off += int64(n - len(sep) + 1)
+	}
+	return nil
+}
+
+// An MPEG audio frame header: 11 sync bits, then version, layer, bit rate,
+// sample rate and padding. Only Layer III is MP3.
+var (
+	bitRates = [2][15]int{ // kbit/s, by MPEG-1 or not and index
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	}
+	sampleRates = [4][3]int{ // by version bits and index
+		{11025, 12000, 8000},  // MPEG-2.5
+		{},                    // reserved
+		{22050, 24000, 16000}, // MPEG-2
+		{44100, 48000, 32000}, // MPEG-1
+	}
+)
+
+// frameLen returns the length of the Layer III frame whose header starts
+// b, and a key that frames of the same stream share.
+func frameLen(b []byte) (n int, stream byte, ok bool) {
+	if len(b) < 4 || b[0] != 0xff || b[1]&0xe0 != 0xe0 {
+		return 0, 0, false
+	}
+	version := b[1] >> 3 & 3
+	layer := b[1] >> 1 & 3
+	rate := int(b[2] >> 4)
+	sr := b[2] >> 2 & 3
+	if version == 1 || layer != 1 || rate == 0 || rate == 15 || sr == 3 {
+		return 0, 0, false
+	}
+	pad := int(b[2] >> 1 & 1)
+	if version == 3 {
+		n = 144 * bitRates[0][rate] * 1000 / sampleRates[version][sr]
+	} else {
+		n = 72 * bitRates[1][rate] * 1000 / sampleRates[version][sr]
+	}
+	return n + pad, version<<2 | sr, true
+}
+
+func matchFrame(b []byte) bool {
+	_, _, ok := frameLen(b)
+	return ok
+}
+
+// Frames needed to believe in an MP3: a bare frame header is only two
+// bytes of signature, so those need a long run of frames.
+const (
+	minFramesID3  = 4
+	minFramesBare = 32
+)
+
+// carveMP3 skips an ID3v2 tag if there is one, then follows frames of the
+// same stream for as long as they last, plus an ID3v1 tag after them. It
+// reads only the headers, since bare frame syncs are tried often.
+func carveMP3(r *io.SectionReader) (int64, string, error) {
+	minFrames := minFramesBare
+	var pos int64
+	var hdr [10]byte
+	if n, _ := r.ReadAt(hdr[:], 0); n == len(hdr) && string(hdr[:3]) == "ID3" {
+		if hdr[3] < 2 || hdr[3] > 4 || hdr[6]|hdr[7]|hdr[8]|hdr[9] >= 0x80 {
+			return 0, "", errors.New("mp3: bad ID3v2 header")
+		}
+		pos = 10 + (int64(hdr[6])<<21 | int64(hdr[7])<<14 | int64(hdr[8])<<7 | int64(hdr[9]))
+		if hdr[5]&0x10 != 0 { // footer
+			pos += 10
+		}
+		minFrames = minFramesID3
+	}
+	var frames int
+	var stream byte
+	for {
+		if n, _ := r.ReadAt(hdr[:4], pos); n < 4 {
+			break
+		}
+		n, s, ok := frameLen(hdr[:4])
+		if !ok || frames > 0 && s != stream || pos+int64(n) > r.Size() {
+			break // a cut-off last frame is dropped
+		}
+		stream = s
+		pos += int64(n)
+		frames++
+	}
+	if frames < minFrames {
+		if pos >= r.Size() {
+			return pos, "", fmt.Errorf("mp3: %w after %d frames", ErrTruncated, frames)
+		}
+		return pos, "", fmt.Errorf("mp3: only %d frames", frames)
+	}
+	if n, _ := r.ReadAt(hdr[:3], pos); n == 3 && string(hdr[:3]) == "TAG" && pos+128 <= r.Size() {
+		pos += 128
+	}
+	return pos, "", nil
+}
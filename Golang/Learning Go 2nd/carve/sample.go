@@ -0,0 +1,182 @@
+package carve
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math/rand/v2"
+)
+
+// sector is the alignment of files in the sample image, as on a real disk.
+const sector = 512
+
+// WriteSample writes a small synthetic disk image to w: one file of each
+// kind between runs of random bytes, plus a truncated JPEG and a PNG with a
+// flipped bit, which a carver should report but not trust. It returns what
+// a perfect scan would find, with Err set on the damaged files.
+func WriteSample(w io.Writer) ([]Found, error) {
+	rng := rand.New(rand.NewPCG(6, 1))
+	jpg, pngData, err := sampleImages()
+	if err != nil {
+		return nil, err
+	}
+	docx, err := zipOf(map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`,
+		"word/document.xml":   `<?xml version="1.0"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p><w:r><w:t>Fotos de la boda</w:t></w:r></w:p></w:body></w:document>`,
+	})
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zipOf(map[string]string{"notas.txt": "recordar respaldar el disco\n"})
+	if err != nil {
+		return nil, err
+	}
+	brokenPNG := bytes.Clone(pngData)
+	brokenPNG[len(brokenPNG)/2] ^= 0x10
+
+	files := []struct {
+		kind Kind
+		ext  string
+		data []byte
+		err  error
+	}{
+		{JPEG, "jpg", jpg, nil},
+		{PNG, "png", pngData, nil},
+		{PDF, "pdf", samplePDF(), nil},
+		{ZIP, "docx", docx, nil},
+		{MP3, "mp3", sampleMP3(true, 40), nil},
+		{ZIP, "zip", archive, nil},
+		{JPEG, "jpg", jpg[:len(jpg)/2], errors.New("cut in half")},
+		{PNG, "png", brokenPNG, errors.New("bit flipped")},
+		{MP3, "mp3", sampleMP3(false, 64), nil},
+	}
+	var want []Found
+	var off int64
+	for _, f := range files {
+		// Random bytes up to the next sector boundary and a few sectors
+		// more, like the unused space between files.
+		gap := (sector-off%sector)%sector + sector*int64(1+rng.IntN(4))
+		if err := writeRandom(w, rng, gap); err != nil {
+			return nil, err
+		}
+		off += gap
+		if _, err := w.Write(f.data); err != nil {
+			return nil, err
+		}
+		want = append(want, Found{Kind: f.kind, Ext: f.ext, Offset: off, Size: int64(len(f.data)), Err: f.err})
+		off += int64(len(f.data))
+	}
+	return want, writeRandom(w, rng, (sector-off%sector)%sector+sector)
+}
+
+// sampleImages encodes one small gradient as a JPEG and as a PNG.
+func sampleImages() (jpg, pngData []byte, err error) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := range 48 {
+		for x := range 64 {
+			img.Set(x, y, color.RGBA{uint8(4 * x), uint8(5 * y), 128, 255})
+		}
+	}
+	var jb, pb bytes.Buffer
+	if err := jpeg.Encode(&jb, img, nil); err != nil {
+		return nil, nil, err
+	}
+	if err := png.Encode(&pb, img); err != nil {
+		return nil, nil, err
+	}
+	return jb.Bytes(), pb.Bytes(), nil
+}
+
+func writeRandom(w io.Writer, rng *rand.Rand, n int64) error {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(rng.Uint32())
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func zipOf(files map[string]string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"[Content_Types].xml", "word/document.xml", "notas.txt"} {
+		body, ok := files[name]
+		if !ok {
+			continue
+		}
+		f, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		io.WriteString(f, body)
+	}
+	err := zw.Close()
+	return buf.Bytes(), err
+}
+
+// samplePDF builds a one-page PDF, saved once more with an incremental
+// update, so it has two %%EOF markers.
+func samplePDF() []byte {
+	var b bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	obj("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 100] /Contents 4 0 R >>")
+	content := "BT /F1 12 Tf 20 50 Td (Hola) Tj ET"
+	obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	// The update replaces the page contents.
+	update := b.Len()
+	content = "BT /F1 12 Tf 20 50 Td (Hola otra vez) Tj ET"
+	fmt.Fprintf(&b, "4 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(content), content)
+	xref2 := b.Len()
+	fmt.Fprintf(&b, "xref\n0 1\n0000000000 65535 f \n4 1\n%010d 00000 n \n", update)
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Prev %d >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref, xref2)
+	return b.Bytes()
+}
+
+// sampleMP3 builds n silent MPEG-1 Layer III frames at 128 kbit/s and
+// 44.1 kHz, optionally wrapped in ID3v2 and ID3v1 tags.
+func sampleMP3(tags bool, n int) []byte {
+	var b bytes.Buffer
+	if tags {
+		frame := append([]byte("TIT2\x00\x00\x00\x0b\x00\x00\x00"), "Cancion 1\x00"...)
+		frame[7] = byte(len(frame) - 10)
+		b.WriteString("ID3\x03\x00\x00\x00\x00\x00")
+		b.WriteByte(byte(len(frame)))
+		b.Write(frame)
+	}
+	for i := range n {
+		hdr := []byte{0xff, 0xfb, 0x90, 0x00}
+		size := 417
+		if i%3 == 2 { // padding keeps the average bit rate exact
+			hdr[2] |= 0x02
+			size++
+		}
+		b.Write(hdr)
+		b.Write(make([]byte, size-len(hdr)))
+	}
+	if tags {
+		tag := make([]byte, 128)
+		copy(tag, "TAGCancion 1")
+		b.Write(tag)
+	}
+	return b.Bytes()
+}
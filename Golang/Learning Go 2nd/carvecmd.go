@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"learning-go.adcon.dev/carve"
+	"learning-go.adcon.dev/cli"
+)
+
+func runCarve(ctx *cli.Context) error {
+	fs := ctx.Flags
+	out := fs.String("o", "", "extract the valid files into this directory")
+	kinds := fs.String("kinds", "", "comma-separated kinds to look for: jpeg, png, pdf, zip, mp3 (default all)")
+	maxSize := fs.Int64("max-size", 0, "largest file to follow, in bytes (default 256 MiB)")
+	all := fs.Bool("all", false, "also list candidates that failed validation")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	var opts []carve.Option
+	if *kinds != "" {
+		opts = append(opts, carve.WithKinds(strings.Split(*kinds, ",")...))
+	}
+	if *maxSize != 0 {
+		opts = append(opts, carve.WithMaxSize(*maxSize))
+	}
+
+	name := fs.Arg(0)
+	if name == "" {
+		tmp, err := os.CreateTemp("", "carve-sample-*.img")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		_, err = carve.WriteSample(tmp)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		name = tmp.Name()
+		*all = true
+	}
+
+	img, err := os.Open(name) // read-only, which is all a damaged disk should see
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+	// Block devices report size 0 to Stat, but can seek to their end.
+	size, err := img.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	found, err := carve.Scan(img, size, carve.Config{Log: ctx.Log()}, opts...)
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		if err := os.MkdirAll(*out, 0o755); err != nil {
+			return err
+		}
+	}
+	var shown []carve.Found
+	var paths []string
+	for _, f := range found {
+		if !f.Valid() && !*all {
+			continue
+		}
+		shown = append(shown, f)
+		path := ""
+		if f.Valid() && *out != "" {
+			if path, err = carve.Extract(img, f, *out); err != nil {
+				return err
+			}
+		}
+		paths = append(paths, path)
+	}
+	valid := 0
+	for _, f := range found {
+		if f.Valid() {
+			valid++
+		}
+	}
+
+	if ctx.JSON() {
+		type file struct {
+			Found carve.Found `json:"found"`
+			Path  string      `json:"path,omitempty"` // where -o extracted it
+		}
+		files := make([]file, len(shown))
+		for i, f := range shown {
+			files[i] = file{f, paths[i]}
+		}
+		return ctx.Encode(struct {
+			Size     int64  `json:"size"`
+			Valid    int    `json:"valid"`
+			Rejected int    `json:"rejected"`
+			Files    []file `json:"files"`
+		}{size, valid, len(found) - valid, files})
+	}
+	fmt.Printf("%12s  %10s  %10s  %-5s %s\n", "offset", "hex", "size", "type", "status")
+	for i, f := range shown {
+		status := "ok"
+		switch {
+		case !f.Valid():
+			status = f.Err.Error()
+		case paths[i] != "":
+			status = paths[i]
+		}
+		fmt.Printf("%12d  %#10x  %10d  %-5s %s\n", f.Offset, f.Offset, f.Size, f.Ext, status)
+	}
+	fmt.Printf("%d valid files in %d bytes, %d rejected candidates\n", valid, size, len(found)-valid)
+	return nil
+}
@@ -0,0 +1,436 @@
+// Package cli runs a tree of subcommands built on the flag package. Each
+// command declares its flags on the FlagSet in its Context and calls Parse,
+// exactly as it would with its own flag.NewFlagSet, and the package adds
+// what a single FlagSet lacks: nested commands, per-command help, global
+// flags, defaults from environment variables and a config file, and shell
+// completion.
+//
+// Commands must not do anything before calling Parse. To list a command's
+// flags for help and completion, the package runs it with a Context whose
+// Parse returns an error right away.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Command is a node in the command tree. A command either runs, or groups
+// other commands under its name; the root of the tree only groups.
+type Command struct {
+	Name  string
+	Args  string // what follows the flags in the usage line, e.g. "src dst"
+	Short string // one line for command lists
+	Long  string // paragraphs for the command's help
+	// Run declares the command's flags on ctx.Flags, calls ctx.Parse and
+	// then does the work, with the arguments left in ctx.Flags.Args().
+	Run      func(ctx *Context) error
+	Commands []*Command
+	// EnvPrefix starts the environment variables that set the command's
+	// flags, so -max-size is read from PREFIX_MAX_SIZE. By default it is
+	// the program and command names, as in LEARNING_GO_KIDS_ANALYZE.
+	EnvPrefix string
+	JSON      bool                         // the command honors --output json
+	Complete  func(args []string) []string // candidates for the next argument
+	Hidden    bool                         // left out of lists and completion
+}
+
+func (c *Command) lookup(name string) *Command {
+	for _, sub := range c.Commands {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+func (c *Command) names() []string {
+	var names []string
+	for _, sub := range c.Commands {
+		if !sub.Hidden {
+			names = append(names, sub.Name)
+		}
+	}
+	return names
+}
+
+// App is a program made of commands.
+type App struct {
+	Name string   // the program name, as typed by users
+	Root *Command // Root.Long describes the program
+	// ConfigFile holds flag defaults when neither --config nor
+	// $PROGRAM_CONFIG name another file. It may be missing.
+	ConfigFile string
+
+	Stdout, Stderr io.Writer                   // os.Stdout and os.Stderr if nil
+	LookupEnv      func(string) (string, bool) // os.LookupEnv if nil
+}
+
+// Output formats for --output.
+const (
+	Table = "table"
+	JSON  = "json"
+)
+
+// globals are the flags every command accepts, before or after its name.
+type globals struct {
+	output  string
+	verbose bool
+	config  string
+}
+
+var globalNames = []string{"config", "output", "verbose"}
+
+func (g *globals) define(fs *flag.FlagSet) {
+	fs.StringVar(&g.config, "config", g.config, "read flag defaults from this JSON `file`")
+	fs.Var(&choice{&g.output, []string{Table, JSON}}, "output", "print results as a `table` or as json")
+	fs.BoolVar(&g.verbose, "verbose", g.verbose, "report progress on stderr")
+}
+
+// Run runs the command named by args, which exclude the program name.
+// Without a command it prints the program's help. A request for help with
+// -h returns flag.ErrHelp after printing it.
+func (a *App) Run(args []string) error {
+	if a.Stdout == nil {
+		a.Stdout = os.Stdout
+	}
+	if a.Stderr == nil {
+		a.Stderr = os.Stderr
+	}
+	if a.LookupEnv == nil {
+		a.LookupEnv = os.LookupEnv
+	}
+	root := a.root()
+	if len(args) > 0 && args[0] == completeCommand {
+		for _, c := range a.complete(args[1:]) {
+			fmt.Fprintln(a.Stdout, c)
+		}
+		return nil
+	}
+
+	g := &globals{output: Table}
+	fs := flag.NewFlagSet(a.Name, flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	g.define(fs)
+	fs.Usage = func() { a.help(a.Stderr, []*Command{root}) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if fs.NArg() == 0 {
+		a.help(a.Stdout, []*Command{root})
+		return nil
+	}
+	path, rest, err := a.resolve(fs.Args())
+	if err != nil {
+		return err
+	}
+	cmd := path[len(path)-1]
+	if cmd.Run == nil {
+		a.help(a.Stderr, path)
+		return fmt.Errorf("%s: missing command (available: %s)", a.pathName(path), strings.Join(cmd.names(), ", "))
+	}
+	return cmd.Run(a.newContext(path, rest, g, set))
+}
+
+// root returns the root command with the built-in commands added.
+func (a *App) root() *Command {
+	root := a.Root
+	if root.lookup("help") == nil {
+		root.Commands = append(root.Commands, a.helpCommand(), a.completionCommand())
+	}
+	return root
+}
+
+// resolve follows command names from the start of args.
+func (a *App) resolve(args []string) (path []*Command, rest []string, err error) {
+	cmd := a.root()
+	path = []*Command{cmd}
+	for len(args) > 0 && len(cmd.Commands) > 0 {
+		sub := cmd.lookup(args[0])
+		if sub == nil {
+			kind := "command"
+			if len(path) > 1 {
+				kind = a.pathName(path)[len(a.Name)+1:] + " command"
+			}
+			return nil, nil, fmt.Errorf("unknown %s %q (available: %s)", kind, args[0], strings.Join(cmd.names(), ", "))
+		}
+		cmd = sub
+		path = append(path, cmd)
+		args = args[1:]
+	}
+	return path, args, nil
+}
+
+func (a *App) pathName(path []*Command) string {
+	names := []string{a.Name}
+	for _, c := range path[1:] {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+// envPrefix returns the prefix of the variables for the command at the end
+// of path, or for the global flags if path is only the root.
+func (a *App) envPrefix(path []*Command) string {
+	if p := path[len(path)-1].EnvPrefix; p != "" {
+		return p
+	}
+	return strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToUpper(a.pathName(path)))
+}
+
+func envName(prefix, flag string) string {
+	return prefix + "_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// Context is what a command runs with.
+type Context struct {
+	Flags   *flag.FlagSet
+	Output  string // Table or JSON, set by Parse
+	Verbose bool   // set by Parse
+	Stdout  io.Writer
+	Stderr  io.Writer
+
+	app      *App
+	path     []*Command
+	args     []string
+	globals  *globals
+	set      map[string]bool // flags given on the command line
+	describe bool
+}
+
+// errDescribe stops a command at Parse when only its flags are wanted.
+var errDescribe = errors.New("cli: describing flags")
+
+func (a *App) newContext(path []*Command, args []string, g *globals, set map[string]bool) *Context {
+	fs := flag.NewFlagSet(a.pathName(path), flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	g.define(fs)
+	fs.Usage = func() { a.help(a.Stderr, path) }
+	return &Context{Flags: fs, Stdout: a.Stdout, Stderr: a.Stderr, app: a, path: path, args: args, globals: g, set: set}
+}
+
+// flagsOf returns the FlagSet of the command at the end of path with its
+// flags declared but not parsed.
+func (a *App) flagsOf(path []*Command) *flag.FlagSet {
+	ctx := a.newContext(path, nil, &globals{output: Table}, nil)
+	ctx.describe = true
+	if cmd := path[len(path)-1]; cmd.Run != nil {
+		cmd.Run(ctx)
+	}
+	return ctx.Flags
+}
+
+// Parse parses the command line, then sets each flag it did not mention
+// from its environment variable or, failing that, from the config file.
+func (c *Context) Parse() error {
+	if c.describe {
+		return errDescribe
+	}
+	if err := c.Flags.Parse(c.args); err != nil {
+		return err
+	}
+	c.Flags.Visit(func(f *flag.Flag) { c.set[f.Name] = true })
+	if err := c.app.bind(c); err != nil {
+		return err
+	}
+	c.Output, c.Verbose = c.globals.output, c.globals.verbose
+	if cmd := c.path[len(c.path)-1]; c.Output == JSON && !cmd.JSON {
+		return fmt.Errorf("%s: --output json is not supported", c.Flags.Name())
+	}
+	return nil
+}
+
+// JSON reports whether results should be printed with Encode.
+func (c *Context) JSON() bool { return c.Output == JSON }
+
+// Encode prints v to Stdout as indented JSON.
+func (c *Context) Encode(v any) error {
+	enc := json.NewEncoder(c.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Log returns Stderr with --verbose and nil otherwise, to pass to the Log
+// field of the Config structs in this module.
+func (c *Context) Log() io.Writer {
+	if c.Verbose {
+		return c.Stderr
+	}
+	return nil
+}
+
+// bind fills in the flags not given on the command line.
+func (a *App) bind(c *Context) error {
+	name, explicit := c.globals.config, c.set["config"]
+	if !explicit {
+		if v, ok := a.LookupEnv(envName(a.envPrefix(c.path[:1]), "config")); ok {
+			name, explicit = v, true
+		} else {
+			name = a.ConfigFile
+		}
+	}
+	file, err := a.loadConfig(name, explicit)
+	if err != nil {
+		return err
+	}
+	section, err := a.configSection(file, name, c)
+	if err != nil {
+		return err
+	}
+
+	prefix, globalPrefix := a.envPrefix(c.path), a.envPrefix(c.path[:1])
+	var errs []error
+	c.Flags.VisitAll(func(f *flag.Flag) {
+		if c.set[f.Name] || f.Name == "config" {
+			return
+		}
+		env, values := envName(prefix, f.Name), section
+		if slices.Contains(globalNames, f.Name) {
+			env, values = envName(globalPrefix, f.Name), file
+		}
+		if s, ok := a.LookupEnv(env); ok {
+			if err := c.Flags.Set(f.Name, s); err != nil {
+				errs = append(errs, fmt.Errorf("$%s: %w", env, err))
+			}
+			return
+		}
+		if v, ok := values[f.Name]; ok {
+			s, err := configValue(v)
+			if err == nil {
+				err = c.Flags.Set(f.Name, s)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", name, f.Name, err))
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// loadConfig reads a config file: a JSON object holding the global flags
+// and an object per command, nested like the commands, holding its flags:
+//
+//	{"verbose": true, "rescue": {"workers": 1}, "kids": {"analyze": {"k": 3}}}
+//
+// A missing file is only an error if it was asked for.
+func (a *App) loadConfig(name string, explicit bool) (map[string]any, error) {
+	if name == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file map[string]any
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return file, nil
+}
+
+// configSection returns the part of a config file for the command in c,
+// rejecting keys that name neither a flag nor a command on the way, so a
+// typo does not silently leave a default in place.
+func (a *App) configSection(file map[string]any, name string, c *Context) (map[string]any, error) {
+	section := file
+	for i, cmd := range c.path {
+		var known []string
+		if i == 0 {
+			known = append(known, globalNames...)
+		}
+		known = append(known, cmd.names()...)
+		if i == len(c.path)-1 {
+			c.Flags.VisitAll(func(f *flag.Flag) {
+				if !slices.Contains(globalNames, f.Name) {
+					known = append(known, f.Name)
+				}
+			})
+		}
+		for key := range section {
+			if !slices.Contains(known, key) {
+				slices.Sort(known)
+				return nil, fmt.Errorf("%s: unknown setting %q for %s (known: %s)", name, key, a.pathName(c.path[:i+1]), strings.Join(known, ", "))
+			}
+		}
+		if i == len(c.path)-1 {
+			break
+		}
+		next, ok := section[c.path[i+1].Name]
+		if !ok {
+			return nil, nil
+		}
+		if section, ok = next.(map[string]any); !ok {
+			return nil, fmt.Errorf("%s: %s: want an object of settings", name, c.path[i+1].Name)
+		}
+	}
+	return section, nil
+}
+
+// configValue turns a JSON value into the string a flag would be given.
+// Lists become comma-separated.
+func configValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := configValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("want a string, number, boolean or list, not %T", v)
+}
+
+// choice is a string flag limited to some values, which completion offers.
+type choice struct {
+	value   *string
+	choices []string
+}
+
+// Choice defines a string flag that only accepts one of choices.
+func Choice(fs *flag.FlagSet, name, value, usage string, choices ...string) *string {
+	p := new(string)
+	*p = value
+	fs.Var(&choice{p, choices}, name, usage)
+	return p
+}
+
+func (c *choice) String() string {
+	if c.value == nil {
+		return ""
+	}
+	return *c.value
+}
+
+func (c *choice) Set(s string) error {
+	if !slices.Contains(c.choices, s) {
+		return fmt.Errorf("want one of %s", strings.Join(c.choices, ", "))
+	}
+	*c.value = s
+	return nil
+}
+
+func (c *choice) Choices() []string { return c.choices }
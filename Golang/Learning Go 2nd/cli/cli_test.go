@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// settings records what the commands of testApp ran with.
+type settings struct {
+	Workers int
+	Mode    string
+	Skip    string
+	K       int
+	Output  string
+	Verbose bool
+}
+
+// testApp returns a program named prog with the commands copy, which
+// supports --output json, and kids analyze, which does not. Its config file
+// holds config, or is missing if config is empty.
+func testApp(t *testing.T, config string, env map[string]string) (*App, *settings, *bytes.Buffer) {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "config.json")
+	if config != "" {
+		if err := os.WriteFile(name, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := &settings{}
+	done := func(ctx *Context) {
+		got.Output, got.Verbose = ctx.Output, ctx.Verbose
+	}
+	copyCmd := &Command{
+		Name: "copy",
+		JSON: true,
+		Run: func(ctx *Context) error {
+			fs := ctx.Flags
+			workers := fs.Int("workers", 4, "copy `n` files at once")
+			mode := Choice(fs, "mode", "fast", "how to copy", "fast", "safe")
+			skip := fs.String("skip", "", "skip these `names`")
+			if err := ctx.Parse(); err != nil {
+				return err
+			}
+			got.Workers, got.Mode, got.Skip = *workers, *mode, *skip
+			done(ctx)
+			return nil
+		},
+	}
+	analyze := &Command{
+		Name: "analyze",
+		Run: func(ctx *Context) error {
+			k := ctx.Flags.Int("k", 2, "use `k` clusters")
+			if err := ctx.Parse(); err != nil {
+				return err
+			}
+			got.K = *k
+			done(ctx)
+			return nil
+		},
+	}
+	app := &App{
+		Name:       "prog",
+		Root:       &Command{Commands: []*Command{copyCmd, {Name: "kids", Commands: []*Command{analyze}}, {Name: "secret", Hidden: true}}},
+		ConfigFile: name,
+		Stderr:     io.Discard,
+		LookupEnv: func(k string) (string, bool) {
+			v, ok := env[k]
+			return v, ok
+		},
+	}
+	out := &bytes.Buffer{}
+	app.Stdout = out
+	return app, got, out
+}
+
+func TestPrecedence(t *testing.T) {
+	const file = `{"verbose": true, "copy": {"workers": 1, "skip": ["a", "b"]}}`
+	tests := []struct {
+		name   string
+		config string
+		env    map[string]string
+		args   []string
+		want   settings
+	}{
+		{"defaults", "", nil, []string{"copy"},
+			settings{Workers: 4, Mode: "fast", Output: Table}},
+		{"config file", file, nil, []string{"copy"},
+			settings{Workers: 1, Mode: "fast", Skip: "a,b", Output: Table, Verbose: true}},
+		{"environment over config file", file, map[string]string{"PROG_COPY_WORKERS": "2", "PROG_VERBOSE": "false", "PROG_OUTPUT": "json"}, []string{"copy"},
+			settings{Workers: 2, Mode: "fast", Skip: "a,b", Output: JSON}},
+		{"command line over environment", file, map[string]string{"PROG_COPY_WORKERS": "2", "PROG_COPY_MODE": "safe"}, []string{"copy", "-workers", "3", "-skip="},
+			settings{Workers: 3, Mode: "safe", Output: Table, Verbose: true}},
+		{"global flags before the command", "", map[string]string{"PROG_VERBOSE": "false"}, []string{"--output", "json", "-verbose", "copy"},
+			settings{Workers: 4, Mode: "fast", Output: JSON, Verbose: true}},
+		{"global flags after the command", "", map[string]string{"PROG_VERBOSE": "false"}, []string{"copy", "--output=json", "-verbose", "-mode", "safe"},
+			settings{Workers: 4, Mode: "safe", Output: JSON, Verbose: true}},
+		{"nested command", `{"kids": {"analyze": {"k": 5}}}`, map[string]string{"PROG_KIDS_ANALYZE_K": "6"}, []string{"kids", "analyze"},
+			settings{K: 6, Output: Table}},
+	}
+	for _, tt := range tests {
+		app, got, _ := testApp(t, tt.config, tt.env)
+		if err := app.Run(tt.args); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("%s: ran with %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+func TestConfigFlag(t *testing.T) {
+	dir := t.TempDir()
+	other, third := filepath.Join(dir, "other.json"), filepath.Join(dir, "third.json")
+	for name, data := range map[string]string{other: `{"copy": {"workers": 5}}`, third: `{"copy": {"workers": 6}}`} {
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want int
+	}{
+		{"default file", nil, []string{"copy"}, 1},
+		{"$PROG_CONFIG", map[string]string{"PROG_CONFIG": other}, []string{"copy"}, 5},
+		{"--config over $PROG_CONFIG", map[string]string{"PROG_CONFIG": other}, []string{"--config", third, "copy"}, 6},
+		{"--config after the command", nil, []string{"copy", "--config", third}, 6},
+		{"empty $PROG_CONFIG reads no file", map[string]string{"PROG_CONFIG": ""}, []string{"copy"}, 4},
+	}
+	for _, tt := range tests {
+		app, got, _ := testApp(t, `{"copy": {"workers": 1}}`, tt.env)
+		if err := app.Run(tt.args); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got.Workers != tt.want {
+			t.Errorf("%s: workers = %d, want %d", tt.name, got.Workers, tt.want)
+		}
+	}
+
+	// Only a file that was asked for has to exist.
+	missing := filepath.Join(dir, "missing.json")
+	app, _, _ := testApp(t, "", map[string]string{"PROG_CONFIG": missing})
+	if err := app.Run([]string{"copy"}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing $PROG_CONFIG: %v", err)
+	}
+	app, _, _ = testApp(t, "", nil)
+	if err := app.Run([]string{"copy", "--config", missing}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing --config: %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		env    map[string]string
+		args   []string
+		err    string
+	}{
+		{"unknown global setting", `{"verbos": true}`, nil, []string{"copy"},
+			`config.json: unknown setting "verbos" for prog (known: completion, config, copy, help, kids, output, verbose)`},
+		{"unknown command setting", `{"copy": {"wokers": 1}}`, nil, []string{"copy"},
+			`config.json: unknown setting "wokers" for prog copy (known: mode, skip, workers)`},
+		{"unknown subcommand", `{"kids": {"analyse": {}}}`, nil, []string{"kids", "analyze"},
+			`config.json: unknown setting "analyse" for prog kids (known: analyze)`},
+		{"unknown nested setting", `{"kids": {"analyze": {"kk": 1}}}`, nil, []string{"kids", "analyze"},
+			`config.json: unknown setting "kk" for prog kids analyze (known: k)`},
+		{"global flag in a command section", `{"copy": {"verbose": true}}`, nil, []string{"copy"},
+			`unknown setting "verbose" for prog copy`},
+		{"section not an object", `{"copy": 3}`, nil, []string{"copy"},
+			"config.json: copy: want an object of settings"},
+		{"bad value in config file", `{"copy": {"mode": "slow"}}`, nil, []string{"copy"},
+			"config.json: mode: want one of fast, safe"},
+		{"object as a value", `{"copy": {"workers": {}}}`, nil, []string{"copy"},
+			"config.json: workers: want a string, number, boolean or list, not map[string]interface {}"},
+		{"bad value in environment", "", map[string]string{"PROG_COPY_WORKERS": "many"}, []string{"copy"},
+			"$PROG_COPY_WORKERS: parse error"},
+		{"bad config JSON", `{"copy": `, nil, []string{"copy"},
+			"config.json: unexpected EOF"},
+		{"--output json without JSON support", "", nil, []string{"kids", "analyze", "--output", "json"},
+			"prog kids analyze: --output json is not supported"},
+		{"$PROG_OUTPUT without JSON support", "", map[string]string{"PROG_OUTPUT": "json"}, []string{"kids", "analyze"},
+			"prog kids analyze: --output json is not supported"},
+		{"unknown output", "", nil, []string{"--output", "xml", "copy"},
+			"want one of table, json"},
+		{"unknown command", "", nil, []string{"kid"},
+			`unknown command "kid" (available: copy, kids, help, completion)`},
+		{"unknown subcommand name", "", nil, []string{"kids", "analyse"},
+			`unknown kids command "analyse" (available: analyze)`},
+		{"group without a command", "", nil, []string{"kids"},
+			"prog kids: missing command (available: analyze)"},
+	}
+	for _, tt := range tests {
+		app, _, _ := testApp(t, tt.config, tt.env)
+		err := app.Run(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error %v, want one containing %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		words []string // after __complete; the last is being typed
+		want  []string
+	}{
+		{nil, []string{"copy", "kids", "help", "completion"}},
+		{[]string{"co"}, []string{"copy", "completion"}},
+		{[]string{"kids", ""}, []string{"analyze"}},
+		{[]string{"-verbose", "k"}, []string{"kids"}},
+		{[]string{"--output", "json", "k"}, []string{"kids"}},
+		{[]string{"--"}, []string{"--config", "--output", "--verbose"}},
+		{[]string{"copy", "-"}, []string{"-config", "-mode", "-output", "-skip", "-verbose", "-workers"}},
+		{[]string{"copy", "--w"}, []string{"--workers"}},
+		{[]string{"kids", "analyze", "-"}, []string{"-config", "-k", "-output", "-verbose"}},
+		{[]string{"copy", "--mode", ""}, []string{"fast", "safe"}},
+		{[]string{"copy", "-mode", "s"}, []string{"safe"}},
+		{[]string{"copy", "-mode=safe", "--output", ""}, []string{"table", "json"}},
+		{[]string{"--output", ""}, []string{"table", "json"}},
+		{[]string{"copy", "-workers", ""}, nil},
+		{[]string{"completion", ""}, []string{"bash", "zsh", "fish"}},
+		{[]string{"completion", "bash", ""}, nil},
+		{[]string{"secret"}, nil},
+	}
+	for _, tt := range tests {
+		app, _, out := testApp(t, "", nil)
+		if err := app.Run(append([]string{completeCommand}, tt.words...)); err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Fields(out.String())
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("__complete %q = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
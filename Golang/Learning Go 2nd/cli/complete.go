@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// completeCommand is the hidden command the completion scripts call with
+// the words typed so far. It prints one candidate per line; when there are
+// none, the scripts fall back to completing file names.
+const completeCommand = "__complete"
+
+// complete returns the candidates for the last of words, which is the word
+// being typed and may be empty.
+func (a *App) complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur, prev := words[len(words)-1], words[:len(words)-1]
+	path := []*Command{a.root()}
+	fs := flag.NewFlagSet(a.Name, flag.ContinueOnError)
+	(&globals{output: Table}).define(fs)
+	var value *flag.Flag // the flag whose value is being typed
+	var args []string
+	for _, w := range prev {
+		if value != nil {
+			value = nil
+			continue
+		}
+		if name, ok := flagName(w); ok {
+			if f := fs.Lookup(name); f != nil && !isBool(f) && !strings.Contains(w, "=") {
+				value = f
+			}
+			continue
+		}
+		cmd := path[len(path)-1]
+		if sub := cmd.lookup(w); sub != nil && len(args) == 0 {
+			path = append(path, sub)
+			if sub.Run != nil {
+				fs = a.flagsOf(path)
+			}
+			continue
+		}
+		args = append(args, w)
+	}
+
+	var candidates []string
+	cmd := path[len(path)-1]
+	switch {
+	case value != nil:
+		if c, ok := value.Value.(interface{ Choices() []string }); ok {
+			candidates = c.Choices()
+		}
+	case strings.HasPrefix(cur, "-"):
+		dashes := "-"
+		if strings.HasPrefix(cur, "--") {
+			dashes = "--"
+		}
+		fs.VisitAll(func(f *flag.Flag) { candidates = append(candidates, dashes+f.Name) })
+	case len(cmd.Commands) > 0 && len(args) == 0:
+		candidates = cmd.names()
+	case cmd.Complete != nil:
+		candidates = cmd.Complete(args)
+	}
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, cur) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// flagName returns the name in -name, --name or -name=value.
+func flagName(w string) (string, bool) {
+	if len(w) < 2 || w[0] != '-' || w == "--" {
+		return "", false
+	}
+	name := strings.TrimPrefix(w[1:], "-")
+	name, _, _ = strings.Cut(name, "=")
+	return name, true
+}
+
+func isBool(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+var shells = []string{"bash", "zsh", "fish"}
+
+func (a *App) completionCommand() *Command {
+	return &Command{
+		Name:  "completion",
+		Args:  "bash|zsh|fish",
+		Short: "print a shell completion script",
+		Long: fmt.Sprintf(`Prints a script that completes commands, flags and flag values in the given
+shell. Load it for the current session with
+
+	source <(%[1]s completion bash)
+	source <(%[1]s completion zsh)
+	%[1]s completion fish | source
+
+or save it where the shell loads completions from.`, a.Name),
+		Complete: func(args []string) []string {
+			if len(args) == 0 {
+				return shells
+			}
+			return nil
+		},
+		Run: func(ctx *Context) error {
+			if err := ctx.Parse(); err != nil {
+				return err
+			}
+			if ctx.Flags.NArg() != 1 {
+				return fmt.Errorf("completion: want one of %s", strings.Join(shells, ", "))
+			}
+			fn := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(a.Name)
+			switch ctx.Flags.Arg(0) {
+			case "bash":
+				fmt.Fprintf(ctx.Stdout, bashScript, a.Name, fn, completeCommand)
+			case "zsh":
+				fmt.Fprintf(ctx.Stdout, zshScript, a.Name, fn, completeCommand)
+			case "fish":
+				fmt.Fprintf(ctx.Stdout, fishScript, a.Name, fn, completeCommand)
+			default:
+				return fmt.Errorf("completion: unknown shell %q (want one of %s)", ctx.Flags.Arg(0), strings.Join(shells, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+// The scripts take the program name, a function name and the hidden
+// command, in that order.
+const (
+	bashScript = `# bash completion for %[1]s
+%[2]s() {
+	local IFS=$'\n'
+	COMPREPLY=($("${COMP_WORDS[0]}" %[3]s "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F %[2]s %[1]s
+`
+	zshScript = `#compdef %[1]s
+# zsh completion for %[1]s
+%[2]s() {
+	local -a candidates
+	candidates=("${(@f)$("${words[1]}" %[3]s "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	if [[ -n "${candidates[1]}" ]]; then
+		compadd -a candidates
+	else
+		_files
+	fi
+}
+compdef %[2]s %[1]s
+`
+	fishScript = `# fish completion for %[1]s
+function %[2]s
+	set -l words (commandline -opc)
+	set -l candidates ($words[1] %[3]s $words[2..-1] (commandline -ct) 2>/dev/null)
+	if test (count $candidates) -gt 0
+		printf '%%s\n' $candidates
+	else
+		__fish_complete_path (commandline -ct)
+	end
+end
+complete -c %[1]s -f -a '(%[2]s)'
+`
+)
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// help prints the usage of the command at the end of path.
+func (a *App) help(w io.Writer, path []*Command) {
+	cmd := path[len(path)-1]
+	name := a.pathName(path)
+	switch {
+	case len(path) == 1:
+		fmt.Fprintf(w, "usage: %s [global flags] <command> [flags] [args]\n", name)
+	case cmd.Run == nil:
+		fmt.Fprintf(w, "usage: %s <command> [flags] [args]\n", name)
+	default:
+		fmt.Fprintf(w, "usage: %s\n", strings.TrimSpace(name+" [flags] "+cmd.Args))
+	}
+	if text := cmp.Or(cmd.Long, cmd.Short); text != "" {
+		fmt.Fprintf(w, "\n%s\n", text)
+	}
+	if names := cmd.names(); len(names) > 0 {
+		fmt.Fprintf(w, "\nCommands:\n")
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		for _, sub := range cmd.Commands {
+			if !sub.Hidden {
+				fmt.Fprintf(tw, "  %s\t%s\n", sub.Name, sub.Short)
+			}
+		}
+		tw.Flush()
+	}
+	if cmd.Run != nil {
+		fs := a.flagsOf(path)
+		if own := only(fs, func(name string) bool { return !slices.Contains(globalNames, name) }); hasFlags(own) {
+			fmt.Fprintf(w, "\nFlags:\n")
+			own.SetOutput(w)
+			own.PrintDefaults()
+		}
+	}
+	fmt.Fprintf(w, "\nGlobal flags:\n")
+	g := flag.NewFlagSet(a.Name, flag.ContinueOnError)
+	(&globals{output: Table}).define(g)
+	g.SetOutput(w)
+	g.PrintDefaults()
+
+	var names []string
+	for _, c := range path[1:] {
+		names = append(names, c.Name)
+	}
+	if cmd.Run != nil {
+		fmt.Fprintf(w, "\nFlags can also be set with %s_<FLAG> variables, or under %q in the\nconfig file given by -config or $%s.\n",
+			a.envPrefix(path), strings.Join(names, "."), envName(a.envPrefix(path[:1]), "config"))
+	} else {
+		fmt.Fprintf(w, "\nRun '%s' for more about a command.\n", strings.Join(slices.Concat([]string{a.Name, "help"}, names, []string{"<command>"}), " "))
+	}
+}
+
+// only returns a FlagSet with the flags of fs whose names pass keep.
+func only(fs *flag.FlagSet, keep func(string) bool) *flag.FlagSet {
+	out := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	fs.VisitAll(func(f *flag.Flag) {
+		if keep(f.Name) {
+			out.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	return out
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	n := 0
+	fs.VisitAll(func(*flag.Flag) { n++ })
+	return n > 0
+}
+
+func (a *App) helpCommand() *Command {
+	return &Command{
+		Name:  "help",
+		Args:  "[command...]",
+		Short: "show help for a command",
+		Complete: func(args []string) []string {
+			path, _, err := a.resolve(args)
+			if err != nil {
+				return nil
+			}
+			return path[len(path)-1].names()
+		},
+		Run: func(ctx *Context) error {
+			if err := ctx.Parse(); err != nil {
+				return err
+			}
+			path, rest, err := a.resolve(ctx.Flags.Args())
+			if err != nil {
+				return err
+			}
+			if len(rest) > 0 {
+				return fmt.Errorf("help: %s has no command %q", a.pathName(path), rest[0])
+			}
+			a.help(ctx.Stdout, path)
+			return nil
+		},
+	}
+}
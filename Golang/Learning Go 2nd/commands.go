@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"learning-go.adcon.dev/carve"
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/dupes"
+	"learning-go.adcon.dev/rescue"
+	"learning-go.adcon.dev/ring"
+)
+
+// app is the command tree of the learning-go binary.
+var app = &cli.App{
+	Name:       "learning-go",
+	ConfigFile: configFile(),
+	Root: &cli.Command{
+		Long: "Demos and tools built while working through the notes in this repository.",
+		Commands: []*cli.Command{
+			{Name: "approx", Run: runApprox, Short: "compare approximation algorithms with exact optima"},
+			{Name: "bench", Run: runBench, Short: "benchmark ordered maps and kv.Store engines"},
+			{
+				Name: "carve", Run: runCarve, Args: "[image]", JSON: true, EnvPrefix: carve.EnvPrefix,
+				Short: "find files in a raw disk image by their signatures",
+				Long: `Scans a raw disk image or block device, read-only, for JPEG, PNG, PDF,
+ZIP/DOCX and MP3 files by their signatures and structure, and lists where
+each one starts. Without an image it carves a synthetic sample and checks
+the result.`,
+			},
+			{
+				Name: "dupes", Run: runDupes, Args: "dir...", JSON: true, EnvPrefix: dupes.EnvPrefix,
+				Short: "find files with identical contents",
+				Long: `Finds files with identical contents under the given directories, comparing
+sizes first, then the start of each file and finally its SHA-256. Every group
+keeps its oldest file; -action delete or hardlink lists what would happen to
+the others, and does it with -dry-run=false.`,
+			},
+			{
+				Name: "escape", Run: runEscape, Args: "[package]",
+				Short: "annotate source with escape analysis and allocations",
+				Long: `Builds the package with -gcflags=-m and lists its source annotated with the
+compiler's escape analysis and inlining decisions, next to allocations from
+its benchmarks. Without a package it explains the Chapter 6 examples.`,
+			},
+			{Name: "json", Run: runJSON, Short: "decode the sample kids from JSON"},
+			{
+				Name:  "kids",
+				Short: "analyze and redistribute the kids' candies",
+				Commands: []*cli.Command{
+					{Name: "analyze", Run: runKidsAnalyze, Short: "fit and cluster candies by age"},
+					{Name: "ranges", Run: runKidsRanges, Short: "range queries and updates over a line of kids"},
+					{Name: "redistribute", Run: runKidsRedistribute, Short: "move candies as little as possible with linear programming"},
+				},
+			},
+			{Name: "linalg", Run: runLinalg, Short: "solve linear systems and least squares"},
+			{
+				Name: "mvcc", Run: runMVCC,
+				Short: "run concurrent snapshot-isolated transactions",
+				Long: `Runs random concurrent transactions on a few contended keys while garbage
+collection runs underneath, then reports how many aborted on write
+conflicts and lists the surviving versions of one key.`,
+			},
+			{
+				Name: "options", Run: runOptions, Args: "[component]", JSON: true,
+				Short:    "list the settings of a configurable constructor",
+				Complete: func([]string) []string { return slices.Sorted(maps.Keys(configurables)) },
+				Long: `Lists the settings of a configurable constructor with their defaults and
+environment variables, and shows the values after applying -json and -env.
+Conflicting or invalid settings are reported as errors.`,
+			},
+			{Name: "prob", Run: runProb, Short: "simulate the probabilistic analyses of CLRS chapter 5"},
+			{
+				Name: "query", Run: runQuery, Args: "[sql]",
+				Short: "run SQL against the candy shop tables",
+				Long: `Runs SQL against the candy shop tables. Without arguments it shows
+EXPLAIN ANALYZE for a few joins.`,
+			},
+			{
+				Name: "repl", Run: runRepl,
+				Short: "replicate a store and check that every node converges",
+				Long: `Runs a primary and several replicas in process on loopback, writes while
+cutting replicas off, then promotes one and checks that every node
+converges to the same contents each time.`,
+			},
+			{
+				Name: "ring", Run: runRing, Args: "[key...]", JSON: true, EnvPrefix: ring.EnvPrefix,
+				Short: "simulate consistent hashing as nodes join and leave",
+				Long: `Places a million keys on a consistent hash ring, with plain and bounded
+loads, with rendezvous hashing and with hash mod n, then adds and removes
+nodes and reports how many keys moved against the fewest possible and how
+evenly they were spread. A last table compares virtual node counts. Given
+keys, it prints the node each one goes to instead.`,
+			},
+			{
+				Name: "rescue", Run: runRescue, Args: "src dst", JSON: true, EnvPrefix: rescue.EnvPrefix,
+				Short: "copy files off a failing disk and verify them",
+				Long: `Copies the tree src into dst, skipping programs and scripts (*.exe, *.scr,
+...) that could carry malware, retrying files whose reads fail, and
+checking every copy against the checksum of the original. The checksums
+are written to dst/SHA256SUMS, which -verify dst, or 'sha256sum -c', can
+check again later on another computer.`,
+			},
+			{Name: "search", Run: runSearch, Args: "text...", Short: "full-text search over the markdown notes"},
+			{Name: "shadow", Run: runShadow, Args: "[packages]", Short: "report shadowed variables, imports and predeclared names"},
+			{Name: "sketch", Run: runSketch, Short: "measure Bloom filters and other sketches"},
+			{
+				Name: "slices", Run: runSlices, Args: "[script.go | -]",
+				Short: "draw slice headers and shared backing arrays",
+				Long: `Runs Go statements over []int and [N]int, drawing slice headers and shared
+backing arrays after each one. Without a script it runs the Chapter 3 lesson.`,
+			},
+			{Name: "tags", Run: runTags, Args: "[packages]", Short: "check struct tags"},
+			{
+				Name: "utf8", Run: runUTF8, Args: "[text...]",
+				Short: "inspect the bytes, runes and graphemes of text",
+				Long:  "Inspects text given as arguments, in -file or, without either, on stdin.",
+			},
+			{
+				Name: "wire", Run: runWire,
+				Short: "serve the candy shop tables over TCP",
+				Long: `Serves the candy shop tables over TCP. Without -listen it runs a loopback
+session instead: pooled clients with prepared statements, database/sql,
+inserts and errors, checked against the catalog in process.`,
+			},
+		},
+	},
+}
+
+// configFile is where the flag defaults live unless --config or
+// $LEARNING_GO_CONFIG say otherwise.
+func configFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "learning-go", "config.json")
+}
+
+// loadKids reads a JSON array of kids from path, or the sample data when path
+// is empty.
+func loadKids(path string) ([]Kid, error) {
+	data := []byte(sampleKids)
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	var kids []Kid
+	if err := json.Unmarshal(data, &kids); err != nil {
+		return nil, fmt.Errorf("unmarshaling kids: %w", err)
+	}
+	return kids, nil
+}
+
+func runKidsRedistribute(ctx *cli.Context) error {
+	fs := ctx.Flags
+	file := fs.String("file", "", "JSON file with kids (default: built-in sample)")
+	minPerYear := fs.Int("min", 3, "minimum candies per year of age")
+	maxPerYear := fs.Int("max", 4, "maximum candies per year of age")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	kids, err := loadKids(*file)
+	if err != nil {
+		return err
+	}
+
+	plan, sol, err := RedistributeCandies(kids, *minPerYear, *maxPerYear)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("LP: final=%v moved=%d (simplex iterations: %d)\n", plan.Final, plan.Moved, sol.Iterations)
+	fmt.Printf("    dual of total candies: %g\n", sol.Dual("total"))
+	for i := range kids {
+		fmt.Printf("    kid %d: dual min=%g max=%g\n", i, sol.Dual(fmt.Sprintf("min%d", i)), sol.Dual(fmt.Sprintf("max%d", i)))
+	}
+	if brute, ok := bruteForceCandies(kids, *minPerYear, *maxPerYear); ok {
+		fmt.Printf("brute force: final=%v moved=%d\n", brute.Final, brute.Moved)
+	}
+
+	return nil
+}
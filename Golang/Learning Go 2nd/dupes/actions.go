@@ -0,0 +1,134 @@
+package dupes
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Action is what to do with the duplicates in a group.
+type Action int
+
+const (
+	Report   Action = iota // nothing
+	Delete                 // remove every path but the kept one
+	Hardlink               // replace every other copy with a hard link to the kept one
+)
+
+var actionNames = [...]string{
+	Report:   "report",
+	Delete:   "delete",
+	Hardlink: "hardlink",
+}
+
+func (a Action) String() string {
+	if a >= 0 && int(a) < len(actionNames) {
+		return actionNames[a]
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+func (a Action) MarshalText() ([]byte, error) { return []byte(a.String()), nil }
+
+// ParseAction is the inverse of Action.String.
+func ParseAction(s string) (Action, error) {
+	for a, name := range actionNames {
+		if name == s {
+			return Action(a), nil
+		}
+	}
+	return 0, fmt.Errorf("dupes: unknown action %q (want one of %s)", s, strings.Join(actionNames[:], ", "))
+}
+
+// Step is one change to a path. Err is set by Execute when the step failed
+// or was refused.
+type Step struct {
+	Action Action `json:"action"`
+	Path   string `json:"path"`
+	Keep   string `json:"keep"` // the path kept in its place
+	Size   int64  `json:"size"`
+	Freed  int64  `json:"freed"`
+	Err    string `json:"error,omitempty"`
+}
+
+// Plan lists the steps that apply a to every group, keeping its oldest
+// file, and frees space only once per copy. Paths that are the kept file
+// already are left alone by every action: they are hard links to it, or
+// the same file reached another way, and removing them would free nothing
+// and might remove the kept file itself.
+func (r *Result) Plan(a Action) []Step {
+	if a == Report {
+		return nil
+	}
+	var steps []Step
+	for _, g := range r.Groups {
+		keep := g.Files[0]
+		freed := map[int]bool{keep.Copy: true}
+		for _, f := range g.Files[1:] {
+			if f.Copy == keep.Copy {
+				continue
+			}
+			s := Step{Action: a, Path: f.Path, Keep: keep.Path, Size: g.Size}
+			if !freed[f.Copy] {
+				freed[f.Copy] = true
+				s.Freed = g.Size
+			}
+			steps = append(steps, s)
+		}
+	}
+	return steps
+}
+
+// Execute carries out steps, unless dryRun is set, and returns them with
+// Err filled in for the ones that failed. A step is refused if the file or
+// the one kept in its place changed size since Find, or if they turn out
+// to be the same file; nothing is compared beyond that, so run Find again
+// rather than reuse an old plan.
+func Execute(steps []Step, dryRun bool) []Step {
+	out := make([]Step, len(steps))
+	for i, s := range steps {
+		if !dryRun {
+			if err := execute(s); err != nil {
+				s.Err = err.Error()
+			}
+		}
+		out[i] = s
+	}
+	return out
+}
+
+func execute(s Step) error {
+	info, err := os.Lstat(s.Path)
+	if err != nil {
+		return err
+	}
+	keep, err := os.Stat(s.Keep)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !info.Mode().IsRegular():
+		return fmt.Errorf("not a regular file any more")
+	case info.Size() != s.Size || keep.Size() != s.Size:
+		return fmt.Errorf("size changed since the scan")
+	case os.SameFile(info, keep):
+		return fmt.Errorf("is the same file as %s", s.Keep)
+	}
+	switch s.Action {
+	case Delete:
+		return os.Remove(s.Path)
+	case Hardlink:
+		// Link under a temporary name and rename it over the duplicate, so
+		// the path is never missing, even if the link fails.
+		tmp := s.Path + ".dupes-link"
+		if err := os.Link(s.Keep, tmp); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, s.Path); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("nothing to do for %v", s.Action)
+}
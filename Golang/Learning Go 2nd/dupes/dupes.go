@@ -0,0 +1,360 @@
+// Package dupes finds files with identical contents. Comparing every file
+// byte by byte is expensive, so candidates are narrowed down in stages that
+// each cost more than the last: files are grouped by size, then by a hash of
+// their first few kilobytes, and only then by the SHA-256 of everything.
+// Most files drop out after the first stage without being opened.
+package dupes
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"learning-go.adcon.dev/options"
+)
+
+// Config tunes Find. Zero fields take the defaults in DefaultConfig.
+type Config struct {
+	Workers     int       `json:"workers"`      // files read at the same time
+	PartialSize int64     `json:"partial_size"` // bytes hashed in the second stage
+	MinSize     int64     `json:"min_size"`     // smaller files are ignored
+	Log         io.Writer `json:"-"`            // if set, receives a line per stage
+}
+
+// EnvPrefix starts the environment variables that set Config fields, as in
+// DUPES_WORKERS.
+const EnvPrefix = "DUPES"
+
+// Option sets one Config field; see options.Apply.
+type Option = options.Option[Config]
+
+func WithWorkers(n int) Option       { return options.New[Config]("workers", n) }
+func WithPartialSize(n int64) Option { return options.New[Config]("partial_size", n) }
+func WithMinSize(n int64) Option     { return options.New[Config]("min_size", n) }
+
+// DefaultConfig returns the settings used for zero Config fields. Empty
+// files are all equal and not worth reporting, so MinSize is 1.
+func DefaultConfig() Config {
+	return Config{Workers: 4, PartialSize: 16 << 10, MinSize: 1}
+}
+
+// Resolve applies opts over c, fills in defaults and validates the result,
+// as Find does.
+func (c Config) Resolve(opts ...Option) (Config, error) {
+	c, err := options.Apply(c, opts...)
+	if err != nil {
+		return c, fmt.Errorf("dupes: %w", err)
+	}
+	d := DefaultConfig()
+	if c.Workers == 0 {
+		c.Workers = d.Workers
+	}
+	if c.PartialSize == 0 {
+		c.PartialSize = d.PartialSize
+	}
+	if c.MinSize == 0 {
+		c.MinSize = d.MinSize
+	}
+	if c.Workers < 0 || c.PartialSize < 0 || c.MinSize < 0 {
+		return c, fmt.Errorf("dupes: settings must be positive")
+	}
+	return c, nil
+}
+
+// File is one path in a group of duplicates.
+type File struct {
+	Path    string    `json:"path"`
+	ModTime time.Time `json:"mod_time"`
+	// Copy numbers the distinct copies of the data within the group. Paths
+	// with the same Copy are hard links to the same file already.
+	Copy int `json:"copy"`
+}
+
+// Group is a set of files with the same contents, oldest first.
+type Group struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	Files  []File `json:"files"`
+	Copies int    `json:"copies"`
+}
+
+// Wasted is the space the group would free if only one copy were kept.
+func (g Group) Wasted() int64 { return g.Size * int64(g.Copies-1) }
+
+// Stats counts what each stage had to look at.
+type Stats struct {
+	Files       int   `json:"files"`        // regular files seen
+	SameSize    int   `json:"same_size"`    // sharing their size with another file
+	SamePartial int   `json:"same_partial"` // also sharing the hash of their start
+	BytesRead   int64 `json:"bytes_read"`
+}
+
+// Error is a path that could not be read.
+type Error struct {
+	Path string `json:"path"`
+	Err  string `json:"error"`
+}
+
+// Result is the outcome of Find.
+type Result struct {
+	Groups []Group `json:"groups"` // largest waste first
+	Wasted int64   `json:"wasted"`
+	Stats  Stats   `json:"stats"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// inode is one file's data: a path, or several hard links to it.
+type inode struct {
+	paths   []string
+	info    fs.FileInfo
+	partial string
+	sum     string
+}
+
+// Find walks roots and groups the regular files in them whose contents are
+// identical. Symbolic links are not followed. A file reached through more
+// than one root, as when the same directory is given twice under different
+// spellings, is listed once. Files that cannot be read are listed in
+// Result.Errors and left out.
+func Find(roots []string, cfg Config, opts ...Option) (*Result, error) {
+	cfg, err := cfg.Resolve(opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Groups: []Group{}}
+	var mu sync.Mutex
+	fail := func(path string, err error) {
+		mu.Lock()
+		res.Errors = append(res.Errors, Error{Path: path, Err: err.Error()})
+		mu.Unlock()
+	}
+
+	// Stage 1: size, from the directory walk alone.
+	bySize := map[int64][]*inode{}
+	seen := map[string]bool{}
+	for _, root := range roots {
+		// Paths are reported as found under root, but remembered by where
+		// they really are, so that no file is seen twice.
+		canon, err := canonical(root)
+		if err != nil {
+			return nil, fmt.Errorf("dupes: %w", err)
+		}
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				fail(path, err)
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				fail(path, err)
+				return nil
+			}
+			key := filepath.Join(canon, rel)
+			if seen[key] {
+				return nil
+			}
+			seen[key] = true
+			info, err := d.Info()
+			if err != nil {
+				fail(path, err)
+				return nil
+			}
+			res.Stats.Files++
+			if info.Size() < cfg.MinSize {
+				return nil
+			}
+			addPath(bySize, info.Size(), path, info)
+			return nil
+		})
+	}
+	candidates := keepShared(bySize)
+	res.Stats.SameSize = countPaths(candidates)
+	cfg.logf("%d files, %d with a common size", res.Stats.Files, res.Stats.SameSize)
+
+	// Stage 2: the hash of the first PartialSize bytes. For files no larger
+	// than that, it is the hash of the whole file.
+	res.Stats.BytesRead += hashAll(candidates, cfg.Workers, func(c *inode) (int64, error) {
+		sum, n, err := hashFile(c.paths[0], cfg.PartialSize)
+		c.partial = sum
+		if c.info.Size() <= cfg.PartialSize {
+			c.sum = sum
+		}
+		return n, err
+	}, fail)
+	candidates = regroup(candidates, func(c *inode) string { return c.partial })
+	res.Stats.SamePartial = countPaths(candidates)
+	cfg.logf("%d with a common start", res.Stats.SamePartial)
+
+	// Stage 3: the hash of everything, for files not fully hashed yet.
+	var rest []*inode
+	for _, c := range candidates {
+		if c.sum == "" {
+			rest = append(rest, c)
+		}
+	}
+	res.Stats.BytesRead += hashAll(rest, cfg.Workers, func(c *inode) (int64, error) {
+		sum, n, err := hashFile(c.paths[0], -1)
+		c.sum = sum
+		return n, err
+	}, fail)
+	candidates = regroup(candidates, func(c *inode) string { return c.sum })
+
+	for _, copies := range groupBy(candidates, func(c *inode) string { return c.sum }) {
+		g := Group{Size: copies[0].info.Size(), SHA256: copies[0].sum, Copies: len(copies)}
+		for i, c := range copies {
+			for _, p := range c.paths {
+				g.Files = append(g.Files, File{Path: p, ModTime: c.info.ModTime(), Copy: i})
+			}
+		}
+		slices.SortStableFunc(g.Files, func(a, b File) int {
+			return cmp.Or(a.ModTime.Compare(b.ModTime), cmp.Compare(a.Path, b.Path))
+		})
+		// Number the copies in the order they now appear.
+		renumber := map[int]int{}
+		for i, f := range g.Files {
+			if _, ok := renumber[f.Copy]; !ok {
+				renumber[f.Copy] = len(renumber)
+			}
+			g.Files[i].Copy = renumber[f.Copy]
+		}
+		res.Groups = append(res.Groups, g)
+		res.Wasted += g.Wasted()
+	}
+	slices.SortFunc(res.Groups, func(a, b Group) int {
+		return cmp.Or(cmp.Compare(b.Wasted(), a.Wasted()), cmp.Compare(a.Files[0].Path, b.Files[0].Path))
+	})
+	slices.SortFunc(res.Errors, func(a, b Error) int { return cmp.Compare(a.Path, b.Path) })
+	cfg.logf("%d groups of duplicates, %d bytes read", len(res.Groups), res.Stats.BytesRead)
+	return res, nil
+}
+
+// canonical is the absolute path of root with every symbolic link in it
+// resolved.
+func canonical(root string) (string, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+func (c Config) logf(format string, args ...any) {
+	if c.Log != nil {
+		fmt.Fprintf(c.Log, format+"\n", args...)
+	}
+}
+
+// addPath adds a file to its size group, as another path of an existing
+// copy if it is a hard link to one.
+func addPath(bySize map[int64][]*inode, size int64, path string, info fs.FileInfo) {
+	for _, c := range bySize[size] {
+		if os.SameFile(c.info, info) {
+			c.paths = append(c.paths, path)
+			return
+		}
+	}
+	bySize[size] = append(bySize[size], &inode{paths: []string{path}, info: info})
+}
+
+// keepShared flattens the groups with more than one copy.
+func keepShared[K comparable](groups map[K][]*inode) []*inode {
+	var out []*inode
+	for _, g := range groups {
+		if len(g) > 1 {
+			out = append(out, g...)
+		}
+	}
+	return out
+}
+
+// groupBy groups copies of the same size by key, dropping unreadable ones,
+// whose key is empty.
+func groupBy(copies []*inode, key func(*inode) string) map[string][]*inode {
+	groups := map[string][]*inode{}
+	for _, c := range copies {
+		if k := key(c); k != "" {
+			k = fmt.Sprint(c.info.Size(), " ", k)
+			groups[k] = append(groups[k], c)
+		}
+	}
+	return groups
+}
+
+func regroup(copies []*inode, key func(*inode) string) []*inode {
+	return keepShared(groupBy(copies, key))
+}
+
+func countPaths(copies []*inode) int {
+	n := 0
+	for _, c := range copies {
+		n += len(c.paths)
+	}
+	return n
+}
+
+// hashAll runs hash on copies with at most workers of them at a time,
+// which bounds the files open and the reads in flight. It returns the bytes
+// read.
+func hashAll(copies []*inode, workers int, hash func(*inode) (int64, error), fail func(string, error)) int64 {
+	jobs := make(chan *inode)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var total int64
+	for range min(workers, max(len(copies), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				n, err := hash(c)
+				if err != nil {
+					fail(c.paths[0], err)
+				}
+				mu.Lock()
+				total += n
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, c := range copies {
+		jobs <- c
+	}
+	close(jobs)
+	wg.Wait()
+	return total
+}
+
+// hashFile returns the hex SHA-256 of the first limit bytes of the file at
+// path, or all of it if limit is negative, and the number of bytes read.
+// On error the hash is empty.
+func hashFile(path string, limit int64) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	var n int64
+	if limit < 0 {
+		n, err = io.Copy(h, f)
+	} else {
+		n, err = io.CopyN(h, f, limit)
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	if err != nil {
+		return "", n, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
@@ -0,0 +1,164 @@
+package dupes
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tree creates files under a new directory, each with the given contents
+// and modified a minute after the previous one, and returns the directory.
+func tree(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	when := time.Now().Add(-time.Hour)
+	for i := 0; i < len(files); i += 2 {
+		name := filepath.Join(dir, files[i])
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(files[i+1]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		when = when.Add(time.Minute)
+		if err := os.Chtimes(name, when, when); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func paths(g Group) []string {
+	var out []string
+	for _, f := range g.Files {
+		out = append(out, filepath.Base(f.Path))
+	}
+	return out
+}
+
+func TestFind(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	dir := tree(t,
+		"a", "same",
+		"sub/b", "same",
+		"c", "diff",
+		"d", long+"1", // same size and start as e, different end
+		"e", long+"2",
+		"f", long+"1",
+		"empty1", "",
+		"empty2", "",
+	)
+	res, err := Find([]string{dir}, Config{}, WithPartialSize(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Groups) != 2 {
+		t.Fatalf("groups = %+v, want 2", res.Groups)
+	}
+	if got := paths(res.Groups[0]); !slices.Equal(got, []string{"d", "f"}) {
+		t.Errorf("largest group = %v, want [d f]", got)
+	}
+	if got := paths(res.Groups[1]); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("second group = %v, want [a b]", got)
+	}
+	want := Stats{Files: 8, SameSize: 6, SamePartial: 5, BytesRead: 3*4 + 3*16 + 3*101}
+	if res.Stats != want {
+		t.Errorf("stats = %+v, want %+v", res.Stats, want)
+	}
+	if res.Wasted != 101+4 {
+		t.Errorf("wasted = %d, want %d", res.Wasted, 101+4)
+	}
+}
+
+// TestSameTreeTwice passes one directory under several spellings, which
+// must not make a file look like its own duplicate.
+func TestSameTreeTwice(t *testing.T) {
+	dir := tree(t, "old", "photo", "new", "photo", "only", "unique")
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := []string{dir, dir + "/", filepath.Join(dir, "..", filepath.Base(dir)), rel, link}
+
+	res, err := Find(roots, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Stats.Files != 3 || len(res.Groups) != 1 || res.Groups[0].Copies != 2 || len(res.Groups[0].Files) != 2 {
+		t.Fatalf("found %d files, groups %+v; want old and new once each", res.Stats.Files, res.Groups)
+	}
+	for _, a := range []Action{Delete, Hardlink} {
+		steps := res.Plan(a)
+		if len(steps) != 1 || filepath.Base(steps[0].Path) != "new" {
+			t.Errorf("%v plan = %+v, want one step for new", a, steps)
+		}
+	}
+	for _, s := range Execute(res.Plan(Delete), false) {
+		if s.Err != "" {
+			t.Errorf("delete %s: %s", s.Path, s.Err)
+		}
+	}
+	for name, want := range map[string]string{"old": "photo", "only": "unique"} {
+		if b, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(b) != want {
+			t.Errorf("%s after deleting duplicates: %q, %v", name, b, err)
+		}
+	}
+}
+
+// TestHardlinks checks that no action touches a path that is the kept file
+// already, and that Execute refuses a step that would.
+func TestHardlinks(t *testing.T) {
+	dir := tree(t, "keep", "data", "copy", "data")
+	keep, alias := filepath.Join(dir, "keep"), filepath.Join(dir, "alias")
+	if err := os.Link(keep, alias); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Find([]string{dir}, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Groups) != 1 || res.Groups[0].Copies != 2 || len(res.Groups[0].Files) != 3 {
+		t.Fatalf("groups = %+v, want 3 paths to 2 copies", res.Groups)
+	}
+	for _, a := range []Action{Delete, Hardlink} {
+		steps := res.Plan(a)
+		if len(steps) != 1 || filepath.Base(steps[0].Path) != "copy" || steps[0].Freed != 4 {
+			t.Errorf("%v plan = %+v, want one step for copy freeing 4 bytes", a, steps)
+		}
+	}
+
+	for _, a := range []Action{Delete, Hardlink} {
+		out := Execute([]Step{{Action: a, Path: alias, Keep: keep, Size: 4}}, false)
+		if !strings.Contains(out[0].Err, "same file") {
+			t.Errorf("%v of a hard link to the kept file: err = %q", a, out[0].Err)
+		}
+		out = Execute([]Step{{Action: a, Path: keep, Keep: keep, Size: 4}}, false)
+		if !strings.Contains(out[0].Err, "same file") {
+			t.Errorf("%v of the kept file itself: err = %q", a, out[0].Err)
+		}
+	}
+	if b, err := os.ReadFile(keep); err != nil || string(b) != "data" {
+		t.Errorf("kept file after refused steps: %q, %v", b, err)
+	}
+
+	out := Execute(res.Plan(Hardlink), false)
+	if out[0].Err != "" {
+		t.Fatal(out[0].Err)
+	}
+	a, _ := os.Stat(keep)
+	b, _ := os.Stat(filepath.Join(dir, "copy"))
+	if !os.SameFile(a, b) {
+		t.Error("copy is not a hard link to keep after Hardlink")
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/dupes"
+)
+
+func runDupes(ctx *cli.Context) error {
+	fs := ctx.Flags
+	action := cli.Choice(fs, "action", "report", "what to do with duplicates: `report`, delete or hardlink", "report", "delete", "hardlink")
+	dryRun := fs.Bool("dry-run", true, "only list what -action would do; -dry-run=false to do it")
+	workers := fs.Int("workers", 0, "files read at the same time (default 4)")
+	minSize := fs.Int64("min-size", 0, "ignore files smaller than this many bytes (default 1)")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("dupes: no directories given")
+	}
+	act, err := dupes.ParseAction(*action)
+	if err != nil {
+		return err
+	}
+	var opts []dupes.Option
+	if *workers != 0 {
+		opts = append(opts, dupes.WithWorkers(*workers))
+	}
+	if *minSize != 0 {
+		opts = append(opts, dupes.WithMinSize(*minSize))
+	}
+	cfg := dupes.Config{Log: ctx.Log()}
+	res, err := dupes.Find(fs.Args(), cfg, opts...)
+	if err != nil {
+		return err
+	}
+	steps := dupes.Execute(res.Plan(act), *dryRun)
+	failed := 0
+	for _, s := range steps {
+		if s.Err != "" {
+			failed++
+		}
+	}
+	// Both output modes exit non-zero when a step failed.
+	var stepsErr error
+	if failed > 0 {
+		stepsErr = fmt.Errorf("dupes: %d steps failed", failed)
+	}
+
+	if ctx.JSON() {
+		if err := ctx.Encode(struct {
+			*dupes.Result
+			DryRun bool         `json:"dry_run"`
+			Steps  []dupes.Step `json:"steps,omitempty"`
+		}{res, *dryRun, steps}); err != nil {
+			return err
+		}
+		return stepsErr
+	}
+
+	for _, g := range res.Groups {
+		fmt.Printf("\n%d bytes x %d copies, %s\n", g.Size, g.Copies, g.SHA256[:16])
+		for i, f := range g.Files {
+			mark := "   "
+			switch {
+			case i == 0:
+				mark = "keep"
+			case f.Copy == g.Files[0].Copy:
+				mark = "link" // already a hard link to the kept file
+			}
+			fmt.Printf("  %-4s %s  %s\n", mark, f.ModTime.Format("2006-01-02 15:04"), f.Path)
+		}
+	}
+	for _, e := range res.Errors {
+		fmt.Printf("error   %s: %s\n", e.Path, e.Err)
+	}
+
+	var freed int64
+	if len(steps) > 0 {
+		fmt.Println()
+	}
+	for _, s := range steps {
+		switch {
+		case s.Err != "":
+			fmt.Printf("FAILED  %-8s %s: %s\n", s.Action, s.Path, s.Err)
+		case *dryRun:
+			fmt.Printf("would   %-8s %s\n", s.Action, s.Path)
+			freed += s.Freed
+		default:
+			fmt.Printf("done    %-8s %s\n", s.Action, s.Path)
+			freed += s.Freed
+		}
+	}
+	fmt.Printf("\n%d files, %d groups of duplicates, %.1f MB wasted\n", res.Stats.Files, len(res.Groups), float64(res.Wasted)/1e6)
+	switch {
+	case act == dupes.Report:
+	case *dryRun:
+		fmt.Printf("dry run: %s would free %.1f MB; run again with -dry-run=false to do it\n", act, float64(freed)/1e6)
+	default:
+		fmt.Printf("freed %.1f MB\n", float64(freed)/1e6)
+	}
+	return stepsErr
+}
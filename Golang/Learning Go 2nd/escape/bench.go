@@ -0,0 +1,80 @@
+package escape
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Bench is one benchmark result. Name is the benchmark without its
+// "Benchmark" prefix and GOMAXPROCS suffix, so BenchmarkKid_String/small-8
+// becomes Kid_String/small.
+type Bench struct {
+	Name        string
+	N           int
+	NsPerOp     float64
+	BytesPerOp  int64
+	AllocsPerOp int64
+}
+
+// RunBenchmarks runs the benchmarks of pkg matching pattern with go test
+// -benchmem. A package without test files has no benchmarks and is not an
+// error.
+func RunBenchmarks(dir string, pkg *Package, pattern string) ([]Bench, error) {
+	cmd := exec.Command("go", "test", "-run", "^$", "-bench", pattern, "-benchmem", pkg.ImportPath)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("escape: go test: %v\n%s", err, bytes.TrimSpace(out))
+	}
+	return ParseBenchmarks(bytes.NewReader(out))
+}
+
+// ParseBenchmarks reads the standard benchmark output format, ignoring any
+// other lines.
+func ParseBenchmarks(r io.Reader) ([]Bench, error) {
+	var benches []Bench
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) < 4 || !strings.HasPrefix(f[0], "Benchmark") {
+			continue
+		}
+		n, err := strconv.Atoi(f[1])
+		if err != nil {
+			continue // a benchmark's own log line
+		}
+		b := Bench{Name: benchName(f[0]), N: n}
+		for i := 2; i+1 < len(f); i += 2 {
+			v, err := strconv.ParseFloat(f[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("escape: bad benchmark value %q in %q", f[i], sc.Text())
+			}
+			switch f[i+1] {
+			case "ns/op":
+				b.NsPerOp = v
+			case "B/op":
+				b.BytesPerOp = int64(v)
+			case "allocs/op":
+				b.AllocsPerOp = int64(v)
+			}
+		}
+		benches = append(benches, b)
+	}
+	return benches, sc.Err()
+}
+
+// benchName strips the Benchmark prefix and the -N GOMAXPROCS suffix.
+func benchName(s string) string {
+	s = strings.TrimPrefix(s, "Benchmark")
+	if i := strings.LastIndexByte(s, '-'); i >= 0 {
+		if _, err := strconv.Atoi(s[i+1:]); err == nil {
+			s = s[:i]
+		}
+	}
+	return s
+}
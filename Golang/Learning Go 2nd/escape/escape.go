@@ -0,0 +1,225 @@
+// Package escape runs the compiler's escape analysis and inliner over a
+// package with -gcflags=-m, maps their diagnostics back to source lines and
+// joins them with allocation counts from benchmarks, so a listing shows which
+// lines put values on the heap next to what that costs at run time.
+package escape
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Kind classifies a compiler diagnostic.
+type Kind int
+
+const (
+	Other    Kind = iota
+	Escapes       // a value escapes to the heap
+	Moved         // a variable is moved to the heap
+	Leak          // a parameter leaks to the heap or a result
+	NoEscape      // a value stays on the stack
+	Inline        // a function can be inlined
+	Inlined       // a call was inlined
+	NoInline      // a function cannot be inlined, with the reason (-m=2)
+)
+
+var kindNames = [...]string{
+	Other:    "other",
+	Escapes:  "escapes",
+	Moved:    "moved",
+	Leak:     "leak",
+	NoEscape: "noescape",
+	Inline:   "inline",
+	Inlined:  "inlined",
+	NoInline: "noinline",
+}
+
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Heap reports whether the diagnostic stands for a heap allocation.
+func (k Kind) Heap() bool { return k == Escapes || k == Moved }
+
+// ParseKind is the inverse of Kind.String.
+func ParseKind(s string) (Kind, error) {
+	for k, name := range kindNames {
+		if name == s {
+			return Kind(k), nil
+		}
+	}
+	return 0, fmt.Errorf("escape: unknown kind %q (want one of %s)", s, strings.Join(kindNames[:], ", "))
+}
+
+// Note is one compiler diagnostic. With -m=2 the compiler explains escapes
+// with indented flow lines, which are kept in Detail.
+type Note struct {
+	File   string // absolute path
+	Line   int
+	Col    int
+	Kind   Kind
+	Text   string
+	Detail []string
+}
+
+// Package is the part of go list output the analysis needs.
+type Package struct {
+	ImportPath string
+	Name       string
+	Dir        string
+	GoFiles    []string
+}
+
+// Lookup resolves pattern to a single package, as seen from dir.
+func Lookup(dir, pattern string) (*Package, error) {
+	cmd := exec.Command("go", "list", "-json", "--", pattern)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("escape: go list: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	var pkgs []*Package
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var p Package
+		if err := dec.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("escape: go list output: %w", err)
+		}
+		pkgs = append(pkgs, &p)
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("escape: %s matches %d packages, want exactly one", pattern, len(pkgs))
+	}
+	return pkgs[0], nil
+}
+
+// Build compiles pkg with -gcflags=-m=level and returns the diagnostics that
+// fall inside its own files. The build cache replays compiler output, so a
+// second run is cheap.
+func Build(dir string, pkg *Package, level int) ([]Note, error) {
+	if level < 1 {
+		level = 1
+	}
+	cmd := exec.Command("go", "build", "-gcflags="+pkg.ImportPath+"=-m="+strconv.Itoa(level), "-o", os.DevNull, pkg.ImportPath)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("escape: go build: %v\n%s", err, bytes.TrimSpace(out))
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("escape: %w", err)
+	}
+	notes, err := Parse(bytes.NewReader(out), abs)
+	if err != nil {
+		return nil, err
+	}
+	own := map[string]bool{}
+	for _, name := range pkg.GoFiles {
+		own[filepath.Join(pkg.Dir, name)] = true
+	}
+	return slices.DeleteFunc(notes, func(n Note) bool { return !own[n.File] }), nil
+}
+
+// notePattern matches "file:line:col: message". Windows paths start with a
+// drive letter, hence the lazy file group.
+var notePattern = regexp.MustCompile(`^(.+?):(\d+):(\d+): (.*)$`)
+
+// Parse reads compiler output, resolving relative file names against dir.
+// Duplicates, which the compiler prints for code shared by several generic
+// instantiations, are dropped. The -m=2 explanations, a header ending in a
+// colon followed by indented flow lines, become the Detail of the summary
+// note at the same position.
+func Parse(r io.Reader, dir string) ([]Note, error) {
+	var notes []Note
+	seen := map[string]bool{}
+	explain := map[string][]string{}
+	pos := ""
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		m := notePattern.FindStringSubmatch(sc.Text())
+		if m == nil {
+			continue // "# package" headers
+		}
+		text := m[4]
+		if strings.HasPrefix(text, " ") {
+			if pos != "" {
+				explain[pos] = append(explain[pos], strings.TrimSpace(text))
+			}
+			continue
+		}
+		file := m[1]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		pos = file + ":" + m[2] + ":" + m[3]
+		if strings.HasSuffix(text, ":") && !strings.HasPrefix(text, "can") {
+			explain[pos] = append(explain[pos], strings.TrimSuffix(text, ":"))
+			continue
+		}
+		key := pos + ":" + text
+		pos = ""
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		line, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		kind, text := classify(text)
+		notes = append(notes, Note{File: file, Line: line, Col: col, Kind: kind, Text: text})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("escape: reading compiler output: %w", err)
+	}
+	for i := range notes {
+		n := &notes[i]
+		key := fmt.Sprintf("%s:%d:%d", n.File, n.Line, n.Col)
+		if n.Kind != Inline && n.Kind != Inlined {
+			n.Detail, explain[key] = explain[key], nil
+		}
+	}
+	return notes, nil
+}
+
+// classify names the kind of a message and shortens the -m=2 inliner output,
+// which appends the whole inlined body after "as:".
+func classify(text string) (Kind, string) {
+	switch {
+	case strings.HasPrefix(text, "moved to heap:"):
+		return Moved, text
+	case strings.HasSuffix(text, "escapes to heap"):
+		return Escapes, text
+	case strings.HasPrefix(text, "leaking param"), strings.HasPrefix(text, "leaking closure"):
+		return Leak, text
+	case strings.HasSuffix(text, "does not escape"):
+		return NoEscape, text
+	case strings.HasPrefix(text, "can inline "):
+		if i := strings.Index(text, " as: "); i >= 0 {
+			text = text[:i]
+		}
+		return Inline, text
+	case strings.HasPrefix(text, "inlining call to "):
+		return Inlined, text
+	case strings.HasPrefix(text, "cannot inline "):
+		return NoInline, text
+	}
+	return Other, text
+}
@@ -0,0 +1,223 @@
+package escape
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// parseFile parses compiler output captured in testdata from building
+// testdata/esc.go.txt, as if it had been run in /src.
+func parseFile(t *testing.T, name string) map[string][]Note {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	notes, err := Parse(f, "/src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPos := map[string][]Note{}
+	seen := map[string]bool{}
+	for _, n := range notes {
+		if n.File != "/src/esc.go" {
+			t.Errorf("note in %s, want /src/esc.go", n.File)
+		}
+		pos := fmt.Sprintf("%d:%d", n.Line, n.Col)
+		if seen[pos+n.Text] {
+			t.Errorf("%s: %q twice", pos, n.Text)
+		}
+		seen[pos+n.Text] = true
+		byPos[pos] = append(byPos[pos], n)
+	}
+	return byPos
+}
+
+// find returns the note of kind k at pos.
+func find(t *testing.T, byPos map[string][]Note, pos string, k Kind) Note {
+	t.Helper()
+	for _, n := range byPos[pos] {
+		if n.Kind == k {
+			return n
+		}
+	}
+	t.Fatalf("no %s note at %s in %v", k, pos, byPos[pos])
+	return Note{}
+}
+
+func TestParse(t *testing.T) {
+	m1, m2 := parseFile(t, "m1.txt"), parseFile(t, "m2.txt")
+	tests := []struct {
+		pos  string
+		kind Kind
+		m1   string
+		m2   string // when it differs from m1
+	}{
+		{"5:6", Inline, "can inline newPoint", "can inline newPoint with cost 12"},
+		{"6:2", Moved, "moved to heap: p", ""},
+		{"10:10", NoEscape, "xs does not escape", ""},
+		{"25:13", Inlined, "inlining call to keep[go.shape.int]", ""},
+		{"28:15", NoEscape, "[]int{...} does not escape", ""},
+		{"31:12", Leak, "leaking param: p", ""},
+		{"44:8", NoEscape, "func literal does not escape", ""},
+		{"18:6", Moved, "moved to heap: v", ""},
+		{"18:18", Moved, "moved to heap: v", ""},
+	}
+	for _, tt := range tests {
+		if n := find(t, m1, tt.pos, tt.kind); n.Text != tt.m1 || n.Detail != nil {
+			t.Errorf("-m=1 %s: %s %q with %d detail lines, want %q and none", tt.pos, n.Kind, n.Text, len(n.Detail), tt.m1)
+		}
+		want := cmp.Or(tt.m2, tt.m1)
+		if n := find(t, m2, tt.pos, tt.kind); n.Text != want {
+			t.Errorf("-m=2 %s: %s %q, want %q", tt.pos, n.Kind, n.Text, want)
+		}
+	}
+
+	// The compiler repeats the notes on generic code for every
+	// instantiation; parseFile has checked that Parse kept one of each.
+	// Notes that differ by shape are all kept.
+	var inline []string
+	for _, n := range m1["18:6"] {
+		if n.Kind == Inline {
+			inline = append(inline, n.Text)
+		}
+	}
+	slices.Sort(inline)
+	if want := []string{"can inline keep[go.shape.int]", "can inline keep[go.shape.string]", "can inline keep[int]", "can inline keep[string]"}; !slices.Equal(inline, want) {
+		t.Errorf("inlining notes at 18:6: %q, want %q", inline, want)
+	}
+
+	// Only -m=2 reports these.
+	if n := find(t, m2, "33:6", NoInline); n.Text != "cannot inline big: unhandled op DEFER" {
+		t.Errorf("NoInline text %q", n.Text)
+	}
+	if n := find(t, m2, "34:2", Other); !strings.HasPrefix(n.Text, "big capturing by ref: t") {
+		t.Errorf("Other text %q", n.Text)
+	}
+
+	// The flow lines go to the summary note at their position.
+	if got, want := find(t, m2, "6:2", Moved).Detail, []string{
+		"p escapes to heap in newPoint",
+		"flow: ~r0 ← &p:",
+		"from &p (address-of) at ./esc.go:7:9",
+		"from return &p (return) at ./esc.go:7:2",
+	}; !slices.Equal(got, want) {
+		t.Errorf("detail of moved to heap: p:\ngot  %q\nwant %q", got, want)
+	}
+	if d := find(t, m2, "31:12", Leak).Detail; len(d) != 4 || d[0] != "parameter p leaks to {heap} for store with derefs=0" {
+		t.Errorf("detail of leaking param: p: %q", d)
+	}
+	// Both instantiations explain the one deduplicated note.
+	d := strings.Join(find(t, m2, "18:6", Moved).Detail, "\n")
+	for _, want := range []string{"v escapes to heap in keep[string]", "v escapes to heap in keep[int]"} {
+		if !strings.Contains(d, want) {
+			t.Errorf("detail of moved to heap: v at 18:6 lacks %q:\n%s", want, d)
+		}
+	}
+	if d := find(t, m2, "5:6", Inline).Detail; d != nil {
+		t.Errorf("inlining note has detail %q", d)
+	}
+}
+
+func TestParsePaths(t *testing.T) {
+	out := "# p\n" +
+		"./a.go:3:2: moved to heap: x\n" +
+		"/abs/b.go:4:5: y escapes to heap\n" +
+		"not a note\n"
+	notes, err := Parse(strings.NewReader(out), "/src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, n := range notes {
+		got = append(got, fmt.Sprintf("%s %d %d %s", n.File, n.Line, n.Col, n.Kind))
+	}
+	want := []string{"/src/a.go 3 2 moved", "/abs/b.go 4 5 escapes"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		in   string
+		kind Kind
+		text string
+	}{
+		{"moved to heap: buf", Moved, ""},
+		{"&Kid{...} escapes to heap", Escapes, ""},
+		{"leaking param: k", Leak, ""},
+		{"leaking param content: ks", Leak, ""},
+		{"leaking param: p to result ~r0 level=0", Leak, ""},
+		{"leaking closure reference t", Leak, ""},
+		{"make([]byte, n) does not escape", NoEscape, ""},
+		{"can inline f", Inline, ""},
+		{"can inline f with cost 12 as: func() { return x }", Inline, "can inline f with cost 12"},
+		{"inlining call to strings.Cut", Inlined, ""},
+		{"cannot inline g: function too complex: cost 95 exceeds budget 80", NoInline, ""},
+		{"g capturing by value: x (addr=false assign=false width=8)", Other, ""},
+	}
+	for _, tt := range tests {
+		want := cmp.Or(tt.text, tt.in)
+		if kind, text := classify(tt.in); kind != tt.kind || text != want {
+			t.Errorf("classify(%q) = %s, %q; want %s, %q", tt.in, kind, text, tt.kind, want)
+		}
+	}
+}
+
+func TestParseBenchmarks(t *testing.T) {
+	const out = `goos: linux
+goarch: amd64
+pkg: learning-go.adcon.dev/skiplist
+cpu: Intel(R) Xeon(R) Processor
+BenchmarkRank/sorted-slice/n=1000           	     100	       108.5 ns/op	       0 B/op	       0 allocs/op
+BenchmarkRank/sorted-slice/n=1000-4         	     100	       114.3 ns/op	       0 B/op	       0 allocs/op
+BenchmarkRank/skiplist-p=1/4/n=100000-4     	     100	      2196 ns/op	       0 B/op	       0 allocs/op
+BenchmarkConcurrentGet/n=100000-4           	     100	      1783 ns/op	      23 B/op	       0 allocs/op
+BenchmarkStores/lsm
+    kvbench_test.go:89: {Flushes:3 Compactions:1}
+BenchmarkStores/lsm/put-8                   	  151045	      7562 ns/op	  12.50 MB/s	     412 B/op	       6 allocs/op
+BenchmarkCopy-16                            	1000000000	         0.2500 ns/op
+PASS
+ok  	learning-go.adcon.dev/skiplist	3.799s
+`
+	got, err := ParseBenchmarks(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Bench{
+		{"Rank/sorted-slice/n=1000", 100, 108.5, 0, 0},
+		{"Rank/sorted-slice/n=1000", 100, 114.3, 0, 0},
+		{"Rank/skiplist-p=1/4/n=100000", 100, 2196, 0, 0},
+		{"ConcurrentGet/n=100000", 100, 1783, 23, 0},
+		{"Stores/lsm/put", 151045, 7562, 412, 6},
+		{"Copy", 1000000000, 0.25, 0, 0},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+
+	if _, err := ParseBenchmarks(strings.NewReader("BenchmarkX-4 100 fast ns/op\n")); err == nil {
+		t.Error("a non-numeric value parsed")
+	}
+}
+
+func TestBenchName(t *testing.T) {
+	for in, want := range map[string]string{
+		"BenchmarkKid_String/small-8": "Kid_String/small",
+		"BenchmarkKid_String/small":   "Kid_String/small",
+		"BenchmarkScan-p=1/4-16":      "Scan-p=1/4",
+		"BenchmarkScan-p=1/4":         "Scan-p=1/4",
+		"BenchmarkSize/-1-2":          "Size/-1",
+		"Benchmark":                   "",
+	} {
+		if got := benchName(in); got != want {
+			t.Errorf("benchName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
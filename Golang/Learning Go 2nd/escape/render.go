@@ -0,0 +1,227 @@
+package escape
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// Options selects what a report shows.
+type Options struct {
+	Kinds map[Kind]bool // nil shows every kind
+	All   bool          // list whole files, not only the functions with notes
+}
+
+func (o Options) shows(k Kind) bool { return o.Kinds == nil || o.Kinds[k] }
+
+// notes returns the diagnostics on line that o shows.
+func (o Options) notes(f *File, line int) []Note {
+	var ns []Note
+	for _, n := range f.Notes[line] {
+		if o.shows(n.Kind) {
+			ns = append(ns, n)
+		}
+	}
+	return ns
+}
+
+// maxCode is the widest code column before notes move to their own lines.
+const maxCode = 60
+
+// WriteText writes a summary of the measured and allocating functions, then
+// an annotated listing with each line's diagnostics to its right.
+func (r *Report) WriteText(w io.Writer, opt Options) error {
+	bw := bufio.NewWriter(w)
+	t := r.Totals()
+	fmt.Fprintf(bw, "package %s: %d heap allocations, %d leaking params, %d inlinable, %d calls inlined\n",
+		r.Package.ImportPath, t[Escapes]+t[Moved], t[Leak], t[Inline], t[Inlined])
+
+	var rows []*Func
+	for _, fn := range r.Funcs {
+		if fn.Heap > 0 || fn.Leaks > 0 || len(fn.Benches) > 0 {
+			rows = append(rows, fn)
+		}
+	}
+	if len(rows) > 0 {
+		fmt.Fprintf(bw, "\n%-32s %5s %5s %7s %10s %10s %12s\n", "function", "heap", "leaks", "inline", "allocs/op", "B/op", "ns/op")
+		for _, fn := range rows {
+			inline := "no"
+			if fn.Inline {
+				inline = "yes"
+			}
+			if len(fn.Benches) == 0 {
+				fmt.Fprintf(bw, "%-32s %5d %5d %7s %10s %10s %12s\n", fn.Name, fn.Heap, fn.Leaks, inline, "-", "-", "-")
+			}
+			for i, b := range fn.Benches {
+				name := fn.Name
+				if _, sub, ok := strings.Cut(b.Name, "/"); ok {
+					name += "/" + sub
+				}
+				if i > 0 {
+					fmt.Fprintf(bw, "%-32s %5s %5s %7s", name, "", "", "")
+				} else {
+					fmt.Fprintf(bw, "%-32s %5d %5d %7s", name, fn.Heap, fn.Leaks, inline)
+				}
+				fmt.Fprintf(bw, " %10d %10d %12.1f\n", b.AllocsPerOp, b.BytesPerOp, b.NsPerOp)
+			}
+			if fn.NoInline != "" {
+				fmt.Fprintf(bw, "%-32s not inlined: %s\n", "", fn.NoInline)
+			}
+		}
+	}
+	for _, b := range r.Unmatched {
+		fmt.Fprintf(bw, "benchmark %s matches no function: %d allocs/op, %d B/op, %.1f ns/op\n", b.Name, b.AllocsPerOp, b.BytesPerOp, b.NsPerOp)
+	}
+
+	for _, f := range r.Files {
+		lines := r.shownLines(f, opt)
+		if len(lines) == 0 {
+			continue
+		}
+		width := 0
+		for _, l := range lines {
+			if n := len(expandTabs(f.Lines[l-1])); n > width && len(opt.notes(f, l)) > 0 {
+				width = n
+			}
+		}
+		width = min(width, maxCode)
+		fmt.Fprintf(bw, "\n%s\n", f.Name)
+		for i, l := range lines {
+			if i > 0 && l != lines[i-1]+1 {
+				fmt.Fprintf(bw, "%5s\n", "...")
+			}
+			code := expandTabs(f.Lines[l-1])
+			ns := opt.notes(f, l)
+			if len(ns) == 0 || len(code) > width {
+				fmt.Fprintf(bw, "%5d  %s\n", l, strings.TrimRight(code, " "))
+				code = ""
+			} else {
+				fmt.Fprintf(bw, "%5d  %-*s  ", l, width, code)
+			}
+			for j, n := range ns {
+				if j > 0 || code == "" {
+					fmt.Fprintf(bw, "%5s  %-*s  ", "", width, "")
+				}
+				fmt.Fprintf(bw, "%-8s %s\n", n.Kind, n.Text)
+				for _, d := range n.Detail {
+					fmt.Fprintf(bw, "%5s  %-*s  %8s   %s\n", "", width, "", "", d)
+				}
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// shownLines returns the line numbers of f a listing includes: every line
+// with opt.All, otherwise whole functions that have a shown note plus any
+// such line outside a function.
+func (r *Report) shownLines(f *File, opt Options) []int {
+	if opt.All {
+		lines := make([]int, len(f.Lines))
+		for i := range lines {
+			lines[i] = i + 1
+		}
+		return lines
+	}
+	var lines []int
+	for l := 1; l <= len(f.Lines); l++ {
+		if fn := r.funcAt(f, l); fn != nil {
+			if r.hasNotes(fn, opt) {
+				lines = append(lines, l)
+			}
+		} else if len(opt.notes(f, l)) > 0 {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+func (r *Report) hasNotes(fn *Func, opt Options) bool {
+	for l := fn.Start; l <= fn.End; l++ {
+		if len(opt.notes(fn.File, l)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func expandTabs(s string) string {
+	var b strings.Builder
+	col := 0
+	for _, r := range s {
+		if r == '\t' {
+			n := 4 - col%4
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(r)
+		col++
+	}
+	return b.String()
+}
+
+//go:embed report.html
+var reportHTML string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportHTML))
+
+type htmlLine struct {
+	Num   int
+	Code  string
+	Class string
+	Notes []Note
+}
+
+type htmlFile struct {
+	Name  string
+	Lines []htmlLine
+}
+
+// WriteHTML writes a standalone page with the summary table and the listing,
+// lines tinted by the most costly diagnostic on them.
+func (r *Report) WriteHTML(w io.Writer, opt Options) error {
+	var files []htmlFile
+	for _, f := range r.Files {
+		hf := htmlFile{Name: f.Name}
+		for _, l := range r.shownLines(f, opt) {
+			ns := opt.notes(f, l)
+			hf.Lines = append(hf.Lines, htmlLine{Num: l, Code: f.Lines[l-1], Class: lineClass(ns), Notes: ns})
+		}
+		if len(hf.Lines) > 0 {
+			files = append(files, hf)
+		}
+	}
+	t := r.Totals()
+	return reportTemplate.Execute(w, map[string]any{
+		"Package":   r.Package.ImportPath,
+		"Heap":      t[Escapes] + t[Moved],
+		"Leaks":     t[Leak],
+		"Inline":    t[Inline],
+		"Inlined":   t[Inlined],
+		"Funcs":     r.Funcs,
+		"Unmatched": r.Unmatched,
+		"Files":     files,
+	})
+}
+
+// lineClass picks the CSS class of the most costly note on a line.
+func lineClass(ns []Note) string {
+	class := ""
+	for _, n := range ns {
+		switch {
+		case n.Kind.Heap():
+			return "heap"
+		case n.Kind == Leak:
+			class = "leak"
+		case class == "" && n.Kind == NoEscape:
+			class = "stack"
+		case class == "" && n.Kind != Other:
+			class = "inline"
+		}
+	}
+	return class
+}
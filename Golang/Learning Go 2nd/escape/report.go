@@ -0,0 +1,195 @@
+package escape
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Report is a package's diagnostics grouped by file, line and function,
+// together with the benchmarks that measure those functions.
+type Report struct {
+	Package   *Package
+	Files     []*File
+	Funcs     []*Func
+	Unmatched []Bench // benchmarks not named after any function
+}
+
+// File is one source file and its diagnostics by line number.
+type File struct {
+	Name  string // relative to the package directory
+	Lines []string
+	Notes map[int][]Note
+}
+
+// Func is a function or method declaration and what the compiler said about
+// its body.
+type Func struct {
+	Name     string // as the compiler prints it: F, T.M or (*T).M
+	File     *File
+	Start    int
+	End      int
+	Heap     int    // escapes and moves to the heap
+	Leaks    int    // leaking parameters
+	Inline   bool   // the function itself can be inlined
+	NoInline string // why it cannot, with -m=2
+	Benches  []Bench
+}
+
+// NewReport reads the package sources and attaches notes and benchmarks to
+// them. A benchmark belongs to a function when its name, up to any
+// sub-benchmark, is the function name or Type_Method for methods, following
+// the go test naming convention, with the first letter lowered for
+// unexported functions.
+func NewReport(pkg *Package, notes []Note, benches []Bench) (*Report, error) {
+	r := &Report{Package: pkg}
+	fset := token.NewFileSet()
+	byPath := map[string]*File{}
+	keys := map[string]*Func{}
+	for _, name := range pkg.GoFiles {
+		path := filepath.Join(pkg.Dir, name)
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("escape: %w", err)
+		}
+		f := &File{
+			Name:  name,
+			Lines: strings.Split(strings.TrimSuffix(string(src), "\n"), "\n"),
+			Notes: map[int][]Note{},
+		}
+		r.Files = append(r.Files, f)
+		byPath[path] = f
+
+		syntax, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("escape: %w", err)
+		}
+		for _, decl := range syntax.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			name, key := funcName(fd)
+			fn := &Func{
+				Name:  name,
+				File:  f,
+				Start: fset.Position(fd.Pos()).Line,
+				End:   fset.Position(fd.End()).Line,
+			}
+			r.Funcs = append(r.Funcs, fn)
+			keys[key] = fn
+		}
+	}
+
+	for _, n := range notes {
+		f := byPath[n.File]
+		if f == nil {
+			continue
+		}
+		f.Notes[n.Line] = append(f.Notes[n.Line], n)
+	}
+	for _, f := range r.Files {
+		for _, ns := range f.Notes {
+			slices.SortStableFunc(ns, func(a, b Note) int { return cmp.Compare(a.Col, b.Col) })
+		}
+	}
+	for _, fn := range r.Funcs {
+		for line := fn.Start; line <= fn.End; line++ {
+			for _, n := range fn.File.Notes[line] {
+				switch {
+				case n.Kind.Heap():
+					fn.Heap++
+				case n.Kind == Leak:
+					fn.Leaks++
+				case n.Kind == Inline && isAbout(n.Text, "can inline ", fn.Name):
+					fn.Inline = true
+				case n.Kind == NoInline && isAbout(n.Text, "cannot inline ", fn.Name):
+					fn.NoInline = strings.TrimPrefix(n.Text, "cannot inline "+fn.Name+": ")
+				}
+			}
+		}
+	}
+
+	for _, b := range benches {
+		base, _, _ := strings.Cut(b.Name, "/")
+		fn := keys[base]
+		if fn == nil {
+			// BenchmarkParse measures an unexported parse just as well.
+			c, size := utf8.DecodeRuneInString(base)
+			fn = keys[string(unicode.ToLower(c))+base[size:]]
+		}
+		if fn != nil {
+			fn.Benches = append(fn.Benches, b)
+		} else {
+			r.Unmatched = append(r.Unmatched, b)
+		}
+	}
+	return r, nil
+}
+
+// funcName returns the compiler's name for a declaration and the name its
+// benchmark would have.
+func funcName(fd *ast.FuncDecl) (name, key string) {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return fd.Name.Name, fd.Name.Name
+	}
+	typ := fd.Recv.List[0].Type
+	ptr := false
+	if star, ok := typ.(*ast.StarExpr); ok {
+		ptr, typ = true, star.X
+	}
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		typ = t.X
+	case *ast.IndexListExpr:
+		typ = t.X
+	}
+	recv := "?"
+	if id, ok := typ.(*ast.Ident); ok {
+		recv = id.Name
+	}
+	key = recv + "_" + fd.Name.Name
+	if ptr {
+		return "(*" + recv + ")." + fd.Name.Name, key
+	}
+	return recv + "." + fd.Name.Name, key
+}
+
+// isAbout reports whether text is prefix followed by name and then the end
+// of the message, a colon or a space. Generic functions carry their type
+// arguments in brackets.
+func isAbout(text, prefix, name string) bool {
+	rest, ok := strings.CutPrefix(text, prefix+name)
+	return ok && (rest == "" || rest[0] == ':' || rest[0] == ' ' || rest[0] == '[')
+}
+
+// funcAt returns the function declared around line of f, if any.
+func (r *Report) funcAt(f *File, line int) *Func {
+	for _, fn := range r.Funcs {
+		if fn.File == f && fn.Start <= line && line <= fn.End {
+			return fn
+		}
+	}
+	return nil
+}
+
+// Totals counts the diagnostics of each kind.
+func (r *Report) Totals() map[Kind]int {
+	t := map[Kind]int{}
+	for _, f := range r.Files {
+		for _, ns := range f.Notes {
+			for _, n := range ns {
+				t[n.Kind]++
+			}
+		}
+	}
+	return t
+}
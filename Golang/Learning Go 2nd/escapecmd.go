@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/escape"
+)
+
+func runEscape(ctx *cli.Context) error {
+	fs := ctx.Flags
+	level := fs.Int("m", 1, "compiler diagnostic level; 2 adds inlining costs and escape explanations")
+	show := fs.String("show", "", "comma-separated kinds to show: escapes, moved, leak, noescape, inline, inlined, noinline, other (default all)")
+	all := fs.Bool("all", false, "list every line, not only functions with diagnostics")
+	bench := fs.String("bench", ".", "run the package's benchmarks matching this regexp with go test -benchmem; empty to skip")
+	html := fs.String("html", "", "write an HTML report to this file instead of a text listing")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	pattern := fs.Arg(0)
+	if pattern == "" {
+		pattern = "./pointers"
+	}
+
+	var opt escape.Options
+	opt.All = *all
+	if *show != "" {
+		opt.Kinds = map[escape.Kind]bool{}
+		for _, name := range strings.Split(*show, ",") {
+			k, err := escape.ParseKind(strings.TrimSpace(name))
+			if err != nil {
+				return err
+			}
+			opt.Kinds[k] = true
+		}
+	}
+
+	pkg, err := escape.Lookup(".", pattern)
+	if err != nil {
+		return err
+	}
+	notes, err := escape.Build(".", pkg, *level)
+	if err != nil {
+		return err
+	}
+	var benches []escape.Bench
+	if *bench != "" {
+		if benches, err = escape.RunBenchmarks(".", pkg, *bench); err != nil {
+			return err
+		}
+	}
+	report, err := escape.NewReport(pkg, notes, benches)
+	if err != nil {
+		return err
+	}
+
+	if *html == "" {
+		return report.WriteText(os.Stdout, opt)
+	}
+	f, err := os.Create(*html)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteHTML(f, opt); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", *html)
+	return nil
+}
@@ -0,0 +1,98 @@
+// Package kv defines the key-value interface shared by the storage engines,
+// so the same workload can run against each design.
+package kv
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	"learning-go.adcon.dev/skiplist"
+)
+
+var (
+	ErrNotFound = errors.New("kv: key not found")
+	ErrClosed   = errors.New("kv: store is closed")
+)
+
+// Store is an ordered byte-string map. Implementations must be safe for
+// concurrent use. Keys and values passed in may be reused by the caller after
+// the call returns; slices handed out must not be modified.
+type Store interface {
+	Get(key []byte) ([]byte, error) // ErrNotFound when absent
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	// Scan calls fn for every key in [start, end) in order until fn returns
+	// false. A nil end means no upper bound.
+	Scan(start, end []byte, fn func(key, value []byte) bool) error
+	Close() error
+}
+
+// Mem is an in-memory Store on a skip list. It is the baseline the disk
+// engines are measured against.
+type Mem struct {
+	mu     sync.RWMutex
+	list   *skiplist.SkipList[string, []byte]
+	closed bool
+}
+
+func NewMem() *Mem {
+	return &Mem{list: skiplist.NewOrdered[string, []byte]()}
+}
+
+func (m *Mem) Get(key []byte) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	v, ok := m.list.Get(string(key))
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return v, nil
+}
+
+func (m *Mem) Put(key, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.list.Put(string(key), bytes.Clone(value))
+	return nil
+}
+
+func (m *Mem) Delete(key []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.list.Delete(string(key))
+	return nil
+}
+
+func (m *Mem) Scan(start, end []byte, fn func(key, value []byte) bool) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return ErrClosed
+	}
+	for k, v := range m.list.From(string(start)) {
+		if end != nil && k >= string(end) {
+			break
+		}
+		if !fn([]byte(k), v) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *Mem) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
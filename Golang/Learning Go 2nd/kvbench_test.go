@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"learning-go.adcon.dev/kv"
+	"learning-go.adcon.dev/lsm"
+)
+
+// kvKids is how many generated kids BenchmarkStores loads into each engine.
+const kvKids = 20000
+
+func kidRecordKey(i int) []byte { return fmt.Appendf(nil, "kid/%08d", i) }
+
+// BenchmarkStores loads generated kids into every kv.Store engine and times
+// puts, point reads and short range scans on the result.
+func BenchmarkStores(b *testing.B) {
+	rng := rand.New(rand.NewPCG(1, 1))
+	kids := generateKids(kvKids, rng)
+	values := make([][]byte, len(kids))
+	for i, k := range kids {
+		var err error
+		if values[i], err = json.Marshal(k); err != nil {
+			b.Fatal(err)
+		}
+	}
+	order := rng.Perm(len(kids))
+
+	engines := []struct {
+		name string
+		open func(dir string) (kv.Store, error)
+	}{
+		{"memory", func(string) (kv.Store, error) { return kv.NewMem(), nil }},
+		// A small memtable so the kids reach several levels.
+		{"lsm", func(dir string) (kv.Store, error) {
+			return lsm.Open(dir, lsm.Config{MemtableSize: 256 << 10, TableSize: 512 << 10, BaseLevelSize: 2 << 20})
+		}},
+	}
+	for _, e := range engines {
+		b.Run(e.name, func(b *testing.B) {
+			s, err := e.open(b.TempDir())
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer s.Close()
+			b.Run("put", func(b *testing.B) {
+				i := 0
+				for b.Loop() {
+					k := order[i%len(order)]
+					if err := s.Put(kidRecordKey(k), values[k]); err != nil {
+						b.Fatal(err)
+					}
+					i++
+				}
+			})
+			// The put benchmark may have stopped short of every kid.
+			for _, k := range order {
+				if err := s.Put(kidRecordKey(k), values[k]); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.Run("get", func(b *testing.B) {
+				i := 0
+				for b.Loop() {
+					if _, err := s.Get(kidRecordKey(order[i%len(order)])); err != nil {
+						b.Fatal(err)
+					}
+					i++
+				}
+			})
+			b.Run("scan100", func(b *testing.B) {
+				i := 0
+				for b.Loop() {
+					left := 100
+					err := s.Scan(kidRecordKey(order[i%len(order)]), nil, func(_, _ []byte) bool {
+						left--
+						return left > 0
+					})
+					if err != nil {
+						b.Fatal(err)
+					}
+					i++
+				}
+			})
+			if db, ok := s.(*lsm.DB); ok {
+				b.Log(db.Stats())
+			}
+		})
+	}
+}
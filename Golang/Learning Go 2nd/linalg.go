@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/matrix"
+)
+
+func runLinalg(ctx *cli.Context) error {
+	fs := ctx.Flags
+	degree := fs.Int("degree", 7, "polynomial degree for the least-squares comparison")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+
+	// The worked example from CLRS Section 28.1.
+	a, _ := matrix.FromRows([][]float64{{1, 2, 0}, {3, 4, 4}, {5, 6, 3}})
+	b := []float64{3, 7, 8}
+	d, err := a.LUP()
+	if err != nil {
+		return err
+	}
+	x, err := d.Solve(b)
+	if err != nil {
+		return err
+	}
+	inv, err := a.Inverse()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("A =\n%vperm=%v\nL =\n%vU =\n%v", a, d.Perm, d.L, d.U)
+	fmt.Printf("x = %.4g (want [-1.4 2.2 0.6]), det(A) = %.4g (want 10)\n", x, d.Det())
+	fmt.Printf("A⁻¹ =\n%v\n", inv)
+
+	// A tiny pivot makes LU without row exchanges divide by almost zero.
+	fmt.Println("Pivoting on [[ε 1] [1 1]]·x = [1 2], exact x ≈ [1 1]:")
+	fmt.Printf("%8s  %12s  %12s  %12s  %12s\n", "ε", "LU f64 err", "LUP f64 err", "LU f32 err", "LUP f32 err")
+	for _, e := range []float64{1e-4, 1e-8, 1e-12, 1e-16, 1e-20} {
+		want := []float64{1 / (1 - e), (1 - 2*e) / (1 - e)}
+		lu64, lup64 := pivotErrors(e, want)
+		lu32, lup32 := pivotErrors(float32(e), want)
+		fmt.Printf("%8.0e  %12.3e  %12.3e  %12.3e  %12.3e\n", e, lu64, lup64, lu32, lup32)
+	}
+
+	// Fitting a polynomial with known coefficients on [0, 1].
+	n := 40
+	vander := matrix.New[float64](n, *degree+1)
+	y := make([]float64, n)
+	for i := range n {
+		t := float64(i) / float64(n-1)
+		for j := range *degree + 1 {
+			p := math.Pow(t, float64(j))
+			vander.Set(i, j, p)
+			y[i] += p // every true coefficient is 1
+		}
+	}
+	normal, err := vander.LeastSquaresNormal(y)
+	if err != nil {
+		return err
+	}
+	qr, err := vander.LeastSquaresQR(y)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nLeast squares, degree %d polynomial with all coefficients 1:\n", *degree)
+	fmt.Printf("  normal equations: max coefficient error %.3e\n", maxAbsError(normal, 1))
+	fmt.Printf("  Householder QR:   max coefficient error %.3e\n", maxAbsError(qr, 1))
+	return nil
+}
+
+// pivotErrors solves the ε system with and without pivoting and returns the
+// largest absolute error of each, or +Inf when the solve fails.
+func pivotErrors[T matrix.Float](e T, want []float64) (lu, lup float64) {
+	a, _ := matrix.FromRows([][]T{{e, 1}, {1, 1}})
+	b := []T{1, 2}
+	lu, lup = math.Inf(1), math.Inf(1)
+	if x, err := a.SolveLU(b); err == nil {
+		lu = maxDiff(x, want)
+	}
+	if x, err := a.Solve(b); err == nil {
+		lup = maxDiff(x, want)
+	}
+	return lu, lup
+}
+
+func maxDiff[T matrix.Float](x []T, want []float64) float64 {
+	worst := 0.0
+	for i := range x {
+		worst = max(worst, math.Abs(float64(x[i])-want[i]))
+	}
+	return worst
+}
+
+func maxAbsError(x []float64, want float64) float64 {
+	worst := 0.0
+	for _, v := range x {
+		worst = max(worst, math.Abs(v-want))
+	}
+	return worst
+}
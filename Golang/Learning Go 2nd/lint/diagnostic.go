@@ -0,0 +1,48 @@
+package lint
+
+import (
+	"cmp"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Diagnostic is one finding at a source position. Fix, when set, is an edit
+// that resolves it without changing what the program means.
+type Diagnostic struct {
+	Pos      token.Pos
+	Category string
+	Message  string
+	Fix      *Fix
+}
+
+// Fix replaces the source between Pos and End with New.
+type Fix struct {
+	Message  string
+	Pos, End token.Pos
+	New      string
+}
+
+// Report prints diagnostics sorted by position, with file names relative to
+// the working directory where possible.
+func Report(w io.Writer, fset *token.FileSet, diags []Diagnostic) {
+	wd, _ := os.Getwd()
+	slices.SortStableFunc(diags, func(a, b Diagnostic) int { return cmp.Compare(a.Pos, b.Pos) })
+	for _, d := range diags {
+		fmt.Fprintf(w, "%s: %s (%s)\n", position(fset, d.Pos, wd), d.Message, d.Category)
+		if d.Fix != nil {
+			fmt.Fprintf(w, "\tfix: %s\n", d.Fix.Message)
+		}
+	}
+}
+
+func position(fset *token.FileSet, pos token.Pos, wd string) string {
+	p := fset.Position(pos)
+	if rel, err := filepath.Rel(wd, p.Filename); err == nil && wd != "" {
+		p.Filename = rel
+	}
+	return p.String()
+}
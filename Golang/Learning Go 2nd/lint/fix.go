@@ -0,0 +1,53 @@
+package lint
+
+import (
+	"cmp"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"slices"
+)
+
+// ApplyFixes rewrites the files the diagnostics' fixes touch and returns
+// their names. Overlapping fixes are skipped after the first; a file that
+// would not format afterwards is left alone.
+func ApplyFixes(fset *token.FileSet, diags []Diagnostic) ([]string, error) {
+	byFile := map[string][]*Fix{}
+	for _, d := range diags {
+		if d.Fix != nil {
+			name := fset.Position(d.Fix.Pos).Filename
+			byFile[name] = append(byFile[name], d.Fix)
+		}
+	}
+	var changed []string
+	for name, fixes := range byFile {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return changed, err
+		}
+		slices.SortFunc(fixes, func(a, b *Fix) int { return cmp.Compare(a.Pos, b.Pos) })
+		var out []byte
+		last := 0
+		for _, f := range fixes {
+			start, end := fset.Position(f.Pos).Offset, fset.Position(f.End).Offset
+			if start < last {
+				continue
+			}
+			out = append(out, src[last:start]...)
+			out = append(out, f.New...)
+			last = end
+		}
+		out = append(out, src[last:]...)
+		formatted, err := format.Source(out)
+		if err != nil {
+			return changed, fmt.Errorf("lint: fixes to %s do not format: %w", name, err)
+		}
+		if err := os.WriteFile(name, formatted, 0o644); err != nil {
+			return changed, err
+		}
+		changed = append(changed, name)
+	}
+	slices.Sort(changed)
+	return changed, nil
+}
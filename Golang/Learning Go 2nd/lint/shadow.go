@@ -0,0 +1,175 @@
+package lint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+)
+
+// ShadowConfig selects what Shadow reports.
+type ShadowConfig struct {
+	Variables bool // a variable hiding an outer variable
+	Imports   bool // any declaration hiding an imported package name
+	Universe  bool // any declaration hiding a predeclared identifier like len or true
+
+	// Strict reports a shadowed variable even when the outer one is never
+	// used again, which is usually harmless.
+	Strict bool
+
+	// Allow lists names that are never reported, such as err.
+	Allow map[string]bool
+}
+
+// Shadow finds declarations that hide a declaration from an enclosing block,
+// as described in CHAPTER4.md. The idioms x := x and switch x := x.(type)
+// are deliberate and not reported.
+func Shadow(pkg *Package, cfg ShadowConfig) []Diagnostic {
+	lastUse := map[types.Object]token.Pos{}
+	for id, obj := range pkg.Info.Uses {
+		lastUse[obj] = max(lastUse[obj], id.Pos())
+	}
+	rebinds := rebinds(pkg)
+	signatures := signatures(pkg)
+
+	wd, _ := os.Getwd()
+	var diags []Diagnostic
+	seen := map[types.Object]bool{}
+	check := func(obj types.Object) {
+		if obj == nil || seen[obj] || obj.Name() == "_" || cfg.Allow[obj.Name()] || obj.Parent() == nil || obj.Parent().Parent() == nil {
+			return
+		}
+		if signatures[obj.Parent()] {
+			return
+		}
+		seen[obj] = true
+		if v, ok := obj.(*types.Var); ok && v.IsField() {
+			return
+		}
+		_, outer := obj.Parent().Parent().LookupParent(obj.Name(), obj.Pos())
+		if outer == nil {
+			return
+		}
+		d := Diagnostic{Pos: obj.Pos()}
+		switch o := outer.(type) {
+		case *types.PkgName:
+			if !cfg.Imports {
+				return
+			}
+			d.Category = "import"
+			d.Message = fmt.Sprintf("declaration of %q shadows import %q", obj.Name(), o.Imported().Path())
+		case *types.Var:
+			if !cfg.Variables || rebinds[obj] == outer {
+				return
+			}
+			if _, local := obj.(*types.Var); !local {
+				return
+			}
+			if !cfg.Strict && lastUse[outer] < obj.Parent().End() {
+				return
+			}
+			d.Category = "variable"
+			d.Message = fmt.Sprintf("declaration of %q shadows variable declared at %s", obj.Name(), position(pkg.Fset, outer.Pos(), wd))
+		default:
+			if outer.Parent() != types.Universe || !cfg.Universe {
+				return
+			}
+			d.Category = "universe"
+			d.Message = fmt.Sprintf("declaration of %q shadows the predeclared %s", obj.Name(), predeclared(outer))
+		}
+		diags = append(diags, d)
+	}
+	for _, obj := range pkg.Info.Defs {
+		check(obj)
+	}
+	for _, obj := range pkg.Info.Implicits {
+		check(obj)
+	}
+	return diags
+}
+
+func predeclared(obj types.Object) string {
+	switch obj.(type) {
+	case *types.Builtin:
+		return "function " + obj.Name()
+	case *types.TypeName:
+		return "type " + obj.Name()
+	case *types.Const:
+		return "constant " + obj.Name()
+	}
+	return obj.Name()
+}
+
+// signatures finds the scopes of function types that have no body, as in
+// var walk func(n *node) or an interface method. Their parameter names
+// document the signature and declare no variable.
+func signatures(pkg *Package) map[*types.Scope]bool {
+	bodies := map[*ast.FuncType]bool{}
+	for _, f := range pkg.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				bodies[n.Type] = true
+			case *ast.FuncLit:
+				bodies[n.Type] = true
+			}
+			return true
+		})
+	}
+	out := map[*types.Scope]bool{}
+	for n, scope := range pkg.Info.Scopes {
+		if ft, ok := n.(*ast.FuncType); ok && !bodies[ft] {
+			out[scope] = true
+		}
+	}
+	return out
+}
+
+// rebinds maps each variable declared by x := x or switch x := x.(type) to
+// the variable it copies.
+func rebinds(pkg *Package) map[types.Object]types.Object {
+	out := map[types.Object]types.Object{}
+	same := func(lhs, rhs ast.Expr) (types.Object, types.Object) {
+		l, ok1 := lhs.(*ast.Ident)
+		r, ok2 := ast.Unparen(rhs).(*ast.Ident)
+		if !ok1 || !ok2 || l.Name != r.Name {
+			return nil, nil
+		}
+		return pkg.Info.Defs[l], pkg.Info.Uses[r]
+	}
+	for _, f := range pkg.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				if n.Tok == token.DEFINE && len(n.Lhs) == len(n.Rhs) {
+					for i := range n.Lhs {
+						if def, use := same(n.Lhs[i], n.Rhs[i]); def != nil {
+							out[def] = use
+						}
+					}
+				}
+			case *ast.TypeSwitchStmt:
+				as, ok := n.Assign.(*ast.AssignStmt)
+				if !ok {
+					break
+				}
+				x, ok := as.Rhs[0].(*ast.TypeAssertExpr)
+				if !ok {
+					break
+				}
+				r, ok := ast.Unparen(x.X).(*ast.Ident)
+				if !ok || r.Name != as.Lhs[0].(*ast.Ident).Name {
+					break
+				}
+				for _, clause := range n.Body.List {
+					if obj := pkg.Info.Implicits[clause]; obj != nil {
+						out[obj] = pkg.Info.Uses[r]
+					}
+				}
+			}
+			return true
+		})
+	}
+	return out
+}
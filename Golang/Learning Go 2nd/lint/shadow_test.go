@@ -0,0 +1,102 @@
+package lint
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var quoted = regexp.MustCompile(`"([^"]*)"`)
+
+func TestShadow(t *testing.T) {
+	all := ShadowConfig{Variables: true, Imports: true, Universe: true}
+	strict := all
+	strict.Strict = true
+	allowErr := all
+	allowErr.Allow = map[string]bool{"err": true}
+	noVars := all
+	noVars.Variables = false
+
+	tests := []struct {
+		name string
+		cfg  ShadowConfig
+		body string   // the body of func f in a file importing strings
+		want []string // line, category and name of each finding; the body starts on line 8
+	}{
+		{
+			name: "variable used after the inner block",
+			cfg:  all,
+			body: "x := 1\nif true {\n\tx := 2\n\t_ = x\n}\n_ = x",
+			want: []string{"10 variable x"},
+		},
+		{
+			name: "variable not used after the inner block",
+			cfg:  all,
+			body: "x := 1\n_ = x\nif true {\n\tx := 2\n\t_ = x\n}",
+		},
+		{
+			name: "strict reports unused outer variables",
+			cfg:  strict,
+			body: "x := 1\n_ = x\nif true {\n\tx := 2\n\t_ = x\n}",
+			want: []string{"11 variable x"},
+		},
+		{
+			name: "variables off",
+			cfg:  noVars,
+			body: "x := 1\nif true {\n\tx := 2\n\t_ = x\n}\n_ = x",
+		},
+		{
+			name: "import",
+			cfg:  noVars,
+			body: "strings := []string{}\n_ = strings",
+			want: []string{"8 import strings"},
+		},
+		{
+			name: "predeclared names",
+			cfg:  all,
+			body: "len := 3\ntrue := false\n_, _ = len, true\ntype error int",
+			want: []string{"8 universe len", "9 universe true", "11 universe error"},
+		},
+		{
+			name: "allowlist",
+			cfg:  allowErr,
+			body: "var err error\nif true {\n\terr := error(nil)\n\t_ = err\n}\n_ = err",
+		},
+		{
+			name: "x := x",
+			cfg:  all,
+			body: "x := 1\nfunc() {\n\tx := x\n\t_ = x\n}()\n_ = x",
+		},
+		{
+			name: "switch x := x.(type)",
+			cfg:  all,
+			body: "var x any\nswitch x := x.(type) {\ncase int:\n\t_ = x\n}\n_ = x",
+		},
+		{
+			name: "function literal parameters",
+			cfg:  all,
+			body: "n := 1\ng := func(n int) {}\n_, _ = g, n",
+			want: []string{"9 variable n"},
+		},
+		{
+			name: "function type parameters",
+			cfg:  all,
+			body: "n := 1\nvar walk func(n int, len string)\ntype I interface{ M(n int) (strings string) }\n_, _ = walk, n",
+		},
+	}
+	for _, tt := range tests {
+		src := "package p\n\nimport \"strings\"\n\nvar _ = strings.ToUpper\n\nfunc f() {\n" + tt.body + "\n}\n"
+		pkg, _ := load(t, src)
+		diags := Shadow(pkg, tt.cfg)
+		slices.SortFunc(diags, func(a, b Diagnostic) int { return cmp.Compare(a.Pos, b.Pos) })
+		var got []string
+		for _, d := range diags {
+			got = append(got, fmt.Sprintf("%d %s %s", pkg.Fset.Position(d.Pos).Line, d.Category, quoted.FindStringSubmatch(d.Message)[1]))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: findings %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
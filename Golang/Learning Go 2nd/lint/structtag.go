@@ -0,0 +1,562 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tagOptions are the options each encoding package understands after the
+// name in its tag.
+var tagOptions = map[string][]string{
+	"json": {"omitempty", "omitzero", "string"},
+	"xml":  {"omitempty", "attr", "chardata", "cdata", "innerxml", "comment", "any"},
+}
+
+// StructTags checks struct field tags. Malformed tags are ignored by
+// reflect.StructTag.Get, so every mistake here fails silently at run time:
+//
+//   - syntax errors and repeated keys;
+//   - JSON names used twice in a struct, counting promoted fields of
+//     embedded structs the way encoding/json does;
+//   - json or xml tags on unexported fields, which are never encoded;
+//   - JSON names that mix camelCase, snake_case and other styles;
+//   - unknown json and xml options.
+//
+// Fixes are offered where the intent is unambiguous: rewriting a malformed
+// tag whose pairs can still be read, and correcting a misspelled option.
+// A rewrite that would give a field a JSON name another field already has
+// is reported without a fix.
+func StructTags(pkg *Package) []Diagnostic {
+	var diags []Diagnostic
+	done := map[*ast.StructType]bool{}
+	for _, f := range pkg.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			name := "struct"
+			st, ok := n.(*ast.StructType)
+			if ts, isSpec := n.(*ast.TypeSpec); isSpec {
+				st, ok = ts.Type.(*ast.StructType)
+				name = ts.Name.Name
+			}
+			if !ok || done[st] {
+				return true
+			}
+			done[st] = true
+			if t, ok := pkg.Info.Types[st].Type.(*types.Struct); ok {
+				diags = append(diags, checkStruct(st, t, name)...)
+			}
+			return true
+		})
+	}
+	return diags
+}
+
+// astFields maps each field of t to the *ast.Field declaring it; a, b int
+// is one *ast.Field for two fields.
+func astFields(st *ast.StructType) []*ast.Field {
+	var out []*ast.Field
+	for _, f := range st.Fields.List {
+		for range max(1, len(f.Names)) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func checkStruct(st *ast.StructType, t *types.Struct, name string) []Diagnostic {
+	var diags []Diagnostic
+	fields := astFields(st)
+	renamed := map[int]int{} // field index → diagnostic whose fix gives it a new JSON name
+	for i := range t.NumFields() {
+		f, af := t.Field(i), fields[i]
+		if af.Tag == nil {
+			continue
+		}
+		tag, err := strconv.Unquote(af.Tag.Value)
+		if err != nil {
+			continue
+		}
+		pairs, err := parseTag(tag)
+		if err != nil {
+			d := Diagnostic{Pos: af.Tag.Pos(), Category: "syntax", Message: fmt.Sprintf("struct tag %s: %v", af.Tag.Value, err)}
+			if fixed, ok := normalizeTag(tag); ok {
+				d.Fix = tagFix(af.Tag, fixed)
+				renamed[i] = len(diags)
+			}
+			diags = append(diags, d)
+			continue
+		}
+		for pi, p := range pairs {
+			opts, ok := tagOptions[p.key]
+			if !ok {
+				continue
+			}
+			if p.value == "-" {
+				continue
+			}
+			if !f.Exported() && !f.Embedded() {
+				diags = append(diags, Diagnostic{Pos: af.Tag.Pos(), Category: "unexported",
+					Message: fmt.Sprintf("unexported field %s has a %s tag, but encoding/%s ignores unexported fields", f.Name(), p.key, p.key)})
+				continue
+			}
+			tagName, options, _ := strings.Cut(p.value, ",")
+			if p.key == "json" && tagName != "" && !validJSONName(tagName) {
+				diags = append(diags, Diagnostic{Pos: af.Tag.Pos(), Category: "syntax",
+					Message: fmt.Sprintf("invalid JSON name %q; encoding/json falls back to %q", tagName, f.Name())})
+			}
+			if options == "" {
+				continue
+			}
+			for _, opt := range strings.Split(options, ",") {
+				switch {
+				case opt == "" || slices.Contains(opts, opt):
+				default:
+					d := Diagnostic{Pos: af.Tag.Pos(), Category: "option", Message: fmt.Sprintf("unknown %s option %q", p.key, opt)}
+					if guess := nearest(opt, opts); guess != "" {
+						fixed := slices.Clone(pairs)
+						fixed[pi].value = replaceOption(p.value, opt, guess)
+						d.Fix = tagFix(af.Tag, renderTag(fixed))
+						d.Fix.Message = fmt.Sprintf("change %q to %q", opt, guess)
+					}
+					diags = append(diags, d)
+				}
+				if p.key == "json" && opt == "string" && !scalar(f.Type()) {
+					diags = append(diags, Diagnostic{Pos: af.Tag.Pos(), Category: "option",
+						Message: fmt.Sprintf("the json string option only applies to strings, numbers and booleans, not %s", f.Type())})
+				}
+			}
+		}
+	}
+	dropCollidingFixes(t, diags, renamed)
+	diags = append(diags, duplicateJSON(t, fields)...)
+	diags = append(diags, namingStyle(t, fields, name)...)
+	return diags
+}
+
+// dropCollidingFixes removes syntax fixes that would give a field a JSON
+// name another field of t already has, or would get from its own fix:
+// encoding/json would then drop one or both of them, losing data the
+// malformed tag let through. Embedded fields count at any depth, since a
+// field at the top shadows them. renamed maps field indexes to the
+// diagnostics carrying their fixes.
+func dropCollidingFixes(t *types.Struct, diags []Diagnostic, renamed map[int]int) {
+	if len(renamed) == 0 {
+		return
+	}
+	fixedName := map[int]string{}
+	for i, di := range renamed {
+		if name := jsonTagName(diags[di].Fix.New); name != "" {
+			fixedName[i] = name
+		}
+	}
+	fields := jsonFields(t, 0, 0, "", map[types.Type]bool{})
+	for i, name := range fixedName {
+		for _, f := range fields {
+			if f.top == i {
+				continue
+			}
+			other, fixed := fixedName[f.top]
+			if f.name == name || fixed && f.depth == 0 && other == name {
+				d := &diags[renamed[i]]
+				d.Message += fmt.Sprintf("; not fixed, since the JSON name %q is already used by %s", name, f.path)
+				d.Fix = nil
+				break
+			}
+		}
+	}
+}
+
+// jsonTagName is the JSON name a tag literal as written by tagFix
+// assigns, or "" if it leaves the field's name alone.
+func jsonTagName(lit string) string {
+	tag, err := strconv.Unquote(lit)
+	if err != nil {
+		return ""
+	}
+	value := reflect.StructTag(tag).Get("json")
+	name, _, _ := strings.Cut(value, ",")
+	if value == "-" || !validJSONName(name) {
+		return ""
+	}
+	return name
+}
+
+type tagPair struct{ key, value string }
+
+// parseTag splits a tag into key:"value" pairs following the convention
+// reflect.StructTag.Lookup relies on.
+func parseTag(tag string) ([]tagPair, error) {
+	var pairs []tagPair
+	for {
+		tag = strings.TrimLeft(tag, " ")
+		if tag == "" {
+			return pairs, nil
+		}
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 {
+			return nil, errors.New("bad syntax for key")
+		}
+		if i+1 >= len(tag) || tag[i] != ':' {
+			return nil, fmt.Errorf("key %q is not followed by a colon and a value", tag[:i])
+		}
+		if tag[i+1] != '"' {
+			return nil, fmt.Errorf("value of %q is not in double quotes", tag[:i])
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return nil, fmt.Errorf("value of %q is not terminated", key)
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			return nil, fmt.Errorf("value of %q: %v", key, err)
+		}
+		tag = tag[i+1:]
+		if tag != "" && tag[0] != ' ' {
+			return nil, fmt.Errorf("pairs are not separated by spaces after %q", key)
+		}
+		for _, p := range pairs {
+			if p.key == key {
+				return nil, fmt.Errorf("repeats key %q", key)
+			}
+		}
+		pairs = append(pairs, tagPair{key, value})
+	}
+}
+
+// normalizeTag reads a malformed tag leniently: spaces around the colon,
+// single or back quotes, bare values, commas between pairs and a key
+// repeated with the same value. It fails where the intent is unclear, such
+// as a key repeated with different values.
+func normalizeTag(tag string) (string, bool) {
+	var pairs []tagPair
+	s := tag
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			break
+		}
+		i := 0
+		for i < len(s) && s[i] > ' ' && s[i] != ':' && s[i] != '"' && s[i] != '\'' && s[i] != 0x7f {
+			i++
+		}
+		if i == 0 {
+			return "", false
+		}
+		key := s[:i]
+		s = strings.TrimLeft(s[i:], " \t")
+		if !strings.HasPrefix(s, ":") {
+			return "", false
+		}
+		s = strings.TrimLeft(s[1:], " \t")
+		if s == "" {
+			return "", false
+		}
+		var value string
+		switch q := s[0]; q {
+		case '"':
+			j := 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return "", false
+			}
+			v, err := strconv.Unquote(s[:j+1])
+			if err != nil {
+				return "", false
+			}
+			value, s = v, s[j+1:]
+		case '\'', '`':
+			j := strings.IndexByte(s[1:], q)
+			if j < 0 {
+				return "", false
+			}
+			value, s = s[1:j+1], s[j+2:]
+		default:
+			j := strings.IndexAny(s, " \t")
+			if j < 0 {
+				j = len(s)
+			}
+			value, s = s[:j], s[j:]
+		}
+		dup := false
+		for _, p := range pairs {
+			if p.key == key {
+				if p.value != value {
+					return "", false
+				}
+				dup = true
+			}
+		}
+		if !dup {
+			pairs = append(pairs, tagPair{key, value})
+		}
+	}
+	if len(pairs) == 0 {
+		return "", false
+	}
+	fixed := renderTag(pairs)
+	return fixed, fixed != tag
+}
+
+func renderTag(pairs []tagPair) string {
+	parts := make([]string, len(pairs))
+	for i, p := range pairs {
+		parts[i] = p.key + ":" + strconv.Quote(p.value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// tagFix replaces a tag literal, keeping it a raw string when possible.
+func tagFix(lit *ast.BasicLit, tag string) *Fix {
+	s := strconv.Quote(tag)
+	if strings.HasPrefix(lit.Value, "`") && !strings.Contains(tag, "`") && strconv.CanBackquote(tag) {
+		s = "`" + tag + "`"
+	}
+	return &Fix{Message: "rewrite as " + s, Pos: lit.Pos(), End: lit.End(), New: s}
+}
+
+func replaceOption(value, old, new string) string {
+	parts := strings.Split(value, ",")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] == old {
+			parts[i] = new
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// nearest is the only option within two edits of s, if there is one.
+func nearest(s string, options []string) string {
+	found := ""
+	for _, o := range options {
+		if editDistance(s, o) <= 2 {
+			if found != "" {
+				return ""
+			}
+			found = o
+		}
+	}
+	return found
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// validJSONName mirrors encoding/json: other names are silently ignored.
+func validJSONName(s string) bool {
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+func scalar(t types.Type) bool {
+	if p, ok := t.Underlying().(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&(types.IsNumeric|types.IsBoolean|types.IsString) != 0
+}
+
+// jsonField is a field as encoding/json sees it: top is the index of the
+// field in the outer struct it is reached through.
+type jsonField struct {
+	name   string
+	path   string
+	depth  int
+	tagged bool
+	top    int
+}
+
+// jsonFields lists the fields encoding/json would consider for t, with
+// untagged embedded structs flattened into their parent.
+func jsonFields(t *types.Struct, depth, top int, prefix string, seen map[types.Type]bool) []jsonField {
+	var out []jsonField
+	for i := range t.NumFields() {
+		f := t.Field(i)
+		if depth == 0 {
+			top = i
+		}
+		tag, _ := reflect.StructTag(t.Tag(i)).Lookup("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name != "" && !validJSONName(name) {
+			name = ""
+		}
+		if f.Embedded() && name == "" {
+			typ := f.Type()
+			if p, ok := typ.(*types.Pointer); ok {
+				typ = p.Elem()
+			}
+			if st, ok := typ.Underlying().(*types.Struct); ok {
+				if !seen[typ] {
+					seen[typ] = true
+					out = append(out, jsonFields(st, depth+1, top, prefix+f.Name()+".", seen)...)
+					delete(seen, typ)
+				}
+				continue
+			}
+		}
+		if !f.Exported() {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = f.Name()
+		}
+		out = append(out, jsonField{name: name, path: prefix + f.Name(), depth: depth, tagged: tagged, top: top})
+	}
+	return out
+}
+
+// duplicateJSON reports JSON names claimed by more than one field at the
+// shallowest depth they appear, where encoding/json cannot pick a winner
+// or picks one silently.
+func duplicateJSON(t *types.Struct, fields []*ast.Field) []Diagnostic {
+	byName := map[string][]jsonField{}
+	var order []string
+	for _, f := range jsonFields(t, 0, 0, "", map[types.Type]bool{}) {
+		if _, ok := byName[f.name]; !ok {
+			order = append(order, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+	var diags []Diagnostic
+	for _, name := range order {
+		fs := byName[name]
+		depth := fs[0].depth
+		for _, f := range fs {
+			depth = min(depth, f.depth)
+		}
+		var paths []string
+		var tagged []jsonField
+		var first *jsonField
+		for i, f := range fs {
+			if f.depth != depth {
+				continue
+			}
+			if first == nil {
+				first = &fs[i]
+			}
+			paths = append(paths, f.path)
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+		if len(paths) < 2 {
+			continue
+		}
+		effect := "encoding/json silently drops all of them"
+		if len(tagged) == 1 {
+			effect = "only the tagged " + tagged[0].path + " is encoded"
+		}
+		diags = append(diags, Diagnostic{Pos: fields[first.top].Pos(), Category: "duplicate",
+			Message: fmt.Sprintf("JSON name %q is used by %s; %s", name, strings.Join(paths, " and "), effect)})
+	}
+	return diags
+}
+
+// jsonStyle classifies a JSON name. A single lowercase word fits any style.
+func jsonStyle(s string) string {
+	switch {
+	case strings.Contains(s, "_"):
+		return "snake_case"
+	case strings.Contains(s, "-"):
+		return "kebab-case"
+	case s == strings.ToLower(s):
+		return ""
+	case unicode.IsUpper([]rune(s)[0]):
+		return "PascalCase"
+	}
+	return "camelCase"
+}
+
+// namingStyle reports explicitly tagged JSON names that differ in style
+// from most of the struct. Renaming changes the wire format, so there is
+// no fix.
+func namingStyle(t *types.Struct, fields []*ast.Field, name string) []Diagnostic {
+	type named struct {
+		i           int
+		name, style string
+	}
+	var names []named
+	count := map[string]int{}
+	var styles []string
+	for i := range t.NumFields() {
+		tag, ok := reflect.StructTag(t.Tag(i)).Lookup("json")
+		n, _, _ := strings.Cut(tag, ",")
+		if !ok || n == "" || n == "-" || !t.Field(i).Exported() {
+			continue
+		}
+		s := jsonStyle(n)
+		if s == "" {
+			continue
+		}
+		if count[s] == 0 {
+			styles = append(styles, s)
+		}
+		count[s]++
+		names = append(names, named{i, n, s})
+	}
+	if len(styles) < 2 {
+		return nil
+	}
+	major := styles[0]
+	for _, s := range styles[1:] {
+		if count[s] > count[major] {
+			major = s
+		}
+	}
+	var diags []Diagnostic
+	for _, n := range names {
+		if n.style != major {
+			diags = append(diags, Diagnostic{Pos: fields[n.i].Tag.Pos(), Category: "style",
+				Message: fmt.Sprintf("JSON name %q is %s, but %s mostly uses %s", n.name, n.style, name, major)})
+		}
+	}
+	return diags
+}
@@ -0,0 +1,198 @@
+package lint
+
+import (
+	"cmp"
+	"go/importer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// load type-checks src as the only file of package p.
+func load(t *testing.T, src string) (*Package, string) {
+	t.Helper()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "p.go")
+	if err := os.WriteFile(name, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := check(token.NewFileSet(), listed{ImportPath: "p", Dir: dir, GoFiles: []string{"p.go"}}, importer.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg, name
+}
+
+// TestStructTagFixes applies every struct tag fix and checks that none of
+// them gives two fields the same JSON name.
+func TestStructTagFixes(t *testing.T) {
+	const src = `package p
+
+type Base struct {
+	Name string
+}
+
+type T struct {
+	Base
+	ID    int    ` + "`json:id`" + `
+	Ident string ` + "`json:\"id\"`" + `
+	Label string ` + "`json:Name`" + `
+	A     int    ` + "`json:x`" + `
+	B     int    ` + "`json:x`" + `
+	Age   int    ` + "`json: age`" + `
+	Note  string ` + "`json:\"note,omitempy\"`" + `
+}
+`
+	const want = `package p
+
+type Base struct {
+	Name string
+}
+
+type T struct {
+	Base
+	ID    int    ` + "`json:id`" + `
+	Ident string ` + "`json:\"id\"`" + `
+	Label string ` + "`json:Name`" + `
+	A     int    ` + "`json:x`" + `
+	B     int    ` + "`json:x`" + `
+	Age   int    ` + "`json:\"age\"`" + `
+	Note  string ` + "`json:\"note,omitempty\"`" + `
+}
+`
+	pkg, name := load(t, src)
+	diags := StructTags(pkg)
+	notFixed := map[string]bool{}
+	for _, d := range diags {
+		if i := strings.Index(d.Message, "not fixed, since the JSON name "); i >= 0 {
+			notFixed[d.Message[i:]] = true
+		}
+	}
+	for _, msg := range []string{
+		`not fixed, since the JSON name "id" is already used by Ident`,
+		`not fixed, since the JSON name "Name" is already used by Base.Name`,
+		`not fixed, since the JSON name "x" is already used by B`,
+		`not fixed, since the JSON name "x" is already used by A`,
+	} {
+		if !notFixed[msg] {
+			t.Errorf("no diagnostic says %s; got %v", msg, notFixed)
+		}
+	}
+
+	if _, err := ApplyFixes(pkg.Fset, diags); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("after fixes:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Nothing the fixes wrote may collide.
+	pkg, _ = load(t, string(got))
+	for _, d := range StructTags(pkg) {
+		if d.Category == "duplicate" {
+			t.Errorf("fixes introduced a duplicate: %s", d.Message)
+		}
+	}
+}
+
+func TestStructTags(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string   // declarations after package p
+		want []string // category, message and any fix, in source order
+	}{
+		{
+			name: "pairs run together",
+			src:  "type T struct {\n\tA int `json:\"a\"xml:\"b\"`\n}",
+			want: []string{"syntax: struct tag `json:\"a\"xml:\"b\"`: pairs are not separated by spaces after \"json\" (fix: `json:\"a\" xml:\"b\"`)"},
+		},
+		{
+			name: "unterminated value",
+			src:  "type T struct {\n\tA int `json:\"a`\n}",
+			want: []string{"syntax: struct tag `json:\"a`: value of \"json\" is not terminated"},
+		},
+		{
+			name: "invalid JSON name",
+			src:  "type T struct {\n\tA int `json:\"it's\"`\n}",
+			want: []string{`syntax: invalid JSON name "it's"; encoding/json falls back to "A"`},
+		},
+		{
+			name: "key repeated with the same value",
+			src:  "type T struct {\n\tA int `json:\"a\" json:\"a\"`\n}",
+			want: []string{"syntax: struct tag `json:\"a\" json:\"a\"`: repeats key \"json\" (fix: `json:\"a\"`)"},
+		},
+		{
+			name: "key repeated with another value",
+			src:  "type T struct {\n\tA int `json:\"a\" json:\"b\"`\n}",
+			want: []string{"syntax: struct tag `json:\"a\" json:\"b\"`: repeats key \"json\""},
+		},
+		{
+			name: "unexported fields",
+			src:  "type T struct {\n\tname string `json:\"name\"`\n\tage  int    `xml:\"age\"`\n\tskip int    `json:\"-\"`\n\tdb   int    `db:\"db\"`\n}",
+			want: []string{
+				"unexported: unexported field name has a json tag, but encoding/json ignores unexported fields",
+				"unexported: unexported field age has a xml tag, but encoding/xml ignores unexported fields",
+			},
+		},
+		{
+			name: "naming style",
+			src:  "type T struct {\n\tUserID   int    `json:\"user_id\"`\n\tUserName string `json:\"user_name\"`\n\tEmail    string `json:\"emailAddress\"`\n\tAge      int    `json:\"age\"`\n}",
+			want: []string{`style: JSON name "emailAddress" is camelCase, but T mostly uses snake_case`},
+		},
+		{
+			name: "misspelled options",
+			src:  "type T struct {\n\tA int `json:\"a,omitempy\"`\n\tB int `xml:\"b,cdta\"`\n\tC int `xml:\"c,atr\"`\n}",
+			want: []string{
+				`option: unknown json option "omitempy" (fix: ` + "`json:\"a,omitempty\"`)",
+				`option: unknown xml option "cdta" (fix: ` + "`xml:\"b,cdata\"`)",
+				// Both attr and any are two edits away.
+				`option: unknown xml option "atr"`,
+			},
+		},
+		{
+			name: "unknown and misapplied options",
+			src:  "type T struct {\n\tA int   `json:\"a,bogus\"`\n\tB []int `json:\"b,string\"`\n\tC *int  `json:\"c,string\"`\n}",
+			want: []string{
+				`option: unknown json option "bogus"`,
+				"option: the json string option only applies to strings, numbers and booleans, not []int",
+			},
+		},
+		{
+			name: "embedded duplicate with one tagged",
+			src:  "type A struct{ X int }\ntype B struct {\n\tY int `json:\"X\"`\n}\ntype T struct {\n\tA\n\t*B\n}",
+			want: []string{`duplicate: JSON name "X" is used by A.X and B.Y; only the tagged B.Y is encoded`},
+		},
+		{
+			name: "embedded duplicate with none tagged",
+			src:  "type A struct{ X int }\ntype B struct{ X string }\ntype T struct {\n\tA\n\tB\n}",
+			want: []string{`duplicate: JSON name "X" is used by A.X and B.X; encoding/json silently drops all of them`},
+		},
+		{
+			name: "shallower field wins",
+			src:  "type A struct{ X int }\ntype T struct {\n\tA\n\tX string\n}",
+		},
+	}
+	for _, tt := range tests {
+		pkg, _ := load(t, "package p\n\n"+tt.src+"\n")
+		diags := StructTags(pkg)
+		slices.SortStableFunc(diags, func(a, b Diagnostic) int { return cmp.Compare(a.Pos, b.Pos) })
+		var got []string
+		for _, d := range diags {
+			s := d.Category + ": " + d.Message
+			if d.Fix != nil {
+				s += " (fix: " + d.Fix.New + ")"
+			}
+			got = append(got, s)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/lint"
+)
+
+func runShadow(ctx *cli.Context) error {
+	fs := ctx.Flags
+	allow := fs.String("allow", "", "comma-separated names never reported, e.g. err,ok")
+	vars := fs.Bool("vars", true, "report variables shadowing outer variables")
+	imports := fs.Bool("imports", true, "report declarations shadowing imports")
+	universe := fs.Bool("universe", true, "report declarations shadowing predeclared identifiers")
+	strict := fs.Bool("strict", false, "report shadowed variables even if the outer one is not used afterwards")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	cfg := lint.ShadowConfig{
+		Variables: *vars,
+		Imports:   *imports,
+		Universe:  *universe,
+		Strict:    *strict,
+		Allow:     map[string]bool{},
+	}
+	for _, name := range strings.Split(*allow, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.Allow[name] = true
+		}
+	}
+	pkgs, err := lint.Load(".", fs.Args()...)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, pkg := range pkgs {
+		diags := lint.Shadow(pkg, cfg)
+		lint.Report(os.Stdout, pkg.Fset, diags)
+		n += len(diags)
+	}
+	if n > 0 {
+		return fmt.Errorf("shadow: %d findings", n)
+	}
+	return nil
+}
+
+func runTags(ctx *cli.Context) error {
+	fs := ctx.Flags
+	fix := fs.Bool("fix", false, "apply the suggested fixes to the source files")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	pkgs, err := lint.Load(".", fs.Args()...)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, pkg := range pkgs {
+		diags := lint.StructTags(pkg)
+		lint.Report(os.Stdout, pkg.Fset, diags)
+		n += len(diags)
+		if !*fix {
+			continue
+		}
+		changed, err := lint.ApplyFixes(pkg.Fset, diags)
+		for _, name := range changed {
+			fmt.Printf("fixed %s\n", name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if n > 0 {
+		return fmt.Errorf("tags: %d findings", n)
+	}
+	return nil
+}
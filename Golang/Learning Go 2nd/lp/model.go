@@ -0,0 +1,243 @@
+package lp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Var is a decision variable declared on a Model.
+type Var struct {
+	id   int
+	name string
+}
+
+func (v Var) Name() string { return v.name }
+
+// Expr is a linear expression: a coefficient per variable.
+type Expr map[Var]float64
+
+// Model builds a linear program from named variables and constraints.
+//
+//	m := lp.NewModel(lp.Maximize)
+//	x, y := m.Var("x"), m.Var("y")
+//	m.SetObjective(lp.Expr{x: 3, y: 2})
+//	m.AddConstraint("wood", lp.Expr{x: 1, y: 1}, lp.LessEq, 4)
+//	sol, err := m.Solve()
+type Model struct {
+	sense     Sense
+	vars      []variable
+	byName    map[string]Var
+	objective Expr
+	cons      []constraint
+	conByName map[string]int
+}
+
+type variable struct {
+	name string
+	free bool
+	col  int // column of the positive part; col+1 is the negative part of a free variable
+}
+
+type constraint struct {
+	name string
+	expr Expr
+	rel  Relation
+	rhs  float64
+}
+
+func NewModel(sense Sense) *Model {
+	return &Model{
+		sense:     sense,
+		byName:    map[string]Var{},
+		conByName: map[string]int{},
+	}
+}
+
+// Var declares a non-negative variable, or returns the existing variable with
+// that name.
+func (m *Model) Var(name string) Var { return m.declare(name, false) }
+
+// FreeVar declares a variable that may take any sign. It is split into two
+// non-negative columns internally.
+func (m *Model) FreeVar(name string) Var { return m.declare(name, true) }
+
+func (m *Model) declare(name string, free bool) Var {
+	if v, ok := m.byName[name]; ok {
+		return v
+	}
+	col := 0
+	if n := len(m.vars); n > 0 {
+		last := m.vars[n-1]
+		col = last.col + 1
+		if last.free {
+			col++
+		}
+	}
+	v := Var{id: len(m.vars), name: name}
+	m.vars = append(m.vars, variable{name: name, free: free, col: col})
+	m.byName[name] = v
+	return v
+}
+
+// Lookup returns the variable declared under name.
+func (m *Model) Lookup(name string) (Var, bool) {
+	v, ok := m.byName[name]
+	return v, ok
+}
+
+func (m *Model) SetObjective(e Expr) { m.objective = e }
+
+// AddConstraint adds the row expr rel rhs under a unique name, which is later
+// used to read its dual value.
+func (m *Model) AddConstraint(name string, expr Expr, rel Relation, rhs float64) error {
+	if _, dup := m.conByName[name]; dup {
+		return fmt.Errorf("lp: duplicate constraint %q", name)
+	}
+	if err := m.check(expr); err != nil {
+		return fmt.Errorf("lp: constraint %q: %w", name, err)
+	}
+	m.conByName[name] = len(m.cons)
+	m.cons = append(m.cons, constraint{name: name, expr: expr, rel: rel, rhs: rhs})
+	return nil
+}
+
+func (m *Model) check(e Expr) error {
+	for v := range e {
+		if v.id >= len(m.vars) || m.vars[v.id].name != v.name {
+			return fmt.Errorf("variable %q does not belong to this model", v.name)
+		}
+	}
+	return nil
+}
+
+func (m *Model) numCols() int {
+	if len(m.vars) == 0 {
+		return 0
+	}
+	last := m.vars[len(m.vars)-1]
+	if last.free {
+		return last.col + 2
+	}
+	return last.col + 1
+}
+
+func (m *Model) row(e Expr) []float64 {
+	row := make([]float64, m.numCols())
+	for v, c := range e {
+		vr := m.vars[v.id]
+		row[vr.col] += c
+		if vr.free {
+			row[vr.col+1] -= c
+		}
+	}
+	return row
+}
+
+// Problem lowers the model to the matrix form accepted by Solve.
+func (m *Model) Problem() (Problem, error) {
+	if err := m.check(m.objective); err != nil {
+		return Problem{}, fmt.Errorf("lp: objective: %w", err)
+	}
+	p := Problem{Sense: m.sense, C: m.row(m.objective)}
+	for _, c := range m.cons {
+		p.A = append(p.A, m.row(c.expr))
+		p.Rel = append(p.Rel, c.rel)
+		p.B = append(p.B, c.rhs)
+	}
+	return p, nil
+}
+
+// Solve lowers and solves the model. The returned Solution is usable even on
+// error, so callers can inspect its Status.
+func (m *Model) Solve() (*Solution, error) {
+	p, err := m.Problem()
+	if err != nil {
+		return nil, err
+	}
+	res, err := Solve(p)
+	return &Solution{Result: res, model: m}, err
+}
+
+// String prints the model in a readable algebraic form.
+func (m *Model) String() string {
+	var b strings.Builder
+	if m.sense == Maximize {
+		b.WriteString("maximize ")
+	} else {
+		b.WriteString("minimize ")
+	}
+	b.WriteString(m.format(m.objective))
+	b.WriteString("\nsubject to\n")
+	for _, c := range m.cons {
+		fmt.Fprintf(&b, "  %s: %s %s %g\n", c.name, m.format(c.expr), c.rel, c.rhs)
+	}
+	return b.String()
+}
+
+func (m *Model) format(e Expr) string {
+	vs := make([]Var, 0, len(e))
+	for v := range e {
+		vs = append(vs, v)
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i].id < vs[j].id })
+	var b strings.Builder
+	for i, v := range vs {
+		c := e[v]
+		switch {
+		case i == 0 && c < 0:
+			b.WriteString("-")
+		case i > 0 && c < 0:
+			b.WriteString(" - ")
+		case i > 0:
+			b.WriteString(" + ")
+		}
+		if c < 0 {
+			c = -c
+		}
+		if c != 1 {
+			fmt.Fprintf(&b, "%g ", c)
+		}
+		b.WriteString(v.name)
+	}
+	if b.Len() == 0 {
+		return "0"
+	}
+	return b.String()
+}
+
+// Solution maps a solver Result back onto the model's names.
+type Solution struct {
+	Result
+	model *Model
+}
+
+func (s *Solution) Value(v Var) float64 {
+	if s.X == nil {
+		return 0
+	}
+	vr := s.model.vars[v.id]
+	x := s.X[vr.col]
+	if vr.free {
+		x -= s.X[vr.col+1]
+	}
+	return x
+}
+
+// ValueOf returns the value of the variable with the given name.
+func (s *Solution) ValueOf(name string) float64 {
+	v, ok := s.model.byName[name]
+	if !ok {
+		return 0
+	}
+	return s.Value(v)
+}
+
+// Dual returns the shadow price of the named constraint.
+func (s *Solution) Dual(name string) float64 {
+	i, ok := s.model.conByName[name]
+	if !ok || s.Duals == nil {
+		return 0
+	}
+	return s.Duals[i]
+}
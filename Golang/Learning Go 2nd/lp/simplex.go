@@ -0,0 +1,356 @@
+// Package lp solves linear programs with a dense two-phase simplex method
+// (CLRS Chapter 29). Bland's rule picks the entering and leaving variables,
+// so the method never cycles on degenerate problems.
+package lp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Sense tells the solver whether to minimize or maximize the objective.
+type Sense int
+
+const (
+	Minimize Sense = iota
+	Maximize
+)
+
+// Relation is the comparison used by a constraint row.
+type Relation int
+
+const (
+	LessEq Relation = iota
+	Equal
+	GreaterEq
+)
+
+func (r Relation) String() string {
+	switch r {
+	case LessEq:
+		return "<="
+	case Equal:
+		return "="
+	case GreaterEq:
+		return ">="
+	}
+	return fmt.Sprintf("Relation(%d)", int(r))
+}
+
+// Status reports how a solve finished.
+type Status int
+
+const (
+	Optimal Status = iota
+	Infeasible
+	Unbounded
+)
+
+func (s Status) String() string {
+	switch s {
+	case Optimal:
+		return "optimal"
+	case Infeasible:
+		return "infeasible"
+	case Unbounded:
+		return "unbounded"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+var (
+	ErrInfeasible = errors.New("lp: problem is infeasible")
+	ErrUnbounded  = errors.New("lp: problem is unbounded")
+)
+
+// eps is the tolerance used to decide whether a tableau entry is zero.
+const eps = 1e-9
+
+// Problem is a linear program in the form
+//
+//	min/max  C·x
+//	s.t.     A[i]·x  Rel[i]  B[i]   for every row i
+//	         x >= 0
+type Problem struct {
+	Sense Sense
+	C     []float64
+	A     [][]float64
+	Rel   []Relation
+	B     []float64
+}
+
+// Result holds the outcome of Solve. X and Duals are only set when Status is
+// Optimal. Duals[i] is the shadow price of row i: how much the objective
+// changes per unit increase of B[i].
+type Result struct {
+	Status     Status
+	X          []float64
+	Objective  float64
+	Duals      []float64
+	Iterations int
+}
+
+// Solve runs the two-phase simplex method on p. Infeasible and unbounded
+// problems return ErrInfeasible and ErrUnbounded along with the matching
+// Status.
+func Solve(p Problem) (Result, error) {
+	if err := p.validate(); err != nil {
+		return Result{}, err
+	}
+	t := newTableau(p)
+
+	// Phase 1: minimize the sum of artificial variables.
+	if t.numArtificial > 0 {
+		cost := make([]float64, t.cols)
+		for j := t.artStart; j < t.cols; j++ {
+			cost[j] = 1
+		}
+		if st := t.optimize(cost, t.cols); st != Optimal {
+			// Phase 1 is bounded below by zero, so this cannot happen.
+			return Result{Status: st, Iterations: t.iterations}, ErrUnbounded
+		}
+		if t.objective(cost) > 1e-7 {
+			return Result{Status: Infeasible, Iterations: t.iterations}, ErrInfeasible
+		}
+		t.evictArtificials()
+	}
+
+	// Phase 2: the real objective, with artificials barred from entering.
+	cost := make([]float64, t.cols)
+	for j, c := range p.C {
+		if p.Sense == Maximize {
+			c = -c
+		}
+		cost[j] = c
+	}
+	if st := t.optimize(cost, t.artStart); st == Unbounded {
+		return Result{Status: Unbounded, Iterations: t.iterations}, ErrUnbounded
+	}
+
+	res := Result{
+		Status:     Optimal,
+		X:          make([]float64, len(p.C)),
+		Duals:      make([]float64, len(p.B)),
+		Iterations: t.iterations,
+	}
+	for i, j := range t.basis {
+		if j < len(p.C) {
+			res.X[j] = t.rhs(i)
+		}
+	}
+	res.Objective = t.objective(cost)
+	d := t.reducedCosts(cost)
+	for i := range p.B {
+		// The identity column of row i has zero cost, so its reduced cost is
+		// -(c_B B⁻¹)_i, the negated dual of the (possibly flipped) row.
+		y := -d[t.identity[i]]
+		if t.flipped[i] {
+			y = -y
+		}
+		if p.Sense == Maximize {
+			y = -y
+		}
+		if y == 0 {
+			y = 0 // drop the sign of negative zero
+		}
+		res.Duals[i] = y
+	}
+	if p.Sense == Maximize {
+		res.Objective = -res.Objective
+	}
+	return res, nil
+}
+
+func (p Problem) validate() error {
+	m := len(p.A)
+	if len(p.B) != m || len(p.Rel) != m {
+		return fmt.Errorf("lp: %d rows but %d right-hand sides and %d relations", m, len(p.B), len(p.Rel))
+	}
+	for i, row := range p.A {
+		if len(row) != len(p.C) {
+			return fmt.Errorf("lp: row %d has %d coefficients, want %d", i, len(row), len(p.C))
+		}
+	}
+	return nil
+}
+
+// tableau is the dense simplex tableau. Columns are laid out as original
+// variables, then slack/surplus variables, then artificials; the last entry of
+// each row is the right-hand side.
+type tableau struct {
+	rows       [][]float64
+	basis      []int
+	cols       int
+	artStart   int
+	identity   []int // column that started as e_i for row i
+	flipped    []bool
+	iterations int
+
+	numArtificial int
+}
+
+func newTableau(p Problem) *tableau {
+	m, n := len(p.A), len(p.C)
+	slacks, arts := 0, 0
+	for _, r := range p.Rel {
+		if r != Equal {
+			slacks++
+		}
+	}
+	rel := make([]Relation, m)
+	flipped := make([]bool, m)
+	for i, r := range p.Rel {
+		rel[i] = r
+		if p.B[i] < 0 {
+			flipped[i] = true
+			switch r {
+			case LessEq:
+				rel[i] = GreaterEq
+			case GreaterEq:
+				rel[i] = LessEq
+			}
+		}
+		if rel[i] != LessEq {
+			arts++
+		}
+	}
+
+	t := &tableau{
+		rows:          make([][]float64, m),
+		basis:         make([]int, m),
+		cols:          n + slacks + arts,
+		artStart:      n + slacks,
+		identity:      make([]int, m),
+		flipped:       flipped,
+		numArtificial: arts,
+	}
+	slack, art := n, n+slacks
+	for i := range p.A {
+		row := make([]float64, t.cols+1)
+		sign := 1.0
+		if flipped[i] {
+			sign = -1
+		}
+		for j, a := range p.A[i] {
+			row[j] = sign * a
+		}
+		row[t.cols] = sign * p.B[i]
+		switch rel[i] {
+		case LessEq:
+			row[slack] = 1
+			t.basis[i], t.identity[i] = slack, slack
+			slack++
+		case GreaterEq:
+			row[slack] = -1
+			slack++
+			fallthrough
+		case Equal:
+			row[art] = 1
+			t.basis[i], t.identity[i] = art, art
+			art++
+		}
+		t.rows[i] = row
+	}
+	return t
+}
+
+func (t *tableau) rhs(i int) float64 { return t.rows[i][t.cols] }
+
+func (t *tableau) objective(cost []float64) float64 {
+	z := 0.0
+	for i, j := range t.basis {
+		z += cost[j] * t.rhs(i)
+	}
+	return z
+}
+
+// reducedCosts returns c_j - c_B B⁻¹ A_j for every column.
+func (t *tableau) reducedCosts(cost []float64) []float64 {
+	d := make([]float64, t.cols)
+	copy(d, cost)
+	for i, b := range t.basis {
+		cb := cost[b]
+		if cb == 0 {
+			continue
+		}
+		for j, a := range t.rows[i][:t.cols] {
+			d[j] -= cb * a
+		}
+	}
+	return d
+}
+
+// optimize pivots until no column below limit has a negative reduced cost.
+func (t *tableau) optimize(cost []float64, limit int) Status {
+	for {
+		d := t.reducedCosts(cost)
+		enter := -1
+		for j := 0; j < limit; j++ {
+			if d[j] < -eps {
+				enter = j // Bland: lowest index with negative reduced cost
+				break
+			}
+		}
+		if enter < 0 {
+			return Optimal
+		}
+		leave := -1
+		best := math.Inf(1)
+		for i, row := range t.rows {
+			if row[enter] <= eps {
+				continue
+			}
+			ratio := row[t.cols] / row[enter]
+			// Bland: on ties leave with the lowest basic variable index.
+			if ratio < best-eps || (ratio <= best+eps && leave >= 0 && t.basis[i] < t.basis[leave]) {
+				best, leave = ratio, i
+			}
+		}
+		if leave < 0 {
+			return Unbounded
+		}
+		t.pivot(leave, enter)
+	}
+}
+
+func (t *tableau) pivot(r, c int) {
+	t.iterations++
+	pr := t.rows[r]
+	inv := 1 / pr[c]
+	for j := range pr {
+		pr[j] *= inv
+	}
+	pr[c] = 1
+	for i, row := range t.rows {
+		if i == r {
+			continue
+		}
+		f := row[c]
+		if f == 0 {
+			continue
+		}
+		for j := range row {
+			row[j] -= f * pr[j]
+		}
+		row[c] = 0
+	}
+	t.basis[r] = c
+}
+
+// evictArtificials pivots artificial variables that stayed basic at zero out
+// of the basis. A row whose real columns are all zero is redundant; its
+// artificial stays basic at zero and never re-enters the ratio test.
+func (t *tableau) evictArtificials() {
+	for i, b := range t.basis {
+		if b < t.artStart {
+			continue
+		}
+		for j := 0; j < t.artStart; j++ {
+			if math.Abs(t.rows[i][j]) > eps {
+				t.pivot(i, j)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package lp
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Problem
+		want float64
+		err  error
+	}{
+		{
+			// CLRS (29.53)–(29.57): the optimum is 28 at (8, 4, 0).
+			name: "clrs",
+			p: Problem{
+				Sense: Maximize,
+				C:     []float64{3, 1, 2},
+				A:     [][]float64{{1, 1, 3}, {2, 2, 5}, {4, 1, 2}},
+				Rel:   []Relation{LessEq, LessEq, LessEq},
+				B:     []float64{30, 24, 36},
+			},
+			want: 28,
+		},
+		{
+			name: "equality and greater-or-equal",
+			p: Problem{
+				C:   []float64{1, 1},
+				A:   [][]float64{{1, 1}, {1, -1}},
+				Rel: []Relation{GreaterEq, Equal},
+				B:   []float64{2, 0},
+			},
+			want: 2,
+		},
+		{
+			name: "degenerate",
+			p: Problem{
+				Sense: Maximize,
+				C:     []float64{1, 1},
+				A:     [][]float64{{1, 0}, {0, 1}, {1, 1}},
+				Rel:   []Relation{LessEq, LessEq, LessEq},
+				B:     []float64{1, 1, 2},
+			},
+			want: 2,
+		},
+		{
+			name: "infeasible",
+			p: Problem{
+				C:   []float64{1},
+				A:   [][]float64{{1}, {1}},
+				Rel: []Relation{LessEq, GreaterEq},
+				B:   []float64{1, 2},
+			},
+			err: ErrInfeasible,
+		},
+		{
+			name: "unbounded",
+			p: Problem{
+				Sense: Maximize,
+				C:     []float64{1},
+				A:     [][]float64{{1}},
+				Rel:   []Relation{GreaterEq},
+				B:     []float64{1},
+			},
+			err: ErrUnbounded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Solve(tt.p)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Solve: err = %v, want %v", err, tt.err)
+			}
+			if err == nil && math.Abs(res.Objective-tt.want) > 1e-7 {
+				t.Errorf("objective = %g, want %g", res.Objective, tt.want)
+			}
+		})
+	}
+}
+
+func TestSolveBadShape(t *testing.T) {
+	_, err := Solve(Problem{C: []float64{1, 2}, A: [][]float64{{1}}, Rel: []Relation{LessEq}, B: []float64{1}})
+	if err == nil {
+		t.Fatal("Solve accepted a row with too few coefficients")
+	}
+}
+
+// TestSolveVertices compares Solve with enumerating the vertices of random
+// two-variable problems: the optimum of a bounded LP is at a vertex.
+func TestSolveVertices(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for trial := range 500 {
+		p := Problem{Sense: Maximize, C: []float64{rng.Float64()*4 - 1, rng.Float64()*4 - 1}}
+		// A row with positive coefficients keeps the region bounded.
+		p.A = append(p.A, []float64{0.5 + rng.Float64(), 0.5 + rng.Float64()})
+		p.Rel = append(p.Rel, LessEq)
+		p.B = append(p.B, 1+rng.Float64()*9)
+		for range 1 + rng.IntN(4) {
+			p.A = append(p.A, []float64{rng.Float64()*6 - 3, rng.Float64()*6 - 3})
+			p.Rel = append(p.Rel, Relation(rng.IntN(3)))
+			p.B = append(p.B, rng.Float64()*6-1)
+		}
+
+		want, feasible := bestVertex(p)
+		res, err := Solve(p)
+		switch {
+		case !feasible:
+			if !errors.Is(err, ErrInfeasible) {
+				t.Fatalf("trial %d: %+v: got %v (objective %g), want infeasible", trial, p, err, res.Objective)
+			}
+		case err != nil:
+			t.Fatalf("trial %d: %+v: %v, want objective %g", trial, p, err, want)
+		case math.Abs(res.Objective-want) > 1e-6:
+			t.Fatalf("trial %d: %+v: objective %g, want %g", trial, p, res.Objective, want)
+		}
+	}
+}
+
+// bestVertex intersects every pair of boundary lines, x = 0 and y = 0
+// included, and returns the best feasible intersection.
+func bestVertex(p Problem) (float64, bool) {
+	type line struct{ a, b, c float64 } // a·x + b·y = c
+	lines := []line{{1, 0, 0}, {0, 1, 0}}
+	for i, row := range p.A {
+		lines = append(lines, line{row[0], row[1], p.B[i]})
+	}
+	best, found := math.Inf(-1), false
+	for i := range lines {
+		for j := i + 1; j < len(lines); j++ {
+			l, m := lines[i], lines[j]
+			det := l.a*m.b - l.b*m.a
+			if math.Abs(det) < 1e-12 {
+				continue
+			}
+			x, y := (l.c*m.b-l.b*m.c)/det, (l.a*m.c-l.c*m.a)/det
+			if feasible(p, x, y) {
+				best, found = max(best, p.C[0]*x+p.C[1]*y), true
+			}
+		}
+	}
+	return best, found
+}
+
+func feasible(p Problem, x, y float64) bool {
+	const tol = 1e-9
+	if x < -tol || y < -tol {
+		return false
+	}
+	for i, row := range p.A {
+		v := row[0]*x + row[1]*y
+		switch p.Rel[i] {
+		case LessEq:
+			if v > p.B[i]+tol {
+				return false
+			}
+		case GreaterEq:
+			if v < p.B[i]-tol {
+				return false
+			}
+		case Equal:
+			if math.Abs(v-p.B[i]) > tol {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// TestDuals checks each shadow price against the change in the optimum
+// when the row's right-hand side grows a little.
+func TestDuals(t *testing.T) {
+	p := Problem{
+		Sense: Maximize,
+		C:     []float64{3, 1, 2},
+		A:     [][]float64{{1, 1, 3}, {2, 2, 5}, {4, 1, 2}},
+		Rel:   []Relation{LessEq, LessEq, LessEq},
+		B:     []float64{30, 24, 36},
+	}
+	base, err := Solve(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const delta = 1e-3
+	for i := range p.B {
+		q := p
+		q.B = append([]float64(nil), p.B...)
+		q.B[i] += delta
+		res, err := Solve(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := (res.Objective - base.Objective) / delta; math.Abs(got-base.Duals[i]) > 1e-6 {
+			t.Errorf("row %d: objective grows %g per unit, dual is %g", i, got, base.Duals[i])
+		}
+	}
+}
+
+func TestModel(t *testing.T) {
+	m := NewModel(Maximize)
+	x, y := m.Var("x"), m.FreeVar("y")
+	m.SetObjective(Expr{x: 1, y: -1})
+	for _, c := range []struct {
+		name string
+		e    Expr
+		rel  Relation
+		rhs  float64
+	}{
+		{"cap", Expr{x: 1}, LessEq, 4},
+		{"link", Expr{x: 1, y: 1}, Equal, 1},
+	} {
+		if err := m.AddConstraint(c.name, c.e, c.rel, c.rhs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.AddConstraint("cap", Expr{x: 1}, LessEq, 1); err == nil {
+		t.Error("AddConstraint accepted a duplicate name")
+	}
+	sol, err := m.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// y is free, so it goes negative: x = 4, y = -3.
+	if got := []float64{sol.Value(x), sol.ValueOf("y"), sol.Objective}; got[0] != 4 || math.Abs(got[1]+3) > 1e-9 || math.Abs(got[2]-7) > 1e-9 {
+		t.Errorf("x, y, objective = %v, want 4, -3, 7", got)
+	}
+}
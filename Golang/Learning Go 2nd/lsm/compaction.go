@@ -0,0 +1,187 @@
+package lsm
+
+import (
+	"bytes"
+	"os"
+	"slices"
+)
+
+// compact runs compactions until every level is within its budget: L0 by
+// table count, deeper levels by total size. Callers hold the write lock.
+func (db *DB) compact() error {
+	for {
+		if len(db.levels[0]) >= db.cfg.L0CompactionTrigger {
+			if err := db.compactLevel(0, db.levels[0]); err != nil {
+				return err
+			}
+			continue
+		}
+		level := -1
+		for i := 1; i < numLevels-1; i++ {
+			if db.levelBytes(i) > db.cfg.maxLevelBytes(i) {
+				level = i
+				break
+			}
+		}
+		if level < 0 {
+			return nil
+		}
+		if err := db.compactLevel(level, []*table{db.pick(level)}); err != nil {
+			return err
+		}
+	}
+}
+
+func (db *DB) levelBytes(i int) int64 {
+	var n int64
+	for _, t := range db.levels[i] {
+		n += t.meta.Size
+	}
+	return n
+}
+
+// pick chooses the next table of a level in round-robin key order, so
+// repeated compactions sweep the whole key space.
+func (db *DB) pick(level int) *table {
+	tables := db.levels[level]
+	t := tables[0]
+	for _, c := range tables {
+		if bytes.Compare(c.meta.Smallest, db.cursor[level]) > 0 {
+			t = c
+			break
+		}
+	}
+	db.cursor[level] = t.meta.Largest
+	return t
+}
+
+// compactLevel merges inputs from level with the overlapping tables of the
+// next level and replaces both with the merged output in the next level.
+func (db *DB) compactLevel(level int, inputs []*table) error {
+	out := level + 1
+	smallest, largest := inputs[0].meta.Smallest, inputs[0].meta.Largest
+	for _, t := range inputs[1:] {
+		if bytes.Compare(t.meta.Smallest, smallest) < 0 {
+			smallest = t.meta.Smallest
+		}
+		if bytes.Compare(t.meta.Largest, largest) > 0 {
+			largest = t.meta.Largest
+		}
+	}
+	var overlapping, keep []*table
+	for _, t := range db.levels[out] {
+		if bytes.Compare(t.meta.Largest, smallest) >= 0 && bytes.Compare(t.meta.Smallest, largest) <= 0 {
+			overlapping = append(overlapping, t)
+		} else {
+			keep = append(keep, t)
+		}
+	}
+
+	// Inputs are newer than the next level, and L0 is kept newest first, so
+	// listing them in this order lets the merge keep the newest version.
+	var sources []source
+	var expected uint64
+	for _, t := range slices.Concat(inputs, overlapping) {
+		sources = append(sources, t.iter(nil))
+		expected += t.meta.Entries
+	}
+	// A tombstone can be dropped once nothing below the output level could
+	// still hold an older value for its key.
+	bottom := true
+	for _, l := range db.levels[out+1:] {
+		if len(l) > 0 {
+			bottom = false
+		}
+	}
+
+	outputs, err := db.writeTables(newMergeIter(sources), expected, bottom)
+	if err != nil {
+		return err
+	}
+	next := append(keep, outputs...)
+	slices.SortFunc(next, func(a, b *table) int { return bytes.Compare(a.meta.Smallest, b.meta.Smallest) })
+	old := db.levels
+	db.levels[out] = next
+	db.levels[level] = slices.DeleteFunc(slices.Clone(db.levels[level]), func(t *table) bool { return slices.Contains(inputs, t) })
+	if err := db.saveManifest(); err != nil {
+		db.levels = old
+		for _, t := range outputs {
+			t.close()
+			os.Remove(db.tablePath(t.meta.Num))
+		}
+		return err
+	}
+	db.stats.Compactions++
+	for _, t := range outputs {
+		db.stats.CompactionBytes += t.meta.Size
+	}
+	// The manifest no longer references the inputs, so they can go.
+	for _, t := range slices.Concat(inputs, overlapping) {
+		t.close()
+		os.Remove(db.tablePath(t.meta.Num))
+	}
+	return nil
+}
+
+// writeTables drains it into new tables of about cfg.TableSize bytes each.
+func (db *DB) writeTables(it *mergeIter, expected uint64, dropTombstones bool) ([]*table, error) {
+	var (
+		outputs []*table
+		w       *tableWriter
+	)
+	fail := func(err error) ([]*table, error) {
+		if w != nil {
+			w.abort()
+		}
+		for _, t := range outputs {
+			t.close()
+			os.Remove(db.tablePath(t.meta.Num))
+		}
+		return nil, err
+	}
+	finish := func() error {
+		meta, err := w.finish()
+		w = nil
+		if err != nil {
+			os.Remove(db.tablePath(meta.Num))
+			return err
+		}
+		t, err := openTable(db.tablePath(meta.Num), meta)
+		if err != nil {
+			return err
+		}
+		outputs = append(outputs, t)
+		return nil
+	}
+	for it.next() {
+		e := it.entry()
+		if dropTombstones && e.kind == kindDelete {
+			continue
+		}
+		if w == nil {
+			num := db.nextNum
+			db.nextNum++
+			var err error
+			if w, err = newTableWriter(db.tablePath(num), num, expected, db.cfg); err != nil {
+				return fail(err)
+			}
+		}
+		if err := w.add(e.key, e.value, e.kind); err != nil {
+			return fail(err)
+		}
+		if int64(w.size()) >= db.cfg.TableSize {
+			if err := finish(); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	if err := it.err(); err != nil {
+		return fail(err)
+	}
+	if w != nil {
+		if err := finish(); err != nil {
+			return fail(err)
+		}
+	}
+	return outputs, nil
+}
@@ -0,0 +1,110 @@
+package lsm
+
+import (
+	"fmt"
+
+	"learning-go.adcon.dev/options"
+)
+
+// numLevels is the depth of the tree: L0 holds freshly flushed, possibly
+// overlapping tables; L1 and below are each one sorted run.
+const numLevels = 7
+
+// Config tunes a DB. Zero fields take the defaults in DefaultConfig.
+type Config struct {
+	MemtableSize        int     `json:"memtable_size"`         // bytes buffered in memory before a flush
+	BlockSize           int     `json:"block_size"`            // target size of an SSTable data block
+	TableSize           int64   `json:"table_size"`            // compaction output is split into tables of about this size
+	L0CompactionTrigger int     `json:"l0_compaction_trigger"` // number of L0 tables that starts an L0→L1 compaction
+	BaseLevelSize       int64   `json:"base_level_size"`       // byte budget of L1
+	LevelSizeMultiplier int     `json:"level_size_multiplier"` // each level below L1 may be this much larger
+	BloomFalsePositive  float64 `json:"bloom_false_positive"`  // per-table Bloom filter rate
+	SyncWrites          bool    `json:"sync_writes"`           // fsync the WAL on every write
+}
+
+// EnvPrefix starts the environment variables that set Config fields, as in
+// LSM_MEMTABLE_SIZE; see options.FromEnv.
+const EnvPrefix = "LSM"
+
+// Option sets one Config field. Open takes options alongside a Config, and
+// options.FromJSON and options.FromEnv load the same settings from files
+// and the environment.
+type Option = options.Option[Config]
+
+func WithMemtableSize(n int) Option           { return options.New[Config]("memtable_size", n) }
+func WithBlockSize(n int) Option              { return options.New[Config]("block_size", n) }
+func WithTableSize(n int64) Option            { return options.New[Config]("table_size", n) }
+func WithL0CompactionTrigger(n int) Option    { return options.New[Config]("l0_compaction_trigger", n) }
+func WithBaseLevelSize(n int64) Option        { return options.New[Config]("base_level_size", n) }
+func WithLevelSizeMultiplier(n int) Option    { return options.New[Config]("level_size_multiplier", n) }
+func WithBloomFalsePositive(p float64) Option { return options.New[Config]("bloom_false_positive", p) }
+func WithSyncWrites(sync bool) Option         { return options.New[Config]("sync_writes", sync) }
+
+// DefaultConfig returns the settings used for zero Config fields.
+func DefaultConfig() Config {
+	return Config{
+		MemtableSize:        4 << 20,
+		BlockSize:           4 << 10,
+		TableSize:           2 << 20,
+		L0CompactionTrigger: 4,
+		BaseLevelSize:       10 << 20,
+		LevelSizeMultiplier: 10,
+		BloomFalsePositive:  0.01,
+	}
+}
+
+// Resolve applies opts over c, fills in defaults and validates the result,
+// as Open does.
+func (c Config) Resolve(opts ...Option) (Config, error) {
+	c, err := options.Apply(c, opts...)
+	if err != nil {
+		return c, fmt.Errorf("lsm: %w", err)
+	}
+	return c.withDefaults()
+}
+
+func (c Config) withDefaults() (Config, error) {
+	d := DefaultConfig()
+	if c.MemtableSize == 0 {
+		c.MemtableSize = d.MemtableSize
+	}
+	if c.BlockSize == 0 {
+		c.BlockSize = d.BlockSize
+	}
+	if c.TableSize == 0 {
+		c.TableSize = d.TableSize
+	}
+	if c.L0CompactionTrigger == 0 {
+		c.L0CompactionTrigger = d.L0CompactionTrigger
+	}
+	if c.BaseLevelSize == 0 {
+		c.BaseLevelSize = d.BaseLevelSize
+	}
+	if c.LevelSizeMultiplier == 0 {
+		c.LevelSizeMultiplier = d.LevelSizeMultiplier
+	}
+	if c.BloomFalsePositive == 0 {
+		c.BloomFalsePositive = d.BloomFalsePositive
+	}
+	switch {
+	case c.MemtableSize < 0 || c.BlockSize < 0 || c.TableSize < 0 || c.BaseLevelSize < 0:
+		return c, fmt.Errorf("lsm: sizes must be positive")
+	case c.L0CompactionTrigger < 1:
+		return c, fmt.Errorf("lsm: L0 compaction trigger %d must be at least 1", c.L0CompactionTrigger)
+	case c.LevelSizeMultiplier < 2:
+		return c, fmt.Errorf("lsm: level size multiplier %d must be at least 2", c.LevelSizeMultiplier)
+	case c.BloomFalsePositive <= 0 || c.BloomFalsePositive >= 1:
+		return c, fmt.Errorf("lsm: Bloom false positive rate %g must be in (0, 1)", c.BloomFalsePositive)
+	}
+	return c, nil
+}
+
+// maxLevelBytes is the size above which level i (i >= 1) is compacted into
+// the next one.
+func (c Config) maxLevelBytes(i int) int64 {
+	n := c.BaseLevelSize
+	for ; i > 1; i-- {
+		n *= int64(c.LevelSizeMultiplier)
+	}
+	return n
+}
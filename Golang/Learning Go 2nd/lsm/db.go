@@ -0,0 +1,395 @@
+// Package lsm is a log-structured merge-tree key-value engine. Writes go to a
+// write-ahead log and an in-memory skip list; full memtables are flushed to
+// immutable SSTables in level 0, and leveled compaction merges them down into
+// non-overlapping sorted runs. Reads check the memtable, then every L0 table
+// newest first, then at most one table per deeper level, using each table's
+// Bloom filter to skip most disk reads.
+package lsm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"learning-go.adcon.dev/kv"
+	"learning-go.adcon.dev/skiplist"
+)
+
+// memEntry is a memtable slot: a value or a tombstone.
+type memEntry struct {
+	value []byte
+	kind  kind
+}
+
+// entryOverhead approximates the per-entry bookkeeping counted against
+// MemtableSize on top of the key and value bytes.
+const entryOverhead = 32
+
+// DB is an LSM-tree store. It implements kv.Store and is safe for concurrent
+// use; writers are serialized and flushes and compactions run inline on the
+// write that triggers them.
+type DB struct {
+	mu      sync.RWMutex
+	dir     string
+	cfg     Config
+	mem     *skiplist.SkipList[string, memEntry]
+	memSize int
+	wal     *wal
+	seq     uint64
+	levels  [numLevels][]*table // L0 newest first; deeper levels sorted by key
+	nextNum uint64
+	flushed uint64 // last sequence number persisted in tables
+	cursor  [numLevels][]byte
+	stats   Stats
+	closed  bool
+
+	watch     func(Record)
+	restoring bool // a restore was interrupted; see NeedsRestore
+}
+
+var _ kv.Store = (*DB)(nil)
+
+// Open opens or creates the database in dir, replaying the WAL into a fresh
+// memtable. Opts are applied over cfg; see Option.
+func Open(dir string, cfg Config, opts ...Option) (*DB, error) {
+	cfg, err := cfg.Resolve(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	m, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{
+		dir:     dir,
+		cfg:     cfg,
+		mem:     skiplist.NewOrdered[string, memEntry](),
+		nextNum: m.NextNum,
+		flushed: m.FlushedSeq,
+		seq:     m.FlushedSeq,
+	}
+	live := map[string]bool{}
+	for level, metas := range m.Levels {
+		for _, meta := range metas {
+			t, err := openTable(db.tablePath(meta.Num), meta)
+			if err != nil {
+				db.closeTables()
+				return nil, err
+			}
+			db.levels[level] = append(db.levels[level], t)
+			live[tableName(meta.Num)] = true
+		}
+	}
+	// Tables missing from the manifest were left by an interrupted flush or
+	// compaction.
+	if names, err := filepath.Glob(filepath.Join(dir, "*.sst")); err == nil {
+		for _, name := range names {
+			if !live[filepath.Base(name)] {
+				os.Remove(name)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, restoreMarker)); err == nil {
+		db.restoring = true
+	}
+	db.wal, err = openWAL(filepath.Join(dir, walName), cfg.SyncWrites, func(r record) {
+		if r.seq <= db.flushed {
+			return // already in a table
+		}
+		db.seq = max(db.seq, r.seq)
+		db.apply(r.key, r.value, r.kind)
+	})
+	if err != nil {
+		db.closeTables()
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *DB) tablePath(num uint64) string { return filepath.Join(db.dir, tableName(num)) }
+
+func (db *DB) apply(key, value []byte, k kind) {
+	db.mem.Put(string(key), memEntry{value: value, kind: k})
+	db.memSize += len(key) + len(value) + entryOverhead
+}
+
+func (db *DB) Put(key, value []byte) error {
+	return db.write(key, bytes.Clone(value), kindPut)
+}
+
+// Delete writes a tombstone; the key's older versions are dropped once
+// compaction pushes the tombstone to the bottom of the tree.
+func (db *DB) Delete(key []byte) error {
+	return db.write(key, nil, kindDelete)
+}
+
+func (db *DB) write(key, value []byte, k kind) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return kv.ErrClosed
+	}
+	return db.writeLocked(db.seq+1, key, value, k)
+}
+
+// writeLocked logs and applies one mutation under the given sequence number.
+func (db *DB) writeLocked(seq uint64, key, value []byte, k kind) error {
+	if err := db.wal.append(record{seq: seq, kind: k, key: key, value: value}); err != nil {
+		return fmt.Errorf("lsm: writing WAL: %w", err)
+	}
+	db.seq = seq
+	db.apply(key, value, k)
+	db.stats.UserBytes += int64(len(key) + len(value))
+	if db.watch != nil {
+		db.watch(Record{Seq: seq, Delete: k == kindDelete, Key: bytes.Clone(key), Value: value})
+	}
+	if db.memSize < db.cfg.MemtableSize {
+		return nil
+	}
+	if err := db.flush(); err != nil {
+		return err
+	}
+	return db.compact()
+}
+
+func (db *DB) Get(key []byte) ([]byte, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return nil, kv.ErrClosed
+	}
+	if e, ok := db.mem.Get(string(key)); ok {
+		return found(e.value, e.kind)
+	}
+	for _, t := range db.levels[0] {
+		v, k, ok, err := t.get(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return found(v, k)
+		}
+	}
+	for _, level := range db.levels[1:] {
+		// Deeper levels do not overlap: only the first table whose largest
+		// key is >= key can hold it.
+		i := sort.Search(len(level), func(i int) bool { return bytes.Compare(level[i].meta.Largest, key) >= 0 })
+		if i == len(level) {
+			continue
+		}
+		v, k, ok, err := level[i].get(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return found(v, k)
+		}
+	}
+	return nil, kv.ErrNotFound
+}
+
+func found(v []byte, k kind) ([]byte, error) {
+	if k == kindDelete {
+		return nil, kv.ErrNotFound
+	}
+	return v, nil
+}
+
+// Scan merges the memtable and every overlapping table. The read lock is held
+// for the whole scan, so fn must not write to the DB.
+func (db *DB) Scan(start, end []byte, fn func(key, value []byte) bool) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return kv.ErrClosed
+	}
+	return db.scanRange(start, end, fn)
+}
+
+func (db *DB) scanRange(start, end []byte, fn func(key, value []byte) bool) error {
+	mem := newMemSource(db.mem.From(string(start)))
+	defer mem.stop()
+	sources := []source{mem}
+	for _, level := range db.levels {
+		for _, t := range level {
+			if t.meta.overlaps(start, end) {
+				sources = append(sources, t.iter(start))
+			}
+		}
+	}
+	it := newMergeIter(sources)
+	for it.next() {
+		e := it.entry()
+		if end != nil && bytes.Compare(e.key, end) >= 0 {
+			break
+		}
+		if e.kind == kindDelete {
+			continue
+		}
+		if !fn(e.key, e.value) {
+			break
+		}
+	}
+	return it.err()
+}
+
+// Flush writes the memtable to a new L0 table even if it is not full.
+func (db *DB) Flush() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return kv.ErrClosed
+	}
+	if err := db.flush(); err != nil {
+		return err
+	}
+	return db.compact()
+}
+
+// flush turns the memtable into an L0 table, records it in the manifest and
+// starts a new WAL. Callers hold the write lock.
+func (db *DB) flush() error {
+	if db.mem.Len() == 0 {
+		return nil
+	}
+	num := db.nextNum
+	db.nextNum++
+	w, err := newTableWriter(db.tablePath(num), num, uint64(db.mem.Len()), db.cfg)
+	if err != nil {
+		return err
+	}
+	for k, e := range db.mem.All() {
+		if err := w.add([]byte(k), e.value, e.kind); err != nil {
+			w.abort()
+			return err
+		}
+	}
+	meta, err := w.finish()
+	if err != nil {
+		os.Remove(db.tablePath(num))
+		return err
+	}
+	t, err := openTable(db.tablePath(num), meta)
+	if err != nil {
+		return err
+	}
+	db.levels[0] = append([]*table{t}, db.levels[0]...)
+	db.flushed = db.seq
+	if err := db.saveManifest(); err != nil {
+		return err
+	}
+	db.stats.Flushes++
+	db.stats.FlushBytes += meta.Size
+
+	// Every record in the log is now in a table.
+	if err := db.wal.close(); err != nil {
+		return err
+	}
+	path := filepath.Join(db.dir, walName)
+	if err := os.Truncate(path, 0); err != nil {
+		return err
+	}
+	if db.wal, err = openWAL(path, db.cfg.SyncWrites, func(record) {}); err != nil {
+		return err
+	}
+	db.mem = skiplist.NewOrdered[string, memEntry]()
+	db.memSize = 0
+	return nil
+}
+
+func (db *DB) saveManifest() error {
+	m := manifest{NextNum: db.nextNum, FlushedSeq: db.flushed}
+	for i, level := range db.levels {
+		for _, t := range level {
+			m.Levels[i] = append(m.Levels[i], t.meta)
+		}
+	}
+	return writeManifest(db.dir, m)
+}
+
+// Close closes the WAL and tables. Unflushed writes stay in the WAL and are
+// replayed by the next Open.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return nil
+	}
+	db.closed = true
+	err := db.wal.close()
+	if cerr := db.closeTables(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (db *DB) closeTables() error {
+	var err error
+	for _, level := range db.levels {
+		for _, t := range level {
+			if cerr := t.close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+// LevelStats describes one level of the tree.
+type LevelStats struct {
+	Tables int
+	Bytes  int64
+}
+
+// Stats reports the shape of the tree and how much I/O it has done.
+type Stats struct {
+	Levels          [numLevels]LevelStats
+	MemtableBytes   int
+	Flushes         int
+	Compactions     int
+	UserBytes       int64 // key and value bytes written by callers
+	FlushBytes      int64 // table bytes written by flushes
+	CompactionBytes int64 // table bytes written by compactions
+}
+
+// WriteAmplification is the table bytes written per byte of user data. The
+// WAL, which adds about one more, is not counted.
+func (s Stats) WriteAmplification() float64 {
+	if s.UserBytes == 0 {
+		return 0
+	}
+	return float64(s.FlushBytes+s.CompactionBytes) / float64(s.UserBytes)
+}
+
+func (s Stats) String() string {
+	var b strings.Builder
+	for i, l := range s.Levels {
+		if l.Tables > 0 {
+			fmt.Fprintf(&b, "L%d: %d tables, %d bytes\n", i, l.Tables, l.Bytes)
+		}
+	}
+	fmt.Fprintf(&b, "memtable %d bytes, %d flushes, %d compactions, write amplification %.2f",
+		s.MemtableBytes, s.Flushes, s.Compactions, s.WriteAmplification())
+	return b.String()
+}
+
+func (db *DB) Stats() Stats {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	s := db.stats
+	s.MemtableBytes = db.memSize
+	for i, level := range db.levels {
+		s.Levels[i].Tables = len(level)
+		for _, t := range level {
+			s.Levels[i].Bytes += t.meta.Size
+		}
+	}
+	return s
+}
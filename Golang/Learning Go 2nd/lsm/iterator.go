@@ -0,0 +1,110 @@
+package lsm
+
+import (
+	"bytes"
+	"container/heap"
+	"iter"
+)
+
+// source is a sorted stream of entries; mergeIter combines several.
+type source interface {
+	next() bool
+	entry() blockEntry
+}
+
+// memSource walks the memtable through a pulled iterator; stop must be
+// called once the scan is done.
+type memSource struct {
+	pull func() (string, memEntry, bool)
+	stop func()
+	cur  blockEntry
+}
+
+func newMemSource(seq iter.Seq2[string, memEntry]) *memSource {
+	next, stop := iter.Pull2(seq)
+	return &memSource{pull: next, stop: stop}
+}
+
+func (s *memSource) next() bool {
+	k, e, ok := s.pull()
+	if ok {
+		s.cur = blockEntry{key: []byte(k), value: e.value, kind: e.kind}
+	}
+	return ok
+}
+
+func (s *memSource) entry() blockEntry { return s.cur }
+
+// mergeIter yields the union of its sources in key order. When several
+// sources hold the same key, the one listed first (the newest) wins.
+type mergeIter struct {
+	sources []source
+	h       sourceHeap
+	cur     blockEntry
+}
+
+func newMergeIter(sources []source) *mergeIter {
+	m := &mergeIter{sources: sources, h: sourceHeap{sources: sources}}
+	for i, s := range sources {
+		if s.next() {
+			m.h.items = append(m.h.items, i)
+		}
+	}
+	heap.Init(&m.h)
+	return m
+}
+
+func (m *mergeIter) next() bool {
+	if m.h.Len() == 0 {
+		return false
+	}
+	top := m.h.items[0]
+	m.cur = m.sources[top].entry()
+	m.advance()
+	for m.h.Len() > 0 && bytes.Equal(m.sources[m.h.items[0]].entry().key, m.cur.key) {
+		m.advance() // an older version of the same key
+	}
+	return true
+}
+
+// advance moves the source at the top of the heap forward.
+func (m *mergeIter) advance() {
+	if m.sources[m.h.items[0]].next() {
+		heap.Fix(&m.h, 0)
+	} else {
+		heap.Pop(&m.h)
+	}
+}
+
+func (m *mergeIter) entry() blockEntry { return m.cur }
+
+// err returns the first error hit by a table source.
+func (m *mergeIter) err() error {
+	for _, s := range m.sources {
+		if t, ok := s.(*tableIter); ok && t.err != nil {
+			return t.err
+		}
+	}
+	return nil
+}
+
+type sourceHeap struct {
+	sources []source
+	items   []int // source indexes; lower index means newer
+}
+
+func (h *sourceHeap) Len() int { return len(h.items) }
+func (h *sourceHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if c := bytes.Compare(h.sources[a].entry().key, h.sources[b].entry().key); c != 0 {
+		return c < 0
+	}
+	return a < b
+}
+func (h *sourceHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *sourceHeap) Push(x any)    { h.items = append(h.items, x.(int)) }
+func (h *sourceHeap) Pop() any {
+	x := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return x
+}
@@ -0,0 +1,344 @@
+package lsm
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"learning-go.adcon.dev/kv"
+)
+
+// small makes every few kilobytes of writes flush, and every few flushes
+// compact, so short tests reach the deeper levels.
+var small = Config{MemtableSize: 2 << 10, BlockSize: 256, TableSize: 4 << 10, L0CompactionTrigger: 2, BaseLevelSize: 8 << 10, LevelSizeMultiplier: 2}
+
+func open(t *testing.T, dir string, cfg Config) *DB {
+	t.Helper()
+	db, err := Open(dir, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func get(db *DB, key string) string {
+	v, err := db.Get([]byte(key))
+	if errors.Is(err, kv.ErrNotFound) {
+		return "<absent>"
+	}
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return string(v)
+}
+
+func contents(t *testing.T, db *DB, start, end []byte) map[string]string {
+	t.Helper()
+	m := map[string]string{}
+	var prev string
+	err := db.Scan(start, end, func(k, v []byte) bool {
+		if len(m) > 0 && string(k) <= prev {
+			t.Errorf("Scan yields %q after %q", k, prev)
+		}
+		prev = string(k)
+		m[string(k)] = string(v)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// tombstones counts the deletion markers stored in tables.
+func tombstones(t *testing.T, db *DB) int {
+	t.Helper()
+	n := 0
+	for _, level := range db.levels {
+		for _, tbl := range level {
+			it := tbl.iter(nil)
+			for it.next() {
+				if it.entry().kind == kindDelete {
+					n++
+				}
+			}
+			if it.err != nil {
+				t.Fatal(it.err)
+			}
+		}
+	}
+	return n
+}
+
+func TestWALTornTail(t *testing.T) {
+	for _, tear := range []struct {
+		name string
+		edit func(data []byte) []byte
+	}{
+		{"truncated", func(data []byte) []byte { return data[:len(data)-3] }},
+		{"bad checksum", func(data []byte) []byte { data[len(data)-1] ^= 0xff; return data }},
+		{"half a header", func(data []byte) []byte { return append(data, 0, 0, 0) }},
+	} {
+		dir := t.TempDir()
+		db := open(t, dir, Config{})
+		for _, k := range []string{"a", "b", "c"} {
+			if err := db.Put([]byte(k), []byte(k+k)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		db.Close()
+		path := filepath.Join(dir, walName)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, tear.edit(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		db = open(t, dir, Config{})
+		want := "aa bb cc"
+		if tear.name != "half a header" {
+			want = "aa bb <absent>"
+		}
+		if got := get(db, "a") + " " + get(db, "b") + " " + get(db, "c"); got != want {
+			t.Errorf("%s: after replay %q, want %q", tear.name, got, want)
+		}
+		// New records go after the last intact one, so they survive the
+		// next replay.
+		if err := db.Put([]byte("d"), []byte("dd")); err != nil {
+			t.Fatal(err)
+		}
+		seq := db.LastSeq()
+		db.Close()
+		db = open(t, dir, Config{})
+		if got := get(db, "d"); got != "dd" || db.LastSeq() != seq {
+			t.Errorf("%s: after a write and reopen, d = %q at seq %d, want dd at %d", tear.name, got, db.LastSeq(), seq)
+		}
+		db.Close()
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	db := open(t, dir, small)
+	want := map[string]string{}
+	put := func(n int) {
+		for i := range n {
+			k, v := fmt.Sprintf("key%04d", i), fmt.Sprintf("value %d/%d", i, n)
+			if err := db.Put([]byte(k), []byte(v)); err != nil {
+				t.Fatal(err)
+			}
+			want[k] = v
+		}
+	}
+	reopen := func(stage string) {
+		t.Helper()
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+		db = open(t, dir, small)
+		if got := contents(t, db, nil, nil); !maps.Equal(got, want) {
+			t.Fatalf("after %s and reopen: %d keys, want %d", stage, len(got), len(want))
+		}
+	}
+
+	put(10)
+	if err := db.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Stats(); s.Flushes != 1 || s.Compactions != 0 {
+		t.Fatalf("after one flush: %+v", s)
+	}
+	reopen("a flush")
+
+	put(2000)
+	if s := db.Stats(); s.Compactions == 0 {
+		t.Fatalf("no compaction after 2000 puts: %v", s)
+	}
+	reopen("compactions")
+
+	// A table the manifest does not list is garbage from a crash.
+	stray := filepath.Join(dir, tableName(999999))
+	if err := os.WriteFile(stray, []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reopen("a crash mid-compaction")
+	if _, err := os.Stat(stray); !os.IsNotExist(err) {
+		t.Errorf("unlisted table survived Open: %v", err)
+	}
+	db.Close()
+}
+
+func TestTombstones(t *testing.T) {
+	dir := t.TempDir()
+	// A trigger this high leaves compaction to the test.
+	db := open(t, dir, Config{L0CompactionTrigger: 100})
+	defer db.Close()
+	for i := range 10 {
+		db.Put(fmt.Appendf(nil, "k%d", i), []byte("v"))
+	}
+	db.Flush()
+	if err := db.compactLevel(0, db.levels[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.compactLevel(1, db.levels[1]); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 5 {
+		db.Delete(fmt.Appendf(nil, "k%d", i))
+	}
+	db.Flush()
+
+	// L2 may still hold the deleted values, so L1 must keep the tombstones.
+	if err := db.compactLevel(0, db.levels[0]); err != nil {
+		t.Fatal(err)
+	}
+	if n := tombstones(t, db); n != 5 {
+		t.Errorf("%d tombstones compacted into L1 above data, want 5", n)
+	}
+	if got := get(db, "k0") + " " + get(db, "k9"); got != "<absent> v" {
+		t.Errorf("k0 k9 = %q, want %q", got, "<absent> v")
+	}
+	// Nothing is below L2, so the tombstones and the values they hide go.
+	if err := db.compactLevel(1, db.levels[1]); err != nil {
+		t.Fatal(err)
+	}
+	if n := tombstones(t, db); n != 0 {
+		t.Errorf("%d tombstones left at the bottom level", n)
+	}
+	var entries uint64
+	for _, tbl := range db.levels[2] {
+		entries += tbl.meta.Entries
+	}
+	if entries != 5 {
+		t.Errorf("bottom level holds %d entries, want the 5 live keys", entries)
+	}
+	if got := get(db, "k0") + " " + get(db, "k9"); got != "<absent> v" {
+		t.Errorf("after dropping tombstones, k0 k9 = %q, want %q", got, "<absent> v")
+	}
+}
+
+// TestAgainstMap runs random puts, deletes and reopens with a tiny memtable
+// and checks scans and reads against a map.
+func TestAgainstMap(t *testing.T) {
+	dir := t.TempDir()
+	cfg := small
+	cfg.BaseLevelSize = 2 << 10
+	db := open(t, dir, cfg)
+	defer func() { db.Close() }()
+	rng := rand.New(rand.NewPCG(1, 10))
+	want := map[string]string{}
+	for step := range 6000 {
+		k := fmt.Sprintf("k%03d", rng.IntN(400))
+		switch r := rng.IntN(100); {
+		case r < 30:
+			if err := db.Delete([]byte(k)); err != nil {
+				t.Fatal(err)
+			}
+			delete(want, k)
+		case r < 99:
+			v := fmt.Sprintf("%s@%d", k, step)
+			if err := db.Put([]byte(k), []byte(v)); err != nil {
+				t.Fatal(err)
+			}
+			want[k] = v
+		default:
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+			db = open(t, dir, cfg)
+		}
+		if got, w := get(db, k), want[k]; w != "" && got != w || w == "" && got != "<absent>" {
+			t.Fatalf("step %d: Get(%s) = %q, want %q", step, k, got, w)
+		}
+		if step%500 == 0 {
+			if got := contents(t, db, nil, nil); !maps.Equal(got, want) {
+				t.Fatalf("step %d: Scan holds %d keys, want %d", step, len(got), len(want))
+			}
+		}
+	}
+
+	keys := slices.Sorted(maps.Keys(want))
+	for _, r := range [][2]string{{"k100", "k200"}, {"k000", "k001"}, {"k3", "k4"}, {"k399", ""}, {"a", "b"}} {
+		var end []byte
+		if r[1] != "" {
+			end = []byte(r[1])
+		}
+		got := contents(t, db, []byte(r[0]), end)
+		n := 0
+		for _, k := range keys {
+			if k >= r[0] && (end == nil || k < r[1]) {
+				n++
+				if got[k] != want[k] {
+					t.Errorf("Scan(%q, %q): %s = %q, want %q", r[0], r[1], k, got[k], want[k])
+				}
+			}
+		}
+		if len(got) != n {
+			t.Errorf("Scan(%q, %q) yields %d keys, want %d", r[0], r[1], len(got), n)
+		}
+	}
+	if s := db.Stats(); s.Levels[2].Tables == 0 {
+		t.Errorf("the test never reached L2: %v", s)
+	}
+}
+
+func TestRestoreMarker(t *testing.T) {
+	dir := t.TempDir()
+	db := open(t, dir, Config{})
+	db.Put([]byte("old"), []byte("1"))
+	db.Close()
+	if err := os.WriteFile(filepath.Join(dir, restoreMarker), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db = open(t, dir, Config{})
+	if !db.NeedsRestore() {
+		t.Fatal("NeedsRestore = false with a RESTORING marker")
+	}
+
+	// An aborted restore leaves the marker behind.
+	r, err := db.BeginRestore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Put([]byte("half"), []byte("done"))
+	r.Abort()
+	db.Close()
+	db = open(t, dir, Config{})
+	if !db.NeedsRestore() {
+		t.Fatal("NeedsRestore = false after an aborted restore")
+	}
+
+	r, err = db.BeginRestore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Put([]byte("new"), []byte("2"))
+	if err := r.Finish(42); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Finish(42); err == nil {
+		t.Error("second Finish succeeded")
+	}
+	db.Close()
+	db = open(t, dir, Config{})
+	defer db.Close()
+	if db.NeedsRestore() {
+		t.Error("NeedsRestore = true after a finished restore")
+	}
+	if _, err := os.Stat(filepath.Join(dir, restoreMarker)); !os.IsNotExist(err) {
+		t.Errorf("marker left after Finish: %v", err)
+	}
+	if got := contents(t, db, nil, nil); !maps.Equal(got, map[string]string{"new": "2"}) {
+		t.Errorf("restored contents %v, want only new=2", got)
+	}
+	if db.LastSeq() != 42 {
+		t.Errorf("LastSeq = %d, want 42", db.LastSeq())
+	}
+}
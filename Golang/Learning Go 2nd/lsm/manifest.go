@@ -0,0 +1,77 @@
+package lsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	manifestName = "MANIFEST"
+	walName      = "wal.log"
+)
+
+// manifest is the durable list of live tables. It is rewritten atomically
+// after every flush and compaction; tables not listed in it are garbage.
+type manifest struct {
+	NextNum    uint64                 `json:"next_num"`
+	FlushedSeq uint64                 `json:"flushed_seq"` // WAL records up to here live in tables
+	Levels     [numLevels][]tableMeta `json:"levels"`
+}
+
+func tableName(num uint64) string { return fmt.Sprintf("%06d.sst", num) }
+
+func readManifest(dir string) (manifest, error) {
+	var m manifest
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest{NextNum: 1}, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("lsm: reading manifest: %w", err)
+	}
+	return m, nil
+}
+
+// writeManifest replaces the manifest through a temporary file and rename,
+// so a crash leaves either the old or the new version.
+func writeManifest(dir string, m manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, manifestName+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, manifestName)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,161 @@
+package lsm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"learning-go.adcon.dev/kv"
+)
+
+// restoreMarker exists while Restore replaces the contents; finding it on
+// Open means the data is a mix of old and new and must be restored again.
+const restoreMarker = "RESTORING"
+
+var ErrOutOfOrder = errors.New("lsm: replicated record out of sequence")
+
+// Record is one logged mutation, as shipped to replicas. Sequence numbers
+// start at 1 and increase by one per write.
+type Record struct {
+	Seq    uint64
+	Delete bool
+	Key    []byte
+	Value  []byte
+}
+
+// LastSeq is the sequence number of the newest write. It survives restarts,
+// so a replica can resume from it.
+func (db *DB) LastSeq() uint64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.seq
+}
+
+// Watch registers fn to be called with every write after it is logged. fn
+// runs under the DB's write lock, in sequence order, and must not call back
+// into the DB. A nil fn removes the watcher.
+func (db *DB) Watch(fn func(Record)) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.watch = fn
+}
+
+// Apply writes a record received from a primary, keeping its sequence
+// number. Records must be applied in order without gaps.
+func (db *DB) Apply(r Record) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return kv.ErrClosed
+	}
+	if r.Seq != db.seq+1 {
+		return fmt.Errorf("%w: got %d after %d", ErrOutOfOrder, r.Seq, db.seq)
+	}
+	k, value := kindPut, bytes.Clone(r.Value)
+	if r.Delete {
+		k, value = kindDelete, nil
+	}
+	return db.writeLocked(r.Seq, r.Key, value, k)
+}
+
+// Snapshot calls fn for every live key under one read lock and returns the
+// sequence number the contents correspond to. Writers wait until it returns.
+func (db *DB) Snapshot(fn func(key, value []byte) bool) (uint64, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return 0, kv.ErrClosed
+	}
+	return db.seq, db.scanRange(nil, nil, fn)
+}
+
+// NeedsRestore reports that an earlier Restore did not finish, so the
+// contents cannot be trusted until a new one completes.
+func (db *DB) NeedsRestore() bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.restoring
+}
+
+// Restorer replaces a DB's contents with a snapshot. It holds the write lock
+// from BeginRestore until Finish or Abort.
+type Restorer struct {
+	db   *DB
+	done bool
+}
+
+// BeginRestore deletes every key and starts accepting snapshot entries.
+func (db *DB) BeginRestore() (*Restorer, error) {
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return nil, kv.ErrClosed
+	}
+	fail := func(err error) (*Restorer, error) {
+		db.mu.Unlock()
+		return nil, err
+	}
+	f, err := os.Create(filepath.Join(db.dir, restoreMarker))
+	if err != nil {
+		return fail(err)
+	}
+	f.Close()
+	if err := syncDir(db.dir); err != nil {
+		return fail(err)
+	}
+	db.restoring = true
+	var keys [][]byte
+	if err := db.scanRange(nil, nil, func(key, _ []byte) bool {
+		keys = append(keys, bytes.Clone(key))
+		return true
+	}); err != nil {
+		return fail(err)
+	}
+	for _, k := range keys {
+		if err := db.writeLocked(db.seq+1, k, nil, kindDelete); err != nil {
+			return fail(err)
+		}
+	}
+	return &Restorer{db: db}, nil
+}
+
+func (r *Restorer) Put(key, value []byte) error {
+	if r.done {
+		return errors.New("lsm: restore already finished")
+	}
+	return r.db.writeLocked(r.db.seq+1, key, bytes.Clone(value), kindPut)
+}
+
+// Finish sets the sequence number to seq, makes the contents durable and
+// releases the DB.
+func (r *Restorer) Finish(seq uint64) error {
+	if r.done {
+		return errors.New("lsm: restore already finished")
+	}
+	r.done = true
+	db := r.db
+	defer db.mu.Unlock()
+	db.seq = seq
+	if err := db.flush(); err != nil {
+		return err
+	}
+	db.flushed = seq
+	if err := db.saveManifest(); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(db.dir, restoreMarker)); err != nil {
+		return err
+	}
+	db.restoring = false
+	return db.compact()
+}
+
+// Abort releases the DB, leaving it marked as needing a restore.
+func (r *Restorer) Abort() {
+	if !r.done {
+		r.done = true
+		r.db.mu.Unlock()
+	}
+}
@@ -0,0 +1,345 @@
+package lsm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"learning-go.adcon.dev/sketch"
+)
+
+// An SSTable is an immutable sorted file:
+//
+//	data blocks | index | bloom filter | footer
+//
+// Each data block holds entries (uvarint len(key) | key | kind | uvarint
+// len(value) | value). The sparse index keeps the first key, offset and length
+// of every block. The footer holds the index and filter locations, the entry
+// count and a magic number.
+const (
+	footerSize = 6 * 8
+	tableMagic = 0x4c534d5441424c45 // "LSMTABLE"
+)
+
+var errCorruptTable = errors.New("lsm: corrupt SSTable")
+
+type indexEntry struct {
+	firstKey []byte
+	offset   uint64
+	length   uint64
+}
+
+// tableMeta describes a table in the manifest.
+type tableMeta struct {
+	Num      uint64 `json:"num"`
+	Smallest []byte `json:"smallest"`
+	Largest  []byte `json:"largest"`
+	Size     int64  `json:"size"`
+	Entries  uint64 `json:"entries"`
+}
+
+func (m tableMeta) overlaps(start, end []byte) bool {
+	return bytes.Compare(m.Largest, start) >= 0 && (end == nil || bytes.Compare(m.Smallest, end) < 0)
+}
+
+type tableWriter struct {
+	f         *os.File
+	w         *bufio.Writer
+	offset    uint64
+	block     []byte
+	blockSize int
+	first     []byte
+	index     []indexEntry
+	bloom     *sketch.Bloom
+	meta      tableMeta
+}
+
+func newTableWriter(path string, num, expected uint64, cfg Config) (*tableWriter, error) {
+	bloom, err := sketch.NewBloomForRate(max(1, expected), cfg.BloomFalsePositive)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &tableWriter{f: f, w: bufio.NewWriter(f), blockSize: cfg.BlockSize, bloom: bloom, meta: tableMeta{Num: num}}, nil
+}
+
+func (t *tableWriter) add(key, value []byte, k kind) error {
+	if len(t.block) == 0 {
+		t.first = append([]byte(nil), key...)
+	}
+	if t.meta.Entries == 0 {
+		t.meta.Smallest = append([]byte(nil), key...)
+	}
+	t.meta.Largest = append(t.meta.Largest[:0], key...)
+	t.meta.Entries++
+	t.bloom.Add(key)
+	t.block = binary.AppendUvarint(t.block, uint64(len(key)))
+	t.block = append(t.block, key...)
+	t.block = append(t.block, byte(k))
+	t.block = binary.AppendUvarint(t.block, uint64(len(value)))
+	t.block = append(t.block, value...)
+	if len(t.block) >= t.blockSize {
+		return t.flushBlock()
+	}
+	return nil
+}
+
+func (t *tableWriter) size() uint64 { return t.offset + uint64(len(t.block)) }
+
+func (t *tableWriter) flushBlock() error {
+	if len(t.block) == 0 {
+		return nil
+	}
+	if _, err := t.w.Write(t.block); err != nil {
+		return err
+	}
+	t.index = append(t.index, indexEntry{firstKey: t.first, offset: t.offset, length: uint64(len(t.block))})
+	t.offset += uint64(len(t.block))
+	t.block = t.block[:0]
+	return nil
+}
+
+// finish writes the index, filter and footer and syncs the file.
+func (t *tableWriter) finish() (tableMeta, error) {
+	err := t.write()
+	if cerr := t.f.Close(); err == nil {
+		err = cerr
+	}
+	return t.meta, err
+}
+
+func (t *tableWriter) write() error {
+	if err := t.flushBlock(); err != nil {
+		return err
+	}
+	indexOffset := t.offset
+	var idx []byte
+	for _, e := range t.index {
+		idx = binary.AppendUvarint(idx, uint64(len(e.firstKey)))
+		idx = append(idx, e.firstKey...)
+		idx = binary.AppendUvarint(idx, e.offset)
+		idx = binary.AppendUvarint(idx, e.length)
+	}
+	filter, err := t.bloom.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	var footer []byte
+	for _, v := range []uint64{indexOffset, uint64(len(idx)), indexOffset + uint64(len(idx)), uint64(len(filter)), t.meta.Entries, tableMagic} {
+		footer = binary.BigEndian.AppendUint64(footer, v)
+	}
+	for _, b := range [][]byte{idx, filter, footer} {
+		if _, err := t.w.Write(b); err != nil {
+			return err
+		}
+	}
+	if err := t.w.Flush(); err != nil {
+		return err
+	}
+	t.meta.Size = int64(indexOffset) + int64(len(idx)+len(filter)+len(footer))
+	return t.f.Sync()
+}
+
+// abort removes a partially written table.
+func (t *tableWriter) abort() {
+	t.f.Close()
+	os.Remove(t.f.Name())
+}
+
+// table is an open SSTable with its index and filter in memory.
+type table struct {
+	meta  tableMeta
+	f     *os.File
+	index []indexEntry
+	bloom *sketch.Bloom
+}
+
+func openTable(path string, meta tableMeta) (*table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := loadTable(f, meta)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+func loadTable(f *os.File, meta tableMeta) (*table, error) {
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() < footerSize {
+		return nil, errCorruptTable
+	}
+	footer := make([]byte, footerSize)
+	if _, err := f.ReadAt(footer, st.Size()-footerSize); err != nil {
+		return nil, err
+	}
+	var v [6]uint64
+	for i := range v {
+		v[i] = binary.BigEndian.Uint64(footer[8*i:])
+	}
+	indexOff, indexLen, filterOff, filterLen, magic := v[0], v[1], v[2], v[3], v[5]
+	if magic != tableMagic || filterOff+filterLen+footerSize != uint64(st.Size()) || indexOff+indexLen != filterOff {
+		return nil, errCorruptTable
+	}
+	raw := make([]byte, indexLen+filterLen)
+	if _, err := f.ReadAt(raw, int64(indexOff)); err != nil {
+		return nil, err
+	}
+	t := &table{meta: meta, f: f, bloom: &sketch.Bloom{}}
+	if err := t.bloom.UnmarshalBinary(raw[indexLen:]); err != nil {
+		return nil, err
+	}
+	idx := raw[:indexLen]
+	for len(idx) > 0 {
+		var e indexEntry
+		n, w := binary.Uvarint(idx)
+		if w <= 0 || uint64(len(idx)-w) < n {
+			return nil, errCorruptTable
+		}
+		e.firstKey, idx = idx[w:w+int(n)], idx[w+int(n):]
+		if e.offset, w = binary.Uvarint(idx); w <= 0 {
+			return nil, errCorruptTable
+		}
+		idx = idx[w:]
+		if e.length, w = binary.Uvarint(idx); w <= 0 {
+			return nil, errCorruptTable
+		}
+		idx = idx[w:]
+		t.index = append(t.index, e)
+	}
+	return t, nil
+}
+
+func (t *table) close() error { return t.f.Close() }
+
+func (t *table) readBlock(i int) ([]byte, error) {
+	e := t.index[i]
+	b := make([]byte, e.length)
+	if _, err := t.f.ReadAt(b, int64(e.offset)); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// get looks key up, consulting the Bloom filter before touching the disk.
+func (t *table) get(key []byte) (value []byte, k kind, found bool, err error) {
+	if bytes.Compare(key, t.meta.Smallest) < 0 || bytes.Compare(key, t.meta.Largest) > 0 || !t.bloom.Test(key) {
+		return nil, 0, false, nil
+	}
+	// The last block whose first key is <= key.
+	i := sort.Search(len(t.index), func(i int) bool { return bytes.Compare(t.index[i].firstKey, key) > 0 }) - 1
+	if i < 0 {
+		return nil, 0, false, nil
+	}
+	block, err := t.readBlock(i)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	for len(block) > 0 {
+		var e blockEntry
+		if e, block, err = nextEntry(block); err != nil {
+			return nil, 0, false, err
+		}
+		switch bytes.Compare(e.key, key) {
+		case 0:
+			return e.value, e.kind, true, nil
+		case 1:
+			return nil, 0, false, nil
+		}
+	}
+	return nil, 0, false, nil
+}
+
+type blockEntry struct {
+	key, value []byte
+	kind       kind
+}
+
+func nextEntry(b []byte) (blockEntry, []byte, error) {
+	var e blockEntry
+	n, w := binary.Uvarint(b)
+	if w <= 0 || uint64(len(b)-w) < n+1 {
+		return e, nil, errCorruptTable
+	}
+	e.key, b = b[w:w+int(n)], b[w+int(n):]
+	e.kind, b = kind(b[0]), b[1:]
+	n, w = binary.Uvarint(b)
+	if w <= 0 || uint64(len(b)-w) < n {
+		return e, nil, errCorruptTable
+	}
+	e.value, b = b[w:w+int(n)], b[w+int(n):]
+	return e, b, nil
+}
+
+// tableIter walks a table in key order one block at a time.
+type tableIter struct {
+	t     *table
+	block int
+	rest  []byte
+	cur   blockEntry
+	err   error
+}
+
+// iter returns an iterator positioned before the first key >= start.
+func (t *table) iter(start []byte) *tableIter {
+	it := &tableIter{t: t}
+	it.block = max(0, sort.Search(len(t.index), func(i int) bool { return bytes.Compare(t.index[i].firstKey, start) > 0 })-1)
+	if it.loadBlock() {
+		for len(it.rest) > 0 {
+			save := it.rest
+			e, rest, err := nextEntry(it.rest)
+			if err != nil {
+				it.err = err
+				return it
+			}
+			if bytes.Compare(e.key, start) >= 0 {
+				it.rest = save
+				break
+			}
+			it.rest = rest
+		}
+	}
+	return it
+}
+
+func (it *tableIter) loadBlock() bool {
+	if it.block >= len(it.t.index) {
+		return false
+	}
+	b, err := it.t.readBlock(it.block)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.rest = b
+	return true
+}
+
+func (it *tableIter) next() bool {
+	for len(it.rest) == 0 {
+		if it.err != nil || it.block >= len(it.t.index) {
+			return false
+		}
+		it.block++
+		if !it.loadBlock() {
+			return false
+		}
+	}
+	it.cur, it.rest, it.err = nextEntry(it.rest)
+	return it.err == nil
+}
+
+func (it *tableIter) entry() blockEntry { return it.cur }
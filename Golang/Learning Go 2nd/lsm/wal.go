@@ -0,0 +1,152 @@
+package lsm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// kind tells a live value from a deletion marker (tombstone).
+type kind uint8
+
+const (
+	kindPut kind = iota + 1
+	kindDelete
+)
+
+// record is one logged mutation.
+type record struct {
+	seq   uint64
+	kind  kind
+	key   []byte
+	value []byte
+}
+
+// WAL framing: crc32(payload) | len(payload) | payload, where the payload is
+// seq | kind | uvarint len(key) | key | uvarint len(value) | value.
+const walHeader = 8
+
+func encodeRecord(buf []byte, r record) []byte {
+	start := len(buf)
+	buf = append(buf, make([]byte, walHeader)...)
+	buf = binary.BigEndian.AppendUint64(buf, r.seq)
+	buf = append(buf, byte(r.kind))
+	buf = binary.AppendUvarint(buf, uint64(len(r.key)))
+	buf = append(buf, r.key...)
+	buf = binary.AppendUvarint(buf, uint64(len(r.value)))
+	buf = append(buf, r.value...)
+	payload := buf[start+walHeader:]
+	binary.BigEndian.PutUint32(buf[start:], crc32.ChecksumIEEE(payload))
+	binary.BigEndian.PutUint32(buf[start+4:], uint32(len(payload)))
+	return buf
+}
+
+var errTornRecord = errors.New("lsm: torn or corrupt WAL record")
+
+func decodePayload(p []byte) (record, error) {
+	if len(p) < 9 {
+		return record{}, errTornRecord
+	}
+	r := record{seq: binary.BigEndian.Uint64(p), kind: kind(p[8])}
+	p = p[9:]
+	for _, dst := range []*[]byte{&r.key, &r.value} {
+		n, w := binary.Uvarint(p)
+		if w <= 0 || uint64(len(p)-w) < n {
+			return record{}, errTornRecord
+		}
+		*dst = append([]byte(nil), p[w:w+int(n)]...)
+		p = p[w+int(n):]
+	}
+	if (r.kind != kindPut && r.kind != kindDelete) || len(p) != 0 {
+		return record{}, errTornRecord
+	}
+	return r, nil
+}
+
+// readRecord reads the next framed record. A clean end of input returns
+// io.EOF; a partial or corrupt record returns errTornRecord.
+func readRecord(r io.Reader) (record, int, error) {
+	var hdr [walHeader]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if err == io.EOF {
+			return record{}, 0, io.EOF
+		}
+		return record{}, 0, errTornRecord
+	}
+	n := binary.BigEndian.Uint32(hdr[4:])
+	if n > 1<<30 {
+		return record{}, 0, errTornRecord
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return record{}, 0, errTornRecord
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(hdr[:4]) {
+		return record{}, 0, errTornRecord
+	}
+	rec, err := decodePayload(payload)
+	return rec, walHeader + int(n), err
+}
+
+// wal is the append-only log of mutations not yet flushed to an SSTable.
+type wal struct {
+	f    *os.File
+	w    *bufio.Writer
+	sync bool
+	buf  []byte
+}
+
+// openWAL replays path, calling apply for every intact record, then truncates
+// any torn tail left by a crash and reopens the file for appending.
+func openWAL(path string, sync bool, apply func(record)) (*wal, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	good := int64(0)
+	br := bufio.NewReader(f)
+	for {
+		rec, n, err := readRecord(br)
+		if err == io.EOF || errors.Is(err, errTornRecord) {
+			break
+		}
+		apply(rec)
+		good += int64(n)
+	}
+	if err := f.Truncate(good); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lsm: truncating WAL tail: %w", err)
+	}
+	if _, err := f.Seek(good, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &wal{f: f, w: bufio.NewWriter(f), sync: sync}, nil
+}
+
+// append writes the record and, in sync mode, waits for it to reach disk.
+func (l *wal) append(r record) error {
+	l.buf = encodeRecord(l.buf[:0], r)
+	if _, err := l.w.Write(l.buf); err != nil {
+		return err
+	}
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	if l.sync {
+		return l.f.Sync()
+	}
+	return nil
+}
+
+func (l *wal) close() error {
+	if err := l.w.Flush(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
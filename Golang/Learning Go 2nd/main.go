@@ -2,9 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"math"
+	"os"
+
+	"learning-go.adcon.dev/cli"
 )
 
 func LinearSearch(arr []int, target int) int {
@@ -55,9 +60,23 @@ type Kid struct {
 	Candies int `json:"candies"`
 }
 
+// sampleKids is the raw JSON used when no input file is given.
+const sampleKids = "[{\"age\": 5, \"candies\": 20},{\"age\": 6, \"candies\": 15}]"
+
 func main() {
+	if err := app.Run(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		log.Fatal(err)
+	}
+}
+
+// runJSON decodes the sample kids, the program's first demo.
+func runJSON(ctx *cli.Context) error {
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+
 	// Raw JSON string
-	rawJson := "[{\"age\": 5, \"candies\": 20},{\"age\": 6, \"candies\": 15}]"
+	rawJson := sampleKids
 
 	// Create a slice to hold the unmarshaled data
 	var kids []Kid
@@ -65,9 +84,10 @@ func main() {
 	// Unmarshal the JSON string into the slice
 	err := json.Unmarshal([]byte(rawJson), &kids)
 	if err != nil {
-		log.Fatalf("Error unmarshaling JSON: %v", err)
+		return fmt.Errorf("unmarshaling JSON: %w", err)
 	}
 
 	// Print the result
 	fmt.Printf("%+v\n", kids)
+	return nil
 }
@@ -0,0 +1,212 @@
+package matrix
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// LU factors a square matrix as L·U without row exchanges (CLRS
+// LU-DECOMPOSITION). It fails on a zero pivot even when the matrix is
+// invertible, and small pivots amplify rounding error; use LUP instead.
+func (m *Matrix[T]) LU() (l, u *Matrix[T], err error) {
+	if m.rows != m.cols {
+		return nil, nil, fmt.Errorf("%w: LU of a %dx%d matrix", ErrShape, m.rows, m.cols)
+	}
+	n := m.rows
+	a := m.Clone()
+	l, u = Identity[T](n), New[T](n, n)
+	for k := range n {
+		pivot := a.At(k, k)
+		if pivot == 0 {
+			return nil, nil, fmt.Errorf("%w: zero pivot at %d", ErrSingular, k)
+		}
+		u.Set(k, k, pivot)
+		for i := k + 1; i < n; i++ {
+			l.Set(i, k, a.At(i, k)/pivot)
+			u.Set(k, i, a.At(k, i))
+		}
+		for i := k + 1; i < n; i++ {
+			for j := k + 1; j < n; j++ {
+				a.Set(i, j, a.At(i, j)-l.At(i, k)*u.At(k, j))
+			}
+		}
+	}
+	return l, u, nil
+}
+
+// LUP is a decomposition P·A = L·U with partial pivoting. P is stored as a
+// permutation: row i of P·A is row Perm[i] of A.
+type LUP[T Float] struct {
+	L, U  *Matrix[T]
+	Perm  []int
+	swaps int
+}
+
+// LUP factors a square matrix with partial pivoting (CLRS
+// LUP-DECOMPOSITION): each step swaps in the row with the largest pivot.
+func (m *Matrix[T]) LUP() (*LUP[T], error) {
+	if m.rows != m.cols {
+		return nil, fmt.Errorf("%w: LUP of a %dx%d matrix", ErrShape, m.rows, m.cols)
+	}
+	n := m.rows
+	a := m.Clone()
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	swaps := 0
+	for k := range n {
+		p, best := k, 0.0
+		for i := k; i < n; i++ {
+			if v := math.Abs(float64(a.At(i, k))); v > best {
+				p, best = i, v
+			}
+		}
+		if best == 0 {
+			return nil, fmt.Errorf("%w: no pivot in column %d", ErrSingular, k)
+		}
+		if p != k {
+			perm[k], perm[p] = perm[p], perm[k]
+			rk, rp := a.Row(k), a.Row(p)
+			for j := range rk {
+				rk[j], rp[j] = rp[j], rk[j]
+			}
+			swaps++
+		}
+		for i := k + 1; i < n; i++ {
+			f := a.At(i, k) / a.At(k, k)
+			a.Set(i, k, f)
+			for j := k + 1; j < n; j++ {
+				a.Set(i, j, a.At(i, j)-f*a.At(k, j))
+			}
+		}
+	}
+
+	// a now holds L below the diagonal and U on and above it.
+	l, u := Identity[T](n), New[T](n, n)
+	for i := range n {
+		for j := range n {
+			if j < i {
+				l.Set(i, j, a.At(i, j))
+			} else {
+				u.Set(i, j, a.At(i, j))
+			}
+		}
+	}
+	return &LUP[T]{L: l, U: u, Perm: perm, swaps: swaps}, nil
+}
+
+// Solve returns x with A·x = b (CLRS LUP-SOLVE).
+func (d *LUP[T]) Solve(b []T) ([]T, error) {
+	if len(b) != len(d.Perm) {
+		return nil, fmt.Errorf("%w: system of %d with right-hand side of %d", ErrShape, len(d.Perm), len(b))
+	}
+	pb := make([]T, len(b))
+	for i, p := range d.Perm {
+		pb[i] = b[p]
+	}
+	return BackSubst(d.U, ForwardSubst(d.L, pb))
+}
+
+// Det is the product of U's diagonal, negated for an odd number of swaps.
+func (d *LUP[T]) Det() T {
+	det := T(1)
+	if d.swaps%2 == 1 {
+		det = -1
+	}
+	for i := range d.U.rows {
+		det *= d.U.At(i, i)
+	}
+	return det
+}
+
+// ForwardSubst solves L·y = b for a unit lower-triangular L.
+func ForwardSubst[T Float](l *Matrix[T], b []T) []T {
+	y := make([]T, len(b))
+	for i := range b {
+		s := b[i]
+		for j := range i {
+			s -= l.At(i, j) * y[j]
+		}
+		y[i] = s
+	}
+	return y
+}
+
+// BackSubst solves U·x = y for an upper-triangular U.
+func BackSubst[T Float](u *Matrix[T], y []T) ([]T, error) {
+	n := len(y)
+	x := make([]T, n)
+	for i := n - 1; i >= 0; i-- {
+		s := y[i]
+		for j := i + 1; j < n; j++ {
+			s -= u.At(i, j) * x[j]
+		}
+		if u.At(i, i) == 0 {
+			return nil, fmt.Errorf("%w: zero on the diagonal at %d", ErrSingular, i)
+		}
+		x[i] = s / u.At(i, i)
+	}
+	return x, nil
+}
+
+// SolveLU solves A·x = b with LU decomposition and no pivoting. It exists to
+// compare against Solve; prefer Solve.
+func (m *Matrix[T]) SolveLU(b []T) ([]T, error) {
+	if len(b) != m.rows {
+		return nil, fmt.Errorf("%w: system of %d with right-hand side of %d", ErrShape, m.rows, len(b))
+	}
+	l, u, err := m.LU()
+	if err != nil {
+		return nil, err
+	}
+	return BackSubst(u, ForwardSubst(l, b))
+}
+
+// Solve returns x with A·x = b using LUP decomposition.
+func (m *Matrix[T]) Solve(b []T) ([]T, error) {
+	d, err := m.LUP()
+	if err != nil {
+		return nil, err
+	}
+	return d.Solve(b)
+}
+
+// Inverse solves A·X = I one column at a time from a single LUP.
+func (m *Matrix[T]) Inverse() (*Matrix[T], error) {
+	d, err := m.LUP()
+	if err != nil {
+		return nil, err
+	}
+	n := m.rows
+	inv := New[T](n, n)
+	e := make([]T, n)
+	for j := range n {
+		clear(e)
+		e[j] = 1
+		x, err := d.Solve(e)
+		if err != nil {
+			return nil, err
+		}
+		for i := range n {
+			inv.Set(i, j, x[i])
+		}
+	}
+	return inv, nil
+}
+
+// Det returns the determinant, which is zero for a singular matrix.
+func (m *Matrix[T]) Det() (T, error) {
+	if m.rows != m.cols {
+		return 0, fmt.Errorf("%w: determinant of a %dx%d matrix", ErrShape, m.rows, m.cols)
+	}
+	d, err := m.LUP()
+	if errors.Is(err, ErrSingular) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return d.Det(), nil
+}
@@ -0,0 +1,124 @@
+// Package matrix provides a generic dense matrix with the decompositions from
+// CLRS Chapter 28: LU and LUP, forward and back substitution, inverse and
+// determinant, plus least-squares solvers.
+package matrix
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Float is the element type a Matrix can hold.
+type Float interface {
+	~float32 | ~float64
+}
+
+var (
+	ErrShape    = errors.New("matrix: incompatible shapes")
+	ErrSingular = errors.New("matrix: matrix is singular")
+)
+
+// Matrix is a dense row-major matrix.
+type Matrix[T Float] struct {
+	rows, cols int
+	data       []T
+}
+
+func New[T Float](rows, cols int) *Matrix[T] {
+	return &Matrix[T]{rows: rows, cols: cols, data: make([]T, rows*cols)}
+}
+
+// FromRows copies a slice of equally long rows into a matrix.
+func FromRows[T Float](rows [][]T) (*Matrix[T], error) {
+	if len(rows) == 0 {
+		return New[T](0, 0), nil
+	}
+	m := New[T](len(rows), len(rows[0]))
+	for i, r := range rows {
+		if len(r) != m.cols {
+			return nil, fmt.Errorf("%w: row %d has %d columns, want %d", ErrShape, i, len(r), m.cols)
+		}
+		copy(m.data[i*m.cols:], r)
+	}
+	return m, nil
+}
+
+func Identity[T Float](n int) *Matrix[T] {
+	m := New[T](n, n)
+	for i := range n {
+		m.Set(i, i, 1)
+	}
+	return m
+}
+
+func (m *Matrix[T]) Rows() int         { return m.rows }
+func (m *Matrix[T]) Cols() int         { return m.cols }
+func (m *Matrix[T]) At(i, j int) T     { return m.data[i*m.cols+j] }
+func (m *Matrix[T]) Set(i, j int, v T) { m.data[i*m.cols+j] = v }
+
+// Row returns row i; the slice aliases the matrix storage.
+func (m *Matrix[T]) Row(i int) []T { return m.data[i*m.cols : (i+1)*m.cols] }
+
+func (m *Matrix[T]) Clone() *Matrix[T] {
+	c := New[T](m.rows, m.cols)
+	copy(c.data, m.data)
+	return c
+}
+
+func (m *Matrix[T]) Transpose() *Matrix[T] {
+	t := New[T](m.cols, m.rows)
+	for i := range m.rows {
+		for j := range m.cols {
+			t.Set(j, i, m.At(i, j))
+		}
+	}
+	return t
+}
+
+func (m *Matrix[T]) Mul(b *Matrix[T]) (*Matrix[T], error) {
+	if m.cols != b.rows {
+		return nil, fmt.Errorf("%w: %dx%d times %dx%d", ErrShape, m.rows, m.cols, b.rows, b.cols)
+	}
+	c := New[T](m.rows, b.cols)
+	for i := range m.rows {
+		for k := range m.cols {
+			a := m.At(i, k)
+			if a == 0 {
+				continue
+			}
+			for j := range b.cols {
+				c.data[i*c.cols+j] += a * b.At(k, j)
+			}
+		}
+	}
+	return c, nil
+}
+
+func (m *Matrix[T]) MulVec(x []T) ([]T, error) {
+	if m.cols != len(x) {
+		return nil, fmt.Errorf("%w: %dx%d times vector of %d", ErrShape, m.rows, m.cols, len(x))
+	}
+	y := make([]T, m.rows)
+	for i := range m.rows {
+		for j, v := range m.Row(i) {
+			y[i] += v * x[j]
+		}
+	}
+	return y, nil
+}
+
+func (m *Matrix[T]) String() string {
+	var b strings.Builder
+	for i := range m.rows {
+		b.WriteString("[")
+		for j := range m.cols {
+			if j > 0 {
+				b.WriteString(" ")
+			}
+			fmt.Fprintf(&b, "%10.4g", m.At(i, j))
+		}
+		b.WriteString("]\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,181 @@
+package matrix
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestSolveCLRS(t *testing.T) {
+	// The worked example from CLRS Section 28.1.
+	a, err := FromRows([][]float64{{1, 2, 0}, {3, 4, 4}, {5, 6, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := a.Solve([]float64{3, 7, 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := maxDiff(x, []float64{-1.4, 2.2, 0.6}); d > 1e-12 {
+		t.Errorf("x = %v, want [-1.4 2.2 0.6]", x)
+	}
+	det, err := a.Det()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(det-10) > 1e-12 {
+		t.Errorf("det = %g, want 10", det)
+	}
+}
+
+func TestShapeErrors(t *testing.T) {
+	square, _ := FromRows([][]float64{{2, 1}, {1, 3}})
+	wide := New[float64](2, 3)
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"Solve short b", second(square.Solve([]float64{1}))},
+		{"SolveLU short b", second(square.SolveLU([]float64{1}))},
+		{"SolveLU long b", second(square.SolveLU([]float64{1, 2, 3}))},
+		{"LUP of wide", second(wide.LUP())},
+		{"Det of wide", second(wide.Det())},
+		{"QR of wide", third(wide.QR())},
+		{"LeastSquaresQR short b", second(square.LeastSquaresQR([]float64{1}))},
+		{"LeastSquaresNormal short b", second(square.LeastSquaresNormal([]float64{1}))},
+		{"Mul", second(square.Mul(wide.Transpose()))},
+		{"MulVec", second(square.MulVec([]float64{1, 2, 3}))},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, ErrShape) {
+			t.Errorf("%s: err = %v, want ErrShape", tt.name, tt.err)
+		}
+	}
+	if _, err := FromRows([][]float64{{1, 2}, {3}}); !errors.Is(err, ErrShape) {
+		t.Errorf("FromRows with a short row: err = %v, want ErrShape", err)
+	}
+}
+
+func TestSingular(t *testing.T) {
+	a, _ := FromRows([][]float64{{1, 2}, {2, 4}})
+	if _, err := a.Solve([]float64{1, 2}); !errors.Is(err, ErrSingular) {
+		t.Errorf("Solve: err = %v, want ErrSingular", err)
+	}
+	if det, err := a.Det(); err != nil || det != 0 {
+		t.Errorf("Det = %g, %v; want 0, nil", det, err)
+	}
+	// LU without pivoting fails on a zero pivot even when A is invertible.
+	b, _ := FromRows([][]float64{{0, 1}, {1, 1}})
+	if _, err := b.SolveLU([]float64{1, 2}); !errors.Is(err, ErrSingular) {
+		t.Errorf("SolveLU with a zero pivot: err = %v, want ErrSingular", err)
+	}
+	if x, err := b.Solve([]float64{1, 2}); err != nil || maxDiff(x, []float64{1, 1}) > 1e-12 {
+		t.Errorf("Solve with a zero pivot = %v, %v; want [1 1]", x, err)
+	}
+}
+
+// TestRandom checks P·A = L·U, A·A⁻¹ = I and a small residual on random
+// well-conditioned matrices.
+func TestRandom(t *testing.T) {
+	rng := rand.New(rand.NewPCG(2, 3))
+	for trial := range 100 {
+		n := 1 + rng.IntN(8)
+		a := New[float64](n, n)
+		for i := range n {
+			for j := range n {
+				a.Set(i, j, rng.Float64()*2-1)
+			}
+			a.Set(i, i, a.At(i, i)+float64(n)) // diagonally dominant
+		}
+		d, err := a.LUP()
+		if err != nil {
+			t.Fatalf("trial %d: %v", trial, err)
+		}
+		lu, _ := d.L.Mul(d.U)
+		for i, p := range d.Perm {
+			if diff := maxDiff(lu.Row(i), a.Row(p)); diff > 1e-12 {
+				t.Fatalf("trial %d: row %d of L·U differs from row %d of A by %g", trial, i, p, diff)
+			}
+		}
+
+		inv, err := a.Inverse()
+		if err != nil {
+			t.Fatalf("trial %d: %v", trial, err)
+		}
+		id, _ := a.Mul(inv)
+		for i := range n {
+			want := make([]float64, n)
+			want[i] = 1
+			if diff := maxDiff(id.Row(i), want); diff > 1e-12 {
+				t.Fatalf("trial %d: A·A⁻¹ is off the identity by %g in row %d", trial, diff, i)
+			}
+		}
+
+		b := make([]float64, n)
+		for i := range b {
+			b[i] = rng.Float64()*10 - 5
+		}
+		for name, solve := range map[string]func([]float64) ([]float64, error){"Solve": a.Solve, "SolveLU": a.SolveLU} {
+			x, err := solve(b)
+			if err != nil {
+				t.Fatalf("trial %d: %s: %v", trial, name, err)
+			}
+			if r, _ := a.Residual(x, b); r > 1e-10 {
+				t.Fatalf("trial %d: %s: residual %g", trial, name, r)
+			}
+		}
+	}
+}
+
+func TestLeastSquares(t *testing.T) {
+	// Fit y = 1 + 2t + 3t² on points that lie exactly on it.
+	const n = 20
+	a := New[float64](n, 3)
+	y := make([]float64, n)
+	for i := range n {
+		ti := float64(i) / (n - 1)
+		a.Set(i, 0, 1)
+		a.Set(i, 1, ti)
+		a.Set(i, 2, ti*ti)
+		y[i] = 1 + 2*ti + 3*ti*ti
+	}
+	want := []float64{1, 2, 3}
+	for name, fit := range map[string]func([]float64) ([]float64, error){"normal": a.LeastSquaresNormal, "QR": a.LeastSquaresQR} {
+		x, err := fit(y)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if d := maxDiff(x, want); d > 1e-9 {
+			t.Errorf("%s: coefficients %v, want %v", name, x, want)
+		}
+	}
+
+	q, r, err := a.QR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	qr, _ := q.Mul(r)
+	for i := range n {
+		if d := maxDiff(qr.Row(i), a.Row(i)); d > 1e-12 {
+			t.Fatalf("row %d of Q·R differs from A by %g", i, d)
+		}
+		for j := range min(i, 3) {
+			if math.Abs(r.At(i, j)) > 1e-12 {
+				t.Fatalf("R[%d][%d] = %g below the diagonal", i, j, r.At(i, j))
+			}
+		}
+	}
+}
+
+func second[T any](_ T, err error) error { return err }
+
+func third[T, U any](_ T, _ U, err error) error { return err }
+
+func maxDiff(x, want []float64) float64 {
+	worst := 0.0
+	for i := range x {
+		worst = max(worst, math.Abs(x[i]-want[i]))
+	}
+	return worst
+}
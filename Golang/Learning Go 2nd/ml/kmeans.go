@@ -0,0 +1,223 @@
+// Package ml holds small machine-learning routines: k-means clustering with
+// k-means++ seeding and ordinary least squares regression.
+package ml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+var ErrTooFewPoints = errors.New("ml: not enough points")
+
+// Clustering is the result of a k-means run.
+type Clustering struct {
+	Centroids  [][]float64
+	Labels     []int   // cluster index of every point
+	Inertia    float64 // sum of squared distances to the assigned centroid
+	Iterations int
+}
+
+// KMeans clusters points with Lloyd's algorithm after k-means++ seeding. It
+// stops when no label changes or after maxIter rounds.
+func KMeans(points [][]float64, k, maxIter int, rng *rand.Rand) (Clustering, error) {
+	if k <= 0 || len(points) < k {
+		return Clustering{}, fmt.Errorf("%w: %d points for %d clusters", ErrTooFewPoints, len(points), k)
+	}
+	c := Clustering{Centroids: seedPlusPlus(points, k, rng), Labels: make([]int, len(points))}
+	for i := range c.Labels {
+		c.Labels[i] = -1
+	}
+	dim := len(points[0])
+	for c.Iterations < maxIter {
+		c.Iterations++
+		changed := false
+		for i, p := range points {
+			best, _ := nearest(p, c.Centroids)
+			if best != c.Labels[i] {
+				c.Labels[i], changed = best, true
+			}
+		}
+		if !changed {
+			break
+		}
+		sums := make([][]float64, k)
+		counts := make([]int, k)
+		for j := range sums {
+			sums[j] = make([]float64, dim)
+		}
+		for i, p := range points {
+			counts[c.Labels[i]]++
+			for d, v := range p {
+				sums[c.Labels[i]][d] += v
+			}
+		}
+		for j := range k {
+			if counts[j] == 0 {
+				continue // keep the old centroid of an emptied cluster
+			}
+			for d := range sums[j] {
+				sums[j][d] /= float64(counts[j])
+			}
+			c.Centroids[j] = sums[j]
+		}
+	}
+	for i, p := range points {
+		c.Inertia += sqDist(p, c.Centroids[c.Labels[i]])
+	}
+	return c, nil
+}
+
+// seedPlusPlus picks the first centroid uniformly and every next one with
+// probability proportional to its squared distance from the closest centroid
+// chosen so far.
+func seedPlusPlus(points [][]float64, k int, rng *rand.Rand) [][]float64 {
+	centroids := [][]float64{clonePoint(points[rng.IntN(len(points))])}
+	dist := make([]float64, len(points))
+	for len(centroids) < k {
+		total := 0.0
+		for i, p := range points {
+			_, dist[i] = nearest(p, centroids)
+			total += dist[i]
+		}
+		next := rng.IntN(len(points)) // every point coincides with a centroid
+		if total > 0 {
+			r := rng.Float64() * total
+			for i, d := range dist {
+				if r -= d; r <= 0 && d > 0 {
+					next = i
+					break
+				}
+			}
+		}
+		centroids = append(centroids, clonePoint(points[next]))
+	}
+	return centroids
+}
+
+// Elbow runs KMeans for k = 1..maxK and returns, for each k, the run with the
+// lowest inertia out of restarts tries. The "elbow" where inertia stops
+// dropping quickly suggests a good k.
+func Elbow(points [][]float64, maxK, restarts int, rng *rand.Rand) ([]Clustering, error) {
+	runs := make([]Clustering, 0, maxK)
+	for k := 1; k <= maxK; k++ {
+		best, err := bestOf(points, k, restarts, rng)
+		if err != nil {
+			return runs, err
+		}
+		runs = append(runs, best)
+	}
+	return runs, nil
+}
+
+func bestOf(points [][]float64, k, restarts int, rng *rand.Rand) (Clustering, error) {
+	var best Clustering
+	for r := range max(1, restarts) {
+		c, err := KMeans(points, k, 100, rng)
+		if err != nil {
+			return Clustering{}, err
+		}
+		if r == 0 || c.Inertia < best.Inertia {
+			best = c
+		}
+	}
+	return best, nil
+}
+
+// Silhouette returns the mean silhouette coefficient in [-1, 1]: for each
+// point, (b-a)/max(a, b) where a is its mean distance to its own cluster and b
+// the mean distance to the nearest other cluster. Singleton clusters score 0.
+func Silhouette(points [][]float64, labels []int, k int) float64 {
+	if len(points) < 2 || k < 2 {
+		return 0
+	}
+	total := 0.0
+	sums := make([]float64, k)
+	counts := make([]int, k)
+	for i, p := range points {
+		clear(sums)
+		clear(counts)
+		for j, q := range points {
+			if i == j {
+				continue
+			}
+			sums[labels[j]] += math.Sqrt(sqDist(p, q))
+			counts[labels[j]]++
+		}
+		own := labels[i]
+		if counts[own] == 0 {
+			continue
+		}
+		a := sums[own] / float64(counts[own])
+		b := math.Inf(1)
+		for c := range k {
+			if c != own && counts[c] > 0 {
+				b = min(b, sums[c]/float64(counts[c]))
+			}
+		}
+		if math.IsInf(b, 1) {
+			continue
+		}
+		total += (b - a) / max(a, b)
+	}
+	return total / float64(len(points))
+}
+
+// Standardize rescales every feature to zero mean and unit variance and
+// returns the scaled copy along with the means and deviations used.
+func Standardize(points [][]float64) (scaled [][]float64, mean, std []float64) {
+	if len(points) == 0 {
+		return nil, nil, nil
+	}
+	dim := len(points[0])
+	mean, std = make([]float64, dim), make([]float64, dim)
+	for _, p := range points {
+		for d, v := range p {
+			mean[d] += v
+		}
+	}
+	for d := range mean {
+		mean[d] /= float64(len(points))
+	}
+	for _, p := range points {
+		for d, v := range p {
+			std[d] += (v - mean[d]) * (v - mean[d])
+		}
+	}
+	for d := range std {
+		std[d] = math.Sqrt(std[d] / float64(len(points)))
+		if std[d] == 0 {
+			std[d] = 1
+		}
+	}
+	scaled = make([][]float64, len(points))
+	for i, p := range points {
+		scaled[i] = make([]float64, dim)
+		for d, v := range p {
+			scaled[i][d] = (v - mean[d]) / std[d]
+		}
+	}
+	return scaled, mean, std
+}
+
+func nearest(p []float64, centroids [][]float64) (int, float64) {
+	best, bestDist := 0, math.Inf(1)
+	for j, c := range centroids {
+		if d := sqDist(p, c); d < bestDist {
+			best, bestDist = j, d
+		}
+	}
+	return best, bestDist
+}
+
+func sqDist(a, b []float64) float64 {
+	s := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		s += d * d
+	}
+	return s
+}
+
+func clonePoint(p []float64) []float64 { return append([]float64(nil), p...) }
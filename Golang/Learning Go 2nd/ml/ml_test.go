@@ -0,0 +1,135 @@
+package ml
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestKMeansSeparatedClusters(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	centers := [][]float64{{0, 0}, {10, 0}, {0, 10}}
+	var points [][]float64
+	var truth []int
+	for c, center := range centers {
+		for range 50 {
+			points = append(points, []float64{center[0] + rng.Float64()*2 - 1, center[1] + rng.Float64()*2 - 1})
+			truth = append(truth, c)
+		}
+	}
+	c, err := KMeans(points, 3, 100, rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every true cluster must map to one label, and no two to the same.
+	label := map[int]int{}
+	used := map[int]bool{}
+	for i, l := range c.Labels {
+		want, ok := label[truth[i]]
+		if !ok {
+			if used[l] {
+				t.Fatalf("clusters %d and another share label %d", truth[i], l)
+			}
+			label[truth[i]], used[l] = l, true
+			continue
+		}
+		if l != want {
+			t.Fatalf("point %d of cluster %d has label %d, want %d", i, truth[i], l, want)
+		}
+	}
+	for ci, center := range centers {
+		if d := math.Sqrt(sqDist(c.Centroids[label[ci]], center)); d > 0.5 {
+			t.Errorf("centroid %v is %.2f from %v", c.Centroids[label[ci]], d, center)
+		}
+	}
+	if s := Silhouette(points, c.Labels, 3); s < 0.8 {
+		t.Errorf("silhouette = %.3f, want above 0.8 for separated clusters", s)
+	}
+}
+
+func TestKMeansDegenerate(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	points := [][]float64{{0}, {1}, {5}}
+	for _, k := range []int{0, -1, 4} {
+		if _, err := KMeans(points, k, 10, rng); !errors.Is(err, ErrTooFewPoints) {
+			t.Errorf("k=%d: err = %v, want ErrTooFewPoints", k, err)
+		}
+	}
+	c, err := KMeans(points, 3, 10, rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Inertia != 0 {
+		t.Errorf("k = n: inertia = %g, want 0", c.Inertia)
+	}
+	// Identical points: seeding must still pick k centroids.
+	same := [][]float64{{2, 2}, {2, 2}, {2, 2}}
+	if c, err = KMeans(same, 2, 10, rng); err != nil || len(c.Centroids) != 2 || c.Inertia != 0 {
+		t.Errorf("identical points: %+v, %v", c, err)
+	}
+}
+
+func TestSilhouette(t *testing.T) {
+	points := [][]float64{{0}, {1}, {10}, {11}}
+	// Each point is 1 from its partner; the other cluster is on average
+	// 10.5 away from the outer points and 9.5 from the inner ones.
+	want := (9.5/10.5 + 8.5/9.5) / 2
+	if got := Silhouette(points, []int{0, 0, 1, 1}, 2); math.Abs(got-want) > 1e-12 {
+		t.Errorf("Silhouette = %g, want %g", got, want)
+	}
+	// Putting the partners apart scores below zero.
+	if got := Silhouette(points, []int{0, 1, 0, 1}, 2); got >= 0 {
+		t.Errorf("Silhouette of crossed labels = %g, want negative", got)
+	}
+	// A singleton scores 0: only the pair counts.
+	if got, want := Silhouette(points[:3], []int{0, 0, 1}, 2), (9.0/10+8.0/9)/3; math.Abs(got-want) > 1e-12 {
+		t.Errorf("Silhouette with a singleton = %g, want %g", got, want)
+	}
+	if got := Silhouette(points, []int{0, 0, 0, 0}, 1); got != 0 {
+		t.Errorf("Silhouette with one cluster = %g, want 0", got)
+	}
+}
+
+func TestFitLinear(t *testing.T) {
+	x := []float64{-2, 0, 1, 3, 7}
+	y := make([]float64, len(x))
+	for i, v := range x {
+		y[i] = 2 + 3*v
+	}
+	fit, err := FitLinear(x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(fit.Intercept-2) > 1e-12 || math.Abs(fit.Slope-3) > 1e-12 || math.Abs(fit.R2-1) > 1e-12 {
+		t.Errorf("fit = %+v, want intercept 2, slope 3, R² 1", fit)
+	}
+	for i, r := range fit.Residuals {
+		if math.Abs(r) > 1e-12 {
+			t.Errorf("residual %d = %g, want 0", i, r)
+		}
+	}
+
+	// Sxy = 3, Sxx = Syy = 5: slope 3/5 and R² = Sxy²/(Sxx·Syy) = 9/25.
+	noisy, err := FitLinear([]float64{0, 1, 2, 3}, []float64{1, 0, 3, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(noisy.Slope-0.6) > 1e-12 || math.Abs(noisy.Intercept-0.6) > 1e-12 || math.Abs(noisy.R2-0.36) > 1e-12 {
+		t.Errorf("noisy fit = %+v, want intercept 0.6, slope 0.6, R² 0.36", noisy)
+	}
+
+	constant, err := FitLinear([]float64{1, 2, 3}, []float64{4, 4, 4})
+	if err != nil || math.Abs(constant.Slope) > 1e-12 || constant.R2 != 1 {
+		t.Errorf("constant y: %+v, %v; want slope 0 and R² 1", constant, err)
+	}
+	if _, err := FitLinear([]float64{5, 5, 5}, []float64{1, 2, 3}); err == nil {
+		t.Error("constant x: no error")
+	}
+	if _, err := FitLinear([]float64{1}, []float64{1}); !errors.Is(err, ErrTooFewPoints) {
+		t.Errorf("one point: err = %v, want ErrTooFewPoints", err)
+	}
+	if _, err := FitLinear([]float64{1, 2}, []float64{1}); err == nil {
+		t.Error("mismatched lengths: no error")
+	}
+}
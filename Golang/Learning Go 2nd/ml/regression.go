@@ -0,0 +1,56 @@
+package ml
+
+import (
+	"fmt"
+
+	"learning-go.adcon.dev/matrix"
+)
+
+// LinearFit is the ordinary least squares line y = Intercept + Slope·x.
+type LinearFit struct {
+	Intercept float64
+	Slope     float64
+	R2        float64   // share of y's variance explained by the line
+	Residuals []float64 // y - prediction, in input order
+}
+
+func (f LinearFit) Predict(x float64) float64 { return f.Intercept + f.Slope*x }
+
+// FitLinear regresses y on x by solving the least-squares problem for the
+// design matrix [1 x] with a QR factorization.
+func FitLinear(x, y []float64) (LinearFit, error) {
+	if len(x) != len(y) {
+		return LinearFit{}, fmt.Errorf("ml: %d x values but %d y values", len(x), len(y))
+	}
+	if len(x) < 2 {
+		return LinearFit{}, fmt.Errorf("%w: regression needs 2, got %d", ErrTooFewPoints, len(x))
+	}
+	design := matrix.New[float64](len(x), 2)
+	for i, v := range x {
+		design.Set(i, 0, 1)
+		design.Set(i, 1, v)
+	}
+	beta, err := design.LeastSquaresQR(y)
+	if err != nil {
+		return LinearFit{}, fmt.Errorf("ml: all x values are equal: %w", err)
+	}
+	fit := LinearFit{Intercept: beta[0], Slope: beta[1], Residuals: make([]float64, len(y))}
+
+	mean := 0.0
+	for _, v := range y {
+		mean += v
+	}
+	mean /= float64(len(y))
+	ssRes, ssTot := 0.0, 0.0
+	for i, v := range y {
+		fit.Residuals[i] = v - fit.Predict(x[i])
+		ssRes += fit.Residuals[i] * fit.Residuals[i]
+		ssTot += (v - mean) * (v - mean)
+	}
+	if ssTot > 0 {
+		fit.R2 = 1 - ssRes/ssTot
+	} else {
+		fit.R2 = 1 // constant y is fitted exactly
+	}
+	return fit, nil
+}
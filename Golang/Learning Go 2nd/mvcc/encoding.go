@@ -0,0 +1,115 @@
+package mvcc
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Every version of a user key is stored under its own store key:
+//
+//	'v' | escaped key | 0x00 0x01 | ^commitTS (8 bytes, big endian)
+//
+// The escaping (0x00 → 0x00 0xFF) keeps user keys in order and makes the
+// terminator unambiguous, and the inverted timestamp puts the newest version
+// of a key first. Metadata lives under 'm', outside the version range.
+const (
+	versionPrefix = 'v'
+	versionEnd    = 'w'
+)
+
+var (
+	clockKey       = []byte("m/clock")
+	errCorruptData = errors.New("mvcc: corrupt version record")
+)
+
+func appendEscaped(dst, key []byte) []byte {
+	for _, c := range key {
+		if c == 0 {
+			dst = append(dst, 0, 0xff)
+		} else {
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// keyPrefix is the common prefix of all versions of key.
+func keyPrefix(key []byte) []byte {
+	return append(appendEscaped([]byte{versionPrefix}, key), 0, 1)
+}
+
+// versionsEnd sorts just after every version of key.
+func versionsEnd(key []byte) []byte {
+	end := keyPrefix(key)
+	end[len(end)-1]++
+	return end
+}
+
+func versionKey(key []byte, ts uint64) []byte {
+	return binary.BigEndian.AppendUint64(keyPrefix(key), ^ts)
+}
+
+// startBound and endBound map a user-key range to store keys. Every version
+// of a key k >= bound sorts at or after the escaped bound, and every version
+// of a smaller key sorts before it. A nil end is the end of the version range.
+func startBound(start []byte) []byte {
+	return appendEscaped([]byte{versionPrefix}, start)
+}
+
+func endBound(end []byte) []byte {
+	if end == nil {
+		return []byte{versionEnd}
+	}
+	return appendEscaped([]byte{versionPrefix}, end)
+}
+
+func decodeVersionKey(k []byte) (key []byte, ts uint64, err error) {
+	if len(k) < 11 || k[0] != versionPrefix {
+		return nil, 0, errCorruptData
+	}
+	body := k[1 : len(k)-8]
+	key = make([]byte, 0, len(body)-2)
+	for i := 0; i < len(body); i++ {
+		if body[i] != 0 {
+			key = append(key, body[i])
+			continue
+		}
+		if i+1 >= len(body) {
+			return nil, 0, errCorruptData
+		}
+		switch body[i+1] {
+		case 0xff:
+			key = append(key, 0)
+			i++
+		case 1:
+			if i+2 != len(body) {
+				return nil, 0, errCorruptData
+			}
+			return key, ^binary.BigEndian.Uint64(k[len(k)-8:]), nil
+		default:
+			return nil, 0, errCorruptData
+		}
+	}
+	return nil, 0, errCorruptData
+}
+
+// A version value is: deleted flag | uvarint writer txn ID | value.
+func encodeVersion(txn uint64, value []byte, deleted bool) []byte {
+	flag := byte(0)
+	if deleted {
+		flag = 1
+	}
+	b := binary.AppendUvarint([]byte{flag}, txn)
+	return append(b, value...)
+}
+
+func decodeVersion(b []byte) (txn uint64, value []byte, deleted bool, err error) {
+	if len(b) < 2 || b[0] > 1 {
+		return 0, nil, false, errCorruptData
+	}
+	txn, n := binary.Uvarint(b[1:])
+	if n <= 0 {
+		return 0, nil, false, errCorruptData
+	}
+	return txn, b[1+n:], b[0] == 1, nil
+}
@@ -0,0 +1,229 @@
+// Package mvcc adds multi-version concurrency control on top of a kv.Store.
+// Every committed write becomes a new version stamped with its commit
+// timestamp and writer transaction ID. Transactions read from the snapshot
+// taken when they began (snapshot isolation), buffer their writes, and fail
+// at commit if another transaction committed a write to the same key after
+// their snapshot (first committer wins). GC drops versions that no active or
+// future snapshot can see.
+package mvcc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"learning-go.adcon.dev/kv"
+)
+
+var (
+	ErrConflict = errors.New("mvcc: write-write conflict")
+	ErrTxnDone  = errors.New("mvcc: transaction already committed or rolled back")
+)
+
+// DB layers transactions over a store. The store must not be written to
+// except through the DB.
+type DB struct {
+	store kv.Store
+
+	clock  atomic.Uint64 // timestamp of the newest visible commit
+	nextID atomic.Uint64
+
+	commitMu sync.Mutex // serializes conflict checks and version writes
+
+	mu     sync.Mutex
+	active map[uint64]uint64 // txn ID → snapshot timestamp
+}
+
+// Open recovers the commit clock from store. Versions stamped after the
+// recorded clock belong to a commit that was interrupted before it finished
+// and are removed, so commits are atomic across crashes.
+func Open(store kv.Store) (*DB, error) {
+	db := &DB{store: store, active: map[uint64]uint64{}}
+	clock := uint64(0)
+	switch b, err := store.Get(clockKey); {
+	case errors.Is(err, kv.ErrNotFound):
+	case err != nil:
+		return nil, err
+	case len(b) != 8:
+		return nil, errCorruptData
+	default:
+		clock = binary.BigEndian.Uint64(b)
+	}
+	var torn [][]byte
+	var maxID uint64
+	var scanErr error
+	err := store.Scan(startBound(nil), endBound(nil), func(k, v []byte) bool {
+		_, ts, err := decodeVersionKey(k)
+		if err == nil {
+			var id uint64
+			id, _, _, err = decodeVersion(v)
+			maxID = max(maxID, id)
+		}
+		if err != nil {
+			scanErr = err
+			return false
+		}
+		if ts > clock {
+			torn = append(torn, bytes.Clone(k))
+		}
+		return true
+	})
+	if err == nil {
+		err = scanErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mvcc: recovering: %w", err)
+	}
+	for _, k := range torn {
+		if err := store.Delete(k); err != nil {
+			return nil, err
+		}
+	}
+	db.clock.Store(clock)
+	db.nextID.Store(maxID)
+	return db, nil
+}
+
+// Begin starts a transaction reading the snapshot of everything committed so
+// far.
+func (db *DB) Begin() *Txn {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t := &Txn{
+		db:       db,
+		id:       db.nextID.Add(1),
+		snapshot: db.clock.Load(),
+		writes:   map[string]pendingWrite{},
+	}
+	db.active[t.id] = t.snapshot
+	return t
+}
+
+func (db *DB) finish(t *Txn) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.active, t.id)
+}
+
+// Clock returns the timestamp of the newest commit.
+func (db *DB) Clock() uint64 { return db.clock.Load() }
+
+// watermark is the oldest snapshot still in use. New transactions start at
+// the clock, which never goes backwards, so nothing older can be read again.
+func (db *DB) watermark() uint64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	w := db.clock.Load()
+	for _, s := range db.active {
+		w = min(w, s)
+	}
+	return w
+}
+
+// latest returns the newest version of key committed at or before ts.
+func (db *DB) latest(key []byte, ts uint64) (v Version, found bool, err error) {
+	var decodeErr error
+	err = db.store.Scan(versionKey(key, ts), versionsEnd(key), func(k, b []byte) bool {
+		_, v.CommitTS, decodeErr = decodeVersionKey(k)
+		if decodeErr == nil {
+			v.TxnID, v.Value, v.Deleted, decodeErr = decodeVersion(b)
+		}
+		found = decodeErr == nil
+		return false
+	})
+	if err == nil {
+		err = decodeErr
+	}
+	return v, found, err
+}
+
+// Version is one committed state of a key.
+type Version struct {
+	TxnID    uint64 // the transaction that wrote it
+	CommitTS uint64
+	Value    []byte
+	Deleted  bool
+}
+
+// Versions returns every stored version of key, newest first.
+func (db *DB) Versions(key []byte) ([]Version, error) {
+	var vs []Version
+	var decodeErr error
+	err := db.store.Scan(keyPrefix(key), versionsEnd(key), func(k, b []byte) bool {
+		var v Version
+		_, v.CommitTS, decodeErr = decodeVersionKey(k)
+		if decodeErr == nil {
+			v.TxnID, v.Value, v.Deleted, decodeErr = decodeVersion(b)
+		}
+		v.Value = bytes.Clone(v.Value)
+		vs = append(vs, v)
+		return decodeErr == nil
+	})
+	if err == nil {
+		err = decodeErr
+	}
+	return vs, err
+}
+
+// GCStats reports what a collection pass did.
+type GCStats struct {
+	Watermark uint64
+	Versions  int // versions examined
+	Removed   int
+}
+
+// GC removes versions hidden from every snapshot at or after the watermark:
+// for each key, everything older than the newest version at or below the
+// watermark, and that version too when it is a deletion.
+func (db *DB) GC() (GCStats, error) {
+	st := GCStats{Watermark: db.watermark()}
+	var (
+		doomed    [][]byte
+		cur       []byte
+		covered   bool // cur already has a version at or below the watermark
+		decodeErr error
+	)
+	err := db.store.Scan(startBound(nil), endBound(nil), func(k, b []byte) bool {
+		var key []byte
+		var ts uint64
+		if key, ts, decodeErr = decodeVersionKey(k); decodeErr != nil {
+			return false
+		}
+		st.Versions++
+		if !bytes.Equal(key, cur) || cur == nil {
+			cur, covered = key, false
+		}
+		if ts > st.Watermark {
+			return true
+		}
+		if covered {
+			doomed = append(doomed, bytes.Clone(k))
+			return true
+		}
+		covered = true
+		var deleted bool
+		if _, _, deleted, decodeErr = decodeVersion(b); decodeErr != nil {
+			return false
+		}
+		if deleted {
+			doomed = append(doomed, bytes.Clone(k))
+		}
+		return true
+	})
+	if err == nil {
+		err = decodeErr
+	}
+	if err != nil {
+		return st, err
+	}
+	for _, k := range doomed {
+		if err := db.store.Delete(k); err != nil {
+			return st, err
+		}
+		st.Removed++
+	}
+	return st, nil
+}
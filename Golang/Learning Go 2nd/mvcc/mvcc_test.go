@@ -0,0 +1,408 @@
+package mvcc
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"learning-go.adcon.dev/kv"
+)
+
+func open(t *testing.T) (*DB, kv.Store) {
+	t.Helper()
+	store := kv.NewMem()
+	db, err := Open(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, store
+}
+
+func put(t *testing.T, db *DB, kvs ...string) {
+	t.Helper()
+	txn := db.Begin()
+	for i := 0; i < len(kvs); i += 2 {
+		txn.Put([]byte(kvs[i]), []byte(kvs[i+1]))
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func get(txn *Txn, key string) string {
+	v, err := txn.Get([]byte(key))
+	if errors.Is(err, kv.ErrNotFound) {
+		return "<absent>"
+	}
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return string(v)
+}
+
+func TestSnapshot(t *testing.T) {
+	db, _ := open(t)
+	put(t, db, "a", "1")
+	old := db.Begin()
+	put(t, db, "a", "2", "b", "new")
+	if got := get(old, "a") + " " + get(old, "b"); got != "1 <absent>" {
+		t.Errorf("old snapshot reads %q, want %q", got, "1 <absent>")
+	}
+	fresh := db.Begin()
+	if got := get(fresh, "a") + " " + get(fresh, "b"); got != "2 new" {
+		t.Errorf("new snapshot reads %q, want %q", got, "2 new")
+	}
+	old.Rollback()
+	fresh.Rollback()
+}
+
+func TestFirstCommitterWins(t *testing.T) {
+	db, _ := open(t)
+	put(t, db, "x", "0", "y", "0")
+	a, b := db.Begin(), db.Begin()
+	a.Put([]byte("x"), []byte("a"))
+	b.Put([]byte("x"), []byte("b"))
+	if err := a.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Commit(); !errors.Is(err, ErrConflict) {
+		t.Fatalf("second writer of x: err = %v, want ErrConflict", err)
+	}
+	if err := b.Commit(); !errors.Is(err, ErrTxnDone) {
+		t.Errorf("Commit after a conflict: err = %v, want ErrTxnDone", err)
+	}
+
+	// Disjoint writes both commit, even when each read the other's key:
+	// snapshot isolation allows write skew.
+	c, d := db.Begin(), db.Begin()
+	get(c, "y")
+	get(d, "x")
+	c.Put([]byte("x"), []byte("c"))
+	d.Put([]byte("y"), []byte("d"))
+	if err := errors.Join(c.Commit(), d.Commit()); err != nil {
+		t.Fatalf("disjoint writers: %v", err)
+	}
+}
+
+func TestOwnWritesAndScan(t *testing.T) {
+	db, _ := open(t)
+	put(t, db, "a", "1", "b", "2", "c", "3", "a\x00z", "nul")
+	txn := db.Begin()
+	txn.Put([]byte("b"), []byte("mine"))
+	txn.Delete([]byte("c"))
+	txn.Put([]byte("bb"), []byte("new"))
+	txn.Put([]byte("z"), []byte("past the end"))
+	if got := get(txn, "b") + " " + get(txn, "c"); got != "mine <absent>" {
+		t.Errorf("own writes read %q, want %q", got, "mine <absent>")
+	}
+	tests := []struct {
+		start, end string
+		nilEnd     bool
+		want       []string
+	}{
+		{"", "", true, []string{"a=1", "a\x00z=nul", "b=mine", "bb=new", "z=past the end"}},
+		{"a", "b", false, []string{"a=1", "a\x00z=nul"}},
+		{"a\x00", "bc", false, []string{"a\x00z=nul", "b=mine", "bb=new"}},
+		{"b", "z", false, []string{"b=mine", "bb=new"}},
+		{"c", "d", false, nil},
+	}
+	for _, tt := range tests {
+		var end []byte
+		if !tt.nilEnd {
+			end = []byte(tt.end)
+		}
+		var got []string
+		err := txn.Scan([]byte(tt.start), end, func(k, v []byte) bool {
+			got = append(got, string(k)+"="+string(v))
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Scan(%q, %q) = %q, want %q", tt.start, tt.end, got, tt.want)
+		}
+	}
+	txn.Rollback()
+	if _, err := txn.Get([]byte("a")); !errors.Is(err, ErrTxnDone) {
+		t.Errorf("Get after Rollback: err = %v, want ErrTxnDone", err)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	db, store := open(t)
+	put(t, db, "k", "committed")
+	clock := db.Clock()
+	// A version stamped past the durable clock is a commit torn by a crash.
+	store.Put(versionKey([]byte("k"), clock+1), encodeVersion(99, []byte("torn"), false))
+
+	db, err := Open(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Clock() != clock {
+		t.Errorf("clock = %d after reopening, want %d", db.Clock(), clock)
+	}
+	vs, err := db.Versions([]byte("k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vs) != 1 || string(vs[0].Value) != "committed" {
+		t.Errorf("versions after recovery: %+v, want only the committed one", vs)
+	}
+	if txn := db.Begin(); txn.ID() <= 99 {
+		t.Errorf("new transaction ID %d reuses the torn writer's ID 99", txn.ID())
+	}
+}
+
+func TestGC(t *testing.T) {
+	db, _ := open(t)
+	for i := range 5 {
+		put(t, db, "k", strconv.Itoa(i), "gone", "x")
+	}
+	reader := db.Begin() // pins the snapshot with k=4
+	put(t, db, "k", "5")
+	del := db.Begin()
+	del.Delete([]byte("gone"))
+	if err := del.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := db.GC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := get(reader, "k") + " " + get(reader, "gone"); got != "4 x" {
+		t.Errorf("pinned snapshot reads %q after GC, want %q", got, "4 x")
+	}
+	// k keeps 4 (pinned) and 5; gone keeps x (pinned) and the deletion.
+	if st.Removed != 8 {
+		t.Errorf("GC removed %d of %d versions, want 8", st.Removed, st.Versions)
+	}
+
+	reader.Rollback()
+	if _, err := db.GC(); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]int{"k": 1, "gone": 0} {
+		if vs, _ := db.Versions([]byte(key)); len(vs) != want {
+			t.Errorf("%s has %d versions after the last reader left, want %d", key, len(vs), want)
+		}
+	}
+}
+
+// TestBank moves money between accounts from several goroutines while
+// others audit the total, which snapshot isolation keeps constant, and GC
+// runs underneath.
+func TestBank(t *testing.T) {
+	db, _ := open(t)
+	const accounts, initial = 5, 100
+	setup := db.Begin()
+	for i := range accounts {
+		setup.Put(account(i), []byte(strconv.Itoa(initial)))
+	}
+	if err := setup.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(uint64(w), 1))
+			for i := range 200 {
+				var err error
+				switch {
+				case w == 0 && i%20 == 0:
+					_, err = db.GC()
+				case w%2 == 0:
+					err = audit(db, accounts, accounts*initial)
+				default:
+					err = transfer(db, rng, accounts)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if err := audit(db, accounts, accounts*initial); err != nil {
+		t.Error(err)
+	}
+}
+
+func account(i int) []byte { return fmt.Appendf(nil, "acct/%d", i) }
+
+func transfer(db *DB, rng *rand.Rand, accounts int) error {
+	from, to := rng.IntN(accounts), rng.IntN(accounts-1)
+	if to >= from {
+		to++
+	}
+	for {
+		txn := db.Begin()
+		a, errA := strconv.Atoi(get(txn, string(account(from))))
+		b, errB := strconv.Atoi(get(txn, string(account(to))))
+		if err := errors.Join(errA, errB); err != nil {
+			txn.Rollback()
+			return err
+		}
+		txn.Put(account(from), []byte(strconv.Itoa(a-1)))
+		txn.Put(account(to), []byte(strconv.Itoa(b+1)))
+		if err := txn.Commit(); !errors.Is(err, ErrConflict) {
+			return err
+		}
+	}
+}
+
+func audit(db *DB, accounts, want int) error {
+	txn := db.Begin()
+	defer txn.Rollback()
+	var balances []int
+	total := 0
+	for i := range accounts {
+		n, err := strconv.Atoi(get(txn, string(account(i))))
+		if err != nil {
+			return err
+		}
+		balances = append(balances, n)
+		total += n
+	}
+	if total != want {
+		return fmt.Errorf("snapshot %d sees balances %v totalling %d, want %d", txn.Snapshot(), balances, total, want)
+	}
+	return nil
+}
+
+// TestHistory runs random concurrent transactions, then replays the
+// committed writes in commit order and checks that every read and scan saw
+// the state as of its snapshot and that no two overlapping transactions
+// both committed a write to the same key.
+func TestHistory(t *testing.T) {
+	db, _ := open(t)
+	type record struct {
+		snapshot, commitTS uint64
+		committed          bool
+		reads, writes      map[string]string
+		scan               map[string]string // taken before any write, if any
+	}
+	var (
+		mu      sync.Mutex
+		history []*record
+		wg      sync.WaitGroup
+	)
+	for w := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(uint64(w), 2))
+			for range 300 {
+				txn := db.Begin()
+				r := &record{snapshot: txn.Snapshot(), reads: map[string]string{}, writes: map[string]string{}}
+				if rng.IntN(4) == 0 {
+					r.scan = map[string]string{}
+					if err := txn.Scan([]byte("key/"), []byte("key0"), func(k, v []byte) bool {
+						r.scan[string(k)] = string(v)
+						return true
+					}); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+				for range 1 + rng.IntN(4) {
+					k := "key/" + strconv.Itoa(rng.IntN(6))
+					if _, own := r.writes[k]; own {
+						continue
+					}
+					switch rng.IntN(4) {
+					case 0, 1:
+						r.reads[k] = get(txn, k)
+					case 2:
+						txn.Delete([]byte(k))
+						r.writes[k] = "<absent>"
+					default:
+						v := fmt.Sprintf("t%d", txn.ID())
+						txn.Put([]byte(k), []byte(v))
+						r.writes[k] = v
+					}
+				}
+				err := txn.Commit()
+				if err != nil && !errors.Is(err, ErrConflict) {
+					t.Error(err)
+					return
+				}
+				r.committed, r.commitTS = err == nil, txn.CommitTS()
+				mu.Lock()
+				history = append(history, r)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	type version struct {
+		ts       uint64
+		snapshot uint64
+		value    string
+	}
+	versions := map[string][]version{}
+	var writers []*record
+	for _, r := range history {
+		if r.committed && len(r.writes) > 0 {
+			writers = append(writers, r)
+		}
+	}
+	sort.Slice(writers, func(i, j int) bool { return writers[i].commitTS < writers[j].commitTS })
+	for _, r := range writers {
+		for k, v := range r.writes {
+			versions[k] = append(versions[k], version{r.commitTS, r.snapshot, v})
+		}
+	}
+	for k, vs := range versions {
+		for i := 1; i < len(vs); i++ {
+			if vs[i].snapshot < vs[i-1].ts {
+				t.Errorf("lost update on %s: snapshot %d committed over commit %d", k, vs[i].snapshot, vs[i-1].ts)
+			}
+		}
+	}
+	at := func(k string, ts uint64) string {
+		vs := versions[k]
+		i := sort.Search(len(vs), func(i int) bool { return vs[i].ts > ts })
+		if i == 0 {
+			return "<absent>"
+		}
+		return vs[i-1].value
+	}
+	for _, r := range history {
+		for k, got := range r.reads {
+			if want := at(k, r.snapshot); got != want {
+				t.Errorf("read %s = %s at snapshot %d, want %s", k, got, r.snapshot, want)
+			}
+		}
+		if r.scan == nil {
+			continue
+		}
+		want := map[string]string{}
+		for k := range versions {
+			if v := at(k, r.snapshot); v != "<absent>" {
+				want[k] = v
+			}
+		}
+		if !maps.Equal(r.scan, want) {
+			t.Errorf("scan at snapshot %d saw %v, want %v", r.snapshot, r.scan, want)
+		}
+	}
+}
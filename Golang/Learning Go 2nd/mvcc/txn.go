@@ -0,0 +1,222 @@
+package mvcc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"learning-go.adcon.dev/kv"
+)
+
+type pendingWrite struct {
+	value   []byte
+	deleted bool
+}
+
+// Txn is a snapshot-isolated transaction. It is not safe for concurrent use;
+// run one transaction per goroutine.
+type Txn struct {
+	db       *DB
+	id       uint64
+	snapshot uint64
+	commitTS uint64
+	writes   map[string]pendingWrite
+	done     bool
+}
+
+func (t *Txn) ID() uint64 { return t.id }
+
+// Snapshot is the commit timestamp the transaction reads at.
+func (t *Txn) Snapshot() uint64 { return t.snapshot }
+
+// CommitTS is the timestamp assigned by a successful Commit, or the snapshot
+// for a transaction that wrote nothing.
+func (t *Txn) CommitTS() uint64 { return t.commitTS }
+
+// Get reads key as of the snapshot, seeing the transaction's own writes.
+func (t *Txn) Get(key []byte) ([]byte, error) {
+	if t.done {
+		return nil, ErrTxnDone
+	}
+	if w, ok := t.writes[string(key)]; ok {
+		if w.deleted {
+			return nil, kv.ErrNotFound
+		}
+		return w.value, nil
+	}
+	v, found, err := t.db.latest(key, t.snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if !found || v.Deleted {
+		return nil, kv.ErrNotFound
+	}
+	return v.Value, nil
+}
+
+func (t *Txn) Put(key, value []byte) error {
+	if t.done {
+		return ErrTxnDone
+	}
+	t.writes[string(key)] = pendingWrite{value: bytes.Clone(value)}
+	return nil
+}
+
+func (t *Txn) Delete(key []byte) error {
+	if t.done {
+		return ErrTxnDone
+	}
+	t.writes[string(key)] = pendingWrite{deleted: true}
+	return nil
+}
+
+// Scan calls fn for every live key in [start, end) as of the snapshot, merged
+// with the transaction's own writes. A nil end means no upper bound.
+func (t *Txn) Scan(start, end []byte, fn func(key, value []byte) bool) error {
+	if t.done {
+		return ErrTxnDone
+	}
+	var local []string
+	for k := range t.writes {
+		if k >= string(start) && (end == nil || k < string(end)) {
+			local = append(local, k)
+		}
+	}
+	sort.Strings(local)
+
+	// emit passes on local writes to keys below upto, or all of them; it
+	// reports false once fn asks to stop.
+	emit := func(upto []byte, all bool) bool {
+		for len(local) > 0 && (all || local[0] < string(upto)) {
+			k := local[0]
+			local = local[1:]
+			if w := t.writes[k]; !w.deleted && !fn([]byte(k), w.value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var (
+		cur       []byte
+		decided   bool // cur's visible version was handled
+		stopped   bool
+		decodeErr error
+	)
+	err := t.db.store.Scan(startBound(start), endBound(end), func(k, b []byte) bool {
+		var key []byte
+		var ts uint64
+		if key, ts, decodeErr = decodeVersionKey(k); decodeErr != nil {
+			return false
+		}
+		if cur == nil || !bytes.Equal(key, cur) {
+			cur, decided = key, false
+		}
+		if decided || ts > t.snapshot {
+			return true
+		}
+		decided = true
+		// Local writes to smaller keys come first; a local write to this key
+		// replaces the stored version and is emitted in its place.
+		if !emit(key, false) {
+			stopped = true
+			return false
+		}
+		if w, own := t.writes[string(key)]; own {
+			local = local[1:] // local[0] is key, as smaller ones were emitted
+			if !w.deleted && !fn(key, w.value) {
+				stopped = true
+				return false
+			}
+			return true
+		}
+		_, value, deleted, err := decodeVersion(b)
+		if err != nil {
+			decodeErr = err
+			return false
+		}
+		if !deleted && !fn(key, value) {
+			stopped = true
+			return false
+		}
+		return true
+	})
+	if err == nil {
+		err = decodeErr
+	}
+	if err != nil || stopped {
+		return err
+	}
+	emit(nil, true)
+	return nil
+}
+
+// Commit makes the writes visible atomically. It returns ErrConflict, and
+// discards the writes, if another transaction committed a write to one of
+// the same keys after this transaction's snapshot.
+func (t *Txn) Commit() error {
+	if t.done {
+		return ErrTxnDone
+	}
+	t.done = true
+	defer t.db.finish(t)
+	if len(t.writes) == 0 {
+		t.commitTS = t.snapshot
+		return nil
+	}
+	db := t.db
+	db.commitMu.Lock()
+	defer db.commitMu.Unlock()
+
+	keys := make([]string, 0, len(t.writes))
+	for k := range t.writes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, found, err := db.latest([]byte(k), math.MaxUint64)
+		if err != nil {
+			return err
+		}
+		if found && v.CommitTS > t.snapshot {
+			return fmt.Errorf("%w on %q with transaction %d", ErrConflict, k, v.TxnID)
+		}
+	}
+
+	// Versions stamped after the durable clock are invisible until the
+	// clock moves, and are discarded by Open if we crash before that.
+	ts := db.clock.Load() + 1
+	for i, k := range keys {
+		w := t.writes[k]
+		if err := db.store.Put(versionKey([]byte(k), ts), encodeVersion(t.id, w.value, w.deleted)); err != nil {
+			db.undo(keys[:i], ts)
+			return err
+		}
+	}
+	if err := db.store.Put(clockKey, binary.BigEndian.AppendUint64(nil, ts)); err != nil {
+		db.undo(keys, ts)
+		return err
+	}
+	db.clock.Store(ts)
+	t.commitTS = ts
+	return nil
+}
+
+// Rollback discards the transaction. It is a no-op after Commit.
+func (t *Txn) Rollback() {
+	if t.done {
+		return
+	}
+	t.done = true
+	t.db.finish(t)
+}
+
+// undo removes the versions of a failed commit so the next commit, which
+// reuses the timestamp, does not publish them.
+func (db *DB) undo(keys []string, ts uint64) {
+	for _, k := range keys {
+		db.store.Delete(versionKey([]byte(k), ts))
+	}
+}
@@ -0,0 +1,237 @@
+// Package options adds functional options to the plain Config structs used by
+// the constructors in this module. A Config names its settings with json tags
+// and its package offers one typed With function per field. The same settings
+// load from JSON files and environment variables, and Apply merges every
+// source into the struct form, rejecting settings that disagree.
+package options
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Option sets one field of a T. It remembers the setting's name, value and
+// where it came from, so conflicts can be reported in those terms.
+type Option[T any] struct {
+	name   string
+	value  any
+	source string
+	index  []int
+}
+
+func (o Option[T]) Name() string   { return o.name }
+func (o Option[T]) Value() any     { return o.value }
+func (o Option[T]) Source() string { return o.source }
+
+func (o Option[T]) String() string { return fmt.Sprintf("%s=%v (%s)", o.name, o.value, o.source) }
+
+// New returns the option that sets the field of T tagged json:"name" to v.
+// It panics when there is no such field or v has another type, which is a
+// bug in the With function calling it rather than bad input.
+func New[T any](name string, v any) Option[T] {
+	f, ok := lookup(reflect.TypeFor[T](), name)
+	if !ok {
+		panic(fmt.Sprintf("options: %s has no setting %q", reflect.TypeFor[T](), name))
+	}
+	if reflect.TypeOf(v) != f.typ {
+		panic(fmt.Sprintf("options: %s.%s is %s, not %T", reflect.TypeFor[T](), name, f.typ, v))
+	}
+	return Option[T]{name: name, value: v, source: "option", index: f.index}
+}
+
+// Apply sets opts on cfg. Non-zero fields of cfg count as settings from the
+// struct form, so an option, file or environment variable that gives the
+// same setting another value is a conflict, as are two options that
+// disagree. Repeating a setting with the same value is fine.
+func Apply[T any](cfg T, opts ...Option[T]) (T, error) {
+	v := reflect.ValueOf(&cfg).Elem()
+	set := map[string]Option[T]{}
+	for _, f := range fields(v.Type()) {
+		if fv := v.FieldByIndex(f.index); !fv.IsZero() {
+			set[f.name] = Option[T]{name: f.name, value: fv.Interface(), source: "Config", index: f.index}
+		}
+	}
+	var errs []error
+	for _, o := range opts {
+		if prev, ok := set[o.name]; ok && !reflect.DeepEqual(prev.value, o.value) {
+			errs = append(errs, fmt.Errorf("conflicting %s: %v from %s, %v from %s", o.name, prev.value, prev.source, o.value, o.source))
+			continue
+		}
+		set[o.name] = o
+		v.FieldByIndex(o.index).Set(reflect.ValueOf(o.value))
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// FromJSON reads settings from a JSON object keyed by setting name. Unknown
+// names are errors, so a typo does not silently leave a default in place.
+// Any value may also be a string in the environment format, which is how
+// durations such as "250ms" are written. Source names the file in error
+// messages.
+func FromJSON[T any](data []byte, source string) ([]Option[T], error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("options: %s: %w", source, err)
+	}
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	typ := reflect.TypeFor[T]()
+	var opts []Option[T]
+	for _, name := range names {
+		f, ok := lookup(typ, name)
+		if !ok {
+			return nil, fmt.Errorf("options: %s: unknown setting %q (known: %s)", source, name, strings.Join(settingNames(typ), ", "))
+		}
+		v := reflect.New(f.typ).Elem()
+		var s string
+		var err error
+		if json.Unmarshal(raw[name], &s) == nil && f.typ.Kind() != reflect.String {
+			// A quoted value, e.g. "250ms" or "0x100": parse it like the
+			// environment would.
+			v, err = parse(f.typ, s)
+		} else {
+			err = json.Unmarshal(raw[name], v.Addr().Interface())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("options: %s: %s: %w", source, name, err)
+		}
+		opts = append(opts, Option[T]{name: name, value: v.Interface(), source: source, index: f.index})
+	}
+	return opts, nil
+}
+
+// FromEnv reads settings from environment variables named prefix_SETTING,
+// such as LSM_MEMTABLE_SIZE. Getenv is usually os.LookupEnv.
+func FromEnv[T any](prefix string, getenv func(string) (string, bool)) ([]Option[T], error) {
+	var opts []Option[T]
+	for _, f := range fields(reflect.TypeFor[T]()) {
+		env := envName(prefix, f.name)
+		s, ok := getenv(env)
+		if !ok {
+			continue
+		}
+		v, err := parse(f.typ, s)
+		if err != nil {
+			return nil, fmt.Errorf("options: $%s: %w", env, err)
+		}
+		opts = append(opts, Option[T]{name: f.name, value: v.Interface(), source: "$" + env, index: f.index})
+	}
+	return opts, nil
+}
+
+// Field describes one setting for documentation.
+type Field struct {
+	Name    string `json:"name"`
+	Env     string `json:"env"`
+	Type    string `json:"type"`
+	Default string `json:"default"`
+}
+
+// Fields lists the settings of T with their values in defaults and the
+// environment variables that set them.
+func Fields[T any](defaults T, prefix string) []Field {
+	v := reflect.ValueOf(defaults)
+	var out []Field
+	for _, f := range fields(v.Type()) {
+		out = append(out, Field{
+			Name:    f.name,
+			Env:     envName(prefix, f.name),
+			Type:    f.typ.String(),
+			Default: fmt.Sprint(v.FieldByIndex(f.index).Interface()),
+		})
+	}
+	return out
+}
+
+type field struct {
+	name  string
+	index []int
+	typ   reflect.Type
+}
+
+// fields returns the json-tagged fields of a struct type in order.
+func fields(t reflect.Type) []field {
+	var out []field
+	for _, sf := range reflect.VisibleFields(t) {
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if !sf.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		out = append(out, field{name: name, index: sf.Index, typ: sf.Type})
+	}
+	return out
+}
+
+func lookup(t reflect.Type, name string) (field, bool) {
+	for _, f := range fields(t) {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func settingNames(t reflect.Type) []string {
+	var names []string
+	for _, f := range fields(t) {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+func envName(prefix, name string) string {
+	return prefix + "_" + strings.ToUpper(name)
+}
+
+// parse converts s to a value of type t. Lists of strings are separated by
+// commas.
+func parse(t reflect.Type, s string) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	var err error
+	switch {
+	case t == reflect.TypeFor[time.Duration]():
+		var d time.Duration
+		d, err = time.ParseDuration(s)
+		v.SetInt(int64(d))
+	case t.Kind() == reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		v.SetBool(b)
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		var n int64
+		n, err = strconv.ParseInt(s, 0, t.Bits())
+		v.SetInt(n)
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		var n uint64
+		n, err = strconv.ParseUint(s, 0, t.Bits())
+		v.SetUint(n)
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(s, t.Bits())
+		v.SetFloat(f)
+	case t.Kind() == reflect.String:
+		v.SetString(s)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		// A comma-separated list, e.g. "*.tmp,Thumbs.db".
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				v = reflect.Append(v, reflect.ValueOf(item).Convert(t.Elem()))
+			}
+		}
+	default:
+		return v, fmt.Errorf("unsupported type %s", t)
+	}
+	if err != nil {
+		return v, fmt.Errorf("bad %s %q", t, s)
+	}
+	return v, nil
+}
@@ -0,0 +1,206 @@
+package options
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Inner struct {
+	Depth int `json:"depth"`
+}
+
+type testConfig struct {
+	Inner
+	Size    int           `json:"size"`
+	Mask    uint16        `json:"mask"`
+	Rate    float64       `json:"rate"`
+	Timeout time.Duration `json:"timeout"`
+	Sync    bool          `json:"sync"`
+	Name    string        `json:"name"`
+	Skip    []string      `json:"skip"`
+	Ignored int           `json:"-"`
+	Untag   int
+}
+
+func withSize(n int) Option[testConfig]              { return New[testConfig]("size", n) }
+func withTimeout(d time.Duration) Option[testConfig] { return New[testConfig]("timeout", d) }
+func withSkip(s []string) Option[testConfig]         { return New[testConfig]("skip", s) }
+
+func TestApply(t *testing.T) {
+	cfg, err := Apply(testConfig{Size: 1, Name: "n"}, withTimeout(time.Second), withSize(1), New[testConfig]("depth", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Size != 1 || cfg.Timeout != time.Second || cfg.Depth != 3 || cfg.Name != "n" {
+		t.Errorf("Apply = %+v", cfg)
+	}
+
+	tests := []struct {
+		name string
+		cfg  testConfig
+		opts []Option[testConfig]
+		want []string // one line per conflict
+	}{
+		{"option against Config", testConfig{Size: 1}, []Option[testConfig]{withSize(2)},
+			[]string{"conflicting size: 1 from Config, 2 from option"}},
+		{"two options", testConfig{}, []Option[testConfig]{withSize(2), withSize(3)},
+			[]string{"conflicting size: 2 from option, 3 from option"}},
+		{"slices compare by value", testConfig{Skip: []string{"a"}}, []Option[testConfig]{withSkip([]string{"a"}), withSkip([]string{"b"})},
+			[]string{"conflicting skip: [a] from option, [b] from option"}},
+		{"every conflict", testConfig{Size: 1, Timeout: time.Second}, []Option[testConfig]{withSize(2), withTimeout(time.Minute)},
+			[]string{"conflicting size: 1 from Config, 2 from option", "conflicting timeout: 1s from Config, 1m0s from option"}},
+	}
+	for _, tt := range tests {
+		_, err := Apply(tt.cfg, tt.opts...)
+		if err == nil {
+			t.Errorf("%s: no error", tt.name)
+			continue
+		}
+		if got := strings.Split(err.Error(), "\n"); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: error %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// A file and the environment disagreeing name both sources.
+	file, err := FromJSON[testConfig]([]byte(`{"size": 5}`), "cfg.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := FromEnv[testConfig]("T", getenv(map[string]string{"T_SIZE": "6"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Apply(testConfig{}, append(file, env...)...); err == nil || err.Error() != "conflicting size: 5 from cfg.json, 6 from $T_SIZE" {
+		t.Errorf("file against environment: %v", err)
+	}
+}
+
+func TestNewPanics(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		v    any
+	}{{"nope", 1}, {"size", int64(1)}, {"Ignored", 1}, {"Untag", 1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("New(%q, %T) did not panic", tt.name, tt.v)
+				}
+			}()
+			New[testConfig](tt.name, tt.v)
+		}()
+	}
+}
+
+func getenv(env map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+}
+
+// apply resolves opts over the zero config, failing the test on conflicts.
+func apply(t *testing.T, opts []Option[testConfig]) testConfig {
+	t.Helper()
+	cfg, err := Apply(testConfig{}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestFromJSON(t *testing.T) {
+	want := testConfig{Inner: Inner{2}, Size: 10, Mask: 0x100, Rate: 0.5, Timeout: 250 * time.Millisecond, Sync: true, Name: "x", Skip: []string{"*.tmp", "a b"}}
+	for _, src := range []string{
+		`{"depth": 2, "size": 10, "mask": 256, "rate": 0.5, "timeout": 250000000, "sync": true, "name": "x", "skip": ["*.tmp", "a b"]}`,
+		// Quoted values parse as in the environment.
+		`{"depth": "2", "size": "10", "mask": "0x100", "rate": "0.5", "timeout": "250ms", "sync": "true", "name": "x", "skip": "*.tmp, a b"}`,
+	} {
+		opts, err := FromJSON[testConfig]([]byte(src), "f.json")
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if got := apply(t, opts); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", src, got, want)
+		}
+		var names []string
+		for _, o := range opts {
+			if o.Source() != "f.json" {
+				t.Errorf("%s comes from %q", o.Name(), o.Source())
+			}
+			names = append(names, o.Name())
+		}
+		if !slices.IsSorted(names) {
+			t.Errorf("options in order %v, want sorted", names)
+		}
+	}
+
+	tests := []struct {
+		src, err string
+	}{
+		{`{"size": 1, "sise": 2}`, `options: f.json: unknown setting "sise" (known: depth, size, mask, rate, timeout, sync, name, skip)`},
+		{`{"Untag": 1}`, `unknown setting "Untag"`},
+		{`{"size": 1.5}`, "options: f.json: size: json: cannot unmarshal number 1.5"},
+		{`{"size": "ten"}`, `options: f.json: size: bad int "ten"`},
+		{`{"mask": 70000}`, "options: f.json: mask: json: cannot unmarshal number 70000"},
+		{`{"timeout": "soon"}`, `options: f.json: timeout: bad time.Duration "soon"`},
+		{`{"size": 1`, "options: f.json: unexpected end of JSON input"},
+		{`[1]`, "options: f.json: json: cannot unmarshal array"},
+	}
+	for _, tt := range tests {
+		_, err := FromJSON[testConfig]([]byte(tt.src), "f.json")
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("FromJSON(%s) = %v, want an error containing %q", tt.src, err, tt.err)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	env := map[string]string{
+		"APP_DEPTH": "2", "APP_SIZE": "0x10", "APP_RATE": "1e-3", "APP_TIMEOUT": "1m",
+		"APP_SYNC": "1", "APP_NAME": "", "APP_SKIP": ",a,,b ,",
+		"APP_UNTAG": "1", "APP_HIDDEN": "1", "OTHER_SIZE": "9",
+	}
+	opts, err := FromEnv[testConfig]("APP", getenv(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testConfig{Inner: Inner{2}, Size: 16, Rate: 0.001, Timeout: time.Minute, Sync: true, Skip: []string{"a", "b"}}
+	if got := apply(t, opts); !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+	var sources []string
+	for _, o := range opts {
+		sources = append(sources, o.Source())
+	}
+	// An empty variable is still a setting.
+	if want := []string{"$APP_DEPTH", "$APP_SIZE", "$APP_RATE", "$APP_TIMEOUT", "$APP_SYNC", "$APP_NAME", "$APP_SKIP"}; !slices.Equal(sources, want) {
+		t.Errorf("sources %v, want %v", sources, want)
+	}
+
+	for name, value := range map[string]string{"APP_SIZE": "big", "APP_MASK": "-1", "APP_SYNC": "maybe", "APP_RATE": "fast"} {
+		_, err := FromEnv[testConfig]("APP", getenv(map[string]string{name: value}))
+		if err == nil || !strings.HasPrefix(err.Error(), "options: $"+name+": bad ") {
+			t.Errorf("$%s=%s: %v", name, value, err)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	got := Fields(testConfig{Size: 4, Timeout: time.Second, Skip: []string{"a", "b"}}, "APP")
+	want := []Field{
+		{"depth", "APP_DEPTH", "int", "0"},
+		{"size", "APP_SIZE", "int", "4"},
+		{"mask", "APP_MASK", "uint16", "0"},
+		{"rate", "APP_RATE", "float64", "0"},
+		{"timeout", "APP_TIMEOUT", "time.Duration", "1s"},
+		{"sync", "APP_SYNC", "bool", "false"},
+		{"name", "APP_NAME", "string", ""},
+		{"skip", "APP_SKIP", "[]string", "[a b]"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
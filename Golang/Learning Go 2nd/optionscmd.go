@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"learning-go.adcon.dev/carve"
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/dupes"
+	"learning-go.adcon.dev/lsm"
+	"learning-go.adcon.dev/options"
+	"learning-go.adcon.dev/repl"
+	"learning-go.adcon.dev/rescue"
+	"learning-go.adcon.dev/ring"
+	"learning-go.adcon.dev/skiplist"
+	"learning-go.adcon.dev/wire"
+)
+
+// setting is one row of the options command's table.
+type setting struct {
+	options.Field
+	Value string `json:"value"`
+	From  string `json:"from"`
+}
+
+// configurable describes a constructor that takes a Config and options.
+type configurable struct {
+	ctor string
+	// settings resolves the options from a JSON file (if any) and the
+	// environment (if env) and returns every setting with its outcome.
+	settings func(file []byte, source string, env bool) ([]setting, error)
+}
+
+var configurables = map[string]configurable{
+	"carve":        {"carve.Scan", settingsOf(carve.EnvPrefix, carve.DefaultConfig(), carve.Config.Resolve)},
+	"dupes":        {"dupes.Find", settingsOf(dupes.EnvPrefix, dupes.DefaultConfig(), dupes.Config.Resolve)},
+	"lsm":          {"lsm.Open", settingsOf(lsm.EnvPrefix, lsm.DefaultConfig(), lsm.Config.Resolve)},
+	"skiplist":     {"skiplist.New", settingsOf(skiplist.EnvPrefix, skiplist.DefaultConfig(), skiplist.Config.Resolve)},
+	"repl-primary": {"repl.NewPrimary", settingsOf(repl.PrimaryEnvPrefix, repl.DefaultPrimaryConfig(), repl.PrimaryConfig.Resolve)},
+	"repl-replica": {"repl.StartReplica", settingsOf(repl.ReplicaEnvPrefix, repl.DefaultReplicaConfig(), repl.ReplicaConfig.Resolve)},
+	"rescue":       {"rescue.Copy", settingsOf(rescue.EnvPrefix, rescue.DefaultConfig(), rescue.Config.Resolve)},
+	"ring":         {"ring.New", settingsOf(ring.EnvPrefix, ring.DefaultConfig(), ring.Config.Resolve)},
+	"wire-pool":    {"wire.NewPool", settingsOf(wire.PoolEnvPrefix, wire.DefaultPoolConfig(), wire.PoolConfig.Resolve)},
+	"wire-server":  {"wire.NewServer", settingsOf(wire.ServerEnvPrefix, wire.DefaultServerConfig(), wire.ServerConfig.Resolve)},
+}
+
+func settingsOf[T any](prefix string, defaults T, resolve func(T, ...options.Option[T]) (T, error)) func([]byte, string, bool) ([]setting, error) {
+	return func(file []byte, source string, env bool) ([]setting, error) {
+		var opts []options.Option[T]
+		if file != nil {
+			fromFile, err := options.FromJSON[T](file, source)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, fromFile...)
+		}
+		if env {
+			fromEnv, err := options.FromEnv[T](prefix, os.LookupEnv)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, fromEnv...)
+		}
+		var zero T
+		cfg, err := resolve(zero, opts...)
+		if err != nil {
+			return nil, err
+		}
+		from := map[string]string{}
+		for _, o := range opts {
+			from[o.Name()] = o.Source()
+		}
+		defs := options.Fields(defaults, prefix)
+		var rows []setting
+		for i, f := range options.Fields(cfg, prefix) {
+			src := from[f.Name]
+			if src == "" {
+				src = "default"
+			}
+			rows = append(rows, setting{Field: defs[i], Value: f.Default, From: src})
+		}
+		return rows, nil
+	}
+}
+
+func runOptions(ctx *cli.Context) error {
+	fs := ctx.Flags
+	file := fs.String("json", "", "JSON file with settings to apply")
+	env := fs.Bool("env", false, "also apply settings from environment variables")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(configurables))
+	for name := range configurables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if fs.NArg() == 0 {
+		if ctx.JSON() {
+			list := map[string]string{}
+			for _, name := range names {
+				list[name] = configurables[name].ctor
+			}
+			return ctx.Encode(list)
+		}
+		for _, name := range names {
+			fmt.Printf("%-14s %s\n", name, configurables[name].ctor)
+		}
+		return nil
+	}
+	c, ok := configurables[fs.Arg(0)]
+	if !ok {
+		return fmt.Errorf("options: unknown component %q (available: %s)", fs.Arg(0), strings.Join(names, ", "))
+	}
+	var data []byte
+	if *file != "" {
+		var err error
+		if data, err = os.ReadFile(*file); err != nil {
+			return err
+		}
+	}
+	rows, err := c.settings(data, *file, *env)
+	if err != nil {
+		return err
+	}
+	if ctx.JSON() {
+		return ctx.Encode(struct {
+			Constructor string    `json:"constructor"`
+			Settings    []setting `json:"settings"`
+		}{c.ctor, rows})
+	}
+	fmt.Printf("%s settings\n", c.ctor)
+	fmt.Printf("%-22s %-14s %-10s %-12s %-28s %s\n", "name", "type", "default", "value", "env", "from")
+	for _, r := range rows {
+		fmt.Printf("%-22s %-14s %-10s %-12s %-28s %s\n", r.Name, r.Type, r.Default, r.Value, r.Env, r.From)
+	}
+	return nil
+}
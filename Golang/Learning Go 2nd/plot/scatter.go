@@ -0,0 +1,75 @@
+// Package plot draws small ASCII charts for terminal output.
+package plot
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Series is a set of points drawn with the same mark. Later series are drawn
+// over earlier ones.
+type Series struct {
+	X, Y []float64
+	Mark rune
+}
+
+// Scatter draws the series on a width×height character grid with the axis
+// ranges printed along the edges.
+func Scatter(w io.Writer, width, height int, xLabel, yLabel string, series ...Series) error {
+	if width < 2 || height < 2 {
+		return fmt.Errorf("plot: %dx%d grid is too small", width, height)
+	}
+	xMin, xMax := math.Inf(1), math.Inf(-1)
+	yMin, yMax := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for i := range s.X {
+			xMin, xMax = min(xMin, s.X[i]), max(xMax, s.X[i])
+			yMin, yMax = min(yMin, s.Y[i]), max(yMax, s.Y[i])
+		}
+	}
+	if math.IsInf(xMin, 1) {
+		_, err := fmt.Fprintln(w, "(no points)")
+		return err
+	}
+	if xMax == xMin {
+		xMin, xMax = xMin-1, xMax+1
+	}
+	if yMax == yMin {
+		yMin, yMax = yMin-1, yMax+1
+	}
+
+	grid := make([][]rune, height)
+	for r := range grid {
+		grid[r] = []rune(strings.Repeat(" ", width))
+	}
+	for _, s := range series {
+		for i := range s.X {
+			col := int(math.Round((s.X[i] - xMin) / (xMax - xMin) * float64(width-1)))
+			row := height - 1 - int(math.Round((s.Y[i]-yMin)/(yMax-yMin)*float64(height-1)))
+			grid[row][col] = s.Mark
+		}
+	}
+
+	top, bottom := fmt.Sprintf("%.4g", yMax), fmt.Sprintf("%.4g", yMin)
+	pad := max(len(top), len(bottom), len(yLabel))
+	var b strings.Builder
+	fmt.Fprintf(&b, "%*s\n", pad, yLabel)
+	for r, line := range grid {
+		label := ""
+		switch r {
+		case 0:
+			label = top
+		case height - 1:
+			label = bottom
+		}
+		fmt.Fprintf(&b, "%*s |%s\n", pad, label, string(line))
+	}
+	fmt.Fprintf(&b, "%*s +%s\n", pad, "", strings.Repeat("-", width))
+	left, right := fmt.Sprintf("%.4g", xMin), fmt.Sprintf("%.4g", xMax)
+	gap := max(1, width-len(left)-len(right))
+	fmt.Fprintf(&b, "%*s  %s%*s%s  %s\n", pad, "", left, gap, "", right, xLabel)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
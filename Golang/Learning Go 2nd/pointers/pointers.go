@@ -0,0 +1,119 @@
+// Package pointers collects the Chapter 6 examples on where Go puts values.
+// Each function is small on purpose: run the escape command over this
+// package to see which lines the compiler moves to the heap and how many
+// allocations each one costs.
+package pointers
+
+import "fmt"
+
+type Kid struct {
+	Name string
+	Age  int
+}
+
+// NewKid returns a value. The caller gets its own copy and nothing is
+// allocated.
+func NewKid(name string, age int) Kid {
+	return Kid{Name: name, Age: age}
+}
+
+// NewKidPtr returns a pointer to a local variable, which must outlive the
+// call, so k is moved to the heap. When the call is inlined the caller can
+// keep k on its stack instead; noinline shows the cost of a constructor too
+// big to inline.
+//
+//go:noinline
+func NewKidPtr(name string, age int) *Kid {
+	k := Kid{Name: name, Age: age}
+	return &k
+}
+
+// Birthday takes a pointer but only writes through it, so k does not escape
+// and the caller's Kid can stay on its stack.
+func Birthday(k *Kid) {
+	k.Age++
+}
+
+// oldest is package state: anything stored in it lives on the heap.
+var oldest *Kid
+
+// Remember keeps k after the call returns, so its parameter leaks and every
+// caller has to allocate what it passes in.
+func Remember(k *Kid) {
+	if oldest == nil || k.Age > oldest.Age {
+		oldest = k
+	}
+}
+
+// Describe passes k's fields to fmt.Sprintf as interface values. Values
+// boxed in an interface usually escape.
+func Describe(k Kid) string {
+	return fmt.Sprintf("%s is %d", k.Name, k.Age)
+}
+
+// SumSquares uses a scratch slice that never leaves the function. Its size
+// is only known at run time, so the compiler reserves a small buffer on the
+// stack (32 bytes, since Go 1.25) and allocates on the heap only when n ints
+// do not fit in it.
+func SumSquares(n int) int {
+	s := make([]int, n)
+	return sumSquares(s)
+}
+
+func sumSquares(s []int) int {
+	sum := 0
+	for i := range s {
+		s[i] = i * i
+		sum += s[i]
+	}
+	return sum
+}
+
+// Ages allocates a new slice on every call.
+func Ages(kids []Kid) []int {
+	ages := make([]int, 0, len(kids))
+	for _, k := range kids {
+		ages = append(ages, k.Age)
+	}
+	return ages
+}
+
+// AppendAges reuses the caller's buffer, the slices-as-buffers pattern:
+// buf leaks only to the result, so nothing is allocated once it is big
+// enough.
+func AppendAges(buf []int, kids []Kid) []int {
+	for _, k := range kids {
+		buf = append(buf, k.Age)
+	}
+	return buf
+}
+
+// Counter returns a closure over n. The closure outlives Counter, so both
+// the closure and n move to the heap.
+func Counter() func() int {
+	n := 0
+	return func() int {
+		n++
+		return n
+	}
+}
+
+// PointerList builds one allocation per Kid, each a separate object for the
+// garbage collector to trace.
+func PointerList(n int) []*Kid {
+	kids := make([]*Kid, n)
+	for i := range kids {
+		kids[i] = &Kid{Age: i}
+	}
+	return kids
+}
+
+// ValueList stores the same Kids in one contiguous allocation: a single
+// object for the collector, and neighbours that share cache lines.
+func ValueList(n int) []Kid {
+	kids := make([]Kid, n)
+	for i := range kids {
+		kids[i].Age = i
+	}
+	return kids
+}
@@ -0,0 +1,102 @@
+package pointers_test
+
+import (
+	"testing"
+
+	"learning-go.adcon.dev/pointers"
+)
+
+// Sinks keep benchmark results alive, the way a real caller that uses them
+// would.
+var (
+	kidSink    pointers.Kid
+	kidPtrSink *pointers.Kid
+	intSink    int
+	intsSink   []int
+	stringSink string
+	funcSink   func() int
+	kidsSink   []pointers.Kid
+	kidPtrs    []*pointers.Kid
+)
+
+// Scratch sizes for SumSquares live in variables the compiler cannot fold
+// into constants, as they would be in a real caller.
+var small, large = 4, 1024
+
+func BenchmarkNewKid(b *testing.B) {
+	for b.Loop() {
+		kidSink = pointers.NewKid("Ana", 5)
+	}
+}
+
+func BenchmarkNewKidPtr(b *testing.B) {
+	for b.Loop() {
+		kidPtrSink = pointers.NewKidPtr("Ana", 5)
+	}
+}
+
+func BenchmarkBirthday(b *testing.B) {
+	for b.Loop() {
+		k := pointers.Kid{Name: "Ana", Age: 5}
+		pointers.Birthday(&k)
+		intSink = k.Age
+	}
+}
+
+func BenchmarkRemember(b *testing.B) {
+	for b.Loop() {
+		pointers.Remember(&pointers.Kid{Name: "Ana", Age: 5})
+	}
+}
+
+func BenchmarkDescribe(b *testing.B) {
+	for b.Loop() {
+		stringSink = pointers.Describe(pointers.Kid{Name: "Ana", Age: 5})
+	}
+}
+
+func BenchmarkSumSquares(b *testing.B) {
+	b.Run("n=4", func(b *testing.B) {
+		for b.Loop() {
+			intSink = pointers.SumSquares(small)
+		}
+	})
+	b.Run("n=1024", func(b *testing.B) {
+		for b.Loop() {
+			intSink = pointers.SumSquares(large)
+		}
+	})
+}
+
+func BenchmarkAges(b *testing.B) {
+	kids := make([]pointers.Kid, 16)
+	for b.Loop() {
+		intsSink = pointers.Ages(kids)
+	}
+}
+
+func BenchmarkAppendAges(b *testing.B) {
+	kids := make([]pointers.Kid, 16)
+	buf := make([]int, 0, len(kids))
+	for b.Loop() {
+		buf = pointers.AppendAges(buf[:0], kids)
+	}
+}
+
+func BenchmarkCounter(b *testing.B) {
+	for b.Loop() {
+		funcSink = pointers.Counter()
+	}
+}
+
+func BenchmarkPointerList(b *testing.B) {
+	for b.Loop() {
+		kidPtrs = pointers.PointerList(100)
+	}
+}
+
+func BenchmarkValueList(b *testing.B) {
+	for b.Loop() {
+		kidsSink = pointers.ValueList(100)
+	}
+}
@@ -0,0 +1,189 @@
+package prob
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// Estimate compares a theoretical expectation with the mean observed over a
+// number of simulated trials.
+type Estimate struct {
+	Name        string
+	Theoretical float64
+	Observed    float64
+	Trials      int
+}
+
+// RelError is |observed - theoretical| / |theoretical|.
+func (e Estimate) RelError() float64 {
+	if e.Theoretical == 0 {
+		return math.Abs(e.Observed)
+	}
+	return math.Abs(e.Observed-e.Theoretical) / math.Abs(e.Theoretical)
+}
+
+// HireAssistant runs CLRS HIRE-ASSISTANT on candidate scores in interview
+// order and returns how many times a better candidate was hired.
+func HireAssistant(scores []int) int {
+	hires, best := 0, math.MinInt
+	for _, s := range scores {
+		if s > best {
+			best = s
+			hires++
+		}
+	}
+	return hires
+}
+
+// HiringProblem interviews n candidates in random order. Candidate i is hired
+// with probability 1/i, so the expected number of hires is H_n ≈ ln n.
+func HiringProblem(n, trials int, rng *rand.Rand) Estimate {
+	scores := make([]int, n)
+	for i := range scores {
+		scores[i] = i
+	}
+	total := 0
+	for range trials {
+		RandomizeInPlace(scores, rng)
+		total += HireAssistant(scores)
+	}
+	return Estimate{
+		Name:        "hiring problem: expected hires",
+		Theoretical: Harmonic(n),
+		Observed:    float64(total) / float64(trials),
+		Trials:      trials,
+	}
+}
+
+// BirthdayShared estimates the probability that k people in a year of days
+// days include at least two with the same birthday. The exact value is
+// 1 - ∏_{i<k} (1 - i/days).
+func BirthdayShared(k, days, trials int, rng *rand.Rand) Estimate {
+	noMatch := 1.0
+	for i := range k {
+		noMatch *= 1 - float64(i)/float64(days)
+	}
+	seen := make([]int, days)
+	hits := 0
+	for t := 1; t <= trials; t++ {
+		for range k {
+			d := rng.IntN(days)
+			if seen[d] == t {
+				hits++
+				break
+			}
+			seen[d] = t
+		}
+	}
+	return Estimate{
+		Name:        "birthday: P(shared birthday)",
+		Theoretical: 1 - noMatch,
+		Observed:    float64(hits) / float64(trials),
+		Trials:      trials,
+	}
+}
+
+// BirthdayPairs estimates the expected number of pairs sharing a birthday
+// among k people, k(k-1)/(2·days) by linearity of expectation.
+func BirthdayPairs(k, days, trials int, rng *rand.Rand) Estimate {
+	count := make([]int, days)
+	total := 0
+	for range trials {
+		clear(count)
+		for range k {
+			d := rng.IntN(days)
+			total += count[d] // the new person pairs with everyone already there
+			count[d]++
+		}
+	}
+	return Estimate{
+		Name:        "birthday: expected matching pairs",
+		Theoretical: float64(k*(k-1)) / float64(2*days),
+		Observed:    float64(total) / float64(trials),
+		Trials:      trials,
+	}
+}
+
+// BirthdayThreshold returns the smallest k whose exact shared-birthday
+// probability reaches one half.
+func BirthdayThreshold(days int) int {
+	noMatch := 1.0
+	for k := 1; ; k++ {
+		noMatch *= 1 - float64(k-1)/float64(days)
+		if 1-noMatch >= 0.5 {
+			return k
+		}
+	}
+}
+
+// CouponCollector tosses balls into bins until every bin holds at least one.
+// The expected number of tosses is bins·H_bins.
+func CouponCollector(bins, trials int, rng *rand.Rand) Estimate {
+	hit := make([]int, bins)
+	total := 0
+	for t := 1; t <= trials; t++ {
+		empty := bins
+		for empty > 0 {
+			total++
+			b := rng.IntN(bins)
+			if hit[b] != t {
+				hit[b] = t
+				empty--
+			}
+		}
+	}
+	return Estimate{
+		Name:        "balls and bins: tosses to fill every bin",
+		Theoretical: float64(bins) * Harmonic(bins),
+		Observed:    float64(total) / float64(trials),
+		Trials:      trials,
+	}
+}
+
+// EmptyBins tosses balls into bins and counts the bins left empty. Each bin
+// stays empty with probability (1 - 1/bins)^balls.
+func EmptyBins(balls, bins, trials int, rng *rand.Rand) Estimate {
+	load := make([]int, bins)
+	total := 0
+	for range trials {
+		clear(load)
+		for range balls {
+			load[rng.IntN(bins)]++
+		}
+		for _, l := range load {
+			if l == 0 {
+				total++
+			}
+		}
+	}
+	return Estimate{
+		Name:        "balls and bins: empty bins",
+		Theoretical: float64(bins) * math.Pow(1-1/float64(bins), float64(balls)),
+		Observed:    float64(total) / float64(trials),
+		Trials:      trials,
+	}
+}
+
+// LongestStreak flips n fair coins and measures the longest run of heads,
+// which is Θ(lg n) (CLRS 5.4.3); lg n is used as the reference value.
+func LongestStreak(n, trials int, rng *rand.Rand) Estimate {
+	total := 0
+	for range trials {
+		longest, run := 0, 0
+		for range n {
+			if rng.IntN(2) == 1 {
+				run++
+				longest = max(longest, run)
+			} else {
+				run = 0
+			}
+		}
+		total += longest
+	}
+	return Estimate{
+		Name:        "streaks: longest run of heads (≈ lg n)",
+		Theoretical: math.Log2(float64(n)),
+		Observed:    float64(total) / float64(trials),
+		Trials:      trials,
+	}
+}
@@ -0,0 +1,109 @@
+// Package prob simulates the probabilistic analyses of CLRS Chapter 5: the
+// hiring problem, random permutations, the birthday paradox, balls and bins,
+// and streaks. Every experiment takes a seeded RNG so runs are reproducible.
+package prob
+
+import (
+	"math/rand/v2"
+	"sort"
+)
+
+// PermuteBySorting is CLRS PERMUTE-BY-SORTING: give every element a random
+// priority in 1..n³ and sort by it. The priorities are all distinct with
+// probability at least 1 - 1/n; when they are not, a tie would keep input
+// order and bias the result, so all priorities are drawn again.
+func PermuteBySorting[T any](a []T, rng *rand.Rand) []T {
+	n := len(a)
+	cube := max(1, n*n*n)
+	type keyed struct {
+		priority int
+		v        T
+	}
+	ks := make([]keyed, n)
+	for distinct := false; !distinct; {
+		for i, v := range a {
+			ks[i] = keyed{1 + rng.IntN(cube), v}
+		}
+		sort.Slice(ks, func(i, j int) bool { return ks[i].priority < ks[j].priority })
+		distinct = true
+		for i := 1; i < n; i++ {
+			if ks[i].priority == ks[i-1].priority {
+				distinct = false
+				break
+			}
+		}
+	}
+	out := make([]T, n)
+	for i, k := range ks {
+		out[i] = k.v
+	}
+	return out
+}
+
+// RandomizeInPlace is the Fisher–Yates shuffle (CLRS RANDOMIZE-IN-PLACE):
+// swap a[i] with a uniformly chosen a[i..n-1].
+func RandomizeInPlace[T any](a []T, rng *rand.Rand) {
+	for i := range a {
+		j := i + rng.IntN(len(a)-i)
+		a[i], a[j] = a[j], a[i]
+	}
+}
+
+// PermuteWithAll swaps a[i] with any element of the whole slice. It looks
+// like Fisher–Yates but produces nⁿ equally likely outcomes, which n! cannot
+// divide evenly (CLRS Exercise 5.3-3), so it is biased. It is kept as a
+// negative control for UniformityTest.
+func PermuteWithAll[T any](a []T, rng *rand.Rand) {
+	for i := range a {
+		j := rng.IntN(len(a))
+		a[i], a[j] = a[j], a[i]
+	}
+}
+
+// Uniformity is the result of a chi-squared goodness-of-fit test of observed
+// permutation counts against the uniform distribution.
+type Uniformity struct {
+	Perms    int // n!, the number of categories
+	Trials   int
+	ChiSq    float64
+	PValue   float64 // probability of a statistic at least this large if uniform
+	MinCount int
+	MaxCount int
+}
+
+// UniformityTest permutes 0..n-1 trials times with shuffle and tests whether
+// every one of the n! orderings is equally likely. Keep n small: the test
+// needs several expected hits per ordering.
+func UniformityTest(n, trials int, rng *rand.Rand, shuffle func([]int, *rand.Rand) []int) Uniformity {
+	counts := map[string]int{}
+	base := make([]int, n)
+	for i := range base {
+		base[i] = i
+	}
+	key := make([]byte, n)
+	for range trials {
+		p := shuffle(append([]int(nil), base...), rng)
+		for i, v := range p {
+			key[i] = byte(v)
+		}
+		counts[string(key)]++
+	}
+	perms := 1
+	for i := 2; i <= n; i++ {
+		perms *= i
+	}
+	expected := float64(trials) / float64(perms)
+	u := Uniformity{Perms: perms, Trials: trials, MinCount: trials}
+	for _, c := range counts {
+		d := float64(c) - expected
+		u.ChiSq += d * d / expected
+		u.MinCount, u.MaxCount = min(u.MinCount, c), max(u.MaxCount, c)
+	}
+	// Orderings never seen contribute (0 - expected)² / expected each.
+	if missing := perms - len(counts); missing > 0 {
+		u.ChiSq += float64(missing) * expected
+		u.MinCount = 0
+	}
+	u.PValue = ChiSquaredSurvival(u.ChiSq, float64(perms-1))
+	return u
+}
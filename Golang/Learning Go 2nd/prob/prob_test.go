@@ -0,0 +1,117 @@
+package prob
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestChiSquaredSurvival(t *testing.T) {
+	tests := []struct {
+		x, df, want float64
+	}{
+		{0, 5, 1},
+		{-1, 5, 1},
+		// Two degrees of freedom are an exponential: Q = e^(-x/2). x = 1
+		// takes the series, x = 4 the continued fraction.
+		{1, 2, math.Exp(-0.5)},
+		{4, 2, math.Exp(-2)},
+		{40, 2, math.Exp(-20)},
+		// Four: Q = e^(-x/2)·(1 + x/2).
+		{1, 4, math.Exp(-0.5) * 1.5},
+		{10, 4, math.Exp(-5) * 6},
+		// Critical values from the standard tables.
+		{3.841458820694124, 1, 0.05},
+		{35.17246162690806, 23, 0.05},
+		{41.63839812251, 23, 0.01},
+	}
+	for _, tt := range tests {
+		if got := ChiSquaredSurvival(tt.x, tt.df); math.Abs(got-tt.want) > 1e-9*tt.want {
+			t.Errorf("ChiSquaredSurvival(%g, %g) = %.12g, want %.12g", tt.x, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestClosedForms(t *testing.T) {
+	if h := Harmonic(4); math.Abs(h-25.0/12) > 1e-15 {
+		t.Errorf("Harmonic(4) = %g, want 25/12", h)
+	}
+	if h := Harmonic(0); h != 0 {
+		t.Errorf("Harmonic(0) = %g, want 0", h)
+	}
+	if k := BirthdayThreshold(365); k != 23 {
+		t.Errorf("BirthdayThreshold(365) = %d, want 23", k)
+	}
+	for _, tt := range []struct {
+		scores []int
+		want   int
+	}{
+		{nil, 0},
+		{[]int{1, 2, 3}, 3},
+		{[]int{3, 2, 1}, 1},
+		{[]int{2, 1, 3, 3}, 2},
+	} {
+		if got := HireAssistant(tt.scores); got != tt.want {
+			t.Errorf("HireAssistant(%v) = %d, want %d", tt.scores, got, tt.want)
+		}
+	}
+}
+
+// TestExperiments runs every experiment with a fixed seed and compares it
+// with its closed form. The tolerances are several standard errors wide.
+func TestExperiments(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 1))
+	tests := []struct {
+		e      Estimate
+		relErr float64
+	}{
+		{HiringProblem(100, 20000, rng), 0.02},
+		{BirthdayShared(23, 365, 20000, rng), 0.03},
+		{BirthdayPairs(28, 365, 20000, rng), 0.03},
+		{CouponCollector(50, 2000, rng), 0.03},
+		{EmptyBins(100, 100, 2000, rng), 0.02},
+		// lg n is only the order of growth; the mean is a little below it.
+		{LongestStreak(1024, 2000, rng), 0.15},
+	}
+	for _, tt := range tests {
+		if err := tt.e.RelError(); err > tt.relErr || math.IsNaN(err) {
+			t.Errorf("%s: observed %.4f, theory %.4f (relative error %.3f > %.3f)", tt.e.Name, tt.e.Observed, tt.e.Theoretical, err, tt.relErr)
+		}
+	}
+	// The exact values behind the estimates.
+	if got := BirthdayShared(23, 365, 1, rng).Theoretical; math.Abs(got-0.5072972343239857) > 1e-12 {
+		t.Errorf("P(shared birthday among 23) = %.15f, want 0.507297234323986", got)
+	}
+	if got := BirthdayPairs(28, 365, 1, rng).Theoretical; got != 378.0/365 {
+		t.Errorf("expected pairs among 28 = %g, want 378/365", got)
+	}
+}
+
+func TestUniformity(t *testing.T) {
+	rng := rand.New(rand.NewPCG(2, 3))
+	fisherYates := func(a []int, r *rand.Rand) []int { RandomizeInPlace(a, r); return a }
+	biased := func(a []int, r *rand.Rand) []int { PermuteWithAll(a, r); return a }
+	for name, fn := range map[string]func([]int, *rand.Rand) []int{
+		"Fisher–Yates":       fisherYates,
+		"permute by sorting": PermuteBySorting[int],
+	} {
+		u := UniformityTest(4, 24000, rng, fn)
+		if u.Perms != 24 || u.MinCount == 0 || u.PValue < 0.001 {
+			t.Errorf("%s: %+v, want uniform", name, u)
+		}
+	}
+	if u := UniformityTest(3, 24000, rng, biased); u.PValue > 1e-6 {
+		t.Errorf("swap with any: %+v, want clearly not uniform", u)
+	}
+	// An ordering that never appears counts towards χ².
+	if u := UniformityTest(3, 600, rng, func(a []int, _ *rand.Rand) []int { return a }); u.MinCount != 0 || u.ChiSq != 5*600 || u.PValue > 1e-12 {
+		t.Errorf("identity shuffle: %+v", u)
+	}
+
+	a := []int{5, 6, 7, 8, 9}
+	p := PermuteBySorting(a, rng)
+	if !slices.Equal(slices.Sorted(slices.Values(p)), a) {
+		t.Errorf("PermuteBySorting(%v) = %v, not a permutation", a, p)
+	}
+}
@@ -0,0 +1,62 @@
+package prob
+
+import "math"
+
+// Harmonic returns H_n = 1 + 1/2 + ... + 1/n.
+func Harmonic(n int) float64 {
+	h := 0.0
+	for k := 1; k <= n; k++ {
+		h += 1 / float64(k)
+	}
+	return h
+}
+
+// ChiSquaredSurvival returns P(X ≥ x) for a chi-squared variable with df
+// degrees of freedom, the upper regularized incomplete gamma Q(df/2, x/2).
+func ChiSquaredSurvival(x, df float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	return upperGamma(df/2, x/2)
+}
+
+// upperGamma computes Q(a, x) with the series for x < a+1 and Lentz's
+// continued fraction otherwise (Numerical Recipes §6.2).
+func upperGamma(a, x float64) float64 {
+	lg, _ := math.Lgamma(a)
+	front := math.Exp(a*math.Log(x) - x - lg)
+	if x < a+1 {
+		sum, term := 1/a, 1/a
+		for n := 1.0; n < 1000; n++ {
+			term *= x / (a + n)
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return 1 - front*sum
+	}
+	const tiny = 1e-300
+	b := x + 1 - a
+	c, d := 1/tiny, 1/b
+	h := d
+	for i := 1.0; i < 1000; i++ {
+		an := -i * (i - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return front * h
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/prob"
+)
+
+func runProb(ctx *cli.Context) error {
+	fs := ctx.Flags
+	seed := fs.Uint64("seed", 1, "random seed")
+	trials := fs.Int("trials", 20000, "trials per experiment")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	if *trials < 1 {
+		return fmt.Errorf("prob: -trials must be at least 1, got %d", *trials)
+	}
+	// The slow experiments run a tenth of the trials, but at least one.
+	slow := max(1, *trials/10)
+	rng := rand.New(rand.NewPCG(*seed, *seed))
+
+	fmt.Printf("%-42s %12s %12s %9s\n", "experiment", "theoretical", "observed", "rel err")
+	for _, e := range []prob.Estimate{
+		prob.HiringProblem(100, *trials, rng),
+		prob.BirthdayShared(23, 365, *trials, rng),
+		prob.BirthdayPairs(28, 365, *trials, rng),
+		prob.CouponCollector(50, slow, rng),
+		prob.EmptyBins(100, 100, slow, rng),
+		prob.LongestStreak(1024, slow, rng),
+	} {
+		fmt.Printf("%-42s %12.4f %12.4f %8.2f%%\n", e.Name, e.Theoretical, e.Observed, 100*e.RelError())
+	}
+	fmt.Printf("smallest room with P(shared birthday) ≥ 1/2: %d people\n", prob.BirthdayThreshold(365))
+
+	fmt.Printf("\nUniformity of permutations of 4 elements (chi-squared, 23 df):\n")
+	shufflers := []struct {
+		name string
+		fn   func([]int, *rand.Rand) []int
+	}{
+		{"Fisher–Yates", func(a []int, r *rand.Rand) []int { prob.RandomizeInPlace(a, r); return a }},
+		{"permute by sorting", prob.PermuteBySorting[int]},
+		{"swap with any (biased)", func(a []int, r *rand.Rand) []int { prob.PermuteWithAll(a, r); return a }},
+	}
+	for _, s := range shufflers {
+		u := prob.UniformityTest(4, *trials, rng, s.fn)
+		verdict := "consistent with uniform"
+		if u.PValue < 0.01 {
+			verdict = "NOT uniform"
+		}
+		fmt.Printf("  %-24s χ²=%9.2f p=%.4f counts %d..%d  %s\n", s.name, u.ChiSq, u.PValue, u.MinCount, u.MaxCount, verdict)
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package query
+
+import (
+	"slices"
+)
+
+// node is a plan operator in the iterator model: open (re)starts it and next
+// returns rows until it reports false.
+type node interface {
+	open()
+	next() (Row, bool)
+	info() *nodeInfo
+}
+
+// nodeInfo is what every operator shares: its output layout, the planner's
+// estimates and the counters EXPLAIN ANALYZE reports.
+type nodeInfo struct {
+	cols     []colID // output columns
+	rows     float64 // estimated output rows per loop
+	cost     float64 // estimated total cost
+	order    colID   // column the output is sorted on; rel is -1 if none
+	actual   int     // rows produced over all loops
+	loops    int
+	label    string
+	details  []string
+	children []node
+}
+
+func (n *nodeInfo) info() *nodeInfo { return n }
+
+// colID is a column of one relation in the query.
+type colID struct{ rel, col int }
+
+var unordered = colID{-1, -1}
+
+func (n *nodeInfo) position(c colID) int { return slices.Index(n.cols, c) }
+
+// filter is a bound predicate: row[left] op value, or row[left] op
+// row[right] when right is not -1.
+type filter struct {
+	left  int
+	op    Op
+	right int
+	value Value
+}
+
+func (f filter) match(r Row) bool {
+	v := f.value
+	if f.right >= 0 {
+		v = r[f.right]
+	}
+	return f.op.holds(Compare(r[f.left], v))
+}
+
+func matchAll(fs []filter, r Row) bool {
+	for _, f := range fs {
+		if !f.match(r) {
+			return false
+		}
+	}
+	return true
+}
+
+type seqScan struct {
+	nodeInfo
+	table   *Table
+	filters []filter
+	pos     int
+}
+
+func (s *seqScan) open() { s.loops++; s.pos = 0 }
+
+func (s *seqScan) next() (Row, bool) {
+	for s.pos < len(s.table.Rows) {
+		r := s.table.Rows[s.pos]
+		s.pos++
+		if matchAll(s.filters, r) {
+			s.actual++
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// indexScan reads the rows in an index range in key order.
+type indexScan struct {
+	nodeInfo
+	ix      *index
+	lo, hi  bound
+	filters []filter
+	i, j    int
+}
+
+func (s *indexScan) open() {
+	s.loops++
+	s.i, s.j = s.ix.span(s.lo, s.hi)
+}
+
+func (s *indexScan) next() (Row, bool) {
+	order := s.ix.order
+	for s.i < s.j {
+		r := s.ix.table.Rows[order[s.i]]
+		s.i++
+		if matchAll(s.filters, r) {
+			s.actual++
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+func concat(a, b Row) Row {
+	r := make(Row, 0, len(a)+len(b))
+	return append(append(r, a...), b...)
+}
+
+// nestedLoop rescans the inner input once per outer row.
+type nestedLoop struct {
+	nodeInfo
+	outer, inner node
+	conds        []filter // over the concatenated row
+	cur          Row
+	active       bool
+}
+
+func (n *nestedLoop) open() {
+	n.loops++
+	n.outer.open()
+	n.active = false
+}
+
+func (n *nestedLoop) next() (Row, bool) {
+	for {
+		if !n.active {
+			var ok bool
+			if n.cur, ok = n.outer.next(); !ok {
+				return nil, false
+			}
+			n.inner.open()
+			n.active = true
+		}
+		r, ok := n.inner.next()
+		if !ok {
+			n.active = false
+			continue
+		}
+		if out := concat(n.cur, r); matchAll(n.conds, out) {
+			n.actual++
+			return out, true
+		}
+	}
+}
+
+// hashJoin builds a hash table on the smaller input and probes it with the
+// other. Output rows are always left columns then right columns.
+type hashJoin struct {
+	nodeInfo
+	left, right       node
+	leftKey, rightKey int
+	buildLeft         bool
+	residual          []filter
+	table             map[Value][]Row
+	probe             Row
+	matches           []Row
+}
+
+func (h *hashJoin) open() {
+	h.loops++
+	build, key, probe := h.right, h.rightKey, h.left
+	if h.buildLeft {
+		build, key, probe = h.left, h.leftKey, h.right
+	}
+	h.table = map[Value][]Row{}
+	build.open()
+	for r, ok := build.next(); ok; r, ok = build.next() {
+		h.table[r[key]] = append(h.table[r[key]], r)
+	}
+	probe.open()
+	h.matches = nil
+}
+
+func (h *hashJoin) next() (Row, bool) {
+	probe, key := h.left, h.leftKey
+	if h.buildLeft {
+		probe, key = h.right, h.rightKey
+	}
+	for {
+		for len(h.matches) > 0 {
+			m := h.matches[0]
+			h.matches = h.matches[1:]
+			out := concat(h.probe, m)
+			if h.buildLeft {
+				out = concat(m, h.probe)
+			}
+			if matchAll(h.residual, out) {
+				h.actual++
+				return out, true
+			}
+		}
+		var ok bool
+		if h.probe, ok = probe.next(); !ok {
+			return nil, false
+		}
+		h.matches = h.table[h.probe[key]]
+	}
+}
+
+// mergeJoin zips two inputs sorted on the join key. Each run of equal right
+// keys is buffered so it can be replayed for every matching left row.
+type mergeJoin struct {
+	nodeInfo
+	left, right       node
+	leftKey, rightKey int
+	residual          []filter
+	lrow, rrow        Row
+	lok, rok          bool
+	group             []Row
+	groupKey          Value
+	gi                int
+}
+
+func (m *mergeJoin) open() {
+	m.loops++
+	m.left.open()
+	m.right.open()
+	m.lrow, m.lok = m.left.next()
+	m.rrow, m.rok = m.right.next()
+	m.group, m.gi = nil, 0
+}
+
+func (m *mergeJoin) next() (Row, bool) {
+	for {
+		if m.gi < len(m.group) {
+			out := concat(m.lrow, m.group[m.gi])
+			m.gi++
+			if matchAll(m.residual, out) {
+				m.actual++
+				return out, true
+			}
+			continue
+		}
+		if m.group != nil {
+			// Done with this left row; the next may share its key.
+			if m.lrow, m.lok = m.left.next(); m.lok && Compare(m.lrow[m.leftKey], m.groupKey) == 0 {
+				m.gi = 0
+				continue
+			}
+			m.group = nil
+		}
+		if !m.lok || !m.rok {
+			return nil, false
+		}
+		switch c := Compare(m.lrow[m.leftKey], m.rrow[m.rightKey]); {
+		case c < 0:
+			m.lrow, m.lok = m.left.next()
+		case c > 0:
+			m.rrow, m.rok = m.right.next()
+		default:
+			m.groupKey = m.rrow[m.rightKey]
+			m.group = []Row{m.rrow}
+			for m.rrow, m.rok = m.right.next(); m.rok && Compare(m.rrow[m.rightKey], m.groupKey) == 0; m.rrow, m.rok = m.right.next() {
+				m.group = append(m.group, m.rrow)
+			}
+			m.gi = 0
+		}
+	}
+}
+
+type sortNode struct {
+	nodeInfo
+	child node
+	key   int
+	rows  []Row
+	pos   int
+}
+
+func (s *sortNode) open() {
+	s.loops++
+	s.child.open()
+	s.rows = s.rows[:0]
+	for r, ok := s.child.next(); ok; r, ok = s.child.next() {
+		s.rows = append(s.rows, r)
+	}
+	slices.SortStableFunc(s.rows, func(a, b Row) int { return Compare(a[s.key], b[s.key]) })
+	s.pos = 0
+}
+
+func (s *sortNode) next() (Row, bool) {
+	if s.pos >= len(s.rows) {
+		return nil, false
+	}
+	s.pos++
+	s.actual++
+	return s.rows[s.pos-1], true
+}
+
+type project struct {
+	nodeInfo
+	child node
+	cols  []int
+}
+
+func (p *project) open() { p.loops++; p.child.open() }
+
+func (p *project) next() (Row, bool) {
+	r, ok := p.child.next()
+	if !ok {
+		return nil, false
+	}
+	out := make(Row, len(p.cols))
+	for i, c := range p.cols {
+		out[i] = r[c]
+	}
+	p.actual++
+	return out, true
+}
@@ -0,0 +1,103 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Result is the outcome of Exec. Plan is set for EXPLAIN queries; Rows only
+// for plain SELECTs.
+type Result struct {
+	Columns []string
+	Rows    []Row
+	Plan    string
+}
+
+// Exec runs sql without parameters and collects its result. EXPLAIN shows
+// the chosen plan with its estimates; EXPLAIN ANALYZE also runs it and adds
+// the actual row counts.
+func (c *Catalog) Exec(sql string) (*Result, error) {
+	st, err := c.Prepare(sql)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := st.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close()
+	res := &Result{Columns: cur.Columns}
+	var plan []string
+	for r, ok := cur.Next(); ok; r, ok = cur.Next() {
+		if st.q.Explain {
+			plan = append(plan, r[0].(string))
+		} else {
+			res.Rows = append(res.Rows, r)
+		}
+	}
+	if st.q.Explain {
+		res.Plan = strings.Join(plan, "\n") + "\n"
+	}
+	return res, nil
+}
+
+// explain prints the plan tree in the style of PostgreSQL. Estimated rows are
+// per loop; actual rows are totals over all loops of a node.
+func explain(root node, analyze bool) string {
+	var b strings.Builder
+	var walk func(n node, depth int)
+	walk = func(n node, depth int) {
+		info := n.info()
+		indent := strings.Repeat("      ", depth)
+		prefix := indent
+		if depth > 0 {
+			prefix = indent[:len(indent)-6] + "  ->  "
+		}
+		fmt.Fprintf(&b, "%s%s  (cost=%.1f rows=%.0f)", prefix, info.label, info.cost, info.rows)
+		if analyze {
+			fmt.Fprintf(&b, " (actual rows=%d loops=%d)", info.actual, info.loops)
+		}
+		b.WriteByte('\n')
+		for _, d := range info.details {
+			fmt.Fprintf(&b, "%s      %s\n", indent, d)
+		}
+		for _, c := range info.children {
+			walk(c, depth+1)
+		}
+	}
+	walk(root, 0)
+	return b.String()
+}
+
+// String formats the result as an aligned text table.
+func (r *Result) String() string {
+	if r.Plan != "" {
+		return r.Plan
+	}
+	cells := [][]string{r.Columns}
+	for _, row := range r.Rows {
+		line := make([]string, len(row))
+		for i, v := range row {
+			line[i] = fmt.Sprint(v)
+		}
+		cells = append(cells, line)
+	}
+	widths := make([]int, len(r.Columns))
+	for _, line := range cells {
+		for i, c := range line {
+			widths[i] = max(widths[i], len(c))
+		}
+	}
+	var b strings.Builder
+	for _, line := range cells {
+		for i, c := range line {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			fmt.Fprintf(&b, "%-*s", widths[i], c)
+		}
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "(%d rows)\n", len(r.Rows))
+	return b.String()
+}
@@ -0,0 +1,443 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"strings"
+)
+
+// Cost units: reading a row sequentially costs 1. Index lookups pay a random
+// access per matching row, and hash tables pay to insert each build row.
+const (
+	seqRowCost    = 1.0
+	indexRowCost  = 2.0
+	hashBuildCost = 2.0
+	hashProbeCost = 1.0
+	mergeRowCost  = 1.0
+	// defaultSelectivity is used for conditions the statistics cannot judge,
+	// such as comparing two columns.
+	defaultSelectivity = 1.0 / 3
+
+	maxJoinTables = 12
+)
+
+// forceJoin, when a test sets it to a node label such as "Hash Join", makes
+// the planner use only that method for joins with an equality condition.
+var forceJoin string
+
+type relation struct {
+	ref   TableRef
+	table *Table
+	stats *TableStats
+}
+
+// boundPred is a WHERE condition with its columns resolved.
+type boundPred struct {
+	left  colID
+	op    Op
+	right *colID
+	value Value
+	text  string
+}
+
+func (p boundPred) isJoin() bool { return p.right != nil && p.right.rel != p.left.rel }
+
+// planner holds one query's relations and conditions during planning.
+type planner struct {
+	rels    []relation
+	filters [][]boundPred // single-table conditions per relation
+	joins   []boundPred
+}
+
+// plan builds the cheapest plan for q. Callers hold the catalog lock.
+func (c *Catalog) plan(q *Query) (node, []string, error) {
+	if len(q.From) > maxJoinTables {
+		return nil, nil, fmt.Errorf("query: at most %d tables can be joined", maxJoinTables)
+	}
+	p := &planner{}
+	for _, ref := range q.From {
+		t, ok := c.tables[ref.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("query: no table %q", ref.Name)
+		}
+		for _, r := range p.rels {
+			if r.ref.Alias == ref.Alias {
+				return nil, nil, fmt.Errorf("query: table name %q used twice", ref.Alias)
+			}
+		}
+		p.rels = append(p.rels, relation{ref: ref, table: t, stats: t.Stats()})
+	}
+	p.filters = make([][]boundPred, len(p.rels))
+	for _, w := range q.Where {
+		left, err := p.resolve(w.Left)
+		if err != nil {
+			return nil, nil, err
+		}
+		bp := boundPred{left: left, op: w.Op, value: w.Value, text: w.String()}
+		if w.Right != nil {
+			right, err := p.resolve(*w.Right)
+			if err != nil {
+				return nil, nil, err
+			}
+			bp.right = &right
+		} else if rows := p.rels[left.rel].table.Rows; len(rows) > 0 && !sameType(rows[0][left.col], w.Value) {
+			return nil, nil, fmt.Errorf("query: %s compares values of different types", w)
+		}
+		if bp.isJoin() {
+			p.joins = append(p.joins, bp)
+		} else {
+			p.filters[left.rel] = append(p.filters[left.rel], bp)
+		}
+	}
+
+	root := p.joinOrder()
+	var cols []colID
+	if len(q.Select) == 0 {
+		// SELECT * lists columns in FROM order, whatever the join order.
+		for rel, r := range p.rels {
+			for c := range r.table.Columns {
+				cols = append(cols, colID{rel, c})
+			}
+		}
+	}
+	for _, s := range q.Select {
+		c, err := p.resolve(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		cols = append(cols, c)
+	}
+	names := make([]string, len(cols))
+	proj := &project{child: root}
+	proj.nodeInfo = nodeInfo{cols: cols, rows: root.info().rows, cost: root.info().cost, order: unordered, label: "Project", children: []node{root}}
+	for i, c := range cols {
+		proj.cols = append(proj.cols, root.info().position(c))
+		names[i] = p.name(c)
+	}
+	return proj, names, nil
+}
+
+func sameType(a, b Value) bool {
+	_, as := a.(string)
+	_, bs := b.(string)
+	return as == bs
+}
+
+func (p *planner) name(c colID) string {
+	return p.rels[c.rel].ref.Alias + "." + p.rels[c.rel].table.Columns[c.col]
+}
+
+func (p *planner) resolve(ref ColumnRef) (colID, error) {
+	found := unordered
+	for i, r := range p.rels {
+		if ref.Table != "" && ref.Table != r.ref.Alias {
+			continue
+		}
+		if c := r.table.column(ref.Column); c >= 0 {
+			if found.rel >= 0 {
+				return found, fmt.Errorf("query: column %s is ambiguous", ref)
+			}
+			found = colID{i, c}
+		}
+	}
+	if found.rel < 0 {
+		return found, fmt.Errorf("query: no column %s", ref)
+	}
+	return found, nil
+}
+
+func (p *planner) colStats(c colID) *ColumnStats { return &p.rels[c.rel].stats.Columns[c.col] }
+
+func (p *planner) selectivity(bp boundPred) float64 {
+	if bp.right == nil {
+		return p.colStats(bp.left).selectivity(bp.op, bp.value)
+	}
+	if bp.isJoin() && bp.op == Eq {
+		// Containment: each value on the side with fewer distinct values
+		// finds a match on the other.
+		l, r := p.colStats(bp.left).Distinct, p.colStats(*bp.right).Distinct
+		return 1 / float64(max(1, l, r))
+	}
+	return defaultSelectivity
+}
+
+// baseRows estimates a relation's rows after its own filters.
+func (p *planner) baseRows(rel int) float64 {
+	n := float64(p.rels[rel].stats.Rows)
+	for _, f := range p.filters[rel] {
+		n *= p.selectivity(f)
+	}
+	return n
+}
+
+// setRows estimates the size of the join of a set of relations. It depends
+// only on the set, not on the plan, so every candidate agrees on it.
+func (p *planner) setRows(set uint) float64 {
+	n := 1.0
+	for rel := range p.rels {
+		if set&(1<<rel) != 0 {
+			n *= p.baseRows(rel)
+		}
+	}
+	for _, j := range p.joins {
+		if set&(1<<j.left.rel) != 0 && set&(1<<j.right.rel) != 0 {
+			n *= p.selectivity(j)
+		}
+	}
+	return n
+}
+
+func (p *planner) bind(bp boundPred, info *nodeInfo) filter {
+	f := filter{left: info.position(bp.left), op: bp.op, right: -1, value: bp.value}
+	if bp.right != nil {
+		f.right = info.position(*bp.right)
+	}
+	return f
+}
+
+// accessPath picks the cheapest way to read one relation: a full scan, or a
+// range scan of an index on a filtered column.
+func (p *planner) accessPath(rel int) node {
+	r := p.rels[rel]
+	cols := make([]colID, len(r.table.Columns))
+	for c := range cols {
+		cols[c] = colID{rel, c}
+	}
+	rows := p.baseRows(rel)
+	n := float64(r.stats.Rows)
+	label := fmt.Sprintf("Seq Scan on %s", r.table.Name)
+	if r.ref.Alias != r.table.Name {
+		label += " " + r.ref.Alias
+	}
+	seq := &seqScan{table: r.table}
+	seq.nodeInfo = nodeInfo{cols: cols, rows: rows, cost: n * seqRowCost, order: unordered, label: label}
+	for _, f := range p.filters[rel] {
+		seq.filters = append(seq.filters, p.bind(f, &seq.nodeInfo))
+	}
+	if len(seq.filters) > 0 {
+		seq.details = append(seq.details, "Filter: "+p.conds(p.filters[rel]))
+	}
+	var best node = seq
+
+	for col, ix := range r.table.indexes {
+		var lo, hi bound
+		var used, rest []boundPred
+		matched := n
+		for _, f := range p.filters[rel] {
+			if f.left.col != col || f.right != nil || f.op == Ne {
+				rest = append(rest, f)
+				continue
+			}
+			used = append(used, f)
+			matched *= p.selectivity(f)
+			b := bound{set: true, value: f.value, inclusive: f.op == Eq || f.op == Le || f.op == Ge}
+			if f.op == Eq || f.op == Gt || f.op == Ge {
+				if !lo.set || Compare(b.value, lo.value) > 0 || Compare(b.value, lo.value) == 0 && !b.inclusive {
+					lo = b
+				}
+			}
+			if f.op == Eq || f.op == Lt || f.op == Le {
+				if !hi.set || Compare(b.value, hi.value) < 0 || Compare(b.value, hi.value) == 0 && !b.inclusive {
+					hi = b
+				}
+			}
+		}
+		if len(used) == 0 {
+			continue
+		}
+		cost := math.Log2(n+1) + matched*indexRowCost
+		if cost >= best.info().cost {
+			continue
+		}
+		s := &indexScan{ix: ix, lo: lo, hi: hi}
+		s.nodeInfo = nodeInfo{cols: cols, rows: rows, cost: cost, order: colID{rel, col},
+			label: fmt.Sprintf("Index Scan using %s_%s_idx on %s", r.table.Name, r.table.Columns[col], strings.TrimPrefix(label, "Seq Scan on "))}
+		s.details = append(s.details, "Index Cond: "+p.conds(used))
+		for _, f := range rest {
+			s.filters = append(s.filters, p.bind(f, &s.nodeInfo))
+		}
+		if len(rest) > 0 {
+			s.details = append(s.details, "Filter: "+p.conds(rest))
+		}
+		best = s
+	}
+	return best
+}
+
+func (p *planner) conds(bps []boundPred) string {
+	parts := make([]string, len(bps))
+	for i, bp := range bps {
+		parts[i] = bp.text
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// joinOrder is the Selinger dynamic program: the best plan for every set of
+// relations is built from the best plan of the set minus one relation, joined
+// to that relation with the cheapest method. Cross products are considered
+// only for sets that no join condition connects.
+func (p *planner) joinOrder() node {
+	n := len(p.rels)
+	best := make([]node, 1<<n)
+	for rel := range n {
+		best[1<<rel] = p.accessPath(rel)
+	}
+	for set := uint(1); set < 1<<n; set++ {
+		if bits.OnesCount(set) < 2 {
+			continue
+		}
+		rows := p.setRows(set)
+		for _, crossOK := range []bool{false, true} {
+			for rel := range n {
+				bit := uint(1) << rel
+				if set&bit == 0 || best[set^bit] == nil {
+					continue
+				}
+				conds := p.connecting(set^bit, bit)
+				if len(conds) == 0 && !crossOK {
+					continue
+				}
+				for _, cand := range p.joinCandidates(best[set^bit], best[bit], conds, rows) {
+					if best[set] == nil || cand.info().cost < best[set].info().cost {
+						best[set] = cand
+					}
+				}
+			}
+			if best[set] != nil {
+				break
+			}
+		}
+	}
+	return best[1<<n-1]
+}
+
+// connecting returns the join conditions between two disjoint sets.
+func (p *planner) connecting(a, b uint) []boundPred {
+	var out []boundPred
+	for _, j := range p.joins {
+		l, r := uint(1)<<j.left.rel, uint(1)<<j.right.rel
+		if a&l != 0 && b&r != 0 || a&r != 0 && b&l != 0 {
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+func joinInfo(left, right node, rows, cost float64, label string) nodeInfo {
+	return nodeInfo{
+		cols:     append(append([]colID(nil), left.info().cols...), right.info().cols...),
+		rows:     rows,
+		cost:     cost,
+		order:    unordered,
+		label:    label,
+		children: []node{left, right},
+	}
+}
+
+// joinCandidates lists the ways to join two subplans: nested loops with
+// either side inner, and, given an equality condition, a hash join building
+// the smaller side and a merge join over inputs sorted on the key.
+func (p *planner) joinCandidates(a, b node, conds []boundPred, rows float64) []node {
+	var out []node
+	for _, pair := range [][2]node{{a, b}, {b, a}} {
+		outer, inner := pair[0], pair[1]
+		oi, ii := outer.info(), inner.info()
+		nl := &nestedLoop{outer: outer, inner: inner}
+		nl.nodeInfo = joinInfo(outer, inner, rows, oi.cost+max(1, oi.rows)*ii.cost, "Nested Loop")
+		for _, c := range conds {
+			nl.conds = append(nl.conds, p.bind(c, &nl.nodeInfo))
+		}
+		if len(conds) > 0 {
+			nl.details = []string{"Join Filter: " + p.conds(conds)}
+		}
+		out = append(out, nl)
+	}
+
+	eq := -1
+	for i, c := range conds {
+		if c.op == Eq {
+			eq = i
+			break
+		}
+	}
+	if eq < 0 {
+		return out
+	}
+	key := conds[eq]
+	residual := append(append([]boundPred(nil), conds[:eq]...), conds[eq+1:]...)
+	ai, bi := a.info(), b.info()
+	lk, rk := ai.position(key.left), bi.position(*key.right)
+	if lk < 0 {
+		lk, rk = ai.position(*key.right), bi.position(key.left)
+	}
+
+	h := &hashJoin{left: a, right: b, leftKey: lk, rightKey: rk, buildLeft: ai.rows < bi.rows}
+	build, probe := bi.rows, ai.rows
+	if h.buildLeft {
+		build, probe = probe, build
+	}
+	h.nodeInfo = joinInfo(a, b, rows, ai.cost+bi.cost+build*hashBuildCost+probe*hashProbeCost, "Hash Join")
+	built := bi
+	if h.buildLeft {
+		built = ai
+	}
+	h.details = []string{fmt.Sprintf("Hash Cond: %s (build %s)", key.text, p.sideName(built))}
+	h.bindResidual(p, residual)
+	out = append(out, h)
+
+	ls, rs := p.sorted(a, colIDAt(ai, lk)), p.sorted(b, colIDAt(bi, rk))
+	lsi, rsi := ls.info(), rs.info()
+	m := &mergeJoin{left: ls, right: rs, leftKey: lk, rightKey: rk}
+	m.nodeInfo = joinInfo(ls, rs, rows, lsi.cost+rsi.cost+(lsi.rows+rsi.rows)*mergeRowCost, "Merge Join")
+	m.order = colIDAt(ai, lk)
+	m.details = []string{"Merge Cond: " + key.text}
+	for _, c := range residual {
+		m.residual = append(m.residual, p.bind(c, &m.nodeInfo))
+	}
+	if len(residual) > 0 {
+		m.details = append(m.details, "Join Filter: "+p.conds(residual))
+	}
+	out = append(out, m)
+	if forceJoin != "" {
+		out = slices.DeleteFunc(out, func(n node) bool { return n.info().label != forceJoin })
+	}
+	return out
+}
+
+func (h *hashJoin) bindResidual(p *planner, residual []boundPred) {
+	for _, c := range residual {
+		h.residual = append(h.residual, p.bind(c, &h.nodeInfo))
+	}
+	if len(residual) > 0 {
+		h.details = append(h.details, "Join Filter: "+p.conds(residual))
+	}
+}
+
+func colIDAt(info *nodeInfo, pos int) colID { return info.cols[pos] }
+
+// sideName names the relations under a subplan, for EXPLAIN.
+func (p *planner) sideName(info *nodeInfo) string {
+	var names []string
+	seen := map[int]bool{}
+	for _, c := range info.cols {
+		if !seen[c.rel] {
+			seen[c.rel] = true
+			names = append(names, p.rels[c.rel].ref.Alias)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// sorted returns child ordered on key, adding a sort unless it already is.
+func (p *planner) sorted(child node, key colID) node {
+	ci := child.info()
+	if ci.order == key {
+		return child
+	}
+	s := &sortNode{child: child, key: ci.position(key)}
+	s.nodeInfo = nodeInfo{cols: ci.cols, rows: ci.rows, cost: ci.cost + ci.rows*math.Log2(ci.rows+2), order: key,
+		label: "Sort", details: []string{"Sort Key: " + p.name(key)}, children: []node{child}}
+	return s
+}
@@ -0,0 +1,231 @@
+package query
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// randomCatalog builds r(a, b), s(b, c) and t(c, d) with small value
+// domains, so joins match often, and indexes on a random choice of columns.
+func randomCatalog(t *testing.T, rng *rand.Rand) *Catalog {
+	t.Helper()
+	var tables []*Table
+	for _, def := range [][]string{{"r", "a", "b"}, {"s", "b", "c"}, {"t", "c", "d"}} {
+		tbl := NewTable(def[0], def[1:]...)
+		for range rng.IntN(25) {
+			if err := tbl.Insert(rng.IntN(8), rng.IntN(8)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, col := range def[1:] {
+			if rng.IntN(2) == 0 {
+				if err := tbl.CreateIndex(col); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		tbl.Analyze(1 + rng.IntN(8))
+		tables = append(tables, tbl)
+	}
+	return NewCatalog(tables...)
+}
+
+// bruteForce evaluates a SELECT * by trying every combination of rows.
+func bruteForce(t *testing.T, c *Catalog, sql string) []string {
+	t.Helper()
+	q, err := Parse(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := map[string]int{}
+	var tables []*Table
+	for _, ref := range q.From {
+		tbl, _ := c.Table(ref.Name)
+		for _, col := range tbl.Columns {
+			pos[ref.Alias+"."+col] = len(pos)
+		}
+		tables = append(tables, tbl)
+	}
+	var out []string
+	var walk func(i int, row Row)
+	walk = func(i int, row Row) {
+		if i == len(tables) {
+			for _, w := range q.Where {
+				v := w.Value
+				if w.Right != nil {
+					v = row[pos[w.Right.String()]]
+				}
+				if !w.Op.holds(Compare(row[pos[w.Left.String()]], v)) {
+					return
+				}
+			}
+			out = append(out, fmt.Sprint(row))
+			return
+		}
+		for _, r := range tables[i].Rows {
+			walk(i+1, concat(row, r))
+		}
+	}
+	walk(0, nil)
+	slices.Sort(out)
+	return out
+}
+
+func rows(res *Result) []string {
+	var out []string
+	for _, r := range res.Rows {
+		out = append(out, fmt.Sprint(r))
+	}
+	slices.Sort(out)
+	return out
+}
+
+var actualRows = regexp.MustCompile(`actual rows=(\d+)`)
+
+// TestJoinsAgainstBruteForce plans random three-table queries with every
+// join method forced in turn and compares the results with brute force.
+func TestJoinsAgainstBruteForce(t *testing.T) {
+	defer func() { forceJoin = "" }()
+	queries := []string{
+		"SELECT * FROM r, s, t WHERE r.b = s.b AND s.c = t.c",
+		"SELECT * FROM r, s, t WHERE r.b = s.b AND s.c = t.c AND r.a < 5 AND t.d >= 3",
+		"SELECT * FROM r JOIN s ON r.b = s.b JOIN t ON s.c = t.c WHERE r.a <= t.d",
+		"SELECT * FROM r, s, t WHERE r.b = s.b AND r.a = s.c AND s.c = t.c",
+		"SELECT * FROM r, s, t WHERE r.b = s.b AND t.c = s.c AND r.b > 2 AND r.b <= 6 AND s.c <> 4",
+		"SELECT * FROM t, s, r WHERE s.b = r.b AND t.c = s.c AND t.d = 3",
+		"SELECT * FROM r, s WHERE r.b = s.b AND r.a >= 7",
+	}
+	rng := rand.New(rand.NewPCG(1, 12))
+	for round := range 60 {
+		c := randomCatalog(t, rng)
+		for _, sql := range queries {
+			want := bruteForce(t, c, sql)
+			for _, method := range []string{"", "Nested Loop", "Hash Join", "Merge Join"} {
+				forceJoin = method
+				res, err := c.Exec(sql)
+				if err != nil {
+					t.Fatalf("%s: %v", sql, err)
+				}
+				if got := rows(res); !slices.Equal(got, want) {
+					t.Fatalf("round %d, %q forced: %s\ngot  %v\nwant %v", round, method, sql, got, want)
+				}
+				res, err = c.Exec("EXPLAIN ANALYZE " + sql)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(res.Plan, method) {
+					t.Fatalf("%q forced but not in the plan:\n%s", method, res.Plan)
+				}
+				m := actualRows.FindStringSubmatch(res.Plan)
+				if m == nil || m[1] != strconv.Itoa(len(want)) {
+					t.Fatalf("round %d, %q forced: %s: EXPLAIN ANALYZE reports %v, want %d rows:\n%s", round, method, sql, m, len(want), res.Plan)
+				}
+			}
+		}
+	}
+}
+
+func TestIndexSpan(t *testing.T) {
+	tbl := NewTable("t", "x")
+	for _, x := range []int{5, 1, 3, 3, 7, 3, 9} {
+		tbl.Insert(x)
+	}
+	if err := tbl.CreateIndex("x"); err != nil {
+		t.Fatal(err)
+	}
+	tbl.Insert(3)
+	ix := tbl.indexes[0]
+	incl := func(v int) bound { return bound{set: true, value: int64(v), inclusive: true} }
+	excl := func(v int) bound { return bound{set: true, value: int64(v)} }
+	tests := []struct {
+		name   string
+		lo, hi bound
+		want   []int64
+	}{
+		{"unbounded", bound{}, bound{}, []int64{1, 3, 3, 3, 3, 5, 7, 9}},
+		{"= 3", incl(3), incl(3), []int64{3, 3, 3, 3}},
+		{"> 3", excl(3), bound{}, []int64{5, 7, 9}},
+		{">= 3", incl(3), bound{}, []int64{3, 3, 3, 3, 5, 7, 9}},
+		{"< 3", bound{}, excl(3), []int64{1}},
+		{"<= 3", bound{}, incl(3), []int64{1, 3, 3, 3, 3}},
+		{"> 3 and < 7", excl(3), excl(7), []int64{5}},
+		{">= 3 and <= 7", incl(3), incl(7), []int64{3, 3, 3, 3, 5, 7}},
+		{"between values", incl(4), incl(6), []int64{5}},
+		{"below every value", bound{}, excl(1), nil},
+		{"above every value", excl(9), bound{}, nil},
+		{"empty range", excl(7), excl(5), nil},
+		{"= absent value", incl(4), incl(4), nil},
+	}
+	for _, tt := range tests {
+		i, j := ix.span(tt.lo, tt.hi)
+		var got []int64
+		for _, r := range ix.order[i:j] {
+			got = append(got, tbl.Rows[r][0].(int64))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: span holds %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	tbl := NewTable("t", "uniform", "skewed")
+	for i := range 100 {
+		skewed := i
+		if i < 60 {
+			skewed = 0 // a run longer than a bucket
+		}
+		tbl.Insert(i+1, skewed)
+	}
+	st := tbl.Analyze(4)
+	if st.Rows != 100 {
+		t.Fatalf("Rows = %d, want 100", st.Rows)
+	}
+
+	u := st.Columns[0]
+	want := []Bucket{{int64(25), 25, 25}, {int64(50), 25, 25}, {int64(75), 25, 25}, {int64(100), 25, 25}}
+	if !slices.Equal(u.Histogram, want) || u.Distinct != 100 || u.Min != int64(1) || u.Max != int64(100) {
+		t.Errorf("uniform column: %+v", u)
+	}
+	// The run of zeros stays in one bucket even though it overflows it.
+	s := st.Columns[1]
+	if s.Histogram[0] != (Bucket{int64(0), 60, 1}) || s.Distinct != 41 {
+		t.Errorf("skewed column: %+v", s)
+	}
+	total := 0
+	for _, b := range s.Histogram {
+		total += b.Count
+	}
+	if total != 100 {
+		t.Errorf("skewed buckets hold %d rows, want 100", total)
+	}
+
+	tests := []struct {
+		col  *ColumnStats
+		op   Op
+		v    int
+		want float64
+	}{
+		{&u, Eq, 10, 0.01},
+		{&u, Ne, 10, 0.99},
+		{&u, Lt, 1, 0},
+		{&u, Le, 100, 1},
+		{&u, Gt, 100, 0},
+		{&u, Eq, 500, 0},
+		{&u, Lt, 51, 0.5},
+		{&u, Ge, 76, 0.25},
+		{&s, Eq, 0, 0.6},
+		{&s, Gt, 0, 0.4},
+		{&s, Eq, -1, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.col.selectivity(tt.op, int64(tt.v)); got < tt.want-0.011 || got > tt.want+0.011 {
+			t.Errorf("%s %s %d: selectivity %.3f, want %.3f", tt.col.Name, tt.op, tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Op is a comparison operator.
+type Op int
+
+const (
+	Eq Op = iota
+	Ne
+	Lt
+	Le
+	Gt
+	Ge
+)
+
+var opNames = [...]string{"=", "<>", "<", "<=", ">", ">="}
+
+func (o Op) String() string { return opNames[o] }
+
+func (o Op) holds(c int) bool {
+	switch o {
+	case Eq:
+		return c == 0
+	case Ne:
+		return c != 0
+	case Lt:
+		return c < 0
+	case Le:
+		return c <= 0
+	case Gt:
+		return c > 0
+	}
+	return c >= 0
+}
+
+// flip gives the operator with its operands swapped: a < b is b > a.
+func (o Op) flip() Op {
+	switch o {
+	case Lt:
+		return Gt
+	case Le:
+		return Ge
+	case Gt:
+		return Lt
+	case Ge:
+		return Le
+	}
+	return o
+}
+
+// ColumnRef names a column, optionally qualified by a table name or alias.
+type ColumnRef struct {
+	Table, Column string
+}
+
+func (c ColumnRef) String() string {
+	if c.Table == "" {
+		return c.Column
+	}
+	return c.Table + "." + c.Column
+}
+
+// Predicate is "Left Op Right" for a join condition or "Left Op Value" for a
+// filter on one table.
+type Predicate struct {
+	Left  ColumnRef
+	Op    Op
+	Right *ColumnRef
+	Value Value
+}
+
+func (p Predicate) String() string {
+	if p.Right != nil {
+		return fmt.Sprintf("%s %s %s", p.Left, p.Op, p.Right)
+	}
+	return fmt.Sprintf("%s %s %s", p.Left, p.Op, formatValue(p.Value))
+}
+
+// TableRef is a FROM entry.
+type TableRef struct {
+	Name, Alias string
+}
+
+// Param is a ? placeholder in a prepared statement, numbered from 0 in the
+// order the placeholders appear.
+type Param int
+
+// Query is a parsed statement: a SELECT, where an empty Select means every
+// column, or an INSERT into the Into table.
+type Query struct {
+	Explain, Analyze bool
+	Select           []ColumnRef
+	From             []TableRef
+	Where            []Predicate
+
+	Into   string
+	Values []Row
+
+	Params int // number of ? placeholders
+}
+
+// Parse reads the supported SQL subset:
+//
+//	[EXPLAIN [ANALYZE]] SELECT * | col, ...
+//	FROM table [[AS] alias] {, table [alias] | JOIN table [alias] ON cond}
+//	[WHERE cond {AND cond}]
+//
+//	INSERT INTO table VALUES (value, ...) {, (value, ...)}
+//
+// where a condition compares a column with a column or a value using =, <>,
+// !=, <, <=, > or >=, and a value is an integer, a quoted string or a ?
+// placeholder.
+func Parse(sql string) (*Query, error) {
+	toks, err := lex(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	q, err := p.query()
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	return q, nil
+}
+
+type token struct {
+	kind byte // 'i' identifier, 'n' number, 's' string, 'p' punctuation
+	text string
+}
+
+func lex(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			toks = append(toks, token{'i', s[i:j]})
+			i = j
+		case unicode.IsDigit(c) || c == '-' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1])):
+			j := i + 1
+			for j < len(s) && unicode.IsDigit(rune(s[j])) {
+				j++
+			}
+			toks = append(toks, token{'n', s[i:j]})
+			i = j
+		case c == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(s); j++ {
+				if s[j] == '\'' {
+					if j+1 < len(s) && s[j+1] == '\'' { // '' escapes a quote
+						b.WriteByte('\'')
+						j++
+						continue
+					}
+					break
+				}
+				b.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("query: unterminated string")
+			}
+			toks = append(toks, token{'s', b.String()})
+			i = j + 1
+		default:
+			for _, op := range []string{"<=", ">=", "<>", "!=", "=", "<", ">", ",", ".", "*", ";", "?", "(", ")"} {
+				if strings.HasPrefix(s[i:], op) {
+					toks = append(toks, token{'p', op})
+					i += len(op)
+					goto next
+				}
+			}
+			return nil, fmt.Errorf("query: unexpected %q", c)
+		next:
+		}
+	}
+	return toks, nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return token{}
+}
+
+func (p *parser) keyword(kw string) bool {
+	if t := p.peek(); t.kind == 'i' && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) punct(s string) bool {
+	if t := p.peek(); t.kind == 'p' && t.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+var reserved = map[string]bool{
+	"select": true, "from": true, "where": true, "and": true, "join": true, "on": true, "as": true,
+	"explain": true, "analyze": true, "insert": true, "into": true, "values": true,
+}
+
+func (p *parser) ident() (string, error) {
+	t := p.peek()
+	if t.kind != 'i' || reserved[strings.ToLower(t.text)] {
+		return "", fmt.Errorf("expected a name, got %q", t.text)
+	}
+	p.pos++
+	return t.text, nil
+}
+
+func (p *parser) query() (*Query, error) {
+	q := &Query{}
+	if p.keyword("insert") {
+		if err := p.insert(q); err != nil {
+			return nil, err
+		}
+		return q, p.end()
+	}
+	if p.keyword("explain") {
+		q.Explain = true
+		q.Analyze = p.keyword("analyze")
+	}
+	if !p.keyword("select") {
+		return nil, fmt.Errorf("expected SELECT")
+	}
+	if !p.punct("*") {
+		for {
+			c, err := p.column()
+			if err != nil {
+				return nil, err
+			}
+			q.Select = append(q.Select, c)
+			if !p.punct(",") {
+				break
+			}
+		}
+	}
+	if !p.keyword("from") {
+		return nil, fmt.Errorf("expected FROM")
+	}
+	ref, err := p.tableRef()
+	if err != nil {
+		return nil, err
+	}
+	q.From = append(q.From, ref)
+	for {
+		join := p.keyword("join")
+		if !join && !p.punct(",") {
+			break
+		}
+		ref, err := p.tableRef()
+		if err != nil {
+			return nil, err
+		}
+		q.From = append(q.From, ref)
+		if !join {
+			continue
+		}
+		if !p.keyword("on") {
+			return nil, fmt.Errorf("expected ON after JOIN %s", ref.Name)
+		}
+		if err := p.conditions(q); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("where") {
+		if err := p.conditions(q); err != nil {
+			return nil, err
+		}
+	}
+	return q, p.end()
+}
+
+func (p *parser) end() error {
+	p.punct(";")
+	if p.pos != len(p.toks) {
+		return fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) insert(q *Query) error {
+	if !p.keyword("into") {
+		return fmt.Errorf("expected INTO")
+	}
+	var err error
+	if q.Into, err = p.ident(); err != nil {
+		return err
+	}
+	if !p.keyword("values") {
+		return fmt.Errorf("expected VALUES")
+	}
+	for {
+		if !p.punct("(") {
+			return fmt.Errorf("expected (")
+		}
+		var row Row
+		for {
+			v, ok, err := p.value(q)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("expected a value, got %q", p.peek().text)
+			}
+			row = append(row, v)
+			if !p.punct(",") {
+				break
+			}
+		}
+		if !p.punct(")") {
+			return fmt.Errorf("expected )")
+		}
+		q.Values = append(q.Values, row)
+		if !p.punct(",") {
+			return nil
+		}
+	}
+}
+
+// value parses a literal or placeholder; ok is false if the next token is
+// neither.
+func (p *parser) value(q *Query) (v Value, ok bool, err error) {
+	switch t := p.peek(); {
+	case t.kind == 'n':
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, false, err
+		}
+		v = n
+	case t.kind == 's':
+		v = t.text
+	case t.kind == 'p' && t.text == "?":
+		v = Param(q.Params)
+		q.Params++
+	default:
+		return nil, false, nil
+	}
+	p.pos++
+	return v, true, nil
+}
+
+func (p *parser) tableRef() (TableRef, error) {
+	name, err := p.ident()
+	if err != nil {
+		return TableRef{}, err
+	}
+	ref := TableRef{Name: name, Alias: name}
+	p.keyword("as")
+	if t := p.peek(); t.kind == 'i' && !reserved[strings.ToLower(t.text)] {
+		ref.Alias = t.text
+		p.pos++
+	}
+	return ref, nil
+}
+
+func (p *parser) column() (ColumnRef, error) {
+	name, err := p.ident()
+	if err != nil {
+		return ColumnRef{}, err
+	}
+	if !p.punct(".") {
+		return ColumnRef{Column: name}, nil
+	}
+	col, err := p.ident()
+	return ColumnRef{Table: name, Column: col}, err
+}
+
+// conditions parses "cond {AND cond}" into q.Where.
+func (p *parser) conditions(q *Query) error {
+	for {
+		left, err := p.column()
+		if err != nil {
+			return err
+		}
+		t := p.peek()
+		op := -1
+		for i, name := range opNames {
+			if t.kind == 'p' && t.text == name {
+				op = i
+			}
+		}
+		if t.kind == 'p' && t.text == "!=" {
+			op = int(Ne)
+		}
+		if op < 0 {
+			return fmt.Errorf("expected a comparison after %s", left)
+		}
+		p.pos++
+		pred := Predicate{Left: left, Op: Op(op)}
+		v, ok, err := p.value(q)
+		switch {
+		case err != nil:
+			return err
+		case ok:
+			pred.Value = v
+		default:
+			right, err := p.column()
+			if err != nil {
+				return err
+			}
+			pred.Right = &right
+		}
+		q.Where = append(q.Where, pred)
+		if !p.keyword("and") {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TableStats are the planner's view of a table, gathered by Analyze.
+type TableStats struct {
+	Rows    int
+	Columns []ColumnStats
+}
+
+// ColumnStats summarize one column with an equi-depth histogram: every
+// bucket holds about the same number of rows, so skewed columns get narrow
+// buckets where the values are dense.
+type ColumnStats struct {
+	Name      string
+	Distinct  int
+	Min, Max  Value
+	Histogram []Bucket
+}
+
+// Bucket covers the values above the previous bucket's Upper, up to and
+// including its own.
+type Bucket struct {
+	Upper    Value
+	Count    int
+	Distinct int
+}
+
+// DefaultBuckets is the histogram resolution used when the planner analyzes
+// a table on its own.
+const DefaultBuckets = 16
+
+// Analyze gathers statistics with the given number of histogram buckets and
+// keeps them for the planner.
+func (t *Table) Analyze(buckets int) *TableStats {
+	buckets = max(1, buckets)
+	st := &TableStats{Rows: len(t.Rows)}
+	for c, name := range t.Columns {
+		values := make([]Value, len(t.Rows))
+		for i, r := range t.Rows {
+			values[i] = r[c]
+		}
+		slices.SortFunc(values, Compare)
+		cs := ColumnStats{Name: name}
+		if len(values) > 0 {
+			cs.Min, cs.Max = values[0], values[len(values)-1]
+		}
+		per := (len(values) + buckets - 1) / max(1, buckets)
+		for i := 0; i < len(values); {
+			// Extend the bucket to the end of a run of equal values, so each
+			// value lives in exactly one bucket.
+			j := min(i+per, len(values))
+			for j < len(values) && Compare(values[j], values[j-1]) == 0 {
+				j++
+			}
+			b := Bucket{Upper: values[j-1], Count: j - i}
+			for k := i; k < j; k++ {
+				if k == i || Compare(values[k], values[k-1]) != 0 {
+					b.Distinct++
+				}
+			}
+			cs.Distinct += b.Distinct
+			cs.Histogram = append(cs.Histogram, b)
+			i = j
+		}
+		st.Columns = append(st.Columns, cs)
+	}
+	t.stats, t.buckets = st, buckets
+	return st
+}
+
+// Stats returns the last Analyze result, analyzing first if the table has
+// never been analyzed.
+func (t *Table) Stats() *TableStats {
+	if t.stats == nil {
+		t.Analyze(DefaultBuckets)
+	}
+	return t.stats
+}
+
+func (s *TableStats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d rows\n", s.Rows)
+	for _, c := range s.Columns {
+		fmt.Fprintf(&b, "  %-10s distinct=%d min=%s max=%s buckets=%d\n",
+			c.Name, c.Distinct, formatValue(c.Min), formatValue(c.Max), len(c.Histogram))
+	}
+	return b.String()
+}
+
+// selectivity estimates the fraction of rows for which "column op v" holds.
+func (c *ColumnStats) selectivity(op Op, v Value) float64 {
+	total := 0
+	for _, b := range c.Histogram {
+		total += b.Count
+	}
+	if total == 0 {
+		return 0
+	}
+	eq := c.equalFraction(v, total)
+	less := c.lessFraction(v, total)
+	var s float64
+	switch op {
+	case Eq:
+		s = eq
+	case Ne:
+		s = 1 - eq
+	case Lt:
+		s = less
+	case Le:
+		s = less + eq
+	case Gt:
+		s = 1 - less - eq
+	case Ge:
+		s = 1 - less
+	}
+	return min(1, max(0, s))
+}
+
+// equalFraction assumes values within a bucket are equally frequent.
+func (c *ColumnStats) equalFraction(v Value, total int) float64 {
+	for _, b := range c.Histogram {
+		if Compare(v, b.Upper) <= 0 {
+			if Compare(v, c.Min) < 0 {
+				return 0
+			}
+			return float64(b.Count) / float64(b.Distinct) / float64(total)
+		}
+	}
+	return 0
+}
+
+// lessFraction estimates the rows strictly below v, interpolating linearly
+// inside the bucket that contains v when the values are integers.
+func (c *ColumnStats) lessFraction(v Value, total int) float64 {
+	if Compare(v, c.Min) <= 0 {
+		return 0
+	}
+	below := 0
+	lower := c.Min
+	for _, b := range c.Histogram {
+		if Compare(v, b.Upper) > 0 {
+			below += b.Count
+			lower = b.Upper
+			continue
+		}
+		// v falls in this bucket, whose values lie in (lower, Upper].
+		frac := 0.5
+		lo, ok1 := lower.(int64)
+		hi, ok2 := b.Upper.(int64)
+		x, ok3 := v.(int64)
+		if ok1 && ok2 && ok3 && hi > lo {
+			frac = float64(x-lo) / float64(hi-lo)
+		}
+		// Values equal to v are not below it.
+		part := frac*float64(b.Count) - float64(b.Count)/float64(b.Distinct)*boolFloat(Compare(v, b.Upper) == 0)
+		return (float64(below) + max(0, part)) / float64(total)
+	}
+	return float64(below) / float64(total)
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,168 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stmt is a parsed statement that can run many times with different
+// parameters. It is planned on every run, so the estimates see the actual
+// parameter values.
+type Stmt struct {
+	cat *Catalog
+	q   *Query
+}
+
+func (c *Catalog) Prepare(sql string) (*Stmt, error) {
+	q, err := Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{cat: c, q: q}, nil
+}
+
+// NumParams is the number of ? placeholders the statement takes.
+func (s *Stmt) NumParams() int { return s.q.Params }
+
+// Cursor streams a statement's result. SELECT rows are produced on demand
+// while the cursor holds the catalog's read lock, so it must be drained or
+// closed. EXPLAIN returns one "QUERY PLAN" row per line; INSERT returns no
+// rows and sets RowsAffected.
+type Cursor struct {
+	Columns      []string
+	RowsAffected int
+	next         func() (Row, bool)
+	release      func()
+}
+
+// Next returns the next row, or false once the result is exhausted.
+func (c *Cursor) Next() (Row, bool) {
+	if c.next == nil {
+		return nil, false
+	}
+	r, ok := c.next()
+	if !ok {
+		c.Close()
+	}
+	return r, ok
+}
+
+// Close releases the cursor early. It is safe to call more than once.
+func (c *Cursor) Close() {
+	c.next = nil
+	if c.release != nil {
+		c.release()
+		c.release = nil
+	}
+}
+
+// Query runs the statement with args bound to its placeholders in order.
+// Plain ints are accepted as int64.
+func (s *Stmt) Query(args ...Value) (*Cursor, error) {
+	q, err := s.bind(args)
+	if err != nil {
+		return nil, err
+	}
+	if q.Into != "" {
+		return s.cat.insert(q)
+	}
+	s.cat.mu.RLock()
+	root, names, err := s.cat.plan(q)
+	if err != nil {
+		s.cat.mu.RUnlock()
+		return nil, err
+	}
+	if q.Explain {
+		defer s.cat.mu.RUnlock()
+		if q.Analyze {
+			root.open()
+			for _, ok := root.next(); ok; _, ok = root.next() {
+			}
+		}
+		lines := strings.Split(strings.TrimSuffix(explain(root, q.Analyze), "\n"), "\n")
+		return &Cursor{Columns: []string{"QUERY PLAN"}, next: func() (Row, bool) {
+			if len(lines) == 0 {
+				return nil, false
+			}
+			r := Row{lines[0]}
+			lines = lines[1:]
+			return r, true
+		}}, nil
+	}
+	root.open()
+	return &Cursor{Columns: names, next: root.next, release: s.cat.mu.RUnlock}, nil
+}
+
+// bind returns a copy of the statement with the placeholders filled in.
+func (s *Stmt) bind(args []Value) (*Query, error) {
+	if len(args) != s.q.Params {
+		return nil, fmt.Errorf("query: statement takes %d parameters, got %d", s.q.Params, len(args))
+	}
+	vals := make([]Value, len(args))
+	for i, a := range args {
+		switch a := a.(type) {
+		case int:
+			vals[i] = int64(a)
+		case int64, string:
+			vals[i] = a
+		default:
+			return nil, fmt.Errorf("query: parameter %d: unsupported value %T", i+1, a)
+		}
+	}
+	sub := func(v Value) Value {
+		if p, ok := v.(Param); ok {
+			return vals[p]
+		}
+		return v
+	}
+	q := *s.q
+	q.Where = make([]Predicate, len(s.q.Where))
+	for i, w := range s.q.Where {
+		w.Value = sub(w.Value)
+		q.Where[i] = w
+	}
+	q.Values = make([]Row, len(s.q.Values))
+	for i, r := range s.q.Values {
+		q.Values[i] = make(Row, len(r))
+		for j, v := range r {
+			q.Values[i][j] = sub(v)
+		}
+	}
+	return &q, nil
+}
+
+// insert adds every row or none. Statistics are refreshed once the table has
+// grown by a tenth since they were gathered.
+func (c *Catalog) insert(q *Query) (*Cursor, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.tables[q.Into]
+	if !ok {
+		return nil, fmt.Errorf("query: no table %q", q.Into)
+	}
+	var like Row // every column keeps the type of its first value
+	if len(t.Rows) > 0 {
+		like = t.Rows[0]
+	} else if len(q.Values) > 0 {
+		like = q.Values[0]
+	}
+	for _, r := range q.Values {
+		if len(r) != len(t.Columns) {
+			return nil, fmt.Errorf("query: %s has %d columns, got %d values", t.Name, len(t.Columns), len(r))
+		}
+		for i, v := range r {
+			if !sameType(like[i], v) {
+				return nil, fmt.Errorf("query: %s.%s: value %s has the wrong type", t.Name, t.Columns[i], formatValue(v))
+			}
+		}
+	}
+	for _, r := range q.Values {
+		if err := t.Insert(r...); err != nil {
+			return nil, err
+		}
+	}
+	if t.stats != nil && len(t.Rows) > t.stats.Rows+t.stats.Rows/10 {
+		t.Analyze(t.buckets)
+	}
+	return &Cursor{RowsAffected: len(q.Values)}, nil
+}
@@ -0,0 +1,185 @@
+// Package query is a small in-memory relational engine: tables with sorted
+// indexes, column statistics, a SQL subset, a cost-based planner that picks
+// access paths, join methods and join order, and EXPLAIN ANALYZE output that
+// compares the planner's row estimates with what execution produced.
+package query
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Value is a column value: an int64 or a string.
+type Value = any
+
+// Compare orders values; integers sort before strings.
+func Compare(a, b Value) int {
+	switch a := a.(type) {
+	case int64:
+		if b, ok := b.(int64); ok {
+			return cmp.Compare(a, b)
+		}
+		return -1
+	case string:
+		if b, ok := b.(string); ok {
+			return cmp.Compare(a, b)
+		}
+		return 1
+	}
+	panic(fmt.Sprintf("query: unsupported value %T", a))
+}
+
+func formatValue(v Value) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case Param:
+		return "?"
+	}
+	return fmt.Sprint(v)
+}
+
+// Row is one tuple; its layout is given by the table or plan node schema.
+type Row []Value
+
+// Table is a heap of rows plus optional single-column indexes.
+type Table struct {
+	Name    string
+	Columns []string
+	Rows    []Row
+	indexes map[int]*index
+	stats   *TableStats
+	buckets int // histogram resolution of the last Analyze
+}
+
+func NewTable(name string, columns ...string) *Table {
+	return &Table{Name: name, Columns: columns, indexes: map[int]*index{}}
+}
+
+// Insert appends a row. Plain ints are stored as int64. Indexes are kept up
+// to date; statistics go stale until the next Analyze.
+func (t *Table) Insert(values ...Value) error {
+	if len(values) != len(t.Columns) {
+		return fmt.Errorf("query: %s has %d columns, got %d values", t.Name, len(t.Columns), len(values))
+	}
+	row := make(Row, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case int:
+			row[i] = int64(v)
+		case int64, string:
+			row[i] = v
+		default:
+			return fmt.Errorf("query: %s.%s: unsupported value %T", t.Name, t.Columns[i], v)
+		}
+	}
+	t.Rows = append(t.Rows, row)
+	for _, ix := range t.indexes {
+		ix.add(len(t.Rows) - 1)
+	}
+	return nil
+}
+
+func (t *Table) column(name string) int { return slices.Index(t.Columns, name) }
+
+// CreateIndex adds a sorted index on column, making index scans available to
+// the planner for predicates on it.
+func (t *Table) CreateIndex(column string) error {
+	c := t.column(column)
+	if c < 0 {
+		return fmt.Errorf("query: %s has no column %q", t.Name, column)
+	}
+	ix := &index{table: t, column: c}
+	for i := range t.Rows {
+		ix.order = append(ix.order, i)
+	}
+	slices.SortStableFunc(ix.order, func(a, b int) int { return Compare(t.Rows[a][c], t.Rows[b][c]) })
+	t.indexes[c] = ix
+	return nil
+}
+
+// index lists row numbers ordered by one column.
+type index struct {
+	table  *Table
+	column int
+	order  []int
+}
+
+// add files a new row after any existing rows with the same value.
+func (ix *index) add(row int) {
+	rows, v := ix.table.Rows, ix.table.Rows[row][ix.column]
+	i, _ := slices.BinarySearchFunc(ix.order, v, func(r int, v Value) int {
+		if c := Compare(rows[r][ix.column], v); c != 0 {
+			return c
+		}
+		return -1
+	})
+	ix.order = slices.Insert(ix.order, i, row)
+}
+
+// span returns the positions in order whose value lies within the bounds.
+func (ix *index) span(lo, hi bound) (int, int) {
+	order, rows := ix.order, ix.table.Rows
+	i, j := 0, len(order)
+	if lo.set {
+		i, _ = slices.BinarySearchFunc(order, lo, func(r int, b bound) int {
+			c := Compare(rows[r][ix.column], b.value)
+			if c == 0 && !b.inclusive {
+				return -1 // exclusive: equal values stay below the span
+			}
+			return c
+		})
+	}
+	if hi.set {
+		j, _ = slices.BinarySearchFunc(order, hi, func(r int, b bound) int {
+			c := Compare(rows[r][ix.column], b.value)
+			if c == 0 && b.inclusive {
+				return -1 // inclusive: equal values stay inside the span
+			}
+			return c
+		})
+	}
+	return i, max(i, j)
+}
+
+// bound is one end of an index range.
+type bound struct {
+	set       bool
+	value     Value
+	inclusive bool
+}
+
+// Catalog holds the tables a query can name. Statements run through it are
+// safe for concurrent use: SELECTs share a read lock for as long as their
+// cursor is open and INSERTs take the write lock. Tables must not be changed
+// directly once added.
+type Catalog struct {
+	mu     sync.RWMutex
+	tables map[string]*Table
+}
+
+func NewCatalog(tables ...*Table) *Catalog {
+	c := &Catalog{tables: map[string]*Table{}}
+	for _, t := range tables {
+		c.Add(t)
+	}
+	return c
+}
+
+// Add registers t, analyzing it if it has no statistics yet.
+func (c *Catalog) Add(t *Table) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t.Stats()
+	c.tables[t.Name] = t
+}
+
+func (c *Catalog) Table(name string) (*Table, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	t, ok := c.tables[name]
+	return t, ok
+}
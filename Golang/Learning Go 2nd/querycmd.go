@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/query"
+)
+
+// candyShop builds a catalog of generated kids, the candy they can buy and
+// their purchases. Purchases skew towards the cheap candy and older kids, so
+// the histograms have something to capture.
+func candyShop(n int, rng *rand.Rand) (*query.Catalog, error) {
+	kids := query.NewTable("kids", "id", "age", "candies")
+	for i, k := range generateKids(n, rng) {
+		if err := kids.Insert(i, k.Age, k.Candies); err != nil {
+			return nil, err
+		}
+	}
+	names := []string{"lollipop", "gum", "toffee", "licorice", "caramel", "fudge", "truffle", "nougat", "marzipan", "praline"}
+	candy := query.NewTable("candy", "name", "price")
+	for i, name := range names {
+		if err := candy.Insert(name, 5+i*i*3); err != nil {
+			return nil, err
+		}
+	}
+	purchases := query.NewTable("purchases", "id", "kid_id", "candy", "qty")
+	for i := range 5 * n {
+		kid := rng.IntN(n)
+		if rng.IntN(2) == 0 {
+			kid = n - 1 - rng.IntN(max(1, n/5))
+		}
+		c := min(len(names)-1, int(rng.ExpFloat64()*2))
+		if err := purchases.Insert(i, kid, names[c], 1+rng.IntN(5)); err != nil {
+			return nil, err
+		}
+	}
+	for _, ix := range []struct {
+		t   *query.Table
+		col string
+	}{{kids, "id"}, {kids, "age"}, {purchases, "kid_id"}, {candy, "name"}} {
+		if err := ix.t.CreateIndex(ix.col); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range []*query.Table{kids, candy, purchases} {
+		t.Analyze(query.DefaultBuckets)
+	}
+	return query.NewCatalog(kids, candy, purchases), nil
+}
+
+var demoQueries = []string{
+	"EXPLAIN ANALYZE SELECT k.id, k.age FROM kids k WHERE k.age >= 11",
+	"EXPLAIN ANALYZE SELECT k.id, p.candy FROM kids k JOIN purchases p ON k.id = p.kid_id WHERE k.age = 4",
+	"EXPLAIN ANALYZE SELECT k.id, c.price FROM purchases p, kids k, candy c WHERE p.kid_id = k.id AND p.candy = c.name AND c.price > 100 AND k.candies > 20",
+	"EXPLAIN ANALYZE SELECT * FROM kids a, kids b WHERE a.candies = b.candies AND a.age < b.age AND a.id < 50",
+}
+
+// runQuery runs SQL against the candy shop tables. Without arguments it
+// shows EXPLAIN ANALYZE for a few joins.
+func runQuery(ctx *cli.Context) error {
+	fs := ctx.Flags
+	n := fs.Int("kids", 2000, "generated kids")
+	seed := fs.Uint64("seed", 1, "random seed")
+	stats := fs.Bool("stats", false, "print table statistics first")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return fmt.Errorf("query: need at least one kid")
+	}
+	cat, err := candyShop(*n, rand.New(rand.NewPCG(*seed, *seed)))
+	if err != nil {
+		return err
+	}
+	if *stats {
+		for _, name := range []string{"kids", "candy", "purchases"} {
+			t, _ := cat.Table(name)
+			fmt.Printf("%s: %s", name, t.Stats())
+		}
+		fmt.Println()
+	}
+	queries := demoQueries
+	if fs.NArg() > 0 {
+		queries = []string{strings.Join(fs.Args(), " ")}
+	}
+	for i, sql := range queries {
+		if i > 0 {
+			fmt.Println()
+		}
+		res, err := cat.Exec(sql)
+		if err != nil {
+			return err
+		}
+		fmt.Println(sql)
+		fmt.Print(res)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Package rangeq answers range queries over arrays: Fenwick trees for prefix
+// sums, segment trees with lazy range updates over any monoid, and sparse
+// tables for O(1) idempotent queries such as range minimum. All ranges are
+// half-open, [l, r), like Go slice expressions.
+package rangeq
+
+// Number is any type Fenwick trees and the built-in monoids can add.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Fenwick is a binary indexed tree: point updates and prefix sums in
+// O(log n).
+type Fenwick[T Number] struct {
+	tree []T // 1-based; tree[i] sums the lowbit(i) elements ending at i
+}
+
+func NewFenwick[T Number](n int) *Fenwick[T] {
+	return &Fenwick[T]{tree: make([]T, n+1)}
+}
+
+// FenwickFrom builds a tree over values in O(n).
+func FenwickFrom[T Number](values []T) *Fenwick[T] {
+	f := NewFenwick[T](len(values))
+	copy(f.tree[1:], values)
+	for i := 1; i < len(f.tree); i++ {
+		if parent := i + i&-i; parent < len(f.tree) {
+			f.tree[parent] += f.tree[i]
+		}
+	}
+	return f
+}
+
+func (f *Fenwick[T]) Len() int { return len(f.tree) - 1 }
+
+// Add adds delta to element i.
+func (f *Fenwick[T]) Add(i int, delta T) {
+	for i++; i < len(f.tree); i += i & -i {
+		f.tree[i] += delta
+	}
+}
+
+// PrefixSum returns the sum of elements [0, i).
+func (f *Fenwick[T]) PrefixSum(i int) T {
+	var s T
+	for ; i > 0; i -= i & -i {
+		s += f.tree[i]
+	}
+	return s
+}
+
+// RangeSum returns the sum of elements [l, r).
+func (f *Fenwick[T]) RangeSum(l, r int) T { return f.PrefixSum(r) - f.PrefixSum(l) }
+
+// Get returns element i.
+func (f *Fenwick[T]) Get(i int) T { return f.RangeSum(i, i+1) }
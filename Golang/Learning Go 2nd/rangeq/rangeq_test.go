@@ -0,0 +1,133 @@
+package rangeq
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestFenwick(t *testing.T) {
+	f := FenwickFrom([]int{3, 1, 4, 1, 5, 9, 2, 6})
+	tests := []struct{ l, r, want int }{
+		{0, 0, 0},
+		{0, 1, 3},
+		{0, 8, 31},
+		{2, 5, 10},
+		{7, 8, 6},
+	}
+	for _, tt := range tests {
+		if got := f.RangeSum(tt.l, tt.r); got != tt.want {
+			t.Errorf("RangeSum(%d, %d) = %d, want %d", tt.l, tt.r, got, tt.want)
+		}
+	}
+	f.Add(3, 10)
+	if got := f.Get(3); got != 11 {
+		t.Errorf("Get(3) after Add(3, 10) = %d, want 11", got)
+	}
+}
+
+func TestSparseTableGCD(t *testing.T) {
+	gcd := func(a, b int) int {
+		for b != 0 {
+			a, b = b, a%b
+		}
+		return a
+	}
+	s := NewSparseTable([]int{12, 18, 24, 7, 14, 28}, gcd)
+	tests := []struct{ l, r, want int }{
+		{0, 1, 12},
+		{0, 3, 6},
+		{3, 6, 7},
+		{4, 6, 14},
+		{0, 6, 1},
+	}
+	for _, tt := range tests {
+		if got := s.Query(tt.l, tt.r); got != tt.want {
+			t.Errorf("Query(%d, %d) = %d, want %d", tt.l, tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestQueryPanics(t *testing.T) {
+	tree := NewSegmentTree([]int{1, 2, 3}, Sum[int](), AddToSum[int]())
+	table := NewMinTable([]int{1, 2, 3})
+	for name, f := range map[string]func(){
+		"segment past the end": func() { tree.Query(0, 4) },
+		"segment reversed":     func() { tree.Update(2, 1, 1) },
+		"sparse empty":         func() { table.Query(1, 1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: no panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+// TestAgainstNaive runs random updates and queries on every structure and a
+// plain slice side by side, answering each query on the slice with a loop.
+func TestAgainstNaive(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 7))
+	for _, n := range []int{1, 2, 7, 64, 100} {
+		values := make([]int, n)
+		for i := range values {
+			values[i] = rng.IntN(100)
+		}
+		fenwick := FenwickFrom(values)
+		sums := NewSegmentTree(values, Sum[int](), AddToSum[int]())
+		mins := NewSegmentTree(values, Min(math.MaxInt), AddToExtremum[int]())
+		maxes := NewSegmentTree(values, Max(math.MinInt), AddToExtremum[int]())
+		assigned := NewSegmentTree(values, Sum[int](), AssignToSum[int]())
+		naive := append([]int(nil), values...)
+		naiveAssigned := append([]int(nil), values...)
+		sparse := NewMinTable(values)
+
+		for op := range 3000 {
+			l := rng.IntN(n)
+			r := l + 1 + rng.IntN(n-l)
+			switch rng.IntN(3) {
+			case 0: // range add
+				d := rng.IntN(21) - 10
+				for i := l; i < r; i++ {
+					naive[i] += d
+					fenwick.Add(i, d)
+				}
+				sums.Update(l, r, d)
+				mins.Update(l, r, d)
+				maxes.Update(l, r, d)
+			case 1: // range assign
+				v := rng.IntN(100)
+				for i := l; i < r; i++ {
+					naiveAssigned[i] = v
+				}
+				assigned.Update(l, r, Assignment[int]{Value: v, Set: true})
+			case 2: // queries
+				sum, low, high, assignedSum, staticLow := 0, math.MaxInt, math.MinInt, 0, math.MaxInt
+				for i := l; i < r; i++ {
+					sum += naive[i]
+					low, high = min(low, naive[i]), max(high, naive[i])
+					assignedSum += naiveAssigned[i]
+					staticLow = min(staticLow, values[i])
+				}
+				for _, c := range []struct {
+					name      string
+					got, want int
+				}{
+					{"fenwick sum", fenwick.RangeSum(l, r), sum},
+					{"segment sum", sums.Query(l, r), sum},
+					{"segment min", mins.Query(l, r), low},
+					{"segment max", maxes.Query(l, r), high},
+					{"segment assigned sum", assigned.Query(l, r), assignedSum},
+					{"sparse min", sparse.Query(l, r), staticLow},
+				} {
+					if c.got != c.want {
+						t.Fatalf("n=%d op %d: %s [%d,%d) = %d, want %d", n, op, c.name, l, r, c.got, c.want)
+					}
+				}
+			}
+		}
+	}
+}
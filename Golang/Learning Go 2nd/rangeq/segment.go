@@ -0,0 +1,188 @@
+package rangeq
+
+import "fmt"
+
+// Monoid is an associative operation with an identity element.
+type Monoid[T any] struct {
+	Identity T
+	Op       func(a, b T) T
+}
+
+// Action describes lazy range updates of type F on values of type T. Apply
+// applies an update to the combined value of a segment of the given length;
+// Compose merges a newer update into an older pending one.
+type Action[T, F any] struct {
+	Identity F
+	Apply    func(f F, v T, length int) T
+	Compose  func(newer, older F) F
+}
+
+// SegmentTree supports range queries over a monoid and lazy range updates,
+// both in O(log n).
+type SegmentTree[T, F any] struct {
+	n      int
+	m      Monoid[T]
+	act    Action[T, F]
+	values []T
+	lazy   []F
+	dirty  []bool
+}
+
+func NewSegmentTree[T, F any](values []T, m Monoid[T], act Action[T, F]) *SegmentTree[T, F] {
+	n := len(values)
+	t := &SegmentTree[T, F]{
+		n:      n,
+		m:      m,
+		act:    act,
+		values: make([]T, 4*max(1, n)),
+		lazy:   make([]F, 4*max(1, n)),
+		dirty:  make([]bool, 4*max(1, n)),
+	}
+	if n > 0 {
+		t.build(1, 0, n, values)
+	}
+	return t
+}
+
+func (t *SegmentTree[T, F]) build(node, lo, hi int, values []T) {
+	if hi-lo == 1 {
+		t.values[node] = values[lo]
+		return
+	}
+	mid := (lo + hi) / 2
+	t.build(2*node, lo, mid, values)
+	t.build(2*node+1, mid, hi, values)
+	t.values[node] = t.m.Op(t.values[2*node], t.values[2*node+1])
+}
+
+func (t *SegmentTree[T, F]) Len() int { return t.n }
+
+func (t *SegmentTree[T, F]) apply(node, length int, f F) {
+	t.values[node] = t.act.Apply(f, t.values[node], length)
+	if t.dirty[node] {
+		t.lazy[node] = t.act.Compose(f, t.lazy[node])
+	} else {
+		t.lazy[node], t.dirty[node] = f, true
+	}
+}
+
+func (t *SegmentTree[T, F]) push(node, lo, mid, hi int) {
+	if !t.dirty[node] {
+		return
+	}
+	t.apply(2*node, mid-lo, t.lazy[node])
+	t.apply(2*node+1, hi-mid, t.lazy[node])
+	t.lazy[node], t.dirty[node] = t.act.Identity, false
+}
+
+// Query combines elements [l, r).
+func (t *SegmentTree[T, F]) Query(l, r int) T {
+	t.check(l, r)
+	if l == r {
+		return t.m.Identity
+	}
+	return t.query(1, 0, t.n, l, r)
+}
+
+func (t *SegmentTree[T, F]) query(node, lo, hi, l, r int) T {
+	if l <= lo && hi <= r {
+		return t.values[node]
+	}
+	mid := (lo + hi) / 2
+	t.push(node, lo, mid, hi)
+	res := t.m.Identity
+	if l < mid {
+		res = t.query(2*node, lo, mid, l, r)
+	}
+	if r > mid {
+		res = t.m.Op(res, t.query(2*node+1, mid, hi, l, r))
+	}
+	return res
+}
+
+// Update applies f to every element in [l, r).
+func (t *SegmentTree[T, F]) Update(l, r int, f F) {
+	t.check(l, r)
+	if l < r {
+		t.update(1, 0, t.n, l, r, f)
+	}
+}
+
+func (t *SegmentTree[T, F]) update(node, lo, hi, l, r int, f F) {
+	if l <= lo && hi <= r {
+		t.apply(node, hi-lo, f)
+		return
+	}
+	mid := (lo + hi) / 2
+	t.push(node, lo, mid, hi)
+	if l < mid {
+		t.update(2*node, lo, mid, l, r, f)
+	}
+	if r > mid {
+		t.update(2*node+1, mid, hi, l, r, f)
+	}
+	t.values[node] = t.m.Op(t.values[2*node], t.values[2*node+1])
+}
+
+func (t *SegmentTree[T, F]) check(l, r int) {
+	if l < 0 || r > t.n || l > r {
+		panic(fmt.Sprintf("rangeq: range [%d, %d) out of bounds for length %d", l, r, t.n))
+	}
+}
+
+// Sum is the addition monoid.
+func Sum[T Number]() Monoid[T] {
+	return Monoid[T]{Op: func(a, b T) T { return a + b }}
+}
+
+// Min and Max use the given identity, typically the largest or smallest
+// value of T.
+func Min[T Number](identity T) Monoid[T] {
+	return Monoid[T]{Identity: identity, Op: func(a, b T) T { return min(a, b) }}
+}
+
+func Max[T Number](identity T) Monoid[T] {
+	return Monoid[T]{Identity: identity, Op: func(a, b T) T { return max(a, b) }}
+}
+
+// AddToSum adds a constant to every element of a range of a Sum tree.
+func AddToSum[T Number]() Action[T, T] {
+	return Action[T, T]{
+		Apply:   func(f, v T, length int) T { return v + f*T(length) },
+		Compose: func(newer, older T) T { return newer + older },
+	}
+}
+
+// AddToExtremum adds a constant to every element of a range of a Min or Max
+// tree, which shifts the extremum by the same constant.
+func AddToExtremum[T Number]() Action[T, T] {
+	return Action[T, T]{
+		Apply:   func(f, v T, _ int) T { return v + f },
+		Compose: func(newer, older T) T { return newer + older },
+	}
+}
+
+// Assignment is a pending "set every element to Value" update; Set is false
+// for the identity update.
+type Assignment[T any] struct {
+	Value T
+	Set   bool
+}
+
+// AssignToSum overwrites every element of a range of a Sum tree.
+func AssignToSum[T Number]() Action[T, Assignment[T]] {
+	return Action[T, Assignment[T]]{
+		Apply: func(f Assignment[T], v T, length int) T {
+			if !f.Set {
+				return v
+			}
+			return f.Value * T(length)
+		},
+		Compose: func(newer, older Assignment[T]) Assignment[T] {
+			if newer.Set {
+				return newer
+			}
+			return older
+		},
+	}
+}
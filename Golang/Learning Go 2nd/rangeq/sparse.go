@@ -0,0 +1,45 @@
+package rangeq
+
+import (
+	"cmp"
+	"fmt"
+	"math/bits"
+)
+
+// SparseTable answers queries for an idempotent operation (op(x, x) == x,
+// such as min, max or gcd) in O(1) after O(n log n) preprocessing. The
+// underlying values cannot change.
+type SparseTable[T any] struct {
+	op    func(a, b T) T
+	table [][]T // table[k][i] combines elements [i, i+2^k)
+}
+
+func NewSparseTable[T any](values []T, op func(a, b T) T) *SparseTable[T] {
+	s := &SparseTable[T]{op: op, table: [][]T{append([]T(nil), values...)}}
+	for k := 1; 1<<k <= len(values); k++ {
+		prev := s.table[k-1]
+		row := make([]T, len(values)-1<<k+1)
+		for i := range row {
+			row[i] = op(prev[i], prev[i+1<<(k-1)])
+		}
+		s.table = append(s.table, row)
+	}
+	return s
+}
+
+// NewMinTable builds a sparse table for range minimum queries.
+func NewMinTable[T cmp.Ordered](values []T) *SparseTable[T] {
+	return NewSparseTable(values, func(a, b T) T { return min(a, b) })
+}
+
+func (s *SparseTable[T]) Len() int { return len(s.table[0]) }
+
+// Query combines elements [l, r) from two overlapping power-of-two blocks.
+// The range must not be empty.
+func (s *SparseTable[T]) Query(l, r int) T {
+	if l < 0 || r > s.Len() || l >= r {
+		panic(fmt.Sprintf("rangeq: range [%d, %d) invalid for length %d", l, r, s.Len()))
+	}
+	k := bits.Len(uint(r-l)) - 1
+	return s.op(s.table[k][l], s.table[k][r-1<<k])
+}
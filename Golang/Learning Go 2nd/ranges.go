@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/rangeq"
+)
+
+func runKidsRanges(ctx *cli.Context) error {
+	fs := ctx.Flags
+	generate := fs.Int("generate", 20, "number of synthetic kids in line")
+	seed := fs.Uint64("seed", 1, "random seed")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	if *generate < 2 {
+		return fmt.Errorf("kids ranges: need at least 2 kids, got %d", *generate)
+	}
+	rng := rand.New(rand.NewPCG(*seed, *seed))
+	kids := generateKids(*generate, rng)
+	candies := make([]int, len(kids))
+	for i, k := range kids {
+		candies[i] = k.Candies
+	}
+
+	// Bulk updates: every kid in a range gets the same number of extra candies.
+	tree := rangeq.NewSegmentTree(candies, rangeq.Sum[int](), rangeq.AddToSum[int]())
+	n := len(kids)
+	fmt.Printf("candies in line: %v\n", candies)
+	fmt.Printf("total candies for kids 0..%d: %d\n", n-1, tree.Query(0, n))
+	tree.Update(n/4, n/2, 5)
+	fmt.Printf("kids %d..%d get 5 more candies each\n", n/4, n/2-1)
+	tree.Update(0, n, 1)
+	fmt.Printf("every kid gets 1 more candy\n")
+	fmt.Printf("total candies for kids %d..%d: %d\n", n/4, n-1, tree.Query(n/4, n))
+	fmt.Printf("total candies for kids 0..%d: %d\n", n-1, tree.Query(0, n))
+	fewest := rangeq.NewMinTable(candies)
+	fmt.Printf("fewest candies before the updates among kids 0..%d: %d\n", n/2-1, fewest.Query(0, n/2))
+	return nil
+}
@@ -0,0 +1,125 @@
+package repl
+
+import (
+	"fmt"
+	"time"
+
+	"learning-go.adcon.dev/options"
+)
+
+// HeartbeatInterval is how often an idle primary tells replicas its last
+// sequence number by default, so they can report lag while nothing is being
+// written.
+const HeartbeatInterval = 100 * time.Millisecond
+
+// PrimaryConfig tunes a Primary. Zero fields take the defaults in
+// DefaultPrimaryConfig.
+type PrimaryConfig struct {
+	Retain       int           `json:"retain"`        // newest writes kept for catching up; replicas further behind get a snapshot
+	Heartbeat    time.Duration `json:"heartbeat"`     // how often an idle primary announces its last sequence number
+	WriteTimeout time.Duration `json:"write_timeout"` // a replica that accepts no data for this long is disconnected
+}
+
+// ReplicaConfig tunes a Replica. Zero fields take the defaults in
+// DefaultReplicaConfig.
+type ReplicaConfig struct {
+	MinBackoff  time.Duration `json:"min_backoff"`  // first wait before reconnecting
+	MaxBackoff  time.Duration `json:"max_backoff"`  // the wait doubles up to this
+	DialTimeout time.Duration `json:"dial_timeout"` // how long one connection attempt may take
+}
+
+// Environment variable prefixes for the two configs, as in
+// REPL_PRIMARY_RETAIN and REPL_REPLICA_MAX_BACKOFF.
+const (
+	PrimaryEnvPrefix = "REPL_PRIMARY"
+	ReplicaEnvPrefix = "REPL_REPLICA"
+)
+
+// PrimaryOption and ReplicaOption set one field of the matching config; see
+// options.Apply.
+type (
+	PrimaryOption = options.Option[PrimaryConfig]
+	ReplicaOption = options.Option[ReplicaConfig]
+)
+
+func WithRetain(n int) PrimaryOption              { return options.New[PrimaryConfig]("retain", n) }
+func WithHeartbeat(d time.Duration) PrimaryOption { return options.New[PrimaryConfig]("heartbeat", d) }
+func WithWriteTimeout(d time.Duration) PrimaryOption {
+	return options.New[PrimaryConfig]("write_timeout", d)
+}
+
+func WithMinBackoff(d time.Duration) ReplicaOption {
+	return options.New[ReplicaConfig]("min_backoff", d)
+}
+func WithMaxBackoff(d time.Duration) ReplicaOption {
+	return options.New[ReplicaConfig]("max_backoff", d)
+}
+func WithDialTimeout(d time.Duration) ReplicaOption {
+	return options.New[ReplicaConfig]("dial_timeout", d)
+}
+
+// DefaultPrimaryConfig returns the settings used for zero PrimaryConfig
+// fields.
+func DefaultPrimaryConfig() PrimaryConfig {
+	return PrimaryConfig{Retain: 1000, Heartbeat: HeartbeatInterval, WriteTimeout: 10 * time.Second}
+}
+
+// DefaultReplicaConfig returns the settings used for zero ReplicaConfig
+// fields.
+func DefaultReplicaConfig() ReplicaConfig {
+	return ReplicaConfig{MinBackoff: 20 * time.Millisecond, MaxBackoff: time.Second, DialTimeout: time.Second}
+}
+
+// Resolve applies opts over c, fills in defaults and validates the result,
+// as NewPrimary does.
+func (c PrimaryConfig) Resolve(opts ...PrimaryOption) (PrimaryConfig, error) {
+	c, err := options.Apply(c, opts...)
+	if err != nil {
+		return c, fmt.Errorf("repl: %w", err)
+	}
+	d := DefaultPrimaryConfig()
+	if c.Retain == 0 {
+		c.Retain = d.Retain
+	}
+	if c.Heartbeat == 0 {
+		c.Heartbeat = d.Heartbeat
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = d.WriteTimeout
+	}
+	switch {
+	case c.Retain < 0:
+		return c, fmt.Errorf("repl: retain %d must be positive", c.Retain)
+	case c.Heartbeat < 0:
+		return c, fmt.Errorf("repl: heartbeat %v must be positive", c.Heartbeat)
+	case c.WriteTimeout < 0:
+		return c, fmt.Errorf("repl: write timeout %v must be positive", c.WriteTimeout)
+	}
+	return c, nil
+}
+
+// Resolve applies opts over c, fills in defaults and validates the result,
+// as StartReplica does.
+func (c ReplicaConfig) Resolve(opts ...ReplicaOption) (ReplicaConfig, error) {
+	c, err := options.Apply(c, opts...)
+	if err != nil {
+		return c, fmt.Errorf("repl: %w", err)
+	}
+	d := DefaultReplicaConfig()
+	if c.MinBackoff == 0 {
+		c.MinBackoff = d.MinBackoff
+	}
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = max(d.MaxBackoff, c.MinBackoff)
+	}
+	if c.DialTimeout == 0 {
+		c.DialTimeout = d.DialTimeout
+	}
+	switch {
+	case c.MinBackoff < 0 || c.MaxBackoff < 0 || c.DialTimeout < 0:
+		return c, fmt.Errorf("repl: durations must be positive")
+	case c.MinBackoff > c.MaxBackoff:
+		return c, fmt.Errorf("repl: min_backoff %v exceeds max_backoff %v", c.MinBackoff, c.MaxBackoff)
+	}
+	return c, nil
+}
@@ -0,0 +1,341 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"learning-go.adcon.dev/lsm"
+)
+
+var ErrClosed = errors.New("repl: closed")
+
+// Primary accepts writes on its DB and ships them to replicas.
+type Primary struct {
+	db           *lsm.DB
+	retain       int
+	heartbeat    time.Duration
+	writeTimeout time.Duration
+
+	mu       sync.Mutex
+	log      []lsm.Record  // recent writes, oldest first
+	first    uint64        // sequence number of log[0]
+	last     uint64        // newest sequence number
+	base     uint64        // sequence number when this primary started
+	notify   chan struct{} // closed and replaced on every write
+	replicas map[string]*peer
+	lns      map[net.Listener]bool
+	conns    map[net.Conn]bool
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// peer is the primary's view of one replica.
+type peer struct {
+	addr      string
+	conn      net.Conn // newest connection, nil when disconnected
+	acked     uint64
+	sent      uint64 // newest record this primary shipped to it
+	snapshots int
+}
+
+// ReplicaStatus is a replica as seen from the primary.
+type ReplicaStatus struct {
+	Name      string
+	Addr      string
+	Connected bool
+	Acked     uint64 // last sequence number the replica confirmed
+	Lag       uint64 // writes it has not confirmed
+	Snapshots int    // full snapshots sent to it
+}
+
+// NewPrimary starts logging db's writes for replicas, keeping at least the
+// newest cfg.Retain of them; a replica further behind receives a snapshot.
+// Opts are applied over cfg.
+func NewPrimary(db *lsm.DB, cfg PrimaryConfig, opts ...PrimaryOption) (*Primary, error) {
+	cfg, err := cfg.Resolve(opts...)
+	if err != nil {
+		return nil, err
+	}
+	last := db.LastSeq()
+	p := &Primary{
+		db:           db,
+		retain:       cfg.Retain,
+		heartbeat:    cfg.Heartbeat,
+		writeTimeout: cfg.WriteTimeout,
+		first:        last + 1,
+		last:         last,
+		base:         last,
+		notify:       make(chan struct{}),
+		replicas:     map[string]*peer{},
+		lns:          map[net.Listener]bool{},
+		conns:        map[net.Conn]bool{},
+	}
+	db.Watch(p.record)
+	return p, nil
+}
+
+// DB is the primary's database; write to it directly.
+func (p *Primary) DB() *lsm.DB { return p.db }
+
+// record runs under the DB's write lock for every write.
+func (p *Primary) record(r lsm.Record) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.log = append(p.log, r)
+	if len(p.log) > 2*p.retain {
+		p.log = slices.Clone(p.log[len(p.log)-p.retain:])
+	}
+	p.first, p.last = p.log[0].Seq, r.Seq
+	close(p.notify)
+	p.notify = make(chan struct{})
+}
+
+// LastSeq is the newest write's sequence number.
+func (p *Primary) LastSeq() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.last
+}
+
+// Status lists every replica that has connected, by name.
+func (p *Primary) Status() []ReplicaStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []ReplicaStatus
+	for name, r := range p.replicas {
+		s := ReplicaStatus{Name: name, Addr: r.addr, Connected: r.conn != nil, Acked: r.acked, Snapshots: r.snapshots}
+		if p.last > r.acked {
+			s.Lag = p.last - r.acked
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Serve accepts replica connections on l until Close.
+func (p *Primary) Serve(l net.Listener) error {
+	if !p.track(func() { p.lns[l] = true }) {
+		l.Close()
+		return ErrClosed
+	}
+	defer p.track(func() { delete(p.lns, l) })
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if p.isClosed() {
+				return ErrClosed
+			}
+			return err
+		}
+		if !p.track(func() { p.conns[c] = true; p.wg.Add(1) }) {
+			c.Close()
+			return ErrClosed
+		}
+		go func() {
+			defer p.wg.Done()
+			p.handle(c)
+			c.Close()
+			p.track(func() { delete(p.conns, c) })
+		}()
+	}
+}
+
+// track runs fn under the lock unless the primary is closed.
+func (p *Primary) track(fn func()) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	fn()
+	return true
+}
+
+func (p *Primary) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// Close stops shipping and disconnects every replica. The DB stays open.
+func (p *Primary) Close() error {
+	p.db.Watch(nil)
+	p.mu.Lock()
+	p.closed = true
+	for l := range p.lns {
+		l.Close()
+	}
+	for c := range p.conns {
+		c.Close()
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+	return nil
+}
+
+// deadlineWriter gives every write to the connection its own deadline, so a
+// replica that stops reading is dropped instead of stalling its sender.
+type deadlineWriter struct {
+	c       net.Conn
+	timeout time.Duration
+}
+
+func (d deadlineWriter) Write(p []byte) (int, error) {
+	if err := d.c.SetWriteDeadline(time.Now().Add(d.timeout)); err != nil {
+		return 0, err
+	}
+	return d.c.Write(p)
+}
+
+func (p *Primary) handle(c net.Conn) {
+	r, w := bufio.NewReader(c), bufio.NewWriter(deadlineWriter{c, p.writeTimeout})
+	typ, hello, err := readFrame(r)
+	if err != nil || typ != msgHello {
+		return
+	}
+	name, from, reset := string(hello.bytes()), hello.uvarint(), hello.uvarint() == 1
+	if hello.done() != nil {
+		return
+	}
+	p.mu.Lock()
+	pr, ok := p.replicas[name]
+	if !ok {
+		pr = &peer{}
+		p.replicas[name] = pr
+	}
+	pr.addr, pr.conn, pr.acked = c.RemoteAddr().String(), c, from
+	// Past base, the replica's history may have come from a previous
+	// primary and only matches ours if we sent it.
+	if from > p.base && from > pr.sent {
+		reset = true
+	}
+	p.mu.Unlock()
+	defer func() {
+		// A reconnect may already have replaced this connection.
+		p.mu.Lock()
+		if pr.conn == c {
+			pr.conn = nil
+		}
+		p.mu.Unlock()
+	}()
+
+	// Acks arrive on their own goroutine; when the replica goes away the
+	// read fails and closing the connection stops the sender too.
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		defer c.Close()
+		for {
+			typ, f, err := readFrame(r)
+			if err != nil || typ != msgAck {
+				return
+			}
+			seq := f.uvarint()
+			if f.done() != nil {
+				return
+			}
+			p.mu.Lock()
+			pr.acked = seq
+			p.mu.Unlock()
+		}
+	}()
+	defer func() { <-gone }()
+
+	tick := time.NewTicker(p.heartbeat)
+	defer tick.Stop()
+	next := from + 1
+	for {
+		p.mu.Lock()
+		// A replica outside the window, or ahead of us after a failover,
+		// starts over from a snapshot.
+		snapshot := reset || next < p.first && next <= p.last || next > p.last+1
+		var batch []lsm.Record
+		if !snapshot && next <= p.last {
+			batch = p.log[next-p.first:]
+		}
+		notify, last := p.notify, p.last
+		p.mu.Unlock()
+
+		switch {
+		case snapshot:
+			seq, err := p.sendSnapshot(w)
+			if err != nil {
+				c.Close()
+				return
+			}
+			p.mu.Lock()
+			pr.snapshots++
+			pr.sent = seq
+			p.mu.Unlock()
+			next, reset = seq+1, false
+			continue
+		case len(batch) > 0:
+			for _, rec := range batch {
+				del := uint64(0)
+				if rec.Delete {
+					del = 1
+				}
+				f := &frame{typ: msgRecord}
+				f.uvarint(rec.Seq).uvarint(del).bytes(rec.Key).bytes(rec.Value)
+				if f.write(w) != nil {
+					c.Close()
+					return
+				}
+			}
+			// Tell the replica how far the primary has got, so its own
+			// lag estimate holds up during a burst.
+			if (&frame{typ: msgHeartbeat}).uvarint(last).write(w) != nil || w.Flush() != nil {
+				c.Close()
+				return
+			}
+			next = batch[len(batch)-1].Seq + 1
+			p.mu.Lock()
+			pr.sent = next - 1
+			p.mu.Unlock()
+			continue
+		}
+		select {
+		case <-notify:
+		case <-tick.C:
+			f := &frame{typ: msgHeartbeat}
+			if f.uvarint(last).write(w) != nil || w.Flush() != nil {
+				c.Close()
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// sendSnapshot copies the DB's contents out under its read lock and sends
+// them after releasing it, so a slow replica never holds up writes.
+func (p *Primary) sendSnapshot(w *bufio.Writer) (uint64, error) {
+	var entries [][2][]byte
+	seq, err := p.db.Snapshot(func(key, value []byte) bool {
+		entries = append(entries, [2][]byte{bytes.Clone(key), bytes.Clone(value)})
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := (&frame{typ: msgSnapshot}).write(w); err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		if err := (&frame{typ: msgEntry}).bytes(e[0]).bytes(e[1]).write(w); err != nil {
+			return 0, err
+		}
+	}
+	if err := (&frame{typ: msgEndSnap}).uvarint(seq).write(w); err != nil {
+		return 0, err
+	}
+	return seq, w.Flush()
+}
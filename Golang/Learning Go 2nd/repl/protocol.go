@@ -0,0 +1,117 @@
+// Package repl replicates an lsm.DB by shipping its write-ahead log. The
+// primary keeps a window of recent log records in memory and streams them to
+// every replica over TCP; a replica that has fallen out of the window, or
+// whose history diverged, gets a full snapshot first. Replicas acknowledge
+// what they applied, both sides report lag, and a replica can be promoted to
+// take writes.
+//
+// Frames are a type byte, a big-endian uint32 length and a payload of
+// uvarints and length-prefixed byte strings:
+//
+//	replica → primary   'H' name, last applied seq, needs snapshot (0/1)
+//	                    'A' applied seq
+//	primary → replica   'R' seq, delete (0/1), key, value
+//	                    'S', then 'K' key, value per entry, then 'E' snapshot seq
+//	                    'P' primary's last seq (heartbeat)
+package repl
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	msgHello     = 'H'
+	msgAck       = 'A'
+	msgRecord    = 'R'
+	msgSnapshot  = 'S'
+	msgEntry     = 'K'
+	msgEndSnap   = 'E'
+	msgHeartbeat = 'P'
+
+	maxFrame = 64 << 20
+)
+
+var errMalformed = errors.New("repl: malformed frame")
+
+type frame struct {
+	typ byte
+	buf []byte
+}
+
+func (f *frame) uvarint(v uint64) *frame {
+	f.buf = binary.AppendUvarint(f.buf, v)
+	return f
+}
+
+func (f *frame) bytes(b []byte) *frame {
+	f.buf = binary.AppendUvarint(f.buf, uint64(len(b)))
+	f.buf = append(f.buf, b...)
+	return f
+}
+
+func (f *frame) write(w *bufio.Writer) error {
+	var hdr [5]byte
+	hdr[0] = f.typ
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(f.buf)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(f.buf)
+	return err
+}
+
+func readFrame(r *bufio.Reader) (byte, *reader, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxFrame {
+		return 0, nil, errMalformed
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, nil, err
+	}
+	return hdr[0], &reader{b: buf}, nil
+}
+
+// reader decodes a payload, remembering the first error.
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.err = errMalformed
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *reader) bytes() []byte {
+	n := r.uvarint()
+	if r.err != nil || n > uint64(len(r.b)) {
+		r.err = errMalformed
+		return nil
+	}
+	b := r.b[:n:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *reader) done() error {
+	if r.err == nil && len(r.b) > 0 {
+		r.err = errMalformed
+	}
+	return r.err
+}
@@ -0,0 +1,344 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"learning-go.adcon.dev/lsm"
+)
+
+func TestConfig(t *testing.T) {
+	if c, err := (PrimaryConfig{}).Resolve(); err != nil || c != DefaultPrimaryConfig() {
+		t.Errorf("zero PrimaryConfig resolves to %+v, %v; want the defaults", c, err)
+	}
+	if c, err := (ReplicaConfig{}).Resolve(WithMinBackoff(2 * time.Second)); err != nil || c.MaxBackoff != 2*time.Second {
+		t.Errorf("MaxBackoff = %v, %v; want it raised to MinBackoff", c.MaxBackoff, err)
+	}
+	if _, err := (PrimaryConfig{}).Resolve(WithRetain(-1)); err == nil {
+		t.Error("negative retain accepted")
+	}
+	if _, err := (ReplicaConfig{}).Resolve(WithMinBackoff(time.Second), WithMaxBackoff(time.Millisecond)); err == nil {
+		t.Error("min_backoff above max_backoff accepted")
+	}
+}
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	f := &frame{typ: msgRecord}
+	if err := f.uvarint(42).uvarint(1).bytes([]byte("key")).bytes(nil).write(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	typ, r, err := readFrame(bufio.NewReader(bytes.NewReader(buf.Bytes())))
+	if err != nil || typ != msgRecord {
+		t.Fatalf("readFrame = %q, %v", typ, err)
+	}
+	seq, del, key, value := r.uvarint(), r.uvarint(), r.bytes(), r.bytes()
+	if err := r.done(); err != nil || seq != 42 || del != 1 || string(key) != "key" || len(value) != 0 {
+		t.Errorf("decoded %d, %d, %q, %q, %v", seq, del, key, value, err)
+	}
+
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"empty", nil},
+		{"truncated varint", []byte{0x80}},
+		{"string past the payload", []byte{9, 'a'}},
+		{"huge string length", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}},
+		{"trailing bytes", []byte{1, 'a', 0}},
+	}
+	for _, tt := range tests {
+		r := &reader{b: tt.b}
+		r.bytes()
+		if err := r.done(); !errors.Is(err, errMalformed) {
+			t.Errorf("%s: err = %v, want errMalformed", tt.name, err)
+		}
+	}
+
+	tooBig := []byte{msgRecord, 0xff, 0xff, 0xff, 0xff}
+	if _, _, err := readFrame(bufio.NewReader(bytes.NewReader(tooBig))); !errors.Is(err, errMalformed) {
+		t.Errorf("oversized length: err = %v, want errMalformed", err)
+	}
+	short := buf.Bytes()[:buf.Len()-1]
+	if _, _, err := readFrame(bufio.NewReader(bytes.NewReader(short))); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated payload: err = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+// open opens an empty DB that is closed when the test ends.
+func open(t *testing.T) *lsm.DB {
+	t.Helper()
+	db, err := lsm.Open(filepath.Join(t.TempDir(), "db"), lsm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// serve starts a primary over a fresh DB on a loopback port.
+func serve(t *testing.T, db *lsm.DB, retain int) (*Primary, string) {
+	t.Helper()
+	p, err := NewPrimary(db, PrimaryConfig{Retain: retain, Heartbeat: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go p.Serve(l)
+	t.Cleanup(func() { p.Close() })
+	return p, l.Addr().String()
+}
+
+func follow(t *testing.T, name, addr string) *Replica {
+	t.Helper()
+	r, err := StartReplica(name, open(t), addr, ReplicaConfig{MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func write(t *testing.T, db *lsm.DB, from, n int) {
+	t.Helper()
+	for i := from; i < from+n; i++ {
+		key := fmt.Appendf(nil, "key%03d", i%150)
+		var err error
+		if i%7 == 6 {
+			err = db.Delete(key)
+		} else {
+			err = db.Put(key, fmt.Appendf(nil, "value%d", i))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// converge waits until every replica has applied and acknowledged the
+// primary's last write, then compares their contents with the primary's.
+func converge(t *testing.T, p *Primary, replicas ...*Replica) {
+	t.Helper()
+	want := p.LastSeq()
+	acked := func(name string) uint64 {
+		for _, s := range p.Status() {
+			if s.Name == name && s.Connected {
+				return s.Acked
+			}
+		}
+		return 0
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for _, r := range replicas {
+		for r.Status().Applied != want || r.DB().NeedsRestore() || acked(r.Name()) != want {
+			if time.Now().After(deadline) {
+				s := r.Status()
+				t.Fatalf("%s stuck at %d of %d (%v)", r.Name(), s.Applied, want, s.Err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if got, ref := contents(t, r.DB()), contents(t, p.DB()); got != ref {
+			t.Fatalf("%s holds\n%s\nprimary holds\n%s", r.Name(), got, ref)
+		}
+	}
+}
+
+func contents(t *testing.T, db *lsm.DB) string {
+	t.Helper()
+	var b bytes.Buffer
+	err := db.Scan(nil, nil, func(k, v []byte) bool {
+		fmt.Fprintf(&b, "%s=%s\n", k, v)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+// TestCatchUp cuts one replica off for less than the primary's log keeps and
+// another for more: the first catches up from the log, the second needs a
+// snapshot.
+func TestCatchUp(t *testing.T) {
+	const retain = 50
+	p, addr := serve(t, open(t), retain)
+	short, long := follow(t, "short", addr), follow(t, "long", addr)
+	write(t, p.DB(), 0, 100)
+	converge(t, p, short, long)
+
+	short.SetOnline(false)
+	long.SetOnline(false)
+	write(t, p.DB(), 100, retain/2)
+	short.SetOnline(true)
+	// A burst longer than the log outruns even a connected replica, so
+	// write in stretches it can keep up with.
+	for i := range 6 {
+		converge(t, p, short)
+		write(t, p.DB(), 100+(i+1)*retain/2, retain/2)
+	}
+	long.SetOnline(true)
+	converge(t, p, short, long)
+
+	if s := short.Status(); s.Snapshots != 0 || s.Lag != 0 {
+		t.Errorf("short: %d snapshots, lag %d; want 0, 0", s.Snapshots, s.Lag)
+	}
+	if s := long.Status(); s.Snapshots != 1 {
+		t.Errorf("long: %d snapshots, want 1", s.Snapshots)
+	}
+}
+
+// TestPromote fails over to a replica that missed writes the other replica
+// applied, so the other replica must drop them and take a snapshot of the
+// new primary.
+func TestPromote(t *testing.T) {
+	p, addr := serve(t, open(t), 1000)
+	next, other := follow(t, "next", addr), follow(t, "other", addr)
+	write(t, p.DB(), 0, 40)
+	converge(t, p, next, other)
+
+	next.SetOnline(false)
+	write(t, p.DB(), 40, 20)
+	converge(t, p, other)
+	before := other.Status().Snapshots
+
+	promoted, err := next.Promote(PrimaryConfig{Retain: 1000, Heartbeat: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { promoted.Close() })
+	p.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go promoted.Serve(l)
+	other.Follow(l.Addr().String())
+	write(t, promoted.DB(), 1000, 30)
+	converge(t, promoted, other)
+	if got := other.Status().Snapshots; got != before+1 {
+		t.Errorf("other took %d snapshots after the failover, want 1", got-before)
+	}
+	if _, err := next.DB().Get([]byte("key045")); err == nil {
+		t.Error("a write the new primary never saw survived the failover")
+	}
+}
+
+// within fails the test if fn does not return in time.
+func within(t *testing.T, d time.Duration, what string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(d):
+		t.Fatalf("%s blocked for %v", what, d)
+	}
+}
+
+// TestStalledSnapshot has each side of a snapshot stop halfway: neither may
+// keep the other side's DB locked.
+func TestStalledSnapshot(t *testing.T) {
+	db := open(t)
+	big := bytes.Repeat([]byte("x"), 4<<10)
+	for i := range 2000 {
+		if err := db.Put(fmt.Appendf(nil, "key%04d", i), big); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := NewPrimary(db, PrimaryConfig{Heartbeat: 10 * time.Millisecond, WriteTimeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go p.Serve(l)
+
+	// A replica that asks for a snapshot, reads a few entries of its 8MB
+	// and stops.
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	rd, w := bufio.NewReader(c), bufio.NewWriter(c)
+	if err := (&frame{typ: msgHello}).bytes([]byte("stalled")).uvarint(0).uvarint(1).write(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	for i := range 10 {
+		if typ, _, err := readFrame(rd); err != nil || i == 0 && typ != msgSnapshot {
+			t.Fatalf("frame %d: %q, %v", i, typ, err)
+		}
+	}
+	within(t, 2*time.Second, "a write on the primary", func() { write(t, db, 0, 10) })
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Status()[0].Connected {
+		if time.Now().After(deadline) {
+			t.Fatal("the primary never dropped the stalled replica")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A primary that sends half a snapshot and goes quiet.
+	fake, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fake.Close()
+	stalled := make(chan net.Conn, 1)
+	go func() {
+		c, err := fake.Accept()
+		if err != nil {
+			return
+		}
+		rd, w := bufio.NewReader(c), bufio.NewWriter(c)
+		readFrame(rd)
+		(&frame{typ: msgSnapshot}).write(w)
+		(&frame{typ: msgEntry}).bytes([]byte("new")).bytes(nil).write(w)
+		w.Flush()
+		stalled <- c
+	}()
+	rdb := open(t)
+	if err := rdb.Put([]byte("old"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	r, err := StartReplica("waiting", rdb, fake.Addr().String(), ReplicaConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	pc := <-stalled
+	defer pc.Close()
+	time.Sleep(50 * time.Millisecond) // let the entry arrive
+	within(t, 2*time.Second, "reads on the replica", func() {
+		if s := r.Status(); !s.Connected || s.Applied != 1 {
+			t.Errorf("mid-snapshot status %+v", s)
+		}
+		if v, err := rdb.Get([]byte("old")); err != nil || string(v) != "1" {
+			t.Errorf("old = %q, %v mid-snapshot", v, err)
+		}
+	})
+	// The snapshot never finishes: the replica keeps its old contents.
+	pc.Close()
+	if got := contents(t, rdb); got != "old=1\n" || rdb.NeedsRestore() {
+		t.Errorf("after an abandoned snapshot the replica holds %q, needs restore %v", got, rdb.NeedsRestore())
+	}
+}
@@ -0,0 +1,288 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"learning-go.adcon.dev/lsm"
+)
+
+// Replica follows a primary, applying its log to a local DB. Reads may go to
+// DB directly; writes must not.
+type Replica struct {
+	name string
+	db   *lsm.DB
+	cfg  ReplicaConfig
+
+	mu         sync.Mutex
+	addr       string
+	online     bool
+	conn       net.Conn
+	connected  bool
+	primarySeq uint64
+	snapshots  int
+	err        error
+	wake       chan struct{}
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// Progress is a replica's own view of how far behind it is.
+type Progress struct {
+	Applied    uint64
+	PrimarySeq uint64 // newest sequence number heard from the primary
+	Lag        uint64
+	Connected  bool
+	Snapshots  int   // full snapshots received
+	Err        error // why the last connection ended
+}
+
+// StartReplica connects db to the primary at addr and keeps it following,
+// reconnecting with backoff until Close or Promote. Opts are applied over
+// cfg.
+func StartReplica(name string, db *lsm.DB, addr string, cfg ReplicaConfig, opts ...ReplicaOption) (*Replica, error) {
+	cfg, err := cfg.Resolve(opts...)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replica{
+		cfg:    cfg,
+		name:   name,
+		db:     db,
+		addr:   addr,
+		online: true,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+func (r *Replica) Name() string { return r.name }
+func (r *Replica) DB() *lsm.DB  { return r.db }
+
+func (r *Replica) Status() Progress {
+	applied := r.db.LastSeq()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := Progress{
+		Applied:    applied,
+		PrimarySeq: r.primarySeq,
+		Connected:  r.connected,
+		Snapshots:  r.snapshots,
+		Err:        r.err,
+	}
+	if p.PrimarySeq > applied {
+		p.Lag = p.PrimarySeq - applied
+	}
+	return p
+}
+
+// SetOnline cuts the replica off from its primary, or lets it reconnect.
+func (r *Replica) SetOnline(online bool) {
+	r.mu.Lock()
+	r.online = online
+	if !online && r.conn != nil {
+		r.conn.Close()
+	}
+	r.mu.Unlock()
+	r.poke()
+}
+
+// Follow points the replica at a different primary, as after a failover.
+func (r *Replica) Follow(addr string) {
+	r.mu.Lock()
+	r.addr, r.primarySeq = addr, 0
+	if r.conn != nil {
+		r.conn.Close()
+	}
+	r.mu.Unlock()
+	r.poke()
+}
+
+// Promote stops following and returns a primary serving writes from this
+// replica's DB, configured as by NewPrimary. Other replicas must be pointed
+// at it with Follow.
+func (r *Replica) Promote(cfg PrimaryConfig, opts ...PrimaryOption) (*Primary, error) {
+	r.Close()
+	if r.db.NeedsRestore() {
+		return nil, fmt.Errorf("repl: %s is in the middle of a snapshot", r.name)
+	}
+	return NewPrimary(r.db, cfg, opts...)
+}
+
+// Close stops following. The DB stays open.
+func (r *Replica) Close() error {
+	r.mu.Lock()
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+	}
+	if r.conn != nil {
+		r.conn.Close()
+	}
+	r.mu.Unlock()
+	<-r.done
+	return nil
+}
+
+func (r *Replica) poke() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Replica) run() {
+	defer close(r.done)
+	minBackoff, maxBackoff := r.cfg.MinBackoff, r.cfg.MaxBackoff
+	backoff := minBackoff
+	for {
+		r.mu.Lock()
+		online, addr := r.online, r.addr
+		r.mu.Unlock()
+		if online {
+			start := time.Now()
+			err := r.follow(addr)
+			r.mu.Lock()
+			r.err, r.connected, r.conn = err, false, nil
+			r.mu.Unlock()
+			// A session that lasted a while resets the backoff.
+			if time.Since(start) > maxBackoff {
+				backoff = minBackoff
+			}
+		}
+		select {
+		case <-r.stop:
+			return
+		case <-r.wake:
+			backoff = minBackoff
+		case <-time.After(backoff):
+			backoff = min(2*backoff, maxBackoff)
+		}
+	}
+}
+
+// follow runs one connection to the primary until it breaks.
+func (r *Replica) follow(addr string) error {
+	c, err := net.DialTimeout("tcp", addr, r.cfg.DialTimeout)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	r.mu.Lock()
+	select {
+	case <-r.stop:
+		r.mu.Unlock()
+		return ErrClosed
+	default:
+	}
+	if !r.online || r.addr != addr {
+		r.mu.Unlock()
+		return errors.New("repl: disconnected")
+	}
+	r.conn, r.connected = c, true
+	r.mu.Unlock()
+
+	rd, w := bufio.NewReader(c), bufio.NewWriter(c)
+	reset := uint64(0)
+	if r.db.NeedsRestore() {
+		reset = 1
+	}
+	hello := (&frame{typ: msgHello}).bytes([]byte(r.name)).uvarint(r.db.LastSeq()).uvarint(reset)
+	if err := hello.write(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	// A snapshot is collected in memory and applied once complete, so the
+	// DB is locked only while it is written, not while it is transferred.
+	var snapshot [][2][]byte
+	inSnapshot := false
+	for {
+		typ, f, err := readFrame(rd)
+		if err != nil {
+			return err
+		}
+		switch {
+		case typ == msgRecord && !inSnapshot:
+			rec := lsm.Record{Seq: f.uvarint(), Delete: f.uvarint() == 1, Key: f.bytes(), Value: f.bytes()}
+			if err := f.done(); err != nil {
+				return err
+			}
+			if err := r.db.Apply(rec); err != nil {
+				return err
+			}
+			r.heard(rec.Seq)
+		case typ == msgHeartbeat && !inSnapshot:
+			seq := f.uvarint()
+			if err := f.done(); err != nil {
+				return err
+			}
+			r.heard(seq)
+		case typ == msgSnapshot && !inSnapshot:
+			snapshot, inSnapshot = nil, true
+		case typ == msgEntry && inSnapshot:
+			key, value := f.bytes(), f.bytes()
+			if err := f.done(); err != nil {
+				return err
+			}
+			snapshot = append(snapshot, [2][]byte{key, value})
+			continue // no ack mid-snapshot
+		case typ == msgEndSnap && inSnapshot:
+			seq := f.uvarint()
+			if err := f.done(); err != nil {
+				return err
+			}
+			if err := r.restore(snapshot, seq); err != nil {
+				return err
+			}
+			snapshot, inSnapshot = nil, false
+			r.mu.Lock()
+			r.snapshots++
+			r.mu.Unlock()
+			r.heard(seq)
+		default:
+			return fmt.Errorf("%w: unexpected %q", errMalformed, typ)
+		}
+		// Acknowledge once the burst is drained rather than per record.
+		if rd.Buffered() == 0 && !inSnapshot {
+			ack := (&frame{typ: msgAck}).uvarint(r.db.LastSeq())
+			if err := ack.write(w); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// restore replaces the DB's contents with a received snapshot.
+func (r *Replica) restore(entries [][2][]byte, seq uint64) error {
+	rs, err := r.db.BeginRestore()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := rs.Put(e[0], e[1]); err != nil {
+			rs.Abort()
+			return err
+		}
+	}
+	return rs.Finish(seq)
+}
+
+func (r *Replica) heard(seq uint64) {
+	r.mu.Lock()
+	r.primarySeq = max(r.primarySeq, seq)
+	r.mu.Unlock()
+}
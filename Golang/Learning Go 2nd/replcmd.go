@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/lsm"
+	"learning-go.adcon.dev/repl"
+)
+
+// runRepl runs a primary and several replicas in process on loopback, writes
+// while cutting replicas off, then promotes one and checks that every node
+// converges to the same contents each time.
+func runRepl(ctx *cli.Context) error {
+	fs := ctx.Flags
+	nodes := fs.Int("replicas", 3, "replicas to start")
+	writes := fs.Int("writes", 5000, "writes per phase")
+	keys := fs.Int("keys", 500, "distinct keys")
+	retain := fs.Int("retain", 1000, "log records the primary keeps for catching up")
+	seed := fs.Uint64("seed", 1, "random seed")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	if *nodes < 2 {
+		return fmt.Errorf("repl: need at least two replicas")
+	}
+	rng := rand.New(rand.NewPCG(*seed, *seed))
+	dir, err := os.MkdirTemp("", "repl")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	cfg := lsm.Config{MemtableSize: 64 << 10}
+
+	open := func(name string) (*lsm.DB, error) { return lsm.Open(filepath.Join(dir, name), cfg) }
+	serve := func(p *repl.Primary) (string, error) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return "", err
+		}
+		go p.Serve(l)
+		return l.Addr().String(), nil
+	}
+
+	db, err := open("primary")
+	if err != nil {
+		return err
+	}
+	dbs := []*lsm.DB{db}
+	defer func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+	}()
+	primary, err := repl.NewPrimary(db, repl.PrimaryConfig{Retain: *retain})
+	if err != nil {
+		return err
+	}
+	defer func() { primary.Close() }()
+	addr, err := serve(primary)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("primary on %s, keeping %d records\n", addr, *retain)
+
+	replicas := make([]*repl.Replica, *nodes)
+	for i := range replicas {
+		db, err := open(fmt.Sprintf("replica%d", i))
+		if err != nil {
+			return err
+		}
+		dbs = append(dbs, db)
+		if replicas[i], err = repl.StartReplica(fmt.Sprintf("replica%d", i), db, addr, repl.ReplicaConfig{}); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		for _, r := range replicas {
+			r.Close()
+		}
+	}()
+
+	// write applies n random writes to the primary. The writer is far faster
+	// than shipping, so it pauses every quarter window for the replicas in
+	// follow to catch up; otherwise they would drop out of the window and
+	// need snapshots too.
+	write := func(n int, follow []*repl.Replica) error {
+		for i := range n {
+			key := fmt.Appendf(nil, "key%05d", rng.IntN(*keys))
+			var err error
+			if rng.IntN(10) == 0 {
+				err = primary.DB().Delete(key)
+			} else {
+				err = primary.DB().Put(key, fmt.Appendf(nil, "value%d", rng.Uint32()))
+			}
+			if err != nil {
+				return err
+			}
+			if (i+1)%max(1, *retain/4) == 0 {
+				if err := settle(primary, follow); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	// Phase 1: replica0 misses a short stretch and catches up from the log;
+	// replica1 misses more than the log keeps and needs a snapshot.
+	if err := write(*writes, replicas); err != nil {
+		return err
+	}
+	if err := converge(primary, replicas); err != nil {
+		return err
+	}
+	replicas[0].SetOnline(false)
+	replicas[1].SetOnline(false)
+	if err := write(*retain/2, replicas[2:]); err != nil {
+		return err
+	}
+	replicas[0].SetOnline(true)
+	if err := write(*writes, append([]*repl.Replica{replicas[0]}, replicas[2:]...)); err != nil {
+		return err
+	}
+	printLag(primary, replicas)
+	replicas[1].SetOnline(true)
+	if err := converge(primary, replicas); err != nil {
+		return err
+	}
+	printLag(primary, replicas)
+
+	// Phase 2: the last replica is cut off and then promoted, so the others
+	// hold writes the new primary never saw and must start over from its
+	// snapshot.
+	last := replicas[len(replicas)-1]
+	replicas = replicas[:len(replicas)-1]
+	last.SetOnline(false)
+	if err := write(20, replicas); err != nil {
+		return err
+	}
+	if err := converge(primary, replicas); err != nil {
+		return err
+	}
+	promoted, err := last.Promote(repl.PrimaryConfig{Retain: *retain})
+	if err != nil {
+		return err
+	}
+	primary.Close()
+	primary = promoted
+	if addr, err = serve(primary); err != nil {
+		return err
+	}
+	fmt.Printf("promoted %s at seq %d, now on %s\n", last.Name(), primary.LastSeq(), addr)
+	for _, r := range replicas {
+		r.Follow(addr)
+	}
+	if err := write(*writes, nil); err != nil {
+		return err
+	}
+	if err := converge(primary, replicas); err != nil {
+		return err
+	}
+	printLag(primary, replicas)
+	return nil
+}
+
+func printLag(p *repl.Primary, replicas []*repl.Replica) {
+	fmt.Printf("primary seq %d\n", p.LastSeq())
+	for _, s := range p.Status() {
+		fmt.Printf("  %-9s connected=%-5v acked=%-6d lag=%-6d snapshots=%d\n", s.Name, s.Connected, s.Acked, s.Lag, s.Snapshots)
+	}
+	for _, r := range replicas {
+		s := r.Status()
+		fmt.Printf("  %-9s applied=%-6d primary=%-6d lag=%d (own view)\n", r.Name(), s.Applied, s.PrimarySeq, s.Lag)
+	}
+}
+
+// settle waits until every replica has applied and acknowledged the
+// primary's last write.
+func settle(p *repl.Primary, replicas []*repl.Replica) error {
+	want := p.LastSeq()
+	acked := func(name string) uint64 {
+		for _, s := range p.Status() {
+			if s.Name == name && s.Connected {
+				return s.Acked
+			}
+		}
+		return 0
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for _, r := range replicas {
+		for r.Status().Applied != want || r.DB().NeedsRestore() || acked(r.Name()) != want {
+			if time.Now().After(deadline) {
+				s := r.Status()
+				return fmt.Errorf("repl: %s stuck at %d of %d (%v)", r.Name(), s.Applied, want, s.Err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// converge settles the replicas, then compares their full contents with the
+// primary's.
+func converge(p *repl.Primary, replicas []*repl.Replica) error {
+	start := time.Now()
+	if err := settle(p, replicas); err != nil {
+		return err
+	}
+	fmt.Printf("all replicas at seq %d after %v\n", p.LastSeq(), time.Since(start).Round(time.Millisecond))
+
+	contents := func(db *lsm.DB) ([][2][]byte, error) {
+		var kvs [][2][]byte
+		err := db.Scan(nil, nil, func(k, v []byte) bool {
+			kvs = append(kvs, [2][]byte{bytes.Clone(k), bytes.Clone(v)})
+			return true
+		})
+		return kvs, err
+	}
+	ref, err := contents(p.DB())
+	if err != nil {
+		return err
+	}
+	for _, r := range replicas {
+		got, err := contents(r.DB())
+		if err != nil {
+			return err
+		}
+		if len(got) != len(ref) {
+			return fmt.Errorf("repl: %s has %d keys, primary %d", r.Name(), len(got), len(ref))
+		}
+		for i := range ref {
+			if !bytes.Equal(got[i][0], ref[i][0]) || !bytes.Equal(got[i][1], ref[i][1]) {
+				return fmt.Errorf("repl: %s differs at %q", r.Name(), ref[i][0])
+			}
+		}
+	}
+	fmt.Printf("contents match: %d keys on %d replicas\n", len(ref), len(replicas))
+	return nil
+}
@@ -0,0 +1,88 @@
+package rescue
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteManifest writes the checksums of files to name in the format of
+// sha256sum, so the copy can also be checked with `sha256sum -c` on a
+// machine without this program. The file is replaced atomically.
+func WriteManifest(name string, files []File) error {
+	tmp := name + ".rescue-part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("rescue: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, file := range files {
+		fmt.Fprintf(w, "%s  %s\n", file.Sum, file.Path)
+	}
+	err = w.Flush()
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rescue: %w", err)
+	}
+	return nil
+}
+
+// ReadManifest parses a file written by WriteManifest or sha256sum. Sizes
+// are not recorded, so the files it returns have Size -1.
+func ReadManifest(r io.Reader) ([]File, error) {
+	var files []File
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if text == "" {
+			continue
+		}
+		sum, p, ok := strings.Cut(text, " ")
+		// sha256sum marks binary mode with '*' instead of a second space.
+		p, _ = strings.CutPrefix(p, " ")
+		p, _ = strings.CutPrefix(p, "*")
+		if !ok || len(sum) != 64 || p == "" {
+			return nil, fmt.Errorf("rescue: manifest line %d: not \"checksum  path\"", line)
+		}
+		files = append(files, File{Path: p, Size: -1, Sum: strings.ToLower(sum)})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("rescue: %w", err)
+	}
+	return files, nil
+}
+
+// Verify checks the files of a copy against the manifest called name in
+// dir, as written by Copy, and returns the ones that are missing or
+// changed. It is meant to be run again later, for instance on another
+// computer, to confirm nothing was corrupted on the way.
+func Verify(dir, name string, workers int) (checked int, failed []Failure, err error) {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return 0, nil, fmt.Errorf("rescue: %w", err)
+	}
+	defer f.Close()
+	files, err := ReadManifest(f)
+	if err != nil {
+		return 0, nil, err
+	}
+	bad := verify(dir, files, workers)
+	for _, file := range files {
+		if err, ok := bad[file.Path]; ok {
+			failed = append(failed, Failure{Path: file.Path, Attempts: 1, Err: err})
+		}
+	}
+	return len(files), failed, nil
+}
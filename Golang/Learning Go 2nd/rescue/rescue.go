@@ -0,0 +1,404 @@
+// Package rescue copies files off an old or failing disk. It walks a tree,
+// copies files with a few workers at a time, skips executables and other
+// patterns, retries reads that fail, and checks every copy against the
+// SHA-256 of what was read from the source.
+package rescue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"learning-go.adcon.dev/options"
+)
+
+// DefaultSkip are the patterns always skipped: programs and scripts that
+// could carry malware from the old machine.
+var DefaultSkip = []string{"*.exe", "*.scr", "*.com", "*.pif", "*.bat", "*.cmd", "*.vbs", "*.msi", "*.dll", "*.lib"}
+
+// Config tunes Copy. Zero fields take the defaults in DefaultConfig.
+type Config struct {
+	Workers    int           `json:"workers"`     // files copied at the same time; 1 is kindest to a failing disk
+	Skip       []string      `json:"skip"`        // patterns skipped on top of DefaultSkip
+	Attempts   int           `json:"attempts"`    // reads of a file before giving up on it
+	Backoff    time.Duration `json:"backoff"`     // wait before the second attempt, doubled after each failure
+	MaxBackoff time.Duration `json:"max_backoff"` // longest wait between attempts
+	Manifest   string        `json:"manifest"`    // name of the checksum file written in the destination
+	Log        io.Writer     `json:"-"`           // if set, receives a line per file
+}
+
+// EnvPrefix starts the environment variables that set Config fields, as in
+// RESCUE_WORKERS.
+const EnvPrefix = "RESCUE"
+
+// Option sets one Config field; see options.Apply.
+type Option = options.Option[Config]
+
+func WithWorkers(n int) Option              { return options.New[Config]("workers", n) }
+func WithSkip(patterns ...string) Option    { return options.New[Config]("skip", patterns) }
+func WithAttempts(n int) Option             { return options.New[Config]("attempts", n) }
+func WithBackoff(d time.Duration) Option    { return options.New[Config]("backoff", d) }
+func WithMaxBackoff(d time.Duration) Option { return options.New[Config]("max_backoff", d) }
+func WithManifest(name string) Option       { return options.New[Config]("manifest", name) }
+
+// DefaultConfig returns the settings used for zero Config fields.
+func DefaultConfig() Config {
+	return Config{Workers: 4, Attempts: 4, Backoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second, Manifest: "SHA256SUMS"}
+}
+
+// Resolve applies opts over c, fills in defaults and validates the result,
+// as Copy does.
+func (c Config) Resolve(opts ...Option) (Config, error) {
+	c, err := options.Apply(c, opts...)
+	if err != nil {
+		return c, fmt.Errorf("rescue: %w", err)
+	}
+	d := DefaultConfig()
+	if c.Workers == 0 {
+		c.Workers = d.Workers
+	}
+	if c.Attempts == 0 {
+		c.Attempts = d.Attempts
+	}
+	if c.Backoff == 0 {
+		c.Backoff = d.Backoff
+	}
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = max(d.MaxBackoff, c.Backoff)
+	}
+	if c.Manifest == "" {
+		c.Manifest = d.Manifest
+	}
+	switch {
+	case c.Workers < 0 || c.Attempts < 0 || c.Backoff < 0 || c.MaxBackoff < 0:
+		return c, fmt.Errorf("rescue: settings must be positive")
+	case c.Backoff > c.MaxBackoff:
+		return c, fmt.Errorf("rescue: backoff %v exceeds max_backoff %v", c.Backoff, c.MaxBackoff)
+	case strings.ContainsAny(c.Manifest, `/\`):
+		return c, fmt.Errorf("rescue: manifest %q must be a file name", c.Manifest)
+	}
+	for _, p := range c.Skip {
+		if _, err := path.Match(p, ""); err != nil {
+			return c, fmt.Errorf("rescue: skip pattern %q: %w", p, err)
+		}
+	}
+	return c, nil
+}
+
+// File is one copied file.
+type File struct {
+	Path string `json:"path"` // slash-separated, relative to the tree
+	Size int64  `json:"size"`
+	Sum  string `json:"sha256"` // hex SHA-256 of the bytes read from the source
+}
+
+// Skipped is a file or directory left out on purpose.
+type Skipped struct {
+	Path    string `json:"path"`
+	Pattern string `json:"pattern"`
+}
+
+// Failure is a file or directory that could not be copied or verified.
+type Failure struct {
+	Path     string
+	Attempts int
+	Err      error
+}
+
+// MarshalJSON writes Err as its message.
+func (f Failure) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Path     string `json:"path"`
+		Attempts int    `json:"attempts"`
+		Err      string `json:"error"`
+	}{f.Path, f.Attempts, f.Err.Error()})
+}
+
+// Result is the outcome of Copy.
+type Result struct {
+	Copied   []File        `json:"copied"`
+	Skipped  []Skipped     `json:"skipped"`
+	Failed   []Failure     `json:"failed"`
+	Bytes    int64         `json:"bytes"`
+	Retries  int           `json:"retries"`  // reads repeated after an error
+	Duration time.Duration `json:"duration"` // copying and verifying
+}
+
+// Copy copies the tree src into the directory dst, which is created if
+// needed. Files matching a skip pattern, symlinks and special files are
+// left out. A file whose read fails is read again from the start after a
+// growing pause; write errors, such as a full destination, are not retried.
+//
+// Once everything is copied, every file is read back from dst and compared
+// with the checksum taken while reading src, and the checksums of the files
+// that passed are written to dst in the format of sha256sum. Problems with
+// single files end up in Result.Failed; the error is for problems with the
+// whole run, such as an unusable destination.
+func Copy(src fs.FS, dst string, cfg Config, opts ...Option) (*Result, error) {
+	cfg, err := cfg.Resolve(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return nil, fmt.Errorf("rescue: %w", err)
+	}
+	start := time.Now()
+	c := &copier{cfg: cfg, src: src, dst: dst, res: &Result{Copied: []File{}, Skipped: []Skipped{}, Failed: []Failure{}}}
+	skip := append(append([]string(nil), DefaultSkip...), cfg.Skip...)
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for range cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				c.copyFile(p)
+			}
+		}()
+	}
+	walkErr := fs.WalkDir(src, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// An unreadable directory: note it and keep going with the rest.
+			c.fail(p, 1, err)
+			return nil
+		}
+		if p == "." {
+			return nil
+		}
+		if pattern, ok := matches(skip, d.Name()); ok {
+			c.skipped(p, pattern)
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		switch {
+		case d.IsDir():
+			if err := os.MkdirAll(filepath.Join(dst, filepath.FromSlash(p)), 0o755); err != nil {
+				return err
+			}
+		case !d.Type().IsRegular():
+			c.skipped(p, "not a regular file")
+		case p == cfg.Manifest:
+			c.fail(p, 0, fmt.Errorf("has the name of the manifest"))
+		default:
+			jobs <- p
+		}
+		return nil
+	})
+	close(jobs)
+	wg.Wait()
+	if walkErr != nil {
+		return c.res, fmt.Errorf("rescue: %w", walkErr)
+	}
+
+	// Verify in a second pass, so most files are read back after the page
+	// cache has moved on to others.
+	copied := c.res.Copied
+	c.res.Copied = nil
+	failed := verify(dst, copied, cfg.Workers)
+	for _, f := range copied {
+		if err, bad := failed[f.Path]; bad {
+			c.fail(f.Path, 1, fmt.Errorf("verify: %w", err))
+			continue
+		}
+		c.res.Copied = append(c.res.Copied, f)
+		c.res.Bytes += f.Size
+	}
+	sort.Slice(c.res.Copied, func(i, j int) bool { return c.res.Copied[i].Path < c.res.Copied[j].Path })
+	sort.Slice(c.res.Skipped, func(i, j int) bool { return c.res.Skipped[i].Path < c.res.Skipped[j].Path })
+	sort.Slice(c.res.Failed, func(i, j int) bool { return c.res.Failed[i].Path < c.res.Failed[j].Path })
+	if err := WriteManifest(filepath.Join(dst, cfg.Manifest), c.res.Copied); err != nil {
+		return c.res, err
+	}
+	c.res.Duration = time.Since(start)
+	return c.res, nil
+}
+
+// matches reports the first pattern matching name, ignoring case, since
+// the disks being rescued are usually from Windows.
+func matches(patterns []string, name string) (string, bool) {
+	name = strings.ToLower(name)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), name); ok {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+type copier struct {
+	cfg Config
+	src fs.FS
+	dst string
+
+	mu  sync.Mutex
+	res *Result
+}
+
+func (c *copier) logf(format string, args ...any) {
+	if c.cfg.Log == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(c.cfg.Log, format+"\n", args...)
+}
+
+func (c *copier) skipped(p, pattern string) {
+	c.logf("skip    %s (%s)", p, pattern)
+	c.mu.Lock()
+	c.res.Skipped = append(c.res.Skipped, Skipped{Path: p, Pattern: pattern})
+	c.mu.Unlock()
+}
+
+func (c *copier) fail(p string, attempts int, err error) {
+	c.logf("FAILED  %s: %v", p, err)
+	c.mu.Lock()
+	c.res.Failed = append(c.res.Failed, Failure{Path: p, Attempts: attempts, Err: err})
+	c.mu.Unlock()
+}
+
+// readError marks errors from the source, the only ones worth retrying.
+type readError struct{ err error }
+
+func (e readError) Error() string { return e.err.Error() }
+func (e readError) Unwrap() error { return e.err }
+
+// copyFile copies one file, retrying failed reads with backoff.
+func (c *copier) copyFile(p string) {
+	backoff := c.cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		f, err := c.copyOnce(p)
+		if err == nil {
+			c.logf("copied  %s", p)
+			c.mu.Lock()
+			c.res.Copied = append(c.res.Copied, f)
+			c.mu.Unlock()
+			return
+		}
+		var rerr readError
+		if !errors.As(err, &rerr) || attempt >= c.cfg.Attempts {
+			c.fail(p, attempt, err)
+			return
+		}
+		c.logf("retry   %s in %v: %v", p, backoff, err)
+		c.mu.Lock()
+		c.res.Retries++
+		c.mu.Unlock()
+		time.Sleep(backoff)
+		backoff = min(2*backoff, c.cfg.MaxBackoff)
+	}
+}
+
+// copyOnce copies p to a temporary file next to its destination and renames
+// it into place once complete, so an interrupted run never leaves a
+// truncated file under the real name.
+func (c *copier) copyOnce(p string) (File, error) {
+	in, err := c.src.Open(p)
+	if err != nil {
+		return File{}, readError{err}
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return File{}, readError{err}
+	}
+	target := filepath.Join(c.dst, filepath.FromSlash(p))
+	tmp := target + ".rescue-part"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return File{}, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(out, io.TeeReader(readErrors{in}, h))
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, target)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return File{}, err
+	}
+	os.Chtimes(target, info.ModTime(), info.ModTime())
+	return File{Path: p, Size: n, Sum: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// readErrors wraps the source's errors so copyFile can tell them from write
+// errors.
+type readErrors struct{ r io.Reader }
+
+func (r readErrors) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = readError{err}
+	}
+	return n, err
+}
+
+// verify hashes the copies in dst with a few workers and returns the paths
+// that do not match, with the reason.
+func verify(dst string, files []File, workers int) map[string]error {
+	failed := map[string]error{}
+	var mu sync.Mutex
+	jobs := make(chan File)
+	var wg sync.WaitGroup
+	for range max(1, workers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h := sha256.New()
+			for f := range jobs {
+				if err := check(h, filepath.Join(dst, filepath.FromSlash(f.Path)), f); err != nil {
+					mu.Lock()
+					failed[f.Path] = err
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, f := range files {
+		jobs <- f
+	}
+	close(jobs)
+	wg.Wait()
+	return failed
+}
+
+func check(h hash.Hash, name string, want File) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h.Reset()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if n != want.Size && want.Size >= 0 {
+		return fmt.Errorf("size %d, want %d", n, want.Size)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != want.Sum {
+		return fmt.Errorf("checksum %s, want %s", sum[:12], want.Sum[:12])
+	}
+	return nil
+}
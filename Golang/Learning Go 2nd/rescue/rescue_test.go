@@ -0,0 +1,334 @@
+package rescue
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var errFlaky = errors.New("input/output error")
+
+// flakyFS fails the first read of a file as many times as fails says,
+// counting across opens, as a disk with a weak sector might.
+type flakyFS struct {
+	fstest.MapFS
+	mu    sync.Mutex
+	fails map[string]int
+}
+
+func (f *flakyFS) Open(name string) (fs.File, error) {
+	file, err := f.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &flakyFile{File: file, fs: f, name: name}, nil
+}
+
+type flakyFile struct {
+	fs.File
+	fs   *flakyFS
+	name string
+}
+
+func (f *flakyFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.fs.fails[f.name] > 0 {
+		f.fs.fails[f.name]--
+		return 0, errFlaky
+	}
+	return f.File.Read(p)
+}
+
+// logWriter collects Copy's log and runs hook on each line.
+type logWriter struct {
+	lines []string
+	hook  func(line string)
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	line := strings.TrimSuffix(string(p), "\n")
+	w.lines = append(w.lines, line)
+	if w.hook != nil {
+		w.hook(line)
+	}
+	return len(p), nil
+}
+
+func sum(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func paths[T any](items []T, path func(T) string) []string {
+	var out []string
+	for _, it := range items {
+		out = append(out, path(it))
+	}
+	return out
+}
+
+func TestRetries(t *testing.T) {
+	src := &flakyFS{
+		MapFS: fstest.MapFS{
+			"ok.txt":    {Data: []byte("fine")},
+			"weak.txt":  {Data: []byte("recovered")},
+			"dead.txt":  {Data: []byte("lost")},
+			"other.txt": {Data: []byte("also fine")},
+		},
+		fails: map[string]int{"weak.txt": 4, "dead.txt": 100},
+	}
+	log := &logWriter{}
+	dst := t.TempDir()
+	cfg := Config{Workers: 1, Attempts: 5, Backoff: time.Millisecond, MaxBackoff: 3 * time.Millisecond, Log: log}
+	res, err := Copy(src, dst, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// weak.txt succeeds on the fifth and last attempt; dead.txt uses all
+	// five and fails.
+	if res.Retries != 8 {
+		t.Errorf("Retries = %d, want 8", res.Retries)
+	}
+	if got, want := paths(res.Copied, func(f File) string { return f.Path }), []string{"ok.txt", "other.txt", "weak.txt"}; !slices.Equal(got, want) {
+		t.Errorf("copied %v, want %v", got, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "weak.txt")); err != nil || string(data) != "recovered" {
+		t.Errorf("weak.txt = %q, %v", data, err)
+	}
+	if len(res.Failed) != 1 || res.Failed[0].Path != "dead.txt" || res.Failed[0].Attempts != 5 || !errors.Is(res.Failed[0].Err, errFlaky) {
+		t.Fatalf("Failed = %+v, want dead.txt after 5 attempts", res.Failed)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "dead.txt")); !os.IsNotExist(err) {
+		t.Errorf("dead.txt exists in dst: %v", err)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dst, "*.rescue-part")); leftovers != nil {
+		t.Errorf("temporary files left: %v", leftovers)
+	}
+
+	// The backoff doubles from 1ms and stops at 3ms.
+	var waits []string
+	for _, line := range log.lines {
+		if rest, ok := strings.CutPrefix(line, "retry   dead.txt in "); ok {
+			wait, _, _ := strings.Cut(rest, ":")
+			waits = append(waits, wait)
+		}
+	}
+	if want := []string{"1ms", "2ms", "3ms", "3ms"}; !slices.Equal(waits, want) {
+		t.Errorf("waits before retrying dead.txt %v, want %v", waits, want)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	src := fstest.MapFS{
+		"photos/a.jpg":              {Data: []byte("a")},
+		"photos/Thumbs.db":          {Data: []byte("t")},
+		"SETUP.EXE":                 {Data: []byte("mz")},
+		"tools/run.Bat":             {Data: []byte("@echo")},
+		"node_modules/x/index.js":   {Data: []byte("js")},
+		"notes.txt":                 {Data: []byte("n")},
+		"link":                      {Data: []byte("notes.txt"), Mode: fs.ModeSymlink},
+		"fifo":                      {Mode: fs.ModeNamedPipe},
+		"dev":                       {Mode: fs.ModeDevice},
+		"photos/exe-not-really.txt": {Data: []byte("e")},
+	}
+	dst := t.TempDir()
+	res, err := Copy(src, dst, Config{}, WithSkip("thumbs.db", "node_modules"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, s := range res.Skipped {
+		got[s.Path] = s.Pattern
+	}
+	want := map[string]string{
+		"SETUP.EXE":        "*.exe",
+		"tools/run.Bat":    "*.bat",
+		"photos/Thumbs.db": "thumbs.db",
+		"node_modules":     "node_modules",
+		"link":             "not a regular file",
+		"fifo":             "not a regular file",
+		"dev":              "not a regular file",
+	}
+	if len(got) != len(want) {
+		t.Errorf("skipped %v, want %v", got, want)
+	}
+	for p, pattern := range want {
+		if got[p] != pattern {
+			t.Errorf("%s skipped by %q, want %q", p, got[p], pattern)
+		}
+		if _, err := os.Lstat(filepath.Join(dst, p)); !os.IsNotExist(err) {
+			t.Errorf("skipped %s exists in dst: %v", p, err)
+		}
+	}
+	if got, want := paths(res.Copied, func(f File) string { return f.Path }), []string{"notes.txt", "photos/a.jpg", "photos/exe-not-really.txt"}; !slices.Equal(got, want) {
+		t.Errorf("copied %v, want %v", got, want)
+	}
+	if len(res.Failed) != 0 {
+		t.Errorf("Failed = %v", res.Failed)
+	}
+}
+
+// TestVerifyAfterCopy changes a copy between its copy and the verify pass,
+// as a failing destination might.
+func TestVerifyAfterCopy(t *testing.T) {
+	src := fstest.MapFS{
+		"a.txt": {Data: []byte("original")},
+		"b.txt": {Data: []byte("untouched")},
+	}
+	dst := t.TempDir()
+	log := &logWriter{hook: func(line string) {
+		if line == "copied  a.txt" {
+			if err := os.WriteFile(filepath.Join(dst, "a.txt"), []byte("origiNal"), 0o644); err != nil {
+				t.Error(err)
+			}
+		}
+	}}
+	res, err := Copy(src, dst, Config{Log: log})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Failed) != 1 || res.Failed[0].Path != "a.txt" || !strings.HasPrefix(res.Failed[0].Err.Error(), "verify: checksum") {
+		t.Fatalf("Failed = %+v, want a.txt failing verification", res.Failed)
+	}
+	if len(res.Copied) != 1 || res.Copied[0].Path != "b.txt" || res.Bytes != 9 {
+		t.Errorf("Copied = %+v, Bytes = %d; want only b.txt", res.Copied, res.Bytes)
+	}
+	// The manifest lists only what passed.
+	manifest, err := os.ReadFile(filepath.Join(dst, "SHA256SUMS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := sum("untouched") + "  b.txt\n"; string(manifest) != want {
+		t.Errorf("manifest %q, want %q", manifest, want)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"a.txt": "alpha", "sub/b b.txt": "beta", "c.bin": "gamma"}
+	var list []File
+	for p, data := range files {
+		name := filepath.Join(dir, filepath.FromSlash(p))
+		os.MkdirAll(filepath.Dir(name), 0o755)
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		list = append(list, File{Path: p, Size: int64(len(data)), Sum: sum(data)})
+	}
+	slices.SortFunc(list, func(a, b File) int { return strings.Compare(a.Path, b.Path) })
+	if err := WriteManifest(filepath.Join(dir, "SUMS"), list); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "SUMS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadManifest(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range list {
+		list[i].Size = -1
+	}
+	if !slices.Equal(read, list) {
+		t.Errorf("ReadManifest = %+v, want %+v", read, list)
+	}
+
+	// sha256sum -b writes '*' for binary mode; upper case sums are fine too.
+	binary := strings.Replace(string(data), "  c.bin", " *c.bin", 1)
+	binary = strings.Replace(binary, sum("alpha"), strings.ToUpper(sum("alpha")), 1)
+	if err := os.WriteFile(filepath.Join(dir, "SUMS"), []byte(binary+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	checked, failed, err := Verify(dir, "SUMS", 2)
+	if err != nil || checked != 3 || len(failed) != 0 {
+		t.Fatalf("Verify = %d, %v, %v; want 3 checked and none failed", checked, failed, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("alphA"), 0o644)
+	os.Remove(filepath.Join(dir, "c.bin"))
+	checked, failed, err = Verify(dir, "SUMS", 2)
+	if err != nil || checked != 3 {
+		t.Fatalf("Verify = %d, %v", checked, err)
+	}
+	if got := paths(failed, func(f Failure) string { return f.Path }); !slices.Equal(got, []string{"a.txt", "c.bin"}) {
+		t.Errorf("failed %v, want a.txt and c.bin", got)
+	}
+
+	for _, bad := range []string{
+		"abc  a.txt",
+		sum("x") + "  ",
+		sum("x") + "a.txt",
+		sum("x"),
+	} {
+		if _, err := ReadManifest(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadManifest(%q) succeeded", bad)
+		}
+	}
+	if _, _, err := Verify(dir, "missing", 1); err == nil {
+		t.Error("Verify without a manifest succeeded")
+	}
+}
+
+func TestCopyContents(t *testing.T) {
+	mtime := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	src := fstest.MapFS{
+		"a/b/c.txt":  {Data: []byte("deep"), ModTime: mtime},
+		"empty":      {},
+		"dir":        {Mode: fs.ModeDir},
+		"SHA256SUMS": {Data: []byte("a file with the manifest's name")},
+	}
+	dst := filepath.Join(t.TempDir(), "new")
+	res, err := Copy(src, dst, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "a/b/c.txt")); err != nil || string(data) != "deep" {
+		t.Errorf("c.txt = %q, %v", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "a/b/c.txt")); err != nil || !info.ModTime().Equal(mtime) {
+		t.Errorf("c.txt modified at %v, want %v", info.ModTime(), mtime)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "dir")); err != nil || !info.IsDir() {
+		t.Errorf("empty directory not copied: %v", err)
+	}
+	if len(res.Failed) != 1 || res.Failed[0].Path != "SHA256SUMS" {
+		t.Errorf("Failed = %v, want the file named like the manifest", res.Failed)
+	}
+	if len(res.Copied) != 2 || res.Bytes != 4 {
+		t.Errorf("copied %d files, %d bytes; want 2 and 4", len(res.Copied), res.Bytes)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		opts []Option
+		ok   bool
+	}{
+		{Config{}, nil, true},
+		{Config{Backoff: 10 * time.Second}, nil, true}, // max_backoff follows
+		{Config{Backoff: time.Second, MaxBackoff: time.Millisecond}, nil, false},
+		{Config{Workers: -1}, nil, false},
+		{Config{Manifest: "sub/SUMS"}, nil, false},
+		{Config{}, []Option{WithSkip("[")}, false},
+		{Config{Attempts: 2}, []Option{WithAttempts(3)}, false},
+	}
+	for _, tt := range tests {
+		if _, err := tt.cfg.Resolve(tt.opts...); (err == nil) != tt.ok {
+			t.Errorf("Resolve %+v %v: err = %v, want ok %v", tt.cfg, tt.opts, err, tt.ok)
+		}
+	}
+}
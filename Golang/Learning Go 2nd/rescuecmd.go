@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/rescue"
+)
+
+func runRescue(ctx *cli.Context) error {
+	fs := ctx.Flags
+	workers := fs.Int("workers", 0, "files copied at the same time; use 1 for a failing disk (default 4)")
+	skip := fs.String("skip", "", "comma-separated extra patterns to skip, e.g. 'Thumbs.db,*.tmp'")
+	attempts := fs.Int("attempts", 0, "reads of a file before giving up on it (default 4)")
+	backoff := fs.Duration("backoff", 0, "wait before retrying a read, doubled after each failure (default 200ms)")
+	maxBackoff := fs.Duration("max-backoff", 0, "longest wait between reads (default 5s)")
+	manifest := fs.String("manifest", "", "name of the checksum file in dst (default SHA256SUMS)")
+	check := fs.Bool("verify", false, "only check an earlier copy in dst against its manifest")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+
+	// Only flags that were set, on the command line, in RESCUE_* variables
+	// or in the config file, become options; the rest keep their defaults.
+	var opts []rescue.Option
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "workers":
+			opts = append(opts, rescue.WithWorkers(*workers))
+		case "skip":
+			opts = append(opts, rescue.WithSkip(strings.Split(*skip, ",")...))
+		case "attempts":
+			opts = append(opts, rescue.WithAttempts(*attempts))
+		case "backoff":
+			opts = append(opts, rescue.WithBackoff(*backoff))
+		case "max-backoff":
+			opts = append(opts, rescue.WithMaxBackoff(*maxBackoff))
+		case "manifest":
+			opts = append(opts, rescue.WithManifest(*manifest))
+		}
+	})
+	cfg, err := rescue.Config{}.Resolve(opts...)
+	if err != nil {
+		return err
+	}
+
+	if *check {
+		if fs.NArg() != 1 {
+			return fmt.Errorf("rescue: -verify takes one directory")
+		}
+		start := time.Now()
+		checked, failed, err := rescue.Verify(fs.Arg(0), cfg.Manifest, cfg.Workers)
+		if err != nil {
+			return err
+		}
+		if ctx.JSON() {
+			err = ctx.Encode(struct {
+				Checked int              `json:"checked"`
+				Failed  []rescue.Failure `json:"failed"`
+			}{checked, failed})
+		} else {
+			for _, f := range failed {
+				fmt.Printf("FAILED  %s: %v\n", f.Path, f.Err)
+			}
+			fmt.Printf("%d files checked in %v, %d failed\n", checked, time.Since(start).Round(time.Millisecond), len(failed))
+		}
+		if err != nil {
+			return err
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("rescue: %d files failed", len(failed))
+		}
+		return nil
+	}
+
+	if fs.NArg() != 2 {
+		return fmt.Errorf("rescue: need a source and a destination")
+	}
+	src, dst := fs.Arg(0), fs.Arg(1)
+	if err := checkTrees(src, dst); err != nil {
+		return err
+	}
+	cfg.Log = ctx.Log()
+	res, err := rescue.Copy(os.DirFS(src), dst, cfg)
+	if res != nil {
+		manifest := filepath.Join(dst, cfg.Manifest)
+		if ctx.JSON() {
+			if jerr := ctx.Encode(struct {
+				*rescue.Result
+				Manifest string `json:"manifest"`
+			}{res, manifest}); err == nil {
+				err = jerr
+			}
+		} else {
+			printRescue(res, manifest)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(res.Failed) > 0 {
+		return fmt.Errorf("rescue: %d files failed", len(res.Failed))
+	}
+	return nil
+}
+
+// checkTrees refuses to copy a tree into itself, which would never end, or
+// over its own parent.
+func checkTrees(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("rescue: %s is not a directory", src)
+	}
+	s, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	d, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	if s, err = filepath.EvalSymlinks(s); err != nil {
+		return err
+	}
+	d = resolveExisting(d)
+	within := func(dir, p string) bool {
+		rel, err := filepath.Rel(dir, p)
+		return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+	if within(s, d) || within(d, s) {
+		return fmt.Errorf("rescue: %s and %s overlap", src, dst)
+	}
+	return nil
+}
+
+// resolveExisting follows the symlinks in the part of p that exists. The
+// destination usually does not exist yet, but a link among its parents can
+// still lead back into the source.
+func resolveExisting(p string) string {
+	rest := ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return filepath.Join(p, rest)
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+		p = parent
+	}
+}
+
+func printRescue(res *rescue.Result, manifest string) {
+	fmt.Println()
+	fmt.Printf("copied   %d files, %.1f MB in %v\n", len(res.Copied), float64(res.Bytes)/1e6, res.Duration.Round(time.Millisecond))
+	fmt.Printf("skipped  %d\n", len(res.Skipped))
+	fmt.Printf("retries  %d\n", res.Retries)
+	fmt.Printf("failed   %d\n", len(res.Failed))
+	for _, f := range res.Failed {
+		if f.Attempts > 1 {
+			fmt.Printf("  %s (after %d attempts): %v\n", f.Path, f.Attempts, f.Err)
+		} else {
+			fmt.Printf("  %s: %v\n", f.Path, f.Err)
+		}
+	}
+	fmt.Printf("manifest %s\n", manifest)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckTrees(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"disk/photos", "disk2", "backup"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "file"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A link that hides that the destination is inside the source.
+	if err := os.Symlink(filepath.Join(root, "disk/photos"), filepath.Join(root, "alias")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		src, dst string
+		ok       bool
+	}{
+		{"disk", "backup", true},
+		{"disk", "backup/new/dir", true},
+		{"disk", "disk2", true}, // a shared prefix is not nesting
+		{"disk2", "disk", true},
+		{"disk", "disk", false},
+		{"disk", "disk/photos", false},
+		{"disk", "disk/rescued", false},
+		{"disk/photos", "disk", false},
+		{"disk", "alias/more", false},
+		{"alias", "disk", false},
+		{"disk/../disk/photos", "disk", false},
+		{"missing", "backup", false},
+		{"file", "backup", false},
+	}
+	for _, tt := range tests {
+		err := checkTrees(filepath.Join(root, tt.src), filepath.Join(root, tt.dst))
+		if (err == nil) != tt.ok {
+			t.Errorf("checkTrees(%s, %s) = %v, want ok %v", tt.src, tt.dst, err, tt.ok)
+		}
+	}
+}
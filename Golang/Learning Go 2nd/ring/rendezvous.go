@@ -0,0 +1,88 @@
+package ring
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Rendezvous is highest-random-weight hashing (Thaler and Ravishankar,
+// 1996): every node scores every key and the highest score wins. Removing a
+// node only moves the keys it won, and an added node only takes the keys it
+// now wins, without virtual nodes or a sorted table; the price is a lookup
+// in O(n) instead of O(log n). A Rendezvous is not safe for concurrent use.
+type Rendezvous struct {
+	nodes []scored // sorted by name
+}
+
+type scored struct {
+	Node
+	seed uint64 // hash of the name, mixed into each key's hash
+}
+
+// NewRendezvous returns a Rendezvous over nodes, with the same rules for
+// names and weights as Ring.Add.
+func NewRendezvous(nodes ...Node) (*Rendezvous, error) {
+	r := &Rendezvous{}
+	if err := r.Add(nodes...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Add adds nodes. Names must be new and weights positive.
+func (r *Rendezvous) Add(nodes ...Node) error {
+	all, err := addNodes(r.Nodes(), nodes)
+	if err != nil {
+		return err
+	}
+	r.nodes = make([]scored, len(all))
+	for i, n := range all {
+		r.nodes[i] = scored{n, hash(n.Name)}
+	}
+	return nil
+}
+
+// Remove removes the named node.
+func (r *Rendezvous) Remove(name string) error {
+	i := slices.IndexFunc(r.nodes, func(n scored) bool { return n.Name == name })
+	if i < 0 {
+		return fmt.Errorf("ring: no node %q", name)
+	}
+	r.nodes = slices.Delete(r.nodes, i, i+1)
+	return nil
+}
+
+// Nodes returns the nodes sorted by name.
+func (r *Rendezvous) Nodes() []Node {
+	nodes := make([]Node, len(r.nodes))
+	for i, n := range r.nodes {
+		nodes[i] = n.Node
+	}
+	return nodes
+}
+
+// Len returns the number of nodes.
+func (r *Rendezvous) Len() int { return len(r.nodes) }
+
+// Get returns the node that wins key, or "" if there are no nodes.
+func (r *Rendezvous) Get(key string) string {
+	if len(r.nodes) == 0 {
+		return ""
+	}
+	return r.nodes[r.locate(hash(key))].Name
+}
+
+// locate scores with -w/ln(u), u uniform in (0, 1): the winner is then node
+// i with probability w_i/Σw (Schindelhauer and Schomaker, 2005). Plain
+// w·u would favor heavy nodes more than their weight.
+func (r *Rendezvous) locate(h uint64) int {
+	best, bestScore := 0, math.Inf(-1)
+	for i, n := range r.nodes {
+		u := (float64(mix64(h^n.seed)>>11) + 0.5) / (1 << 53)
+		if score := -n.Weight / math.Log(u); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
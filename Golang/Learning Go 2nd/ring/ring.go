@@ -0,0 +1,326 @@
+// Package ring spreads keys over a changing set of nodes so that adding or
+// removing a node moves as few keys as possible. Ring is consistent hashing
+// with virtual nodes, weights and, through Assign, bounded loads (Mirrokni,
+// Thorup and Zadimoghaddam, 2018); Rendezvous is highest-random-weight
+// hashing. Simulate measures how many keys each strategy moves.
+package ring
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	"learning-go.adcon.dev/options"
+)
+
+// Node is a member of a ring. A node with weight 2 gets twice the virtual
+// nodes, and so about twice the keys, of a node with weight 1.
+type Node struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"` // 1 if 0
+}
+
+// Config tunes a Ring. Zero fields take the defaults in DefaultConfig.
+type Config struct {
+	Replicas   int     `json:"replicas"`    // virtual nodes per unit of weight
+	LoadFactor float64 `json:"load_factor"` // Assign keeps every node under LoadFactor times its fair share
+}
+
+// DefaultConfig returns the settings used for zero Config fields. 160
+// virtual nodes per server is what ketama, the memcached client that
+// popularized the technique, uses.
+func DefaultConfig() Config { return Config{Replicas: 160, LoadFactor: 1.25} }
+
+// maxVnodes is the most virtual nodes a node can have, Replicas times its
+// weight. It keeps a large weight from allocating points without bound.
+const maxVnodes = 1 << 20
+
+// EnvPrefix starts the environment variables that set Config fields, as in
+// RING_REPLICAS.
+const EnvPrefix = "RING"
+
+// Option sets one Config field; see options.Apply.
+type Option = options.Option[Config]
+
+func WithReplicas(n int) Option       { return options.New[Config]("replicas", n) }
+func WithLoadFactor(c float64) Option { return options.New[Config]("load_factor", c) }
+
+// Resolve applies opts over c, fills in defaults and validates the result,
+// as New does.
+func (c Config) Resolve(opts ...Option) (Config, error) {
+	c, err := options.Apply(c, opts...)
+	if err != nil {
+		return c, fmt.Errorf("ring: %w", err)
+	}
+	d := DefaultConfig()
+	if c.Replicas == 0 {
+		c.Replicas = d.Replicas
+	}
+	if c.LoadFactor == 0 {
+		c.LoadFactor = d.LoadFactor
+	}
+	if c.Replicas < 1 || c.Replicas > maxVnodes {
+		return c, fmt.Errorf("ring: Replicas must be between 1 and %d, got %d", maxVnodes, c.Replicas)
+	}
+	if c.LoadFactor <= 1 {
+		return c, fmt.Errorf("ring: LoadFactor must be above 1, got %g", c.LoadFactor)
+	}
+	return c, nil
+}
+
+// Ring is a consistent hash ring. Each node owns the arcs that end at its
+// virtual nodes, so a key belongs to the first virtual node clockwise from
+// its hash. A Ring is not safe for concurrent use.
+type Ring struct {
+	cfg    Config
+	nodes  []member // sorted by name
+	points []point  // sorted by hash
+	weight float64  // sum of the node weights
+	keys   int      // keys placed with Assign
+	skips  int      // Assign calls that passed over a full node
+}
+
+type member struct {
+	Node
+	load int // keys placed with Assign
+}
+
+// point is a virtual node.
+type point struct {
+	hash uint64
+	node int // index in Ring.nodes
+}
+
+// New creates an empty ring configured by cfg and then opts.
+func New(cfg Config, opts ...Option) (*Ring, error) {
+	cfg, err := cfg.Resolve(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Ring{cfg: cfg}, nil
+}
+
+// Add adds nodes to the ring. Names must be new and weights positive, and
+// no node can have more than 1<<20 virtual nodes.
+func (r *Ring) Add(nodes ...Node) error {
+	all, err := addNodes(r.members(), nodes)
+	if err != nil {
+		return err
+	}
+	for _, n := range all {
+		// In floating point, so that a huge weight cannot overflow int.
+		if v := float64(r.cfg.Replicas) * n.Weight; v > maxVnodes {
+			return fmt.Errorf("ring: node %q: weight %g gives %.0f virtual nodes at %d replicas, more than %d", n.Name, n.Weight, v, r.cfg.Replicas, maxVnodes)
+		}
+	}
+	loads := map[string]int{}
+	for _, m := range r.nodes {
+		loads[m.Name] = m.load
+	}
+	r.nodes = make([]member, len(all))
+	for i, n := range all {
+		r.nodes[i] = member{n, loads[n.Name]}
+	}
+	r.rebuild()
+	return nil
+}
+
+// Remove removes the named node. Its keys go to the next virtual nodes
+// clockwise; keys placed with Assign are forgotten and should be assigned
+// again.
+func (r *Ring) Remove(name string) error {
+	i, ok := r.find(name)
+	if !ok {
+		return fmt.Errorf("ring: no node %q", name)
+	}
+	r.keys -= r.nodes[i].load
+	r.nodes = slices.Delete(r.nodes, i, i+1)
+	r.rebuild()
+	return nil
+}
+
+// addNodes returns the nodes in have and add, sorted by name, after
+// checking add.
+func addNodes(have, add []Node) ([]Node, error) {
+	all := slices.Clone(have)
+	for _, n := range add {
+		if n.Name == "" {
+			return nil, fmt.Errorf("ring: node without a name")
+		}
+		if n.Weight == 0 {
+			n.Weight = 1
+		}
+		if n.Weight < 0 || math.IsNaN(n.Weight) || math.IsInf(n.Weight, 0) {
+			return nil, fmt.Errorf("ring: node %q: weight must be positive, got %g", n.Name, n.Weight)
+		}
+		if slices.ContainsFunc(all, func(m Node) bool { return m.Name == n.Name }) {
+			return nil, fmt.Errorf("ring: node %q added twice", n.Name)
+		}
+		all = append(all, n)
+	}
+	slices.SortFunc(all, func(a, b Node) int { return cmp.Compare(a.Name, b.Name) })
+	return all, nil
+}
+
+// rebuild places the virtual nodes again. Each one hashes "name#i", so a
+// node's points do not depend on the other nodes and only its own arcs
+// change hands when it joins or leaves.
+func (r *Ring) rebuild() {
+	r.points = r.points[:0]
+	r.weight = 0
+	var buf []byte
+	for i, n := range r.nodes {
+		r.weight += n.Weight
+		for v := range r.vnodes(n.Weight) {
+			buf = strconv.AppendInt(append(append(buf[:0], n.Name...), '#'), int64(v), 10)
+			r.points = append(r.points, point{hash(buf), i})
+		}
+	}
+	// Ties are broken by name, so the ring does not depend on the order
+	// nodes were added in.
+	slices.SortFunc(r.points, func(a, b point) int {
+		return cmp.Or(cmp.Compare(a.hash, b.hash), cmp.Compare(r.nodes[a.node].Name, r.nodes[b.node].Name))
+	})
+}
+
+func (r *Ring) vnodes(weight float64) int {
+	return max(1, int(math.Round(float64(r.cfg.Replicas)*weight)))
+}
+
+func (r *Ring) find(name string) (int, bool) {
+	return slices.BinarySearchFunc(r.nodes, name, func(m member, name string) int { return cmp.Compare(m.Name, name) })
+}
+
+func (r *Ring) members() []Node {
+	nodes := make([]Node, len(r.nodes))
+	for i, m := range r.nodes {
+		nodes[i] = m.Node
+	}
+	return nodes
+}
+
+// Nodes returns the nodes sorted by name.
+func (r *Ring) Nodes() []Node { return r.members() }
+
+// Len returns the number of nodes.
+func (r *Ring) Len() int { return len(r.nodes) }
+
+// Config returns the resolved configuration.
+func (r *Ring) Config() Config { return r.cfg }
+
+// Get returns the node that owns key, or "" if the ring is empty.
+func (r *Ring) Get(key string) string {
+	if len(r.nodes) == 0 {
+		return ""
+	}
+	return r.nodes[r.locate(hash(key))].Name
+}
+
+// successor returns the index of the first point at or after h.
+func (r *Ring) successor(h uint64) int {
+	i, _ := slices.BinarySearchFunc(r.points, h, func(p point, h uint64) int { return cmp.Compare(p.hash, h) })
+	if i == len(r.points) {
+		i = 0 // wrap around
+	}
+	return i
+}
+
+func (r *Ring) locate(h uint64) int { return r.points[r.successor(h)].node }
+
+// Assign places key like Get, but skips nodes that already hold their
+// capacity: LoadFactor times their weighted share of the keys assigned so
+// far, plus this one, rounded up. No node then ends up with more than
+// about LoadFactor times its fair share, while keys still move little when
+// nodes change. It returns "" if the ring is empty.
+func (r *Ring) Assign(key string) string {
+	if len(r.nodes) == 0 {
+		return ""
+	}
+	return r.nodes[r.assign(hash(key))].Name
+}
+
+func (r *Ring) assign(h uint64) int {
+	// The capacities add up to at least LoadFactor·(keys+1), more than the
+	// keys assigned, so some node has room and the walk ends within one
+	// turn of the ring.
+	start := r.successor(h)
+	for k := range r.points {
+		i := r.points[(start+k)%len(r.points)].node
+		if m := &r.nodes[i]; m.load < r.capacity(m.Weight) {
+			m.load++
+			r.keys++
+			if k > 0 {
+				r.skips++
+			}
+			return i
+		}
+	}
+	panic("ring: no node below capacity")
+}
+
+func (r *Ring) capacity(weight float64) int {
+	return int(math.Ceil(r.cfg.LoadFactor * float64(r.keys+1) * weight / r.weight))
+}
+
+// Skipped returns how many Assign calls passed over a node at capacity and
+// so placed their key somewhere other than Get would. With the load factor
+// close to 1 or few virtual nodes this is a good part of the keys; with the
+// defaults, hardly any.
+func (r *Ring) Skipped() int { return r.skips }
+
+// Release undoes one Assign to the named node.
+func (r *Ring) Release(name string) {
+	if i, ok := r.find(name); ok && r.nodes[i].load > 0 {
+		r.nodes[i].load--
+		r.keys--
+	}
+}
+
+// Load returns the number of keys assigned to the named node.
+func (r *Ring) Load(name string) int {
+	if i, ok := r.find(name); ok {
+		return r.nodes[i].load
+	}
+	return 0
+}
+
+// Shares returns the fraction of the hash space each node owns, which is
+// the fraction of keys it gets from Get on average. With one virtual node
+// per node the largest share is about ln n / n; more virtual nodes bring
+// every share closer to the node's weight over the total.
+func (r *Ring) Shares() map[string]float64 {
+	shares := make(map[string]float64, len(r.nodes))
+	for _, n := range r.nodes {
+		shares[n.Name] = 0
+	}
+	for i, p := range r.points {
+		prev := r.points[(i+len(r.points)-1)%len(r.points)].hash
+		shares[r.nodes[p.node].Name] += float64(p.hash-prev) / (1 << 64) // wraps correctly for the first point
+	}
+	if len(r.points) == 1 {
+		shares[r.nodes[0].Name] = 1
+	}
+	return shares
+}
+
+// hash is FNV-1a followed by the splitmix64 finalizer. FNV alone leaves
+// the names "node-1#1" and "node-1#2" close together on the ring.
+func hash[S string | []byte](s S) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return mix64(h)
+}
+
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
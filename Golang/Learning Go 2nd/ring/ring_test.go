@@ -0,0 +1,133 @@
+package ring
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func nodes(n int) []Node {
+	out := make([]Node, n)
+	for i := range out {
+		out[i] = Node{Name: "node-" + strconv.Itoa(i), Weight: 1}
+	}
+	return out
+}
+
+// TestAssignBound assigns keys with few virtual nodes and a tight load
+// factor, where the bound decides where many keys go.
+func TestAssignBound(t *testing.T) {
+	tests := []struct {
+		replicas   int
+		loadFactor float64
+		skips      bool // whether some Assign must pass over a full node
+	}{
+		{1, 1.05, true},
+		{10, 1.05, true},
+		{1, 1.25, true},
+		{1000, 2, false},
+	}
+	for _, tt := range tests {
+		r, err := New(Config{Replicas: tt.replicas, LoadFactor: tt.loadFactor})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Add(nodes(10)...); err != nil {
+			t.Fatal(err)
+		}
+		const keys = 20000
+		moved := 0
+		for i := range keys {
+			key := "key-" + strconv.Itoa(i)
+			if r.Assign(key) != r.Get(key) {
+				moved++
+			}
+		}
+		limit := int(math.Ceil(tt.loadFactor * keys / 10))
+		for _, n := range r.Nodes() {
+			if load := r.Load(n.Name); load > limit {
+				t.Errorf("replicas %d, load factor %g: %s holds %d keys, want at most %d", tt.replicas, tt.loadFactor, n.Name, load, limit)
+			}
+		}
+		if r.Skipped() != moved {
+			t.Errorf("replicas %d, load factor %g: Skipped() = %d, want %d keys placed off their Get node", tt.replicas, tt.loadFactor, r.Skipped(), moved)
+		}
+		if tt.skips != (moved > 0) {
+			t.Errorf("replicas %d, load factor %g: %d keys skipped a full node", tt.replicas, tt.loadFactor, moved)
+		}
+	}
+}
+
+// TestSimulateSkipped checks that only Bounded reports skips, and that they
+// buy a balance within the load factor.
+func TestSimulateSkipped(t *testing.T) {
+	reports, err := Simulate(Simulation{
+		Nodes:      nodes(10),
+		Change:     Change{Add: []Node{{Name: "node-10"}}},
+		Keys:       50000,
+		Strategies: []Strategy{Consistent, Bounded},
+	}, Config{Replicas: 1, LoadFactor: 1.05})
+	if err != nil {
+		t.Fatal(err)
+	}
+	consistent, bounded := reports[0], reports[1]
+	if consistent.Before.Skipped != 0 || consistent.After.Skipped != 0 {
+		t.Errorf("consistent skipped %d and %d keys, want none", consistent.Before.Skipped, consistent.After.Skipped)
+	}
+	if bounded.Before.Skipped == 0 || bounded.After.Skipped == 0 {
+		t.Errorf("bounded skipped %d and %d keys with one virtual node each, want some", bounded.Before.Skipped, bounded.After.Skipped)
+	}
+	for _, b := range []Balance{bounded.Before, bounded.After} {
+		if b.Max > 1.05+0.001 {
+			t.Errorf("bounded max load = %.3f, want at most 1.05", b.Max)
+		}
+	}
+	if consistent.Before.Max <= 1.05 {
+		t.Errorf("consistent max load = %.3f with one virtual node each, want above 1.05", consistent.Before.Max)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		cfg Config
+		ok  bool
+	}{
+		{Config{}, true},
+		{Config{Replicas: 1, LoadFactor: 1.01}, true},
+		{Config{Replicas: -1}, false},
+		{Config{LoadFactor: 1}, false},
+		{Config{LoadFactor: 0.5}, false},
+		{Config{Replicas: maxVnodes}, true},
+		{Config{Replicas: maxVnodes + 1}, false},
+	}
+	for _, tt := range tests {
+		_, err := tt.cfg.Resolve()
+		if (err == nil) != tt.ok {
+			t.Errorf("%+v.Resolve() err = %v, want ok %v", tt.cfg, err, tt.ok)
+		}
+	}
+}
+
+func TestAddWeight(t *testing.T) {
+	r, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add(Node{"a", 100}, Node{"b", 0.001}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.points) != 16001 {
+		t.Errorf("%d virtual nodes, want 16001", len(r.points))
+	}
+	// Weights past the cap, including ones that would overflow int, are
+	// refused rather than allocated or turned into a single point.
+	for _, w := range []float64{1e6, 1e300, math.MaxFloat64} {
+		if err := r.Add(Node{"c", w}); err == nil || !strings.Contains(err.Error(), "more than 1048576") {
+			t.Errorf("weight %g: %v", w, err)
+		}
+	}
+	if r.Len() != 2 || len(r.points) != 16001 {
+		t.Errorf("a refused Add changed the ring: %d nodes, %d virtual nodes", r.Len(), len(r.points))
+	}
+}
@@ -0,0 +1,284 @@
+package ring
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Strategy is a way of placing keys that Simulate can measure.
+type Strategy int
+
+const (
+	Consistent Strategy = iota // Ring.Get
+	Bounded                    // Ring.Assign, with keys assigned in order
+	HRW                        // highest random weight: Rendezvous.Get
+	Modulo                     // hash mod the number of nodes, the baseline; ignores weights
+)
+
+var strategyNames = [...]string{
+	Consistent: "consistent",
+	Bounded:    "bounded",
+	HRW:        "rendezvous",
+	Modulo:     "modulo",
+}
+
+func (s Strategy) String() string {
+	if s >= 0 && int(s) < len(strategyNames) {
+		return strategyNames[s]
+	}
+	return fmt.Sprintf("Strategy(%d)", int(s))
+}
+
+func (s Strategy) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// ParseStrategy is the inverse of Strategy.String.
+func ParseStrategy(s string) (Strategy, error) {
+	for st, name := range strategyNames {
+		if name == s {
+			return Strategy(st), nil
+		}
+	}
+	return 0, fmt.Errorf("ring: unknown strategy %q (want one of %s)", s, strings.Join(strategyNames[:], ", "))
+}
+
+// Strategies returns every strategy.
+func Strategies() []Strategy {
+	all := make([]Strategy, len(strategyNames))
+	for i := range all {
+		all[i] = Strategy(i)
+	}
+	return all
+}
+
+// Change is a set of nodes joining or leaving.
+type Change struct {
+	Add    []Node   `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+}
+
+func (c Change) String() string {
+	var parts []string
+	if len(c.Add) > 0 {
+		names := make([]string, len(c.Add))
+		for i, n := range c.Add {
+			names[i] = n.Name
+		}
+		parts = append(parts, "add "+strings.Join(names, ", "))
+	}
+	if len(c.Remove) > 0 {
+		parts = append(parts, "remove "+strings.Join(c.Remove, ", "))
+	}
+	if parts == nil {
+		return "no change"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// apply returns the nodes left after c, sorted by name.
+func (c Change) apply(nodes []Node) ([]Node, error) {
+	kept := slices.Clone(nodes)
+	for _, name := range c.Remove {
+		i := slices.IndexFunc(kept, func(n Node) bool { return n.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("ring: no node %q to remove", name)
+		}
+		kept = slices.Delete(kept, i, i+1)
+	}
+	return addNodes(kept, c.Add)
+}
+
+// Simulation describes an experiment for Simulate.
+type Simulation struct {
+	Nodes      []Node     `json:"nodes"`
+	Change     Change     `json:"change"`
+	Keys       int        `json:"keys"`       // keys "key-0", "key-1", ...
+	Strategies []Strategy `json:"strategies"` // all if empty
+}
+
+// Report is what Simulate measured for one strategy.
+type Report struct {
+	Strategy Strategy `json:"strategy"`
+	Keys     int      `json:"keys"`
+	Moved    int      `json:"moved"` // keys on a different node after the change
+	// Ideal is the fewest keys any placement has to move: those on the
+	// nodes that left, plus the fair share of the nodes that joined.
+	Ideal    int           `json:"ideal"`
+	Before   Balance       `json:"before"`
+	After    Balance       `json:"after"`
+	Duration time.Duration `json:"duration"` // placing every key before and after
+}
+
+// Balance compares the keys on each node with its fair share, the keys
+// times its weight over the total weight. 1 is a perfect balance.
+// Skipped counts the keys Bounded placed past a full node, which is zero
+// when the bound never mattered.
+type Balance struct {
+	Max     float64 `json:"max"`
+	Min     float64 `json:"min"`
+	StdDev  float64 `json:"stddev"`
+	Skipped int     `json:"skipped"`
+}
+
+// maxNodes is the most nodes Simulate can tell apart; it stores a uint16
+// per key to keep millions of keys cheap.
+const maxNodes = math.MaxUint16
+
+// Simulate places s.Keys keys on s.Nodes, applies s.Change, places them
+// again and reports, for each strategy, how many keys moved and how evenly
+// they were spread. Rings are configured by cfg and then opts.
+func Simulate(s Simulation, cfg Config, opts ...Option) ([]Report, error) {
+	cfg, err := cfg.Resolve(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if s.Keys < 1 {
+		return nil, fmt.Errorf("ring: Simulate needs at least one key, got %d", s.Keys)
+	}
+	before, err := addNodes(nil, s.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.Change.apply(before)
+	if err != nil {
+		return nil, err
+	}
+	if len(before) == 0 || len(after) == 0 {
+		return nil, fmt.Errorf("ring: Simulate needs nodes before and after the change")
+	}
+
+	// Nodes are numbered across both sets, so a key that stays on a node
+	// keeps its number.
+	ids := map[string]uint16{}
+	for _, n := range slices.Concat(before, after) {
+		if _, ok := ids[n.Name]; !ok {
+			if len(ids) == maxNodes {
+				return nil, fmt.Errorf("ring: Simulate supports at most %d nodes", maxNodes)
+			}
+			ids[n.Name] = uint16(len(ids))
+		}
+	}
+	strategies := s.Strategies
+	if len(strategies) == 0 {
+		strategies = Strategies()
+	}
+
+	owner := make([]uint16, s.Keys)
+	var reports []Report
+	for _, st := range strategies {
+		start := time.Now()
+		rep := Report{Strategy: st, Keys: s.Keys}
+
+		locate, skipped, err := placer(st, cfg, before)
+		if err != nil {
+			return nil, err
+		}
+		global := globalIDs(before, ids)
+		loads := make([]int, len(ids))
+		eachKey(s.Keys, func(i int, h uint64) {
+			owner[i] = global[locate(h)]
+			loads[owner[i]]++
+		})
+		rep.Before = balance(before, ids, loads, s.Keys)
+		rep.Before.Skipped = skipped()
+		for _, name := range s.Change.Remove {
+			rep.Ideal += loads[ids[name]]
+		}
+
+		if locate, skipped, err = placer(st, cfg, after); err != nil {
+			return nil, err
+		}
+		global = globalIDs(after, ids)
+		clear(loads)
+		eachKey(s.Keys, func(i int, h uint64) {
+			id := global[locate(h)]
+			if id != owner[i] {
+				rep.Moved++
+			}
+			loads[id]++
+		})
+		rep.After = balance(after, ids, loads, s.Keys)
+		rep.After.Skipped = skipped()
+		var added, total float64
+		for _, n := range after {
+			total += n.Weight
+		}
+		for _, n := range s.Change.Add {
+			added += cmp.Or(n.Weight, 1) // as addNodes
+		}
+		rep.Ideal += int(math.Round(float64(s.Keys) * added / total))
+		rep.Duration = time.Since(start)
+		reports = append(reports, rep)
+	}
+	return reports, nil
+}
+
+// placer returns a function from key hashes to indexes in nodes, which are
+// sorted by name as Ring and Rendezvous keep them, and one that reports how
+// many keys it has placed past a full node so far.
+func placer(st Strategy, cfg Config, nodes []Node) (locate func(uint64) int, skipped func() int, err error) {
+	none := func() int { return 0 }
+	switch st {
+	case Consistent, Bounded:
+		r, err := New(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := r.Add(nodes...); err != nil {
+			return nil, nil, err
+		}
+		if st == Bounded {
+			return r.assign, r.Skipped, nil
+		}
+		return r.locate, none, nil
+	case HRW:
+		r, err := NewRendezvous(nodes...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return r.locate, none, nil
+	case Modulo:
+		n := uint64(len(nodes))
+		return func(h uint64) int { return int(h % n) }, none, nil
+	}
+	return nil, nil, fmt.Errorf("ring: unknown strategy %v", st)
+}
+
+func globalIDs(nodes []Node, ids map[string]uint16) []uint16 {
+	global := make([]uint16, len(nodes))
+	for i, n := range nodes {
+		global[i] = ids[n.Name]
+	}
+	return global
+}
+
+// eachKey calls f with the index and hash of every key, without building
+// the key strings.
+func eachKey(keys int, f func(i int, h uint64)) {
+	buf := []byte("key-")
+	for i := range keys {
+		f(i, hash(strconv.AppendInt(buf[:4], int64(i), 10)))
+	}
+}
+
+func balance(nodes []Node, ids map[string]uint16, loads []int, keys int) Balance {
+	var total float64
+	for _, n := range nodes {
+		total += n.Weight
+	}
+	b := Balance{Min: math.Inf(1)}
+	var sum, sumSq float64
+	for _, n := range nodes {
+		r := float64(loads[ids[n.Name]]) / (float64(keys) * n.Weight / total)
+		b.Max, b.Min = max(b.Max, r), min(b.Min, r)
+		sum += r
+		sumSq += r * r
+	}
+	mean := sum / float64(len(nodes))
+	b.StdDev = math.Sqrt(max(0, sumSq/float64(len(nodes))-mean*mean))
+	return b
+}
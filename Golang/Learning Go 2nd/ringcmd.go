@@ -0,0 +1,230 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/ring"
+)
+
+// sweepReplicas are the virtual node counts compared by the ring command,
+// on at most sweepKeys keys to keep it quick. The sweep bounds loads at
+// sweepLoadFactor, or -load-factor if that is tighter: at the default 1.25
+// the bound hardly ever binds once there are a few virtual nodes, and the
+// bounded rows would only repeat the consistent ones.
+var sweepReplicas = []int{1, 10, 100, 1000}
+
+const (
+	sweepKeys       = 200000
+	sweepLoadFactor = 1.05
+)
+
+func runRing(ctx *cli.Context) error {
+	fs := ctx.Flags
+	n := fs.Int("nodes", 10, "nodes on the ring, named node-0, node-1, ...")
+	weights := fs.String("weights", "", "comma-separated node weights, e.g. 'node-0=2,node-1=0.5' (default 1 each)")
+	keys := fs.Int("keys", 1000000, "keys placed in each simulation")
+	add := fs.Int("add", 1, "nodes joining in the first simulation")
+	remove := fs.Int("remove", 1, "nodes leaving in the second simulation, from node-0 on")
+	strategies := fs.String("strategies", "", "comma-separated strategies: consistent, bounded, rendezvous, modulo (default all)")
+	replicas := fs.Int("replicas", 0, "virtual nodes per unit of weight (default 160)")
+	loadFactor := fs.Float64("load-factor", 0, "bounded loads keep nodes under this times their fair share (default 1.25)")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	var opts []ring.Option
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "replicas":
+			opts = append(opts, ring.WithReplicas(*replicas))
+		case "load-factor":
+			opts = append(opts, ring.WithLoadFactor(*loadFactor))
+		}
+	})
+	cfg, err := ring.Config{}.Resolve(opts...)
+	if err != nil {
+		return err
+	}
+	nodes, err := ringNodes(*n, *weights)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return placeKeys(ctx, cfg, nodes, fs.Args())
+	}
+
+	var sts []ring.Strategy
+	if *strategies != "" {
+		for _, name := range strings.Split(*strategies, ",") {
+			st, err := ring.ParseStrategy(strings.TrimSpace(name))
+			if err != nil {
+				return err
+			}
+			sts = append(sts, st)
+		}
+	}
+	if *remove >= *n {
+		return fmt.Errorf("ring: cannot remove %d of %d nodes", *remove, *n)
+	}
+	var changes []ring.Change
+	if *add > 0 {
+		var c ring.Change
+		for i := range *add {
+			c.Add = append(c.Add, ring.Node{Name: "node-" + strconv.Itoa(*n+i)})
+		}
+		changes = append(changes, c)
+	}
+	if *remove > 0 {
+		var c ring.Change
+		for i := range *remove {
+			c.Remove = append(c.Remove, "node-"+strconv.Itoa(i))
+		}
+		changes = append(changes, c)
+	}
+
+	type simulation struct {
+		Change  ring.Change   `json:"change"`
+		Reports []ring.Report `json:"reports"`
+	}
+	var sims []simulation
+	for _, c := range changes {
+		reports, err := ring.Simulate(ring.Simulation{Nodes: nodes, Change: c, Keys: *keys, Strategies: sts}, cfg)
+		if err != nil {
+			return err
+		}
+		sims = append(sims, simulation{c, reports})
+	}
+
+	// The sweep shows what virtual nodes buy, and what bounded loads add
+	// when there are few of them.
+	type sweep struct {
+		Replicas   int         `json:"replicas"`
+		Consistent ring.Report `json:"consistent"`
+		Bounded    ring.Report `json:"bounded"`
+	}
+	var sweeps []sweep
+	if len(changes) > 0 {
+		for _, r := range sweepReplicas {
+			c := cfg
+			c.Replicas = r
+			c.LoadFactor = min(c.LoadFactor, sweepLoadFactor)
+			reports, err := ring.Simulate(ring.Simulation{
+				Nodes:      nodes,
+				Change:     changes[0],
+				Keys:       min(*keys, sweepKeys),
+				Strategies: []ring.Strategy{ring.Consistent, ring.Bounded},
+			}, c)
+			if err != nil {
+				return err
+			}
+			sweeps = append(sweeps, sweep{r, reports[0], reports[1]})
+		}
+	}
+
+	if ctx.JSON() {
+		return ctx.Encode(struct {
+			Config ring.Config  `json:"config"`
+			Nodes  []ring.Node  `json:"nodes"`
+			Runs   []simulation `json:"simulations"`
+			Sweep  []sweep      `json:"sweep"`
+		}{cfg, nodes, sims, sweeps})
+	}
+
+	fmt.Printf("%d nodes, %d keys, %d virtual nodes per unit of weight, load factor %g\n", len(nodes), *keys, cfg.Replicas, cfg.LoadFactor)
+	pct := func(n, of int) string { return fmt.Sprintf("%.2f%%", 100*float64(n)/float64(of)) }
+	for _, s := range sims {
+		fmt.Printf("\n%s\n", s.Change)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "strategy\tmoved\tideal\tmax load before\tmax load after\tstddev after\tskipped after\ttime\t")
+		for _, r := range s.Reports {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%.3f\t%.3f\t%.3f\t%s\t%v\t\n", r.Strategy, pct(r.Moved, r.Keys), pct(r.Ideal, r.Keys),
+				r.Before.Max, r.After.Max, r.After.StdDev, pct(r.After.Skipped, r.Keys), r.Duration.Round(time.Millisecond))
+		}
+		tw.Flush()
+	}
+	if len(sweeps) > 0 {
+		fmt.Printf("\nvirtual nodes, %d keys, %s, bounded at load factor %g (max load is the fullest node over its fair share)\n",
+			sweeps[0].Consistent.Keys, changes[0], min(cfg.LoadFactor, sweepLoadFactor))
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "replicas\tconsistent max load\tmoved\tbounded max load\tmoved\tskipped\t")
+		for _, s := range sweeps {
+			fmt.Fprintf(tw, "%d\t%.3f\t%s\t%.3f\t%s\t%s\t\n", s.Replicas, s.Consistent.Before.Max, pct(s.Consistent.Moved, s.Consistent.Keys),
+				s.Bounded.Before.Max, pct(s.Bounded.Moved, s.Bounded.Keys), pct(s.Bounded.Before.Skipped, s.Bounded.Keys))
+		}
+		tw.Flush()
+	}
+	return nil
+}
+
+// ringNodes names n nodes and applies weights like "node-0=2,node-1=0.5".
+func ringNodes(n int, weights string) ([]ring.Node, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("ring: need at least one node")
+	}
+	nodes := make([]ring.Node, n)
+	for i := range nodes {
+		nodes[i] = ring.Node{Name: "node-" + strconv.Itoa(i), Weight: 1}
+	}
+	if weights == "" {
+		return nodes, nil
+	}
+	for _, item := range strings.Split(weights, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		w, err := strconv.ParseFloat(value, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("ring: bad weight %q, want name=weight", item)
+		}
+		i := -1
+		for j, node := range nodes {
+			if node.Name == name {
+				i = j
+			}
+		}
+		if i < 0 {
+			return nil, fmt.Errorf("ring: weight for unknown node %q", name)
+		}
+		nodes[i].Weight = w
+	}
+	return nodes, nil
+}
+
+// placeKeys prints the node each key goes to under every strategy. Keys
+// are assigned with bounded loads in the order given.
+func placeKeys(ctx *cli.Context, cfg ring.Config, nodes []ring.Node, keys []string) error {
+	r, err := ring.New(cfg)
+	if err != nil {
+		return err
+	}
+	if err := r.Add(nodes...); err != nil {
+		return err
+	}
+	hrw, err := ring.NewRendezvous(nodes...)
+	if err != nil {
+		return err
+	}
+	type placement struct {
+		Key        string `json:"key"`
+		Consistent string `json:"consistent"`
+		Bounded    string `json:"bounded"`
+		Rendezvous string `json:"rendezvous"`
+	}
+	var rows []placement
+	for _, k := range keys {
+		rows = append(rows, placement{k, r.Get(k), r.Assign(k), hrw.Get(k)})
+	}
+	if ctx.JSON() {
+		return ctx.Encode(rows)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "key\tconsistent\tbounded\trendezvous")
+	for _, p := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Key, p.Consistent, p.Bounded, p.Rendezvous)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/suffix"
+	"learning-go.adcon.dev/trie"
+)
+
+// notesIndex is a full-text index over markdown notes: one suffix array over
+// the lowercased concatenation of every file, plus a radix tree of words for
+// completion.
+type notesIndex struct {
+	files  []string
+	starts []int  // offset of every file in the concatenated text
+	text   []byte // original text, for printing results
+	lower  []byte // ASCII-lowercased text, same offsets as text
+	sa     *suffix.Array
+	words  trie.Radix[int]
+}
+
+// asciiLower lowercases A-Z only, so offsets in the result match the input.
+func asciiLower(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		out[i] = c
+	}
+	return out
+}
+
+func buildNotesIndex(root string) (*notesIndex, error) {
+	idx := &notesIndex{}
+	var buf bytes.Buffer
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != root {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		idx.files = append(idx.files, path)
+		idx.starts = append(idx.starts, buf.Len())
+		buf.Write(data)
+		buf.WriteByte(0) // keeps matches from spanning two files
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	idx.text = buf.Bytes()
+	idx.lower = asciiLower(idx.text)
+	idx.sa = suffix.New(idx.lower)
+	for _, w := range bytes.FieldsFunc(idx.lower, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		n, _ := idx.words.Get(string(w))
+		idx.words.Put(string(w), n+1)
+	}
+	return idx, nil
+}
+
+// locate maps an offset in the concatenated text to a file, a 1-based line
+// number and the text of that line.
+func (idx *notesIndex) locate(off int) (file string, line int, text string) {
+	f := sort.SearchInts(idx.starts, off+1) - 1
+	start := idx.starts[f]
+	line = 1 + bytes.Count(idx.text[start:off], []byte{'\n'})
+	lo := bytes.LastIndexByte(idx.text[:off], '\n') + 1
+	lo = max(lo, start)
+	hi := off + bytes.IndexAny(idx.text[off:], "\n\x00")
+	return idx.files[f], line, strings.TrimSpace(string(idx.text[lo:hi]))
+}
+
+func runSearch(ctx *cli.Context) error {
+	fset := ctx.Flags
+	root := fset.String("root", ".", "directory whose markdown notes are indexed")
+	complete := fset.Bool("complete", false, "list indexed words starting with the query instead of searching")
+	limit := fset.Int("limit", 20, "maximum number of results to print")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	if fset.NArg() == 0 {
+		return fmt.Errorf("search: no text given")
+	}
+	query := string(asciiLower([]byte(strings.Join(fset.Args(), " "))))
+	idx, err := buildNotesIndex(*root)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("indexed %d notes, %d bytes, %d distinct words\n", len(idx.files), len(idx.text), idx.words.Len())
+
+	if *complete {
+		type word struct {
+			w string
+			n int
+		}
+		var words []word
+		for w, n := range idx.words.WithPrefix(query) {
+			words = append(words, word{w, n})
+		}
+		sort.SliceStable(words, func(i, j int) bool { return words[i].n > words[j].n })
+		for i, w := range words {
+			if i == *limit {
+				break
+			}
+			fmt.Printf("%6d  %s\n", w.n, w.w)
+		}
+		return nil
+	}
+
+	offsets := idx.sa.Lookup([]byte(query))
+	fmt.Printf("%d matches for %q\n", len(offsets), query)
+	printed := 0
+	seen := map[string]bool{}
+	for _, off := range offsets {
+		file, line, text := idx.locate(off)
+		key := fmt.Sprintf("%s:%d", file, line)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if printed == *limit {
+			fmt.Println("...")
+			break
+		}
+		printed++
+		if len(text) > 100 {
+			cut := 100
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut-- // back up to the start of the rune that 100 falls in
+			}
+			text = text[:cut] + "..."
+		}
+		fmt.Printf("%s: %s\n", key, text)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Bloom is a Bloom filter: a set that may report false positives but never
+// false negatives. Test may be called concurrently; Add and Merge need
+// exclusive access.
+type Bloom struct {
+	m, k  uint64
+	bits  []uint64
+	added uint64
+}
+
+// NewBloom creates a filter of m bits probed by k hash functions.
+func NewBloom(m uint64, k int) (*Bloom, error) {
+	if m == 0 || k <= 0 || k > maxProbes {
+		return nil, fmt.Errorf("sketch: Bloom filter needs m > 0 and 0 < k <= %d, got m=%d k=%d", maxProbes, m, k)
+	}
+	return &Bloom{m: m, k: uint64(k), bits: make([]uint64, (m+63)/64)}, nil
+}
+
+// NewBloomForRate sizes a filter for n items at false-positive rate p:
+// m = -n·ln p / (ln 2)² bits and k = (m/n)·ln 2 hash functions.
+func NewBloomForRate(n uint64, p float64) (*Bloom, error) {
+	m, k, err := bloomSize(n, p)
+	if err != nil {
+		return nil, err
+	}
+	return NewBloom(m, k)
+}
+
+func bloomSize(n uint64, p float64) (uint64, int, error) {
+	if n == 0 || p <= 0 || p >= 1 {
+		return 0, 0, fmt.Errorf("sketch: sizing needs n > 0 and 0 < p < 1, got n=%d p=%g", n, p)
+	}
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	k := max(1, int(math.Round(m/float64(n)*math.Ln2)))
+	return uint64(m), k, nil
+}
+
+func (b *Bloom) Bits() uint64       { return b.m }
+func (b *Bloom) Hashes() int        { return int(b.k) }
+func (b *Bloom) Added() uint64      { return b.added }
+func (b *Bloom) AddString(s string) { b.Add([]byte(s)) }
+
+func (b *Bloom) Add(data []byte) {
+	p := newProbe(data)
+	for j := range b.k {
+		i := p.at(j, b.m)
+		b.bits[i/64] |= 1 << (i % 64)
+	}
+	b.added++
+}
+
+// Test reports whether data may have been added.
+func (b *Bloom) Test(data []byte) bool {
+	p := newProbe(data)
+	for j := range b.k {
+		if i := p.at(j, b.m); b.bits[i/64]&(1<<(i%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Bloom) TestString(s string) bool { return b.Test([]byte(s)) }
+
+// TestAndAdd reports whether data may already be present and adds it. It is
+// the primitive for deduplicating a stream such as print jobs.
+func (b *Bloom) TestAndAdd(data []byte) bool {
+	present := b.Test(data)
+	if !present {
+		b.Add(data)
+	}
+	return present
+}
+
+// FalsePositiveRate is the expected rate (1 - e^(-kn/m))^k for the number of
+// items added so far.
+func (b *Bloom) FalsePositiveRate() float64 {
+	return math.Pow(1-math.Exp(-float64(b.k*b.added)/float64(b.m)), float64(b.k))
+}
+
+// Merge ORs another filter into b, giving the filter of the union of both
+// sets. Added becomes the sum of both counts, an upper bound on the union.
+func (b *Bloom) Merge(o *Bloom) error {
+	if b.m != o.m || b.k != o.k {
+		return fmt.Errorf("%w: m=%d k=%d vs m=%d k=%d", ErrIncompatible, b.m, b.k, o.m, o.k)
+	}
+	for i := range b.bits {
+		b.bits[i] |= o.bits[i]
+	}
+	b.added += o.added
+	return nil
+}
+
+func (b *Bloom) MarshalBinary() ([]byte, error) {
+	buf := writeHeader(make([]byte, 0, 29+8*len(b.bits)), "BLOM")
+	buf = binary.BigEndian.AppendUint64(buf, b.m)
+	buf = binary.BigEndian.AppendUint64(buf, b.k)
+	buf = binary.BigEndian.AppendUint64(buf, b.added)
+	for _, w := range b.bits {
+		buf = binary.BigEndian.AppendUint64(buf, w)
+	}
+	return buf, nil
+}
+
+func (b *Bloom) UnmarshalBinary(data []byte) error {
+	data, err := readHeader(data, "BLOM")
+	if err != nil {
+		return err
+	}
+	r := &reader{data: data}
+	m, k, added := r.u64(), r.u64(), r.u64()
+	if r.err != nil {
+		return r.err
+	}
+	// Check m against the payload before allocating: (m+63)/64 overflows
+	// past 2^64-64, and a large m alone would allocate whatever it says.
+	if m == 0 || k == 0 || k > maxProbes || m > math.MaxUint64-63 || (m+63)/64 != uint64(len(r.data))/8 {
+		return fmt.Errorf("%w: m=%d k=%d with %d bytes of bits", ErrCorrupt, m, k, len(r.data))
+	}
+	bits := make([]uint64, (m+63)/64)
+	for i := range bits {
+		bits[i] = r.u64()
+	}
+	if err := r.done(); err != nil {
+		return err
+	}
+	*b = Bloom{m: m, k: k, bits: bits, added: added}
+	return nil
+}
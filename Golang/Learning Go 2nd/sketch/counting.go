@@ -0,0 +1,117 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// CountingBloom replaces each bit of a Bloom filter with a saturating 8-bit
+// counter, which makes deletion possible. A counter that reached 255 stays
+// there, so removing never creates false negatives.
+type CountingBloom struct {
+	m, k     uint64
+	counters []uint8
+	added    uint64
+}
+
+func NewCountingBloom(m uint64, k int) (*CountingBloom, error) {
+	if m == 0 || k <= 0 || k > maxProbes {
+		return nil, fmt.Errorf("sketch: counting Bloom filter needs m > 0 and 0 < k <= %d, got m=%d k=%d", maxProbes, m, k)
+	}
+	return &CountingBloom{m: m, k: uint64(k), counters: make([]uint8, m)}, nil
+}
+
+// NewCountingBloomForRate sizes the filter like NewBloomForRate.
+func NewCountingBloomForRate(n uint64, p float64) (*CountingBloom, error) {
+	m, k, err := bloomSize(n, p)
+	if err != nil {
+		return nil, err
+	}
+	return NewCountingBloom(m, k)
+}
+
+func (c *CountingBloom) Added() uint64 { return c.added }
+
+func (c *CountingBloom) Add(data []byte) {
+	p := newProbe(data)
+	for j := range c.k {
+		if i := p.at(j, c.m); c.counters[i] < math.MaxUint8 {
+			c.counters[i]++
+		}
+	}
+	c.added++
+}
+
+// Remove deletes one earlier Add of data. Removing something that was never
+// added corrupts the filter, so it reports false when data is surely absent
+// and leaves the counters untouched.
+func (c *CountingBloom) Remove(data []byte) bool {
+	if !c.Test(data) {
+		return false
+	}
+	p := newProbe(data)
+	for j := range c.k {
+		if i := p.at(j, c.m); c.counters[i] < math.MaxUint8 {
+			c.counters[i]--
+		}
+	}
+	c.added--
+	return true
+}
+
+func (c *CountingBloom) Test(data []byte) bool {
+	p := newProbe(data)
+	for j := range c.k {
+		if c.counters[p.at(j, c.m)] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FalsePositiveRate is the expected rate for the items currently present.
+func (c *CountingBloom) FalsePositiveRate() float64 {
+	return math.Pow(1-math.Exp(-float64(c.k*c.added)/float64(c.m)), float64(c.k))
+}
+
+// Merge adds another filter's counters, saturating at 255.
+func (c *CountingBloom) Merge(o *CountingBloom) error {
+	if c.m != o.m || c.k != o.k {
+		return fmt.Errorf("%w: m=%d k=%d vs m=%d k=%d", ErrIncompatible, c.m, c.k, o.m, o.k)
+	}
+	for i, v := range o.counters {
+		c.counters[i] = uint8(min(int(c.counters[i])+int(v), math.MaxUint8))
+	}
+	c.added += o.added
+	return nil
+}
+
+func (c *CountingBloom) MarshalBinary() ([]byte, error) {
+	buf := writeHeader(make([]byte, 0, 29+len(c.counters)), "CBLM")
+	buf = binary.BigEndian.AppendUint64(buf, c.m)
+	buf = binary.BigEndian.AppendUint64(buf, c.k)
+	buf = binary.BigEndian.AppendUint64(buf, c.added)
+	return append(buf, c.counters...), nil
+}
+
+func (c *CountingBloom) UnmarshalBinary(data []byte) error {
+	data, err := readHeader(data, "CBLM")
+	if err != nil {
+		return err
+	}
+	r := &reader{data: data}
+	m, k, added := r.u64(), r.u64(), r.u64()
+	if r.err != nil {
+		return r.err
+	}
+	if m == 0 || k == 0 || k > maxProbes || m != uint64(len(r.data)) {
+		return fmt.Errorf("%w: m=%d k=%d with %d bytes of counters", ErrCorrupt, m, k, len(r.data))
+	}
+	counters := r.bytes(m)
+	if err := r.done(); err != nil {
+		return err
+	}
+	*c = CountingBloom{m: m, k: k, counters: append([]uint8(nil), counters...), added: added}
+	return nil
+}
@@ -0,0 +1,210 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CountMin is a count-min sketch: depth rows of width counters. Estimates
+// never undercount, and with probability 1-δ they overcount by at most ε·N,
+// where N is the total count added, width = ⌈e/ε⌉ and depth = ⌈ln(1/δ)⌉.
+//
+// It also tracks up to capacity heavy-hitter candidates: the keys with the
+// largest estimates seen so far.
+type CountMin struct {
+	width, depth uint64
+	counts       []uint64
+	total        uint64
+	capacity     int
+	candidates   map[string]uint64
+}
+
+// NewCountMin creates a sketch with the given error bounds that tracks up to
+// capacity heavy-hitter candidates (0 disables tracking).
+func NewCountMin(epsilon, delta float64, capacity int) (*CountMin, error) {
+	if epsilon <= 0 || epsilon >= 1 || delta <= 0 || delta >= 1 {
+		return nil, fmt.Errorf("sketch: count-min needs 0 < ε, δ < 1, got ε=%g δ=%g", epsilon, delta)
+	}
+	width := uint64(math.Ceil(math.E / epsilon))
+	depth := uint64(math.Ceil(math.Log(1 / delta)))
+	if depth > maxProbes {
+		return nil, fmt.Errorf("sketch: count-min needs δ >= e^-%d, got δ=%g", maxProbes, delta)
+	}
+	return newCountMin(width, depth, capacity), nil
+}
+
+func newCountMin(width, depth uint64, capacity int) *CountMin {
+	return &CountMin{
+		width:      width,
+		depth:      depth,
+		counts:     make([]uint64, width*depth),
+		capacity:   capacity,
+		candidates: map[string]uint64{},
+	}
+}
+
+func (c *CountMin) Width() uint64 { return c.width }
+func (c *CountMin) Depth() uint64 { return c.depth }
+func (c *CountMin) Total() uint64 { return c.total }
+
+// Epsilon and Delta are the guarantees implied by the sketch dimensions.
+func (c *CountMin) Epsilon() float64 { return math.E / float64(c.width) }
+func (c *CountMin) Delta() float64   { return math.Exp(-float64(c.depth)) }
+
+// cell returns the counter index of key in a row: row r uses the r-th
+// double-hashing probe, offset into its own block of width counters.
+func (c *CountMin) cell(p probe, row uint64) uint64 {
+	return row*c.width + p.at(row, c.width)
+}
+
+func (c *CountMin) Add(key []byte, count uint64) {
+	est := uint64(math.MaxUint64)
+	p := newProbe(key)
+	for row := range c.depth {
+		i := c.cell(p, row)
+		c.counts[i] += count
+		est = min(est, c.counts[i])
+	}
+	c.total += count
+	c.track(string(key), est)
+}
+
+func (c *CountMin) AddString(key string, count uint64) { c.Add([]byte(key), count) }
+
+func (c *CountMin) Estimate(key []byte) uint64 {
+	est := uint64(math.MaxUint64)
+	p := newProbe(key)
+	for row := range c.depth {
+		est = min(est, c.counts[c.cell(p, row)])
+	}
+	return est
+}
+
+func (c *CountMin) EstimateString(key string) uint64 { return c.Estimate([]byte(key)) }
+
+// track keeps key among the candidates if its estimate beats the smallest
+// one when the candidate set is full.
+func (c *CountMin) track(key string, est uint64) {
+	if c.capacity <= 0 {
+		return
+	}
+	if _, ok := c.candidates[key]; ok || len(c.candidates) < c.capacity {
+		c.candidates[key] = est
+		return
+	}
+	minKey, minEst := "", uint64(math.MaxUint64)
+	for k, v := range c.candidates {
+		if v < minEst || (v == minEst && k < minKey) {
+			minKey, minEst = k, v
+		}
+	}
+	if est > minEst {
+		delete(c.candidates, minKey)
+		c.candidates[key] = est
+	}
+}
+
+// HeavyHitter is a key whose estimated count is at least a given share of
+// the total.
+type HeavyHitter struct {
+	Key      string
+	Estimate uint64
+}
+
+// HeavyHitters returns the tracked candidates with an estimate of at least
+// phi·Total, largest first.
+func (c *CountMin) HeavyHitters(phi float64) []HeavyHitter {
+	threshold := uint64(math.Ceil(phi * float64(c.total)))
+	var out []HeavyHitter
+	for k := range c.candidates {
+		if est := c.EstimateString(k); est >= threshold {
+			out = append(out, HeavyHitter{k, est})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Estimate != out[j].Estimate {
+			return out[i].Estimate > out[j].Estimate
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+// Merge adds another sketch's counters and candidates.
+func (c *CountMin) Merge(o *CountMin) error {
+	if c.width != o.width || c.depth != o.depth {
+		return fmt.Errorf("%w: %dx%d vs %dx%d", ErrIncompatible, c.depth, c.width, o.depth, o.width)
+	}
+	for i, v := range o.counts {
+		c.counts[i] += v
+	}
+	c.total += o.total
+	for k := range c.candidates {
+		c.candidates[k] = c.EstimateString(k)
+	}
+	for k := range o.candidates {
+		c.track(k, c.EstimateString(k))
+	}
+	return nil
+}
+
+func (c *CountMin) MarshalBinary() ([]byte, error) {
+	buf := writeHeader(nil, "CMSK")
+	buf = binary.BigEndian.AppendUint64(buf, c.width)
+	buf = binary.BigEndian.AppendUint64(buf, c.depth)
+	buf = binary.BigEndian.AppendUint64(buf, c.total)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(c.capacity))
+	for _, v := range c.counts {
+		buf = binary.BigEndian.AppendUint64(buf, v)
+	}
+	keys := make([]string, 0, len(c.candidates))
+	for k := range c.candidates {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(len(keys)))
+	for _, k := range keys {
+		buf = binary.BigEndian.AppendUint64(buf, uint64(len(k)))
+		buf = append(buf, k...)
+	}
+	return buf, nil
+}
+
+func (c *CountMin) UnmarshalBinary(data []byte) error {
+	data, err := readHeader(data, "CMSK")
+	if err != nil {
+		return err
+	}
+	r := &reader{data: data}
+	width, depth, total, capacity := r.u64(), r.u64(), r.u64(), r.u64()
+	if r.err != nil {
+		return r.err
+	}
+	// Divide rather than multiply: width*depth can wrap around.
+	if width == 0 || depth == 0 || depth > maxProbes || width > uint64(len(r.data))/8/depth {
+		return fmt.Errorf("%w: %dx%d counters with %d bytes left", ErrCorrupt, depth, width, len(r.data))
+	}
+	if capacity > math.MaxInt {
+		return fmt.Errorf("%w: capacity %d", ErrCorrupt, capacity)
+	}
+	s := newCountMin(width, depth, int(capacity))
+	s.total = total
+	for i := range s.counts {
+		s.counts[i] = r.u64()
+	}
+	n := r.u64()
+	if n > capacity {
+		return fmt.Errorf("%w: %d candidates for a capacity of %d", ErrCorrupt, n, capacity)
+	}
+	for i := uint64(0); i < n && r.err == nil; i++ {
+		k := string(r.bytes(r.u64()))
+		s.candidates[k] = s.EstimateString(k)
+	}
+	if err := r.done(); err != nil {
+		return err
+	}
+	*c = *s
+	return nil
+}
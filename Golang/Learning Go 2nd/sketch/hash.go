@@ -0,0 +1,110 @@
+// Package sketch implements probabilistic data structures: Bloom filters,
+// counting Bloom filters, count-min sketches and HyperLogLog. Each one can be
+// serialized with encoding.BinaryMarshaler and merged with another sketch
+// built with the same parameters.
+package sketch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
+
+var (
+	ErrIncompatible = errors.New("sketch: sketches have different parameters")
+	ErrCorrupt      = errors.New("sketch: corrupt serialized data")
+)
+
+// hash64 is FNV-1a followed by the splitmix64 finalizer, which spreads
+// FNV's weak high bits across the whole word. It is deterministic, so
+// serialized sketches stay valid across processes.
+func hash64(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	return mix64(h.Sum64())
+}
+
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// probe derives any number of positions from one hash with double hashing
+// (Kirsch–Mitzenmacher): g_i = h1 + i·h2. It keeps no state, so lookups stay
+// safe for concurrent readers.
+type probe struct{ h1, h2 uint64 }
+
+func newProbe(data []byte) probe {
+	h1 := hash64(data)
+	return probe{h1, mix64(h1) | 1}
+}
+
+// at returns the i-th position in [0, m).
+func (p probe) at(i, m uint64) uint64 { return (p.h1 + i*p.h2) % m }
+
+// maxProbes bounds the hash functions of a Bloom filter and the rows of a
+// count-min sketch. Sizing for any sensible error rate stays far below it;
+// a larger count read from corrupt data would make every lookup spin.
+const maxProbes = 64
+
+// version is written after the four-byte magic of every serialized sketch.
+const version = 1
+
+func writeHeader(buf []byte, magic string) []byte {
+	buf = append(buf, magic...)
+	return append(buf, version)
+}
+
+func readHeader(data []byte, magic string) ([]byte, error) {
+	if len(data) < 5 || string(data[:4]) != magic {
+		return nil, fmt.Errorf("%w: not a %s sketch", ErrCorrupt, magic)
+	}
+	if data[4] != version {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrCorrupt, data[4])
+	}
+	return data[5:], nil
+}
+
+// reader pulls fixed-size big-endian values and remembers the first error.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) u64() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.data) < 8 {
+		r.err = fmt.Errorf("%w: truncated", ErrCorrupt)
+		return 0
+	}
+	v := binary.BigEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return v
+}
+
+func (r *reader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.data)) < n {
+		r.err = fmt.Errorf("%w: truncated", ErrCorrupt)
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) done() error {
+	if r.err == nil && len(r.data) != 0 {
+		r.err = fmt.Errorf("%w: %d trailing bytes", ErrCorrupt, len(r.data))
+	}
+	return r.err
+}
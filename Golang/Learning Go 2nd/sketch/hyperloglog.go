@@ -0,0 +1,99 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// HyperLogLog estimates the number of distinct items with 2^precision
+// one-byte registers. Its relative standard error is 1.04/√(2^precision).
+type HyperLogLog struct {
+	p         uint8
+	registers []uint8
+}
+
+// NewHyperLogLog accepts precisions from 4 (16 registers) to 18.
+func NewHyperLogLog(precision int) (*HyperLogLog, error) {
+	if precision < 4 || precision > 18 {
+		return nil, fmt.Errorf("sketch: HyperLogLog precision must be in [4, 18], got %d", precision)
+	}
+	return &HyperLogLog{p: uint8(precision), registers: make([]uint8, 1<<precision)}, nil
+}
+
+func (h *HyperLogLog) Add(data []byte) {
+	x := hash64(data)
+	i := x >> (64 - h.p)
+	// Rank of the first 1 bit in the remaining bits; the sentinel bit caps it.
+	w := x<<h.p | 1<<(h.p-1)
+	rank := uint8(bits.LeadingZeros64(w)) + 1
+	if rank > h.registers[i] {
+		h.registers[i] = rank
+	}
+}
+
+func (h *HyperLogLog) AddString(s string) { h.Add([]byte(s)) }
+
+// StdError is the expected relative standard error of Count.
+func (h *HyperLogLog) StdError() float64 {
+	return 1.04 / math.Sqrt(float64(len(h.registers)))
+}
+
+// Count returns the cardinality estimate, switching to linear counting for
+// small cardinalities where the raw estimate is biased.
+func (h *HyperLogLog) Count() uint64 {
+	m := float64(len(h.registers))
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	var alpha float64
+	switch len(h.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+	est := alpha * m * m / sum
+	if est <= 2.5*m && zeros > 0 {
+		est = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(est))
+}
+
+// Merge keeps the larger register of each pair, giving the sketch of the
+// union of both streams.
+func (h *HyperLogLog) Merge(o *HyperLogLog) error {
+	if h.p != o.p {
+		return fmt.Errorf("%w: precision %d vs %d", ErrIncompatible, h.p, o.p)
+	}
+	for i, r := range o.registers {
+		h.registers[i] = max(h.registers[i], r)
+	}
+	return nil
+}
+
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	buf := writeHeader(make([]byte, 0, 6+len(h.registers)), "HLLG")
+	buf = append(buf, h.p)
+	return append(buf, h.registers...), nil
+}
+
+func (h *HyperLogLog) UnmarshalBinary(data []byte) error {
+	data, err := readHeader(data, "HLLG")
+	if err != nil {
+		return err
+	}
+	if len(data) < 1 || data[0] < 4 || data[0] > 18 || len(data) != 1+1<<data[0] {
+		return fmt.Errorf("%w: bad HyperLogLog payload", ErrCorrupt)
+	}
+	*h = HyperLogLog{p: data[0], registers: append([]uint8(nil), data[1:]...)}
+	return nil
+}
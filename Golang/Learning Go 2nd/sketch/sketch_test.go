@@ -0,0 +1,280 @@
+package sketch
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"testing"
+)
+
+func TestBloom(t *testing.T) {
+	const n = 20000
+	b, err := NewBloomForRate(n, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range n {
+		b.AddString(fmt.Sprint("in-", i))
+	}
+	fp := 0
+	for i := range n {
+		if !b.TestString(fmt.Sprint("in-", i)) {
+			t.Fatalf("false negative for in-%d", i)
+		}
+		if b.TestString(fmt.Sprint("out-", i)) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / n; rate > 2*b.FalsePositiveRate() {
+		t.Errorf("false-positive rate %.4f, theory %.4f", rate, b.FalsePositiveRate())
+	}
+}
+
+func TestCountingBloomRemove(t *testing.T) {
+	c, err := NewCountingBloomForRate(1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 1000 {
+		c.Add(fmt.Append(nil, i))
+	}
+	for i := 0; i < 1000; i += 2 {
+		if !c.Remove(fmt.Append(nil, i)) {
+			t.Fatalf("Remove(%d) found nothing to remove", i)
+		}
+	}
+	for i := 1; i < 1000; i += 2 {
+		if !c.Test(fmt.Append(nil, i)) {
+			t.Fatalf("false negative for %d after removing its neighbours", i)
+		}
+	}
+	if c.Added() != 500 {
+		t.Errorf("Added = %d, want 500", c.Added())
+	}
+}
+
+// TestCountMin checks the guarantees against exact counts: estimates never
+// undercount, and at least a 1-δ share of them are within ε·N.
+func TestCountMin(t *testing.T) {
+	cm, err := NewCountMin(0.001, 0.01, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipf := rand.NewZipf(rand.New(rand.NewPCG(1, 2)), 1.2, 1, 5000)
+	exact := map[string]uint64{}
+	for range 50000 {
+		k := fmt.Sprint(zipf.Uint64())
+		cm.AddString(k, 1)
+		exact[k]++
+	}
+	bound := cm.Epsilon() * float64(cm.Total())
+	within := 0
+	for k, c := range exact {
+		est := cm.EstimateString(k)
+		if est < c {
+			t.Fatalf("%s: estimate %d under the exact count %d", k, est, c)
+		}
+		if float64(est-c) <= bound {
+			within++
+		}
+	}
+	if share := float64(within) / float64(len(exact)); share < 1-cm.Delta() {
+		t.Errorf("%.4f of keys within ε·N, want at least %.4f", share, 1-cm.Delta())
+	}
+	hh := cm.HeavyHitters(0.05)
+	if len(hh) == 0 || hh[0].Key != "0" {
+		t.Errorf("heavy hitters %v, want the most frequent Zipf value 0 first", hh)
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{10, 1000, 100000} {
+		h, err := NewHyperLogLog(12)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range 2 * n {
+			h.AddString(fmt.Sprint(i % n))
+		}
+		if rel := math.Abs(float64(h.Count())-float64(n)) / float64(n); rel > 4*h.StdError() {
+			t.Errorf("n=%d: estimate %d, relative error %.4f over 4σ = %.4f", n, h.Count(), rel, 4*h.StdError())
+		}
+	}
+}
+
+// TestMergeRoundTrip splits a stream between two sketches of each kind,
+// merges them through a serialization round trip and compares with one
+// sketch of the whole stream.
+func TestMergeRoundTrip(t *testing.T) {
+	newBloom := func() *Bloom { b, _ := NewBloom(4096, 5); return b }
+	newCounting := func() *CountingBloom { c, _ := NewCountingBloom(4096, 5); return c }
+	newCountMin := func() *CountMin { c, _ := NewCountMin(0.01, 0.01, 0); return c }
+	newHLL := func() *HyperLogLog { h, _ := NewHyperLogLog(8); return h }
+
+	b := [3]*Bloom{newBloom(), newBloom(), newBloom()}
+	c := [3]*CountingBloom{newCounting(), newCounting(), newCounting()}
+	cm := [3]*CountMin{newCountMin(), newCountMin(), newCountMin()}
+	h := [3]*HyperLogLog{newHLL(), newHLL(), newHLL()}
+	for i := range 500 {
+		key, part := fmt.Append(nil, "key-", i), i%2
+		for _, j := range []int{part, 2} {
+			b[j].Add(key)
+			c[j].Add(key)
+			cm[j].Add(key, uint64(i))
+			h[j].Add(key)
+		}
+	}
+
+	var rb Bloom
+	var rc CountingBloom
+	var rcm CountMin
+	var rh HyperLogLog
+	for _, tc := range []struct {
+		src encoding.BinaryMarshaler
+		dst encoding.BinaryUnmarshaler
+	}{{b[1], &rb}, {c[1], &rc}, {cm[1], &rcm}, {h[1], &rh}} {
+		if err := roundTrip(tc.src, tc.dst); err != nil {
+			t.Fatalf("%T: %v", tc.src, err)
+		}
+	}
+	if err := errors.Join(b[0].Merge(&rb), c[0].Merge(&rc), cm[0].Merge(&rcm), h[0].Merge(&rh)); err != nil {
+		t.Fatal(err)
+	}
+	for _, pair := range [][2]encoding.BinaryMarshaler{{b[0], b[2]}, {c[0], c[2]}, {cm[0], cm[2]}, {h[0], h[2]}} {
+		got, _ := pair[0].MarshalBinary()
+		want, _ := pair[1].MarshalBinary()
+		if string(got) != string(want) {
+			t.Errorf("%T: merged halves differ from the whole stream", pair[0])
+		}
+	}
+
+	small, _ := NewBloom(64, 5)
+	if err := b[0].Merge(small); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Merge of different sizes: err = %v, want ErrIncompatible", err)
+	}
+}
+
+func roundTrip(src encoding.BinaryMarshaler, dst encoding.BinaryUnmarshaler) error {
+	data, err := src.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return dst.UnmarshalBinary(data)
+}
+
+// payload builds a serialized sketch from a magic and big-endian words.
+func payload(magic string, words ...uint64) []byte {
+	buf := writeHeader(nil, magic)
+	for _, w := range words {
+		buf = binary.BigEndian.AppendUint64(buf, w)
+	}
+	return buf
+}
+
+func TestUnmarshalCorrupt(t *testing.T) {
+	valid, _ := NewBloom(128, 3)
+	good, _ := valid.MarshalBinary()
+	tests := []struct {
+		name string
+		dst  encoding.BinaryUnmarshaler
+		data []byte
+	}{
+		{"empty", &Bloom{}, nil},
+		{"wrong magic", &Bloom{}, payload("CBLM", 128, 3, 0, 0, 0)},
+		{"bad version", &Bloom{}, append([]byte("BLOM\x02"), good[5:]...)},
+		{"truncated", &Bloom{}, good[:len(good)-3]},
+		{"trailing", &Bloom{}, append(good, 0)},
+		{"bloom m=0", &Bloom{}, payload("BLOM", 0, 3, 0)},
+		{"bloom k=0", &Bloom{}, payload("BLOM", 64, 0, 0, 0)},
+		{"bloom k near 2^64", &Bloom{}, payload("BLOM", 64, math.MaxUint64-1, 0, 0)},
+		{"bloom k=65", &Bloom{}, payload("BLOM", 64, 65, 0, 0)},
+		{"bloom m overflows", &Bloom{}, payload("BLOM", math.MaxUint64, 3, 0)},
+		{"bloom m past 2^64-64", &Bloom{}, payload("BLOM", math.MaxUint64-62, 3, 0)},
+		{"bloom m larger than payload", &Bloom{}, payload("BLOM", 1<<40, 3, 0, 0)},
+		{"bloom m smaller than payload", &Bloom{}, payload("BLOM", 64, 3, 0, 0, 0)},
+		{"counting m larger than payload", &CountingBloom{}, payload("CBLM", 1<<40, 3, 0)},
+		{"counting m=2^64-1", &CountingBloom{}, payload("CBLM", math.MaxUint64, 3, 0)},
+		{"counting k near 2^64", &CountingBloom{}, append(payload("CBLM", 8, math.MaxUint64, 0), make([]byte, 8)...)},
+		{"count-min width*depth wraps", &CountMin{}, payload("CMSK", 1<<32, 1<<32, 0, 0, 0, 0)},
+		{"count-min larger than payload", &CountMin{}, payload("CMSK", 1<<20, 4, 0, 0, 0)},
+		{"count-min zero depth", &CountMin{}, payload("CMSK", 1, 0, 0, 0, 0)},
+		{"count-min depth 65", &CountMin{}, append(payload("CMSK", 1, 65, 0, 0), make([]byte, 8*66)...)},
+		{"count-min capacity", &CountMin{}, payload("CMSK", 1, 1, 0, math.MaxUint64, 0, 0)},
+		{"count-min too many candidates", &CountMin{}, payload("CMSK", 1, 1, 0, 0, 0, 1, 0)},
+		{"count-min candidate truncated", &CountMin{}, payload("CMSK", 1, 1, 0, 1, 0, 1, 100)},
+		{"hyperloglog precision", &HyperLogLog{}, append(writeHeader(nil, "HLLG"), 3, 0, 0, 0, 0, 0, 0, 0, 0)},
+		{"hyperloglog registers", &HyperLogLog{}, append(writeHeader(nil, "HLLG"), 4, 0)},
+	}
+	for _, tt := range tests {
+		if err := tt.dst.UnmarshalBinary(tt.data); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: err = %v, want ErrCorrupt", tt.name, err)
+		}
+	}
+	var b Bloom
+	if err := b.UnmarshalBinary(good); err != nil || b.Bits() != 128 || b.Hashes() != 3 {
+		t.Errorf("valid Bloom: %v, m=%d k=%d", err, b.Bits(), b.Hashes())
+	}
+}
+
+// TestProbeLimit checks that constructors refuse what UnmarshalBinary would
+// reject, so every sketch they build survives a round trip.
+func TestProbeLimit(t *testing.T) {
+	if _, err := NewBloom(1024, maxProbes+1); err == nil {
+		t.Errorf("NewBloom with k=%d: no error", maxProbes+1)
+	}
+	if _, err := NewCountingBloom(1024, maxProbes+1); err == nil {
+		t.Errorf("NewCountingBloom with k=%d: no error", maxProbes+1)
+	}
+	if _, err := NewCountMin(0.1, 1e-30, 0); err == nil {
+		t.Error("NewCountMin with δ=1e-30: no error")
+	}
+	b, err := NewBloom(1024, maxProbes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := roundTrip(b, &Bloom{}); err != nil {
+		t.Errorf("Bloom with k=%d: %v", maxProbes, err)
+	}
+}
+
+// TestConcurrentLookups runs lookups from many goroutines at once, as the
+// LSM tree does with its table filters; run it with -race.
+func TestConcurrentLookups(t *testing.T) {
+	const n = 2000
+	b, err := NewBloomForRate(n, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewCountingBloomForRate(n, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm, err := NewCountMin(0.001, 0.01, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range n {
+		key := []byte(fmt.Sprint("in-", i))
+		b.Add(key)
+		c.Add(key)
+		cm.Add(key, 1)
+	}
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := g; i < n; i += 8 {
+				key := []byte(fmt.Sprint("in-", i))
+				if !b.Test(key) || !c.Test(key) || cm.Estimate(key) < 1 {
+					t.Errorf("false negative for %s", key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/sketch"
+)
+
+// kidKey is the JSON encoding of a kid, the identity used when deduplicating
+// imported records.
+func kidKey(k Kid) []byte {
+	b, _ := json.Marshal(k)
+	return b
+}
+
+func runSketch(ctx *cli.Context) error {
+	fs := ctx.Flags
+	n := fs.Int("n", 100000, "items per experiment")
+	rate := fs.Float64("p", 0.01, "target Bloom false-positive rate")
+	seed := fs.Uint64("seed", 1, "random seed")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	rng := rand.New(rand.NewPCG(*seed, *seed))
+
+	// Bloom filter: deduplicate print jobs.
+	bloom, err := sketch.NewBloomForRate(uint64(*n), *rate)
+	if err != nil {
+		return err
+	}
+	for i := range *n {
+		bloom.AddString(fmt.Sprintf("job-%d", i))
+	}
+	fp := 0
+	for i := range *n {
+		if bloom.TestString(fmt.Sprintf("other-job-%d", i)) {
+			fp++
+		}
+	}
+	fmt.Printf("Bloom (m=%d bits, k=%d): false positives %.4f, theory %.4f, target %.4f\n",
+		bloom.Bits(), bloom.Hashes(), float64(fp)/float64(*n), bloom.FalsePositiveRate(), *rate)
+
+	// Counting Bloom filter: remove half of the jobs again.
+	counting, err := sketch.NewCountingBloomForRate(uint64(*n), *rate)
+	if err != nil {
+		return err
+	}
+	for i := range *n {
+		counting.Add(fmt.Appendf(nil, "job-%d", i))
+	}
+	for i := 0; i < *n; i += 2 {
+		counting.Remove(fmt.Appendf(nil, "job-%d", i))
+	}
+	missing, removedSeen := 0, 0
+	for i := range *n {
+		present := counting.Test(fmt.Appendf(nil, "job-%d", i))
+		switch {
+		case i%2 == 1 && !present:
+			missing++
+		case i%2 == 0 && present:
+			removedSeen++
+		}
+	}
+	fmt.Printf("counting Bloom after removing half: false negatives %d, removed still reported %.4f, theory %.4f\n",
+		missing, float64(removedSeen)/float64(*n/2), counting.FalsePositiveRate())
+
+	// Count-min: Zipf-distributed kid records.
+	cm, err := sketch.NewCountMin(0.001, 0.01, 20)
+	if err != nil {
+		return err
+	}
+	zipf := rand.NewZipf(rand.New(rand.NewPCG(*seed, 2)), 1.2, 1, 5000)
+	exact := map[string]uint64{}
+	for range *n {
+		v := int(zipf.Uint64())
+		key := kidKey(Kid{Age: 3 + v%10, Candies: v})
+		cm.Add(key, 1)
+		exact[string(key)]++
+	}
+	bound := cm.Epsilon() * float64(cm.Total())
+	within, worst := 0, uint64(0)
+	for k, c := range exact {
+		over := cm.EstimateString(k) - c
+		worst = max(worst, over)
+		if float64(over) <= bound {
+			within++
+		}
+	}
+	fmt.Printf("count-min (%dx%d): %.4f of keys within ε·N=%.1f (guarantee ≥ %.2f), worst overcount %d\n",
+		cm.Depth(), cm.Width(), float64(within)/float64(len(exact)), bound, 1-cm.Delta(), worst)
+	for _, h := range cm.HeavyHitters(0.05) {
+		fmt.Printf("  heavy hitter %s: estimate %d, exact %d\n", h.Key, h.Estimate, exact[h.Key])
+	}
+
+	// HyperLogLog: unique kids in a large import with many duplicates.
+	for _, cardinality := range []int{100, 10000, *n, 10 * *n} {
+		hll, err := sketch.NewHyperLogLog(12)
+		if err != nil {
+			return err
+		}
+		seen := make([]bool, cardinality)
+		distinct := 0
+		for range 2 * cardinality {
+			age := rng.IntN(cardinality)
+			if !seen[age] {
+				seen[age] = true
+				distinct++
+			}
+			hll.Add(kidKey(Kid{Age: age, Candies: -1}))
+		}
+		rel := math.Abs(float64(hll.Count())-float64(distinct)) / float64(distinct)
+		fmt.Printf("HyperLogLog p=12: distinct %d, estimate %d, error %.4f (std error %.4f)\n",
+			distinct, hll.Count(), rel, hll.StdError())
+	}
+
+	// Serialization round trips and merges must reproduce the same sketch.
+	a, _ := sketch.NewHyperLogLog(10)
+	b, _ := sketch.NewHyperLogLog(10)
+	whole, _ := sketch.NewHyperLogLog(10)
+	for i := range *n {
+		key := fmt.Appendf(nil, "kid-%d", i)
+		whole.Add(key)
+		if i%2 == 0 {
+			a.Add(key)
+		} else {
+			b.Add(key)
+		}
+	}
+	if err := a.Merge(b); err != nil {
+		return err
+	}
+	var restored sketch.HyperLogLog
+	if err := roundTrip(a, &restored); err != nil {
+		return err
+	}
+	fmt.Printf("HyperLogLog merge+round trip: %d, single sketch: %d\n", restored.Count(), whole.Count())
+	var restoredBloom sketch.Bloom
+	if err := roundTrip(bloom, &restoredBloom); err != nil {
+		return err
+	}
+	fmt.Printf("Bloom round trip still finds job-42: %v\n", restoredBloom.TestString("job-42"))
+	return nil
+}
+
+func roundTrip(src encoding.BinaryMarshaler, dst encoding.BinaryUnmarshaler) error {
+	data, err := src.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return dst.UnmarshalBinary(data)
+}
@@ -0,0 +1,176 @@
+package skiplist_test
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"learning-go.adcon.dev/avl"
+	"learning-go.adcon.dev/skiplist"
+)
+
+// orderedMap is what the benchmarks need from each implementation;
+// *skiplist.SkipList, *avl.Tree and *sortedSlice have it.
+type orderedMap interface {
+	Put(k, v int) bool
+	Get(k int) (int, bool)
+	Rank(k int) int
+	From(lo int) iter.Seq2[int, int]
+}
+
+// sortedSlice is the baseline: parallel sorted slices searched with binary
+// search. Inserts shift the tail, so they cost O(n).
+type sortedSlice struct {
+	keys, values []int
+}
+
+func (m *sortedSlice) Put(k, v int) bool {
+	i, found := slices.BinarySearch(m.keys, k)
+	if found {
+		m.values[i] = v
+		return false
+	}
+	m.keys = slices.Insert(m.keys, i, k)
+	m.values = slices.Insert(m.values, i, v)
+	return true
+}
+
+func (m *sortedSlice) Get(k int) (int, bool) {
+	if i, found := slices.BinarySearch(m.keys, k); found {
+		return m.values[i], true
+	}
+	return 0, false
+}
+
+func (m *sortedSlice) Rank(k int) int {
+	i, _ := slices.BinarySearch(m.keys, k)
+	return i
+}
+
+func (m *sortedSlice) From(lo int) iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		for i := m.Rank(lo); i < len(m.keys); i++ {
+			if !yield(m.keys[i], m.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+var impls = []struct {
+	name string
+	make func() orderedMap
+}{
+	{"sorted-slice", func() orderedMap { return &sortedSlice{} }},
+	{"avl", func() orderedMap { return avl.NewOrdered[int, int]() }},
+	{"skiplist-p=1/4", func() orderedMap { return newSkipList(0.25) }},
+	{"skiplist-p=1/2", func() orderedMap { return newSkipList(0.5) }},
+}
+
+var sizes = []int{1000, 100000}
+
+func newSkipList(p float64) *skiplist.SkipList[int, int] {
+	s, _ := skiplist.New[int, int](cmp.Compare[int], skiplist.Config{P: p, Seed: 1})
+	return s
+}
+
+// filled returns keys in random order and a map holding all of them.
+func filled(n int, make func() orderedMap) ([]int, orderedMap) {
+	keys := rand.New(rand.NewPCG(1, 1)).Perm(n)
+	m := make()
+	for _, k := range keys {
+		m.Put(k, k)
+	}
+	return keys, m
+}
+
+// run runs op as a sub-benchmark for every implementation and size.
+func run(b *testing.B, op func(b *testing.B, keys []int, m orderedMap)) {
+	for _, impl := range impls {
+		for _, n := range sizes {
+			b.Run(fmt.Sprintf("%s/n=%d", impl.name, n), func(b *testing.B) {
+				keys, m := filled(n, impl.make)
+				b.ResetTimer()
+				op(b, keys, m)
+			})
+		}
+	}
+}
+
+func BenchmarkPut(b *testing.B) {
+	for _, impl := range impls {
+		for _, n := range sizes {
+			b.Run(fmt.Sprintf("%s/n=%d", impl.name, n), func(b *testing.B) {
+				keys := rand.New(rand.NewPCG(1, 1)).Perm(n)
+				for b.Loop() {
+					m := impl.make()
+					for _, k := range keys {
+						m.Put(k, k)
+					}
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/put")
+			})
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	run(b, func(b *testing.B, keys []int, m orderedMap) {
+		i := 0
+		for b.Loop() {
+			m.Get(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkRank(b *testing.B) {
+	run(b, func(b *testing.B, keys []int, m orderedMap) {
+		i := 0
+		for b.Loop() {
+			m.Rank(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkScan100(b *testing.B) {
+	run(b, func(b *testing.B, keys []int, m orderedMap) {
+		i := 0
+		for b.Loop() {
+			left := 100
+			for range m.From(keys[i%len(keys)]) {
+				if left--; left == 0 {
+					break
+				}
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkConcurrentGet(b *testing.B) {
+	for _, n := range sizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			c, err := skiplist.NewConcurrent[int, int](cmp.Compare[int], skiplist.Config{Seed: 1})
+			if err != nil {
+				b.Fatal(err)
+			}
+			keys := rand.New(rand.NewPCG(1, 1)).Perm(n)
+			for _, k := range keys {
+				c.Put(k, k)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					c.Get(keys[i%n])
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package skiplist
+
+import (
+	"iter"
+	"sync"
+)
+
+// Concurrent guards a SkipList with a read-write mutex, so any number of
+// readers proceed in parallel while writers get exclusive access.
+//
+// Iterators hold the read lock until the loop finishes; writing to the same
+// list from inside the loop body deadlocks.
+type Concurrent[K, V any] struct {
+	mu   sync.RWMutex
+	list *SkipList[K, V]
+}
+
+func NewConcurrent[K, V any](compare func(a, b K) int, cfg Config, opts ...Option) (*Concurrent[K, V], error) {
+	list, err := New[K, V](compare, cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Concurrent[K, V]{list: list}, nil
+}
+
+func (c *Concurrent[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.list.Len()
+}
+
+func (c *Concurrent[K, V]) Put(key K, value V) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.list.Put(key, value)
+}
+
+func (c *Concurrent[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.list.Delete(key)
+}
+
+func (c *Concurrent[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.list.Get(key)
+}
+
+func (c *Concurrent[K, V]) Rank(key K) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.list.Rank(key)
+}
+
+func (c *Concurrent[K, V]) At(i int) (K, V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.list.At(i)
+}
+
+func (c *Concurrent[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return c.locked(c.list.Range(lo, hi))
+}
+
+func (c *Concurrent[K, V]) From(lo K) iter.Seq2[K, V] {
+	return c.locked(c.list.From(lo))
+}
+
+func (c *Concurrent[K, V]) All() iter.Seq2[K, V] {
+	return c.locked(c.list.All())
+}
+
+func (c *Concurrent[K, V]) locked(seq iter.Seq2[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		seq(yield)
+	}
+}
@@ -0,0 +1,273 @@
+// Package skiplist implements an ordered map as a skip list. Every forward
+// pointer also records how many elements it skips, which makes rank and
+// select queries O(log n) like lookups.
+package skiplist
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"math/rand/v2"
+
+	"learning-go.adcon.dev/options"
+)
+
+const (
+	DefaultMaxLevel = 32
+	DefaultP        = 0.25
+)
+
+// SkipList is an ordered map from K to V. It is not safe for concurrent use;
+// see Concurrent.
+type SkipList[K, V any] struct {
+	cmp      func(a, b K) int
+	head     *node[K, V]
+	level    int
+	length   int
+	maxLevel int
+	p        float64
+	rng      *rand.Rand
+	update   []*node[K, V] // scratch space for Put and Delete
+	rank     []int
+}
+
+type node[K, V any] struct {
+	key   K
+	value V
+	next  []link[K, V]
+}
+
+type link[K, V any] struct {
+	node *node[K, V]
+	span int // number of elements this link moves forward
+}
+
+// Config tunes a skip list. Zero fields take the defaults.
+type Config struct {
+	MaxLevel int     `json:"max_level"` // maximum tower height, DefaultMaxLevel if 0
+	P        float64 `json:"p"`         // probability of growing a tower by one level, DefaultP if 0
+	Seed     uint64  `json:"seed"`      // seed of the level generator
+}
+
+// DefaultConfig returns the settings used for zero Config fields.
+func DefaultConfig() Config { return Config{MaxLevel: DefaultMaxLevel, P: DefaultP} }
+
+// EnvPrefix starts the environment variables that set Config fields, as in
+// SKIPLIST_MAX_LEVEL.
+const EnvPrefix = "SKIPLIST"
+
+// Option sets one Config field; see options.Apply.
+type Option = options.Option[Config]
+
+func WithMaxLevel(n int) Option   { return options.New[Config]("max_level", n) }
+func WithP(p float64) Option      { return options.New[Config]("p", p) }
+func WithSeed(seed uint64) Option { return options.New[Config]("seed", seed) }
+
+// Resolve applies opts over c, fills in defaults and validates the result,
+// as New does.
+func (c Config) Resolve(opts ...Option) (Config, error) {
+	c, err := options.Apply(c, opts...)
+	if err != nil {
+		return c, fmt.Errorf("skiplist: %w", err)
+	}
+	if c.MaxLevel == 0 {
+		c.MaxLevel = DefaultMaxLevel
+	}
+	if c.P == 0 {
+		c.P = DefaultP
+	}
+	if c.MaxLevel < 1 || c.MaxLevel > 64 {
+		return c, fmt.Errorf("skiplist: MaxLevel must be in [1, 64], got %d", c.MaxLevel)
+	}
+	if c.P <= 0 || c.P >= 1 {
+		return c, fmt.Errorf("skiplist: P must be in (0, 1), got %g", c.P)
+	}
+	return c, nil
+}
+
+// New creates a skip list ordered by compare, configured by cfg and then
+// opts.
+func New[K, V any](compare func(a, b K) int, cfg Config, opts ...Option) (*SkipList[K, V], error) {
+	cfg, err := cfg.Resolve(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &SkipList[K, V]{
+		cmp:      compare,
+		head:     &node[K, V]{next: make([]link[K, V], cfg.MaxLevel)},
+		level:    1,
+		maxLevel: cfg.MaxLevel,
+		p:        cfg.P,
+		rng:      rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15)),
+		update:   make([]*node[K, V], cfg.MaxLevel),
+		rank:     make([]int, cfg.MaxLevel),
+	}, nil
+}
+
+// NewOrdered creates a skip list over a naturally ordered key type with the
+// default configuration.
+func NewOrdered[K cmp.Ordered, V any]() *SkipList[K, V] {
+	s, _ := New[K, V](cmp.Compare[K], Config{})
+	return s
+}
+
+func (s *SkipList[K, V]) Len() int { return s.length }
+
+func (s *SkipList[K, V]) randomLevel() int {
+	l := 1
+	for l < s.maxLevel && s.rng.Float64() < s.p {
+		l++
+	}
+	return l
+}
+
+// findPath fills s.update with the last node before key on every level and
+// s.rank with that node's position (head is 0, the first element 1).
+func (s *SkipList[K, V]) findPath(key K) *node[K, V] {
+	x, pos := s.head, 0
+	for i := s.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && s.cmp(x.next[i].node.key, key) < 0 {
+			pos += x.next[i].span
+			x = x.next[i].node
+		}
+		s.update[i], s.rank[i] = x, pos
+	}
+	return x.next[0].node
+}
+
+// Put stores value under key and reports whether the key was new.
+func (s *SkipList[K, V]) Put(key K, value V) bool {
+	if n := s.findPath(key); n != nil && s.cmp(n.key, key) == 0 {
+		n.value = value
+		return false
+	}
+	lvl := s.randomLevel()
+	for i := s.level; i < lvl; i++ {
+		s.update[i], s.rank[i] = s.head, 0
+		s.head.next[i].span = s.length + 1 // the head points past the end
+	}
+	s.level = max(s.level, lvl)
+	n := &node[K, V]{key: key, value: value, next: make([]link[K, V], lvl)}
+	pos := s.rank[0] + 1 // position of the new node
+	for i := range s.level {
+		prev := s.update[i]
+		if i < lvl {
+			n.next[i] = link[K, V]{prev.next[i].node, s.rank[i] + prev.next[i].span + 1 - pos}
+			prev.next[i] = link[K, V]{n, pos - s.rank[i]}
+		} else {
+			prev.next[i].span++
+		}
+	}
+	s.length++
+	return true
+}
+
+func (s *SkipList[K, V]) Get(key K) (V, bool) {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && s.cmp(x.next[i].node.key, key) < 0 {
+			x = x.next[i].node
+		}
+	}
+	if n := x.next[0].node; n != nil && s.cmp(n.key, key) == 0 {
+		return n.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Delete removes key and reports whether it was present.
+func (s *SkipList[K, V]) Delete(key K) bool {
+	n := s.findPath(key)
+	if n == nil || s.cmp(n.key, key) != 0 {
+		return false
+	}
+	for i := range s.level {
+		prev := s.update[i]
+		if prev.next[i].node == n {
+			prev.next[i] = link[K, V]{n.next[i].node, prev.next[i].span + n.next[i].span - 1}
+		} else {
+			prev.next[i].span--
+		}
+	}
+	for s.level > 1 && s.head.next[s.level-1].node == nil {
+		s.level--
+	}
+	s.length--
+	return true
+}
+
+// Rank returns the number of keys strictly less than key.
+func (s *SkipList[K, V]) Rank(key K) int {
+	x, pos := s.head, 0
+	for i := s.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && s.cmp(x.next[i].node.key, key) < 0 {
+			pos += x.next[i].span
+			x = x.next[i].node
+		}
+	}
+	return pos
+}
+
+// At returns the i-th smallest entry, counting from 0.
+func (s *SkipList[K, V]) At(i int) (K, V, bool) {
+	if i < 0 || i >= s.length {
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	x, pos := s.head, 0
+	for l := s.level - 1; l >= 0; l-- {
+		for x.next[l].node != nil && pos+x.next[l].span <= i+1 {
+			pos += x.next[l].span
+			x = x.next[l].node
+		}
+	}
+	return x.key, x.value, true
+}
+
+// seek returns the first node with a key >= key.
+func (s *SkipList[K, V]) seek(key K) *node[K, V] {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && s.cmp(x.next[i].node.key, key) < 0 {
+			x = x.next[i].node
+		}
+	}
+	return x.next[0].node
+}
+
+// Range yields the entries with lo <= key < hi in order.
+func (s *SkipList[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for n := s.seek(lo); n != nil && s.cmp(n.key, hi) < 0; n = n.next[0].node {
+			if !yield(n.key, n.value) {
+				return
+			}
+		}
+	}
+}
+
+// From yields the entries with key >= lo in order.
+func (s *SkipList[K, V]) From(lo K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for n := s.seek(lo); n != nil; n = n.next[0].node {
+			if !yield(n.key, n.value) {
+				return
+			}
+		}
+	}
+}
+
+// All yields every entry in order.
+func (s *SkipList[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for n := s.head.next[0].node; n != nil; n = n.next[0].node {
+			if !yield(n.key, n.value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package skiplist
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// check returns a description of the first broken invariant: keys strictly
+// increase on level 0, and on every level each link's span is the number of
+// level-0 steps it covers. Spans of nil links are never read.
+func check[K cmp.Ordered, V any](s *SkipList[K, V]) string {
+	pos := map[*node[K, V]]int{s.head: 0}
+	i := 0
+	for n := s.head.next[0].node; n != nil; n = n.next[0].node {
+		i++
+		pos[n] = i
+		if next := n.next[0].node; next != nil && next.key <= n.key {
+			return fmt.Sprintf("keys %v then %v on level 0", n.key, next.key)
+		}
+	}
+	if i != s.length {
+		return fmt.Sprintf("%d nodes on level 0, length %d", i, s.length)
+	}
+	for l := range s.level {
+		for x := s.head; x.next[l].node != nil; x = x.next[l].node {
+			if want := pos[x.next[l].node] - pos[x]; x.next[l].span != want {
+				return fmt.Sprintf("level %d: span %d from position %d, want %d", l, x.next[l].span, pos[x], want)
+			}
+		}
+	}
+	return ""
+}
+
+// TestAgainstMap runs random puts and deletes on skip lists and a map side by
+// side, checking the spans and every query.
+func TestAgainstMap(t *testing.T) {
+	configs := []Config{{}, {P: 0.5, Seed: 3}, {MaxLevel: 1}, {MaxLevel: 3, P: 0.9, Seed: 7}}
+	for _, cfg := range configs {
+		s, err := New[int, int](cmp.Compare[int], cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rng := rand.New(rand.NewPCG(1, 9))
+		want := map[int]int{}
+		for step := range 3000 {
+			k := rng.IntN(500)
+			_, ok := want[k]
+			if rng.IntN(3) == 0 {
+				if got := s.Delete(k); got != ok {
+					t.Fatalf("%+v step %d: Delete(%d) = %v, want %v", cfg, step, k, got, ok)
+				}
+				delete(want, k)
+			} else {
+				if got := s.Put(k, step); got != !ok {
+					t.Fatalf("%+v step %d: Put(%d) = %v, want %v", cfg, step, k, got, !ok)
+				}
+				want[k] = step
+			}
+			if step%100 == 0 {
+				if err := check(s); err != "" {
+					t.Fatalf("%+v step %d: %s", cfg, step, err)
+				}
+			}
+		}
+		if err := check(s); err != "" {
+			t.Fatalf("%+v: %s", cfg, err)
+		}
+
+		keys := slices.Sorted(maps.Keys(want))
+		if s.Len() != len(keys) {
+			t.Fatalf("%+v: Len = %d, want %d", cfg, s.Len(), len(keys))
+		}
+		if got := slices.Collect(maps.Keys(maps.Collect(s.All()))); len(got) != len(keys) {
+			t.Fatalf("%+v: All yields %d keys, want %d", cfg, len(got), len(keys))
+		}
+		for i, k := range keys {
+			if r := s.Rank(k); r != i {
+				t.Errorf("%+v: Rank(%d) = %d, want %d", cfg, k, r, i)
+			}
+			if ak, av, ok := s.At(i); !ok || ak != k || av != want[k] {
+				t.Errorf("%+v: At(%d) = %d, %d, %v; want %d, %d", cfg, i, ak, av, ok, k, want[k])
+			}
+			if v, ok := s.Get(k); !ok || v != want[k] {
+				t.Errorf("%+v: Get(%d) = %d, %v; want %d", cfg, k, v, ok, want[k])
+			}
+		}
+		for _, k := range []int{-1, 500, 1000} {
+			if _, ok := s.Get(k); ok {
+				t.Errorf("%+v: Get(%d) found a key never put", cfg, k)
+			}
+		}
+		if r := s.Rank(1000); r != len(keys) {
+			t.Errorf("%+v: Rank past the end = %d, want %d", cfg, r, len(keys))
+		}
+		for _, i := range []int{-1, len(keys)} {
+			if _, _, ok := s.At(i); ok {
+				t.Errorf("%+v: At(%d) succeeded", cfg, i)
+			}
+		}
+		for lo := -1; lo <= 501; lo += 50 {
+			hi := lo + 75
+			var inRange, from []int
+			for k, v := range s.Range(lo, hi) {
+				if v != want[k] {
+					t.Errorf("%+v: Range yields %d=%d, want %d", cfg, k, v, want[k])
+				}
+				inRange = append(inRange, k)
+			}
+			for k := range s.From(lo) {
+				from = append(from, k)
+			}
+			i, _ := slices.BinarySearch(keys, lo)
+			j, _ := slices.BinarySearch(keys, hi)
+			if !slices.Equal(inRange, keys[i:j]) {
+				t.Errorf("%+v: Range(%d, %d) yields %v, want %v", cfg, lo, hi, inRange, keys[i:j])
+			}
+			if !slices.Equal(from, keys[i:]) {
+				t.Errorf("%+v: From(%d) yields %v, want %v", cfg, lo, from, keys[i:])
+			}
+		}
+	}
+}
+
+// TestConcurrent has writers on disjoint keys and readers on every key run
+// at once; run it with -race.
+func TestConcurrent(t *testing.T) {
+	c, err := NewConcurrent[int, int](cmp.Compare[int], Config{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const writers, perWriter = 4, 500
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				k := i*writers + w
+				c.Put(k, k)
+				if i%5 == 0 {
+					c.Delete(k)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				if v, ok := c.Get(i); ok && v != i {
+					t.Errorf("Get(%d) = %d", i, v)
+				}
+				c.Rank(i)
+				c.At(i)
+				for k, v := range c.From(i) {
+					if k != v {
+						t.Errorf("From yields %d=%d", k, v)
+					}
+					break
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	want := writers * perWriter * 4 / 5
+	if c.Len() != want {
+		t.Fatalf("Len = %d, want %d", c.Len(), want)
+	}
+	prev, n := -1, 0
+	for k := range c.All() {
+		if k <= prev || k/writers%5 == 0 {
+			t.Fatalf("All yields %d after %d", k, prev)
+		}
+		prev, n = k, n+1
+	}
+	if n != want {
+		t.Errorf("All yields %d keys, want %d", n, want)
+	}
+	var inRange int
+	for range c.Range(0, 100) {
+		inRange++
+	}
+	if inRange != 80 {
+		t.Errorf("Range(0, 100) yields %d keys, want 80", inRange)
+	}
+	if r := c.Rank(100); r != 80 {
+		t.Errorf("Rank(100) = %d, want 80", r)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		cfg Config
+		ok  bool
+	}{
+		{Config{}, true},
+		{Config{MaxLevel: 64, P: 0.5}, true},
+		{Config{MaxLevel: 65}, false},
+		{Config{MaxLevel: -1}, false},
+		{Config{P: 1}, false},
+		{Config{P: -0.5}, false},
+	}
+	for _, tt := range tests {
+		if _, err := New[int, int](cmp.Compare[int], tt.cfg); (err == nil) != tt.ok {
+			t.Errorf("New with %+v: err = %v, want ok %v", tt.cfg, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/slicevis"
+)
+
+// sliceLesson walks through the slice pitfalls from CHAPTER3.md.
+const sliceLesson = `var none []int            // nil: no backing array at all
+x := make([]int, 3, 4)
+x[0] = 1
+x[1] = 2
+x[2] = 3
+y := x[:2]                // shares x's array
+y = append(y, 40)         // fits in the capacity, so it overwrites x[2]
+x = append(x, 50)         // fills the last free slot, which y cannot see yet
+x = append(x, 60)         // full: append moves x to a new array, y stays behind
+y[0] = 100                // no longer visible through x
+z := x[1:3:3]             // full slice expression: no spare capacity
+z = append(z, 70)         // so this append cannot clobber x[3]
+w := x[1:3]
+w = append(w, 80)         // without the limit it does
+var a [4]int
+s := a[:]                 // slicing an array shares its memory
+n := copy(s, x[2:])
+b := a                    // assigning an array copies it
+s[0] = -1
+c := [2]int(x)            // converting a slice to an array copies too
+none = append(none, 1)
+`
+
+func runSlices(ctx *cli.Context) error {
+	fs := ctx.Flags
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	src := sliceLesson
+	switch path := fs.Arg(0); path {
+	case "":
+	case "-":
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		src = string(b)
+	default:
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		src = string(b)
+	}
+	return slicevis.Run(os.Stdout, src)
+}
@@ -0,0 +1,192 @@
+package slicevis
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxShown is how many elements of a slice, and cells of a backing array,
+// are printed before the rest is elided.
+const maxShown = 32
+
+// brief formats s like %v, eliding the middle of a long slice.
+func brief(s []int) string {
+	if len(s) <= maxShown {
+		return fmt.Sprint(s)
+	}
+	head, tail := s[:maxShown-4], s[len(s)-4:]
+	h, t := fmt.Sprint(head), fmt.Sprint(tail)
+	return fmt.Sprintf("%s … %s (%d elements)", h[:len(h)-1], t[1:], len(s))
+}
+
+// render prints the notes, aliased writes, a table of slice headers and a
+// drawing of every live backing array.
+func (m *Machine) render() {
+	for _, n := range m.notes {
+		fmt.Fprintf(m.w, "   %s\n", n)
+	}
+	m.reportWrites()
+
+	names := m.slices()
+	fmt.Fprintf(m.w, "   %-6s %-14s %4s %4s  %-6s %s\n", "var", "ptr", "len", "cap", "array", "values")
+	for _, name := range names {
+		v := m.vars[name]
+		p, where := "nil", "-"
+		switch {
+		case v.s == nil:
+		case cap(v.s) == 0:
+			p = "(zero-size)"
+		default:
+			p = fmt.Sprintf("%#x", ptr(v.s))
+			if a, off, ok := m.locate(ptr(v.s)); ok {
+				where = fmt.Sprintf("%s+%d", a.label, off)
+			}
+		}
+		kind := ""
+		if v.array {
+			kind = " (array)"
+		}
+		fmt.Fprintf(m.w, "   %-6s %-14s %4d %4d  %-6s %s%s\n", name, p, len(v.s), cap(v.s), where, brief(v.s), kind)
+	}
+	for _, name := range m.order {
+		if v := m.vars[name]; v.isInt {
+			fmt.Fprintf(m.w, "   %-6s = %d\n", name, v.n)
+		}
+	}
+	m.draw(names)
+	fmt.Fprintln(m.w)
+}
+
+// slices lists the slice and array variables in declaration order.
+func (m *Machine) slices() []string {
+	var out []string
+	for _, name := range m.order {
+		if !m.vars[name].isInt {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func (m *Machine) locate(p uintptr) (alloc, int, bool) {
+	for _, a := range m.allocs {
+		if p >= a.start && p < a.end {
+			return a, int((p - a.start) / elemSize), true
+		}
+	}
+	return alloc{}, 0, false
+}
+
+// reportWrites flags every store that another variable can see: inside its
+// length it is visible now, inside its spare capacity it will resurface
+// after reslicing or appending.
+func (m *Machine) reportWrites() {
+	for _, name := range m.slices() {
+		if name == m.via {
+			continue
+		}
+		s := m.vars[name].s
+		if cap(s) == 0 {
+			continue
+		}
+		start := ptr(s)
+		for _, w := range m.writes {
+			if w.addr < start || w.addr >= start+uintptr(cap(s))*elemSize {
+				continue
+			}
+			i := int((w.addr - start) / elemSize)
+			if i < len(s) {
+				fmt.Fprintf(m.w, "   ! %s[%d] changed %d → %d through %s\n", name, i, w.old, w.new, m.via)
+			} else {
+				fmt.Fprintf(m.w, "   ! %s's spare capacity at index %d changed %d → %d through %s (hidden until %s is resliced)\n", name, i, w.old, w.new, m.via, name)
+			}
+		}
+	}
+}
+
+// draw prints each live backing array as a row of cells, with a bar per
+// variable viewing it: '=' within its length, '-' over spare capacity.
+// Cells written in this step are marked with '^'.
+func (m *Machine) draw(names []string) {
+	type view struct {
+		name     string
+		off, len int
+		cap      int
+	}
+	for _, a := range m.allocs {
+		var views []view
+		for _, name := range names {
+			s := m.vars[name].s
+			if cap(s) == 0 {
+				continue
+			}
+			if b, off, ok := m.locate(ptr(s)); ok && b == a {
+				views = append(views, view{name, off, len(s), cap(s)})
+			}
+		}
+		if len(views) == 0 {
+			continue
+		}
+		// The cells come from whichever variable reaches them, through its
+		// capacity; cells no variable reaches are unknown.
+		size := int((a.end - a.start) / elemSize)
+		n := min(size, maxShown)
+		cells := make([]string, n)
+		for i := range cells {
+			cells[i] = "?"
+		}
+		for _, v := range views {
+			s := m.vars[v.name].s[:v.cap]
+			for i, x := range s[:max(0, min(len(s), n-v.off))] {
+				cells[v.off+i] = strconv.Itoa(x)
+			}
+		}
+		width := 2
+		for _, c := range cells {
+			width = max(width, len(c))
+		}
+		width++
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "   %-6s ", a.label)
+		for _, c := range cells {
+			fmt.Fprintf(&b, "%*s", width, c)
+		}
+		if size > n {
+			fmt.Fprintf(&b, " … %d more", size-n)
+		}
+		b.WriteString("\n")
+		written := false
+		marks := []byte(strings.Repeat(" ", n*width))
+		for _, w := range m.writes {
+			if i := int((w.addr - a.start) / elemSize); w.addr >= a.start && i < n {
+				marks[i*width+width-1] = '^'
+				written = true
+			}
+		}
+		if written {
+			fmt.Fprintf(&b, "   %-6s %s\n", "", strings.TrimRight(string(marks), " "))
+		}
+		for _, v := range views {
+			bar := make([]byte, 0, n*width)
+			for i := range n {
+				c := byte(' ')
+				switch {
+				case i >= v.off && i < v.off+v.len:
+					c = '='
+				case i >= v.off && i < v.off+v.cap:
+					c = '-'
+				}
+				bar = append(bar, slices.Repeat([]byte{c}, width)...)
+			}
+			line := strings.TrimRight(string(bar), " ")
+			if v.off+v.cap > n {
+				line += " …"
+			}
+			fmt.Fprintf(&b, "   %-6s %s\n", v.name, line)
+		}
+		fmt.Fprint(m.w, b.String())
+	}
+}
@@ -0,0 +1,646 @@
+// Package slicevis runs a script of slice statements on real []int values
+// and shows, after every step, each variable's pointer, length and capacity,
+// which variables share a backing array, and which writes became visible
+// through another variable.
+//
+// Scripts are Go statements over int slices and arrays:
+//
+//	x := make([]int, 3, 4)
+//	y := x[:2]
+//	y = append(y, 40) // lands in x[2]
+//	z := x[1:2:2]     // capacity capped: the next append must reallocate
+//	var a [3]int
+//	s := a[:]
+//	n := copy(s, x)
+//
+// Because the statements operate on real slices, growth on append follows
+// the Go runtime rather than a model of it.
+package slicevis
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+const elemSize = unsafe.Sizeof(int(0))
+
+// MaxLen bounds the length and capacity of every slice and array a script
+// creates, so that a stray make([]int, 1e12) is an error rather than an
+// out-of-memory crash.
+const MaxLen = 1 << 16
+
+// value is a variable's contents. Arrays are held as a slice over storage
+// the variable owns; assigning one copies it, as in Go.
+type value struct {
+	s     []int
+	array bool
+	n     int // plain int variable, e.g. the result of copy
+	isInt bool
+}
+
+// alloc is one backing array the script created.
+type alloc struct {
+	label      string
+	start, end uintptr
+}
+
+// write is a store into memory made by one statement.
+type write struct {
+	addr uintptr
+	old  int
+	new  int
+}
+
+// Machine holds the script's variables between steps.
+type Machine struct {
+	w      io.Writer
+	fset   *token.FileSet
+	src    string
+	vars   map[string]value
+	order  []string
+	allocs []alloc
+	labels int
+
+	// per step
+	via    string
+	writes []write
+	notes  []string
+}
+
+// Run executes src, a sequence of statements, printing the state after each
+// one to w. It stops at the first error, reporting its line; runtime panics
+// such as an out-of-range index are reported the same way.
+func Run(w io.Writer, src string) error {
+	const prefix = "package p; func _() {\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "script", prefix+src+"\n}", parser.SkipObjectResolution)
+	if list, ok := err.(scanner.ErrorList); ok {
+		// Errors at the closing brace we added belong to the last line.
+		e := list[0]
+		return fmt.Errorf("slicevis: line %d: %s", min(e.Pos.Line-1, strings.Count(strings.TrimRight(src, "\n"), "\n")+1), e.Msg)
+	} else if err != nil {
+		return fmt.Errorf("slicevis: %w", err)
+	}
+	m := &Machine{w: w, fset: fset, src: prefix + src, vars: map[string]value{}}
+	body := f.Decls[0].(*ast.FuncDecl).Body
+	for i, st := range body.List {
+		if err := m.step(i+1, st); err != nil {
+			return fmt.Errorf("slicevis: line %d: %w", fset.Position(st.Pos()).Line-1, err)
+		}
+	}
+	return nil
+}
+
+func (m *Machine) step(n int, st ast.Stmt) (err error) {
+	m.via, m.writes, m.notes = "", nil, nil
+	start, end := m.fset.Position(st.Pos()).Offset, m.fset.Position(st.End()).Offset
+	fmt.Fprintf(m.w, "── %d: %s\n", n, m.src[start:end])
+	defer func() {
+		if r := recover(); r != nil {
+			re, ok := r.(runtime.Error)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("panic: %v", re)
+		}
+	}()
+	if err := m.exec(st); err != nil {
+		return err
+	}
+	m.render()
+	return nil
+}
+
+func (m *Machine) exec(st ast.Stmt) error {
+	switch st := st.(type) {
+	case *ast.AssignStmt:
+		if len(st.Lhs) != 1 || len(st.Rhs) != 1 {
+			return fmt.Errorf("one assignment per statement")
+		}
+		if ix, ok := st.Lhs[0].(*ast.IndexExpr); ok {
+			if st.Tok != token.ASSIGN {
+				return fmt.Errorf("cannot use %s with an index expression", st.Tok)
+			}
+			return m.store(ix, st.Rhs[0])
+		}
+		id, ok := st.Lhs[0].(*ast.Ident)
+		if !ok {
+			return fmt.Errorf("can only assign to a variable or an element")
+		}
+		if call, ok := st.Rhs[0].(*ast.CallExpr); ok && isBuiltin(call, "append") && len(call.Args) > 0 {
+			if src, ok := call.Args[0].(*ast.Ident); ok && src.Name != id.Name {
+				m.notes = append(m.notes, fmt.Sprintf("%s gets the result of appending to %s; the two may now share memory", id.Name, src.Name))
+			}
+		}
+		v, err := m.eval(st.Rhs[0], id.Name)
+		if err != nil {
+			return err
+		}
+		return m.assign(id.Name, v, st.Tok == token.DEFINE)
+	case *ast.DeclStmt:
+		gd, ok := st.Decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.VAR {
+			return fmt.Errorf("only var declarations are supported")
+		}
+		for _, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				var v value
+				var err error
+				switch {
+				case i < len(vs.Values):
+					v, err = m.eval(vs.Values[i], name.Name)
+				case vs.Type != nil:
+					v, err = m.zero(vs.Type)
+				default:
+					err = fmt.Errorf("%s needs a type or a value", name.Name)
+				}
+				if err != nil {
+					return err
+				}
+				if err := m.assign(name.Name, v, true); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case *ast.ExprStmt:
+		call, ok := st.X.(*ast.CallExpr)
+		switch {
+		case ok && isBuiltin(call, "copy"):
+			v, err := m.eval(call, "")
+			if err != nil {
+				return err
+			}
+			m.notes = append(m.notes, fmt.Sprintf("copied %d elements", v.n))
+			return nil
+		case ok && isBuiltin(call, "append"):
+			return fmt.Errorf("append's result is discarded; the slice header it returns must be assigned")
+		}
+	}
+	return fmt.Errorf("unsupported statement")
+}
+
+func isBuiltin(call *ast.CallExpr, name string) bool {
+	id, ok := call.Fun.(*ast.Ident)
+	return ok && id.Name == name
+}
+
+func (m *Machine) assign(name string, v value, define bool) error {
+	old, exists := m.vars[name]
+	switch {
+	case define && exists:
+		return fmt.Errorf("no new variables on left side of :=")
+	case !define && !exists:
+		return fmt.Errorf("undefined: %s", name)
+	case exists && (old.array != v.array || old.isInt != v.isInt || old.array && len(old.s) != len(v.s)):
+		return fmt.Errorf("cannot assign %s to %s", v.typ(), old.typ())
+	}
+	if v.array {
+		// Arrays are values: the variable gets its own storage.
+		v.s = m.newArray(v.s, name)
+	}
+	if !exists {
+		m.order = append(m.order, name)
+	}
+	m.vars[name] = v
+	return nil
+}
+
+func (v value) typ() string {
+	switch {
+	case v.isInt:
+		return "int"
+	case v.array:
+		return fmt.Sprintf("[%d]int", len(v.s))
+	}
+	return "[]int"
+}
+
+// newArray copies s into a freshly allocated array owned by name.
+func (m *Machine) newArray(s []int, name string) []int {
+	a := make([]int, len(s))
+	copy(a, s)
+	m.track(a, fmt.Sprintf("%s is a new array", name))
+	return a
+}
+
+// track records the backing array of s, newly allocated by the script.
+func (m *Machine) track(s []int, note string) {
+	if cap(s) == 0 {
+		return
+	}
+	start := ptr(s)
+	end := start + uintptr(cap(s))*elemSize
+	// Memory the garbage collector reused can no longer be reachable from
+	// a variable, so forget anything overlapping.
+	kept := m.allocs[:0]
+	for _, a := range m.allocs {
+		if a.end <= start || a.start >= end {
+			kept = append(kept, a)
+		}
+	}
+	label := string(rune('A' + m.labels%26))
+	if m.labels >= 26 {
+		label += strconv.Itoa(m.labels / 26)
+	}
+	m.labels++
+	m.allocs = append(kept, alloc{label: label, start: start, end: end})
+	m.notes = append(m.notes, fmt.Sprintf("%s (array %s, cap %d)", note, label, cap(s)))
+}
+
+func ptr(s []int) uintptr {
+	if cap(s) == 0 {
+		return 0
+	}
+	return uintptr(unsafe.Pointer(unsafe.SliceData(s)))
+}
+
+// zero is the zero value of an int, []int or [N]int type expression.
+func (m *Machine) zero(t ast.Expr) (value, error) {
+	if isInt(t) {
+		return value{isInt: true}, nil
+	}
+	at, ok := t.(*ast.ArrayType)
+	if !ok || !isInt(at.Elt) {
+		return value{}, fmt.Errorf("only int, []int and [N]int are supported")
+	}
+	if at.Len == nil {
+		return value{}, nil
+	}
+	n, err := m.evalInt(at.Len)
+	if err != nil {
+		return value{}, err
+	}
+	if err := checkLen("array length", n); err != nil {
+		return value{}, err
+	}
+	return value{s: make([]int, n), array: true}, nil
+}
+
+func isInt(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && id.Name == "int"
+}
+
+// eval evaluates a slice, array or int expression. target names the
+// variable being assigned, for notes and for reporting writes through it.
+func (m *Machine) eval(e ast.Expr, target string) (value, error) {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return m.eval(e.X, target)
+	case *ast.Ident:
+		if e.Name == "nil" {
+			return value{}, nil
+		}
+		v, ok := m.vars[e.Name]
+		if !ok {
+			return value{}, fmt.Errorf("undefined: %s", e.Name)
+		}
+		return v, nil
+	case *ast.CompositeLit:
+		v, err := m.zero(e.Type)
+		if err != nil {
+			return value{}, err
+		}
+		vals := make([]int, len(e.Elts))
+		for i, elt := range e.Elts {
+			if vals[i], err = m.evalInt(elt); err != nil {
+				return value{}, err
+			}
+		}
+		if !v.array {
+			// A literal's backing array is exactly as long as the literal.
+			v.s = vals[:len(vals):len(vals)]
+			m.track(v.s, "new backing array")
+			if v.s == nil {
+				v.s = []int{}
+			}
+			return v, nil
+		}
+		if len(vals) > len(v.s) {
+			return value{}, fmt.Errorf("array index %d out of bounds [0:%d]", len(v.s), len(v.s))
+		}
+		copy(v.s, vals)
+		return v, nil
+	case *ast.SliceExpr:
+		v, err := m.eval(e.X, target)
+		if err != nil {
+			return value{}, err
+		}
+		if v.isInt {
+			return value{}, fmt.Errorf("cannot slice an int")
+		}
+		if v.array {
+			if _, ok := e.X.(*ast.Ident); !ok {
+				return value{}, fmt.Errorf("cannot slice an unaddressable array")
+			}
+		}
+		lo, hi, mx := 0, len(v.s), cap(v.s)
+		if e.Low != nil {
+			if lo, err = m.evalInt(e.Low); err != nil {
+				return value{}, err
+			}
+		}
+		if e.High != nil {
+			if hi, err = m.evalInt(e.High); err != nil {
+				return value{}, err
+			}
+		}
+		if e.Max != nil {
+			if mx, err = m.evalInt(e.Max); err != nil {
+				return value{}, err
+			}
+		}
+		if !e.Slice3 {
+			return value{s: v.s[lo:hi]}, nil
+		}
+		return value{s: v.s[lo:hi:mx]}, nil
+	case *ast.CallExpr:
+		if at, ok := e.Fun.(*ast.ArrayType); ok && at.Len != nil {
+			return m.convert(e, at)
+		}
+		if p, ok := e.Fun.(*ast.ParenExpr); ok {
+			if at, ok := p.X.(*ast.ArrayType); ok && at.Len != nil {
+				return m.convert(e, at)
+			}
+		}
+		switch {
+		case isBuiltin(e, "make"):
+			return m.makeSlice(e)
+		case isBuiltin(e, "append"):
+			return m.appendTo(e, target)
+		case isBuiltin(e, "copy"):
+			return m.copyInto(e)
+		}
+	}
+	n, err := m.evalInt(e)
+	return value{n: n, isInt: true}, err
+}
+
+func (m *Machine) evalSlice(e ast.Expr) ([]int, error) {
+	v, err := m.eval(e, "")
+	if err != nil {
+		return nil, err
+	}
+	if v.isInt || v.array {
+		return nil, fmt.Errorf("%s is not a slice", v.typ())
+	}
+	return v.s, nil
+}
+
+func (m *Machine) evalInt(e ast.Expr) (int, error) {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		if e.Kind == token.INT {
+			return strconv.Atoi(e.Value)
+		}
+	case *ast.ParenExpr:
+		return m.evalInt(e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.SUB {
+			n, err := m.evalInt(e.X)
+			return -n, err
+		}
+	case *ast.BinaryExpr:
+		a, err := m.evalInt(e.X)
+		if err != nil {
+			return 0, err
+		}
+		b, err := m.evalInt(e.Y)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case token.ADD:
+			return a + b, nil
+		case token.SUB:
+			return a - b, nil
+		case token.MUL:
+			return a * b, nil
+		}
+	case *ast.Ident:
+		v, ok := m.vars[e.Name]
+		if !ok {
+			return 0, fmt.Errorf("undefined: %s", e.Name)
+		}
+		if !v.isInt {
+			return 0, fmt.Errorf("%s is %s, not int", e.Name, v.typ())
+		}
+		return v.n, nil
+	case *ast.IndexExpr:
+		v, err := m.eval(e.X, "")
+		if err != nil {
+			return 0, err
+		}
+		i, err := m.evalInt(e.Index)
+		if err != nil {
+			return 0, err
+		}
+		return v.s[i], nil
+	case *ast.CallExpr:
+		if (isBuiltin(e, "len") || isBuiltin(e, "cap")) && len(e.Args) == 1 {
+			v, err := m.eval(e.Args[0], "")
+			if err != nil {
+				return 0, err
+			}
+			if isBuiltin(e, "len") {
+				return len(v.s), nil
+			}
+			return cap(v.s), nil
+		}
+		if isBuiltin(e, "copy") {
+			v, err := m.copyInto(e)
+			return v.n, err
+		}
+	}
+	return 0, fmt.Errorf("unsupported expression")
+}
+
+func (m *Machine) makeSlice(e *ast.CallExpr) (value, error) {
+	if len(e.Args) < 2 || len(e.Args) > 3 {
+		return value{}, fmt.Errorf("make needs a type, a length and an optional capacity")
+	}
+	if at, ok := e.Args[0].(*ast.ArrayType); !ok || at.Len != nil || !isInt(at.Elt) {
+		return value{}, fmt.Errorf("make only builds []int here")
+	}
+	n, err := m.evalInt(e.Args[1])
+	if err != nil {
+		return value{}, err
+	}
+	c := n
+	if len(e.Args) == 3 {
+		if c, err = m.evalInt(e.Args[2]); err != nil {
+			return value{}, err
+		}
+	}
+	if err := checkLen("len", n); err != nil {
+		return value{}, err
+	}
+	if err := checkLen("cap", c); err != nil {
+		return value{}, err
+	}
+	if n > c {
+		return value{}, fmt.Errorf("len %d larger than cap %d in make", n, c)
+	}
+	s := make([]int, n, c)
+	m.track(s, "new backing array")
+	return value{s: s}, nil
+}
+
+// checkLen rejects a length or capacity outside [0, MaxLen].
+func checkLen(what string, n int) error {
+	switch {
+	case n < 0:
+		return fmt.Errorf("%s %d must not be negative", what, n)
+	case n > MaxLen:
+		return fmt.Errorf("%s %d exceeds the limit of %d", what, n, MaxLen)
+	}
+	return nil
+}
+
+// appendTo runs a real append, then works out whether it wrote into the
+// existing array or moved to a new one.
+func (m *Machine) appendTo(e *ast.CallExpr, target string) (value, error) {
+	if len(e.Args) == 0 {
+		return value{}, fmt.Errorf("append needs a slice")
+	}
+	s, err := m.evalSlice(e.Args[0])
+	if err != nil {
+		return value{}, err
+	}
+	var vals []int
+	if e.Ellipsis.IsValid() {
+		if len(e.Args) != 2 {
+			return value{}, fmt.Errorf("append with ... takes exactly one other slice")
+		}
+		if vals, err = m.evalSlice(e.Args[1]); err != nil {
+			return value{}, err
+		}
+	} else {
+		for _, a := range e.Args[1:] {
+			n, err := m.evalInt(a)
+			if err != nil {
+				return value{}, err
+			}
+			vals = append(vals, n)
+		}
+	}
+	if len(vals) == 0 {
+		return value{s: s}, nil
+	}
+	if err := checkLen("appended length", len(s)+len(vals)); err != nil {
+		return value{}, err
+	}
+	var olds []int
+	if len(s)+len(vals) <= cap(s) {
+		olds = append(olds, s[len(s):len(s)+len(vals)]...)
+	}
+	r := append(s, vals...)
+	if cap(s) > 0 && ptr(r) == ptr(s) {
+		for i := range vals {
+			m.writes = append(m.writes, write{addr: ptr(r) + uintptr(len(s)+i)*elemSize, old: olds[i], new: r[len(s)+i]})
+		}
+		// The result, not the argument, is what sees the new elements.
+		m.via = target
+		if m.via == "" {
+			m.via = name(e.Args[0])
+		}
+		m.notes = append(m.notes, fmt.Sprintf("append fit in the spare capacity (%d of %d used): wrote in place", len(r), cap(r)))
+		return value{s: r}, nil
+	}
+	m.track(r, fmt.Sprintf("append grew cap %d → %d and copied %d elements to a new array", cap(s), cap(r), len(s)))
+	return value{s: r}, nil
+}
+
+func (m *Machine) copyInto(e *ast.CallExpr) (value, error) {
+	if len(e.Args) != 2 {
+		return value{}, fmt.Errorf("copy takes a destination and a source")
+	}
+	dst, err := m.evalSlice(e.Args[0])
+	if err != nil {
+		return value{}, err
+	}
+	src, err := m.evalSlice(e.Args[1])
+	if err != nil {
+		return value{}, err
+	}
+	olds := append([]int(nil), dst[:min(len(dst), len(src))]...)
+	n := copy(dst, src)
+	for i := range n {
+		m.writes = append(m.writes, write{addr: ptr(dst) + uintptr(i)*elemSize, old: olds[i], new: dst[i]})
+	}
+	m.via = name(e.Args[0])
+	return value{n: n, isInt: true}, nil
+}
+
+// convert is [N]int(s): a copy, which panics if s is too short.
+func (m *Machine) convert(e *ast.CallExpr, at *ast.ArrayType) (value, error) {
+	if !isInt(at.Elt) || len(e.Args) != 1 {
+		return value{}, fmt.Errorf("only [N]int(slice) conversions are supported")
+	}
+	n, err := m.evalInt(at.Len)
+	if err != nil {
+		return value{}, err
+	}
+	s, err := m.evalSlice(e.Args[0])
+	if err != nil {
+		return value{}, err
+	}
+	if len(s) < n {
+		return value{}, fmt.Errorf("panic: cannot convert slice with length %d to array or pointer to array with length %d", len(s), n)
+	}
+	return value{s: s[:n:n], array: true}, nil
+}
+
+// store is x[i] = v.
+func (m *Machine) store(ix *ast.IndexExpr, rhs ast.Expr) error {
+	id, ok := ix.X.(*ast.Ident)
+	if !ok {
+		return fmt.Errorf("can only assign to an element of a variable")
+	}
+	v, ok := m.vars[id.Name]
+	if !ok {
+		return fmt.Errorf("undefined: %s", id.Name)
+	}
+	if v.isInt {
+		return fmt.Errorf("cannot index an int")
+	}
+	i, err := m.evalInt(ix.Index)
+	if err != nil {
+		return err
+	}
+	n, err := m.evalInt(rhs)
+	if err != nil {
+		return err
+	}
+	old := v.s[i]
+	v.s[i] = n
+	m.writes = append(m.writes, write{addr: ptr(v.s) + uintptr(i)*elemSize, old: old, new: n})
+	m.via = id.Name
+	return nil
+}
+
+// name is the variable an expression is rooted in, such as x for x[1:3].
+func name(e ast.Expr) string {
+	for {
+		switch x := e.(type) {
+		case *ast.Ident:
+			return x.Name
+		case *ast.SliceExpr:
+			e = x.X
+		case *ast.ParenExpr:
+			e = x.X
+		default:
+			return "a temporary"
+		}
+	}
+}
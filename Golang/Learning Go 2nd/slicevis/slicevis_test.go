@@ -0,0 +1,172 @@
+package slicevis
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"x := make([]int, 65536)", ""},
+		{"x := make([]int, 65537)", "line 1: len 65537 exceeds the limit of 65536"},
+		{"x := make([]int, 1, 1000000000000)", "line 1: cap 1000000000000 exceeds the limit"},
+		{"x := make([]int, -1)", "line 1: len -1 must not be negative"},
+		{"x := make([]int, 3, 2)", "line 1: len 3 larger than cap 2"},
+		{"var a [70000]int", "line 1: array length 70000 exceeds the limit"},
+		{"x := make([]int, 40000)\nx = append(x, x...)", "line 2: appended length 80000 exceeds the limit"},
+	}
+	for _, tt := range tests {
+		err := Run(io.Discard, tt.src)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%q: %v", tt.src, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%q: err = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestLongSlicesElided(t *testing.T) {
+	var b strings.Builder
+	if err := Run(&b, "x := make([]int, 1000)\nx[999] = 7"); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if !strings.Contains(out, "0 0 0 7] (1000 elements)") || !strings.Contains(out, "… 968 more") {
+		t.Errorf("long slice not elided:\n%s", out)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) > 200 {
+			t.Errorf("line of %d bytes: %.80s…", len(line), line)
+		}
+	}
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "[]"},
+		{3, "[0 1 2]"},
+		{maxShown, "[0 1 2"},
+		{maxShown + 1, "[0 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16 17 18 19 20 21 22 23 24 25 26 27 … 29 30 31 32] (33 elements)"},
+	}
+	for _, tt := range tests {
+		s := make([]int, tt.n)
+		for i := range s {
+			s[i] = i
+		}
+		if got := brief(s); !strings.HasPrefix(got, tt.want) || tt.n <= maxShown && strings.Contains(got, "…") {
+			t.Errorf("brief(%d elements) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+// steps runs src and returns the output of each statement.
+func steps(t *testing.T, src string) []string {
+	t.Helper()
+	var b strings.Builder
+	if err := Run(&b, src); err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	return strings.Split(b.String(), "── ")[1:]
+}
+
+// array returns the backing array column of a variable's row, such as A+2.
+func array(step, name string) string {
+	for _, line := range strings.Split(step, "\n") {
+		if f := strings.Fields(line); len(f) >= 5 && f[0] == name && strings.HasPrefix(f[1], "0x") {
+			return f[4]
+		}
+	}
+	return ""
+}
+
+func TestSharing(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		arrays map[string]string // backing array per variable after the last step
+		want   []string          // lines the last step prints
+		not    []string          // lines it must not print
+	}{
+		{
+			name:   "append in place",
+			src:    "x := make([]int, 3, 10)\ny := x[:2]\ny = append(y, 5)",
+			arrays: map[string]string{"x": "A+0", "y": "A+0"},
+			want:   []string{"wrote in place", "! x[2] changed 0 → 5 through y"},
+		},
+		{
+			name:   "append into spare capacity",
+			src:    "x := make([]int, 1, 4)\ny := append(x, 7)",
+			arrays: map[string]string{"x": "A+0", "y": "A+0"},
+			want:   []string{"y gets the result of appending to x", "! x's spare capacity at index 1 changed 0 → 7 through y (hidden until x is resliced)"},
+		},
+		{
+			name:   "reallocating append",
+			src:    "x := make([]int, 3, 10)\nx[2] = 5\nz := append(x, 1, 2, 3, 4, 5, 6, 7, 8)",
+			arrays: map[string]string{"x": "A+0", "z": "B+0"},
+			want:   []string{"append grew cap 10 → 20 and copied 3 elements to a new array (array B, cap 20)"},
+			not:    []string{"!"},
+		},
+		{
+			name:   "offset into the array",
+			src:    "x := make([]int, 5)\ny := x[2:]\ny[0] = 4",
+			arrays: map[string]string{"x": "A+0", "y": "A+2"},
+			want:   []string{"! x[2] changed 0 → 4 through y"},
+		},
+		{
+			name:   "full slice expression",
+			src:    "x := make([]int, 3, 10)\nw := x[0:1:1]\nw = append(w, 9)",
+			arrays: map[string]string{"x": "A+0", "w": "B+0"},
+			want:   []string{"append grew cap 1 → 2"},
+			not:    []string{"!"},
+		},
+		{
+			name:   "two-index slice of the same array",
+			src:    "x := make([]int, 3, 10)\nw := x[0:1]\nw = append(w, 9)",
+			arrays: map[string]string{"x": "A+0", "w": "A+0"},
+			want:   []string{"! x[1] changed 0 → 9 through w"},
+		},
+		{
+			name:   "overlapping copy",
+			src:    "a := make([]int, 5)\nb := a[2:]\nb[0] = 7\nb[1] = 8\nn := copy(a, b)",
+			arrays: map[string]string{"a": "A+0", "b": "A+2"},
+			want:   []string{"! b[0] changed 7 → 0 through a", "n      = 3"},
+		},
+		{
+			name:   "copy between arrays",
+			src:    "a := make([]int, 2)\nb := make([]int, 2)\nb[1] = 3\nn := copy(a, b)",
+			arrays: map[string]string{"a": "A+0", "b": "B+0"},
+			want:   []string{"n      = 2", "a      0x"},
+			not:    []string{"!"},
+		},
+		{
+			name:   "arrays are copied on assignment",
+			src:    "var a [3]int\nb := a\nb[0] = 1\ns := a[:]",
+			arrays: map[string]string{"s": "A+0"},
+			not:    []string{"!"},
+		},
+	}
+	for _, tt := range tests {
+		out := steps(t, tt.src)
+		last := out[len(out)-1]
+		for name, want := range tt.arrays {
+			if got := array(last, name); got != want {
+				t.Errorf("%s: %s is in array %q, want %q\n%s", tt.name, name, got, want, last)
+			}
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(last, want) {
+				t.Errorf("%s: output lacks %q:\n%s", tt.name, want, last)
+			}
+		}
+		for _, not := range tt.not {
+			if strings.Contains(last, "   "+not) {
+				t.Errorf("%s: output has %q:\n%s", tt.name, not, last)
+			}
+		}
+	}
+}
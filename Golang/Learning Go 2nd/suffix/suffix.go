@@ -0,0 +1,169 @@
+// Package suffix builds suffix arrays with prefix doubling and LCP arrays with
+// Kasai's algorithm, and uses them for substring search and for counting
+// distinct substrings.
+package suffix
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Array is the suffix array of a text: SA[i] is the start of the i-th
+// smallest suffix, and LCP[i] the length of the longest common prefix of
+// suffixes SA[i-1] and SA[i] (LCP[0] is 0).
+type Array struct {
+	text []byte
+	SA   []int
+	LCP  []int
+}
+
+// New builds the suffix array in O(n log n) by prefix doubling: suffixes are
+// ranked by their first 2^k bytes, and each round sorts pairs of ranks with
+// two counting-sort passes.
+func New(text []byte) *Array {
+	n := len(text)
+	a := &Array{text: text, SA: make([]int, n)}
+	if n == 0 {
+		a.LCP = []int{}
+		return a
+	}
+	rank := make([]int, n)
+	for i, b := range text {
+		rank[i] = int(b)
+	}
+	tmp := make([]int, n)
+	sa := a.SA
+	for i := range sa {
+		sa[i] = i
+	}
+	buckets := max(256, n) + 1
+	count := make([]int, buckets)
+	for k := 1; ; k <<= 1 {
+		// second ranks the suffix by its next k bytes; suffixes that run out
+		// before then sort first.
+		second := func(i int) int {
+			if i+k < n {
+				return rank[i+k] + 1
+			}
+			return 0
+		}
+		countingSort(sa, tmp, count, second)
+		countingSort(sa, tmp, count, func(i int) int { return rank[i] + 1 })
+
+		tmp[sa[0]] = 0
+		for i := 1; i < n; i++ {
+			prev, cur := sa[i-1], sa[i]
+			tmp[cur] = tmp[prev]
+			if rank[prev] != rank[cur] || second(prev) != second(cur) {
+				tmp[cur]++
+			}
+		}
+		rank, tmp = tmp, rank
+		if rank[sa[n-1]] == n-1 {
+			break // all ranks distinct
+		}
+	}
+	a.LCP = kasai(text, a.SA, rank)
+	return a
+}
+
+// countingSort stably reorders sa by key, using out as scratch space.
+func countingSort(sa, out, count []int, key func(int) int) {
+	clear(count)
+	for _, s := range sa {
+		count[key(s)]++
+	}
+	sum := 0
+	for i, c := range count {
+		count[i] = sum
+		sum += c
+	}
+	for _, s := range sa {
+		k := key(s)
+		out[count[k]] = s
+		count[k]++
+	}
+	copy(sa, out)
+}
+
+// kasai computes the LCP array in O(n) given the suffix array and the
+// inverse permutation rank.
+func kasai(text []byte, sa, rank []int) []int {
+	n := len(text)
+	lcp := make([]int, n)
+	h := 0
+	for i := range n {
+		if rank[i] == 0 {
+			h = 0
+			continue
+		}
+		j := sa[rank[i]-1]
+		for i+h < n && j+h < n && text[i+h] == text[j+h] {
+			h++
+		}
+		lcp[rank[i]] = h
+		if h > 0 {
+			h--
+		}
+	}
+	return lcp
+}
+
+func (a *Array) Text() []byte { return a.text }
+
+// Range returns the interval [lo, hi) of SA whose suffixes start with
+// pattern.
+func (a *Array) Range(pattern []byte) (lo, hi int) {
+	n := len(a.SA)
+	lo = sort.Search(n, func(i int) bool {
+		return bytes.Compare(a.suffixPrefix(i, len(pattern)), pattern) >= 0
+	})
+	hi = sort.Search(n, func(i int) bool {
+		return bytes.Compare(a.suffixPrefix(i, len(pattern)), pattern) > 0
+	})
+	return lo, hi
+}
+
+func (a *Array) suffixPrefix(i, length int) []byte {
+	s := a.text[a.SA[i]:]
+	if len(s) > length {
+		s = s[:length]
+	}
+	return s
+}
+
+// Count returns the number of occurrences of pattern in O(m log n).
+func (a *Array) Count(pattern []byte) int {
+	lo, hi := a.Range(pattern)
+	return hi - lo
+}
+
+// Lookup returns the sorted start offsets of every occurrence of pattern.
+func (a *Array) Lookup(pattern []byte) []int {
+	lo, hi := a.Range(pattern)
+	out := append([]int(nil), a.SA[lo:hi]...)
+	sort.Ints(out)
+	return out
+}
+
+// DistinctSubstrings counts the distinct non-empty substrings: every suffix
+// contributes its length minus the prefix it shares with its predecessor.
+func (a *Array) DistinctSubstrings() int {
+	n := len(a.text)
+	total := n * (n + 1) / 2
+	for _, l := range a.LCP {
+		total -= l
+	}
+	return total
+}
+
+// LongestRepeated returns the longest substring that occurs at least twice.
+func (a *Array) LongestRepeated() []byte {
+	best, at := 0, 0
+	for i, l := range a.LCP {
+		if l > best {
+			best, at = l, a.SA[i]
+		}
+	}
+	return a.text[at : at+best]
+}
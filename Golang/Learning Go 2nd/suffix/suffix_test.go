@@ -0,0 +1,98 @@
+package suffix
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// naiveSA sorts the suffixes directly.
+func naiveSA(text []byte) []int {
+	sa := make([]int, len(text))
+	for i := range sa {
+		sa[i] = i
+	}
+	slices.SortFunc(sa, func(a, b int) int { return bytes.Compare(text[a:], text[b:]) })
+	return sa
+}
+
+func commonLen(a, b []byte) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func naiveLookup(text, pattern []byte) []int {
+	var out []int
+	for i := 0; i+len(pattern) <= len(text); i++ {
+		if bytes.HasPrefix(text[i:], pattern) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func TestAgainstNaive(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	texts := [][]byte{nil, []byte("a"), []byte("banana"), []byte("mississippi"), bytes.Repeat([]byte("ab"), 50), bytes.Repeat([]byte{0}, 40), {255, 0, 255, 0}}
+	for range 300 {
+		// Small alphabets give long repeats and many rounds of doubling.
+		text := make([]byte, rng.IntN(60))
+		alphabet := 1 + rng.IntN(4)
+		for i := range text {
+			text[i] = byte('a' + rng.IntN(alphabet))
+		}
+		texts = append(texts, text)
+	}
+	for _, text := range texts {
+		a := New(text)
+		want := naiveSA(text)
+		if !slices.Equal(a.SA, want) {
+			t.Fatalf("SA(%q) = %v, want %v", text, a.SA, want)
+		}
+		if len(a.LCP) != len(text) {
+			t.Fatalf("LCP(%q) has %d entries", text, len(a.LCP))
+		}
+		distinct := map[string]bool{}
+		longest := 0
+		for i := range want {
+			if i > 0 {
+				l := commonLen(text[want[i-1]:], text[want[i]:])
+				if a.LCP[i] != l {
+					t.Fatalf("LCP(%q)[%d] = %d, want %d", text, i, a.LCP[i], l)
+				}
+				longest = max(longest, l)
+			}
+		}
+		for i := range text {
+			for j := i + 1; j <= len(text); j++ {
+				distinct[string(text[i:j])] = true
+			}
+		}
+		if got := a.DistinctSubstrings(); got != len(distinct) {
+			t.Errorf("DistinctSubstrings(%q) = %d, want %d", text, got, len(distinct))
+		}
+		if rep := a.LongestRepeated(); len(rep) != longest || len(rep) > 0 && len(naiveLookup(text, rep)) < 2 {
+			t.Errorf("LongestRepeated(%q) = %q, want a repeat of %d bytes", text, rep, longest)
+		}
+		for range 5 {
+			var pattern []byte
+			if len(text) > 0 && rng.IntN(2) == 0 {
+				i := rng.IntN(len(text))
+				pattern = text[i : i+1+rng.IntN(len(text)-i)]
+			} else {
+				pattern = []byte{byte('a' + rng.IntN(4)), byte('a' + rng.IntN(4))}
+			}
+			want := naiveLookup(text, pattern)
+			if got := a.Lookup(pattern); !slices.Equal(got, want) && len(got)+len(want) > 0 {
+				t.Errorf("Lookup(%q, %q) = %v, want %v", text, pattern, got, want)
+			}
+			if got := a.Count(pattern); got != len(want) {
+				t.Errorf("Count(%q, %q) = %d, want %d", text, pattern, got, len(want))
+			}
+		}
+	}
+}
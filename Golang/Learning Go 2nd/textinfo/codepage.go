@@ -0,0 +1,58 @@
+package textinfo
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// CodePage is a single-byte character set. EscPos is the table number that
+// selects it with ESC t n on an ESC/POS printer, or -1.
+type CodePage struct {
+	Name   string
+	EscPos int
+	table  [256]rune
+
+	once    sync.Once
+	reverse map[rune]byte
+}
+
+// CodePages lists the supported code pages.
+func CodePages() []*CodePage { return codePages }
+
+// LookupCodePage finds a code page by name, ignoring case, or by its ESC t
+// number written as "escpos:N".
+func LookupCodePage(name string) (*CodePage, error) {
+	for _, cp := range codePages {
+		if strings.EqualFold(cp.Name, name) || cp.EscPos >= 0 && name == fmt.Sprintf("escpos:%d", cp.EscPos) {
+			return cp, nil
+		}
+	}
+	names := make([]string, len(codePages))
+	for i, cp := range codePages {
+		names[i] = cp.Name
+	}
+	return nil, fmt.Errorf("textinfo: unknown code page %q (known: %s)", name, strings.Join(names, ", "))
+}
+
+// Decode returns the rune byte b stands for, and false if b is unassigned.
+func (cp *CodePage) Decode(b byte) (rune, bool) {
+	r := cp.table[b]
+	return r, r != utf8.RuneError
+}
+
+// Encode returns the byte that stands for r, and false if the code page
+// cannot represent it.
+func (cp *CodePage) Encode(r rune) (byte, bool) {
+	cp.once.Do(func() {
+		cp.reverse = make(map[rune]byte, 256)
+		for b := 255; b >= 0; b-- {
+			if r := cp.table[b]; r != utf8.RuneError {
+				cp.reverse[r] = byte(b)
+			}
+		}
+	})
+	b, ok := cp.reverse[r]
+	return b, ok
+}
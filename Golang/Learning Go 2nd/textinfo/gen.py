@@ -0,0 +1,154 @@
+#!/usr/bin/env python3
+"""Generates names.txt.gz and codepages_gen.go from Python's unicodedata and
+codecs. Run with go generate from this directory.
+
+Python's unicodedata usually lags Go's unicode package. To make the names
+match unicode.Version, pass that version's UnicodeData.txt from
+https://www.unicode.org/Public/<version>/ucd/ and the version itself:
+
+    python3 gen.py UnicodeData.txt 17.0.0
+"""
+
+import gzip
+import sys
+import unicodedata
+
+# Names computed in names.go from the code point instead of stored.
+ALGORITHMIC = (
+    "CJK UNIFIED IDEOGRAPH-",
+    "HANGUL SYLLABLE ",
+    "TANGUT IDEOGRAPH-",
+    "KHITAN SMALL SCRIPT CHARACTER-",
+    "NUSHU CHARACTER-",
+)
+
+# Controls have no Name property; these are their Name_Alias "control"
+# entries.
+CONTROLS = [
+    "NULL", "START OF HEADING", "START OF TEXT", "END OF TEXT",
+    "END OF TRANSMISSION", "ENQUIRY", "ACKNOWLEDGE", "ALERT", "BACKSPACE",
+    "CHARACTER TABULATION", "LINE FEED", "LINE TABULATION", "FORM FEED",
+    "CARRIAGE RETURN", "SHIFT OUT", "SHIFT IN", "DATA LINK ESCAPE",
+    "DEVICE CONTROL ONE", "DEVICE CONTROL TWO", "DEVICE CONTROL THREE",
+    "DEVICE CONTROL FOUR", "NEGATIVE ACKNOWLEDGE", "SYNCHRONOUS IDLE",
+    "END OF TRANSMISSION BLOCK", "CANCEL", "END OF MEDIUM", "SUBSTITUTE",
+    "ESCAPE", "INFORMATION SEPARATOR FOUR", "INFORMATION SEPARATOR THREE",
+    "INFORMATION SEPARATOR TWO", "INFORMATION SEPARATOR ONE",
+]
+C1 = [
+    "PADDING CHARACTER", "HIGH OCTET PRESET", "BREAK PERMITTED HERE",
+    "NO BREAK HERE", "INDEX", "NEXT LINE", "START OF SELECTED AREA",
+    "END OF SELECTED AREA", "CHARACTER TABULATION SET",
+    "CHARACTER TABULATION WITH JUSTIFICATION", "LINE TABULATION SET",
+    "PARTIAL LINE FORWARD", "PARTIAL LINE BACKWARD", "REVERSE LINE FEED",
+    "SINGLE SHIFT TWO", "SINGLE SHIFT THREE", "DEVICE CONTROL STRING",
+    "PRIVATE USE ONE", "PRIVATE USE TWO", "SET TRANSMIT STATE",
+    "CANCEL CHARACTER", "MESSAGE WAITING", "START OF GUARDED AREA",
+    "END OF GUARDED AREA", "START OF STRING",
+    "SINGLE GRAPHIC CHARACTER INTRODUCER", "SINGLE CHARACTER INTRODUCER",
+    "CONTROL SEQUENCE INTRODUCER", "STRING TERMINATOR",
+    "OPERATING SYSTEM COMMAND", "PRIVACY MESSAGE",
+    "APPLICATION PROGRAM COMMAND",
+]
+
+# Code pages selectable with ESC t n on ESC/POS printers, plus the ISO
+# Latin sets. Katakana is built by hand: Python has no single-byte codec
+# for JIS X 0201.
+CODE_PAGES = [
+    # name, codec, ESC t number (-1: none)
+    ("cp437", "cp437", 0),
+    ("katakana", None, 1),
+    ("cp850", "cp850", 2),
+    ("cp860", "cp860", 3),
+    ("cp863", "cp863", 4),
+    ("cp865", "cp865", 5),
+    ("cp1252", "cp1252", 16),
+    ("cp866", "cp866", 17),
+    ("cp852", "cp852", 18),
+    ("cp858", "cp858", 19),
+    ("iso8859-1", "latin_1", -1),
+    ("iso8859-15", "iso8859_15", -1),
+]
+
+
+def ucd_names(path):
+    """Reads the Name field of UnicodeData.txt. Controls and the First/Last
+    pairs of ranges have names in angle brackets instead."""
+    out = {}
+    with open(path, encoding="utf-8") as f:
+        for line in f:
+            fields = line.split(";")
+            if len(fields) > 1 and not fields[1].startswith("<"):
+                out[int(fields[0], 16)] = fields[1]
+    return out
+
+
+if len(sys.argv) == 3:
+    UCD = ucd_names(sys.argv[1])
+    VERSION = sys.argv[2]
+    name_of = lambda cp: UCD.get(cp, "")
+elif len(sys.argv) == 1:
+    VERSION = unicodedata.unidata_version
+    name_of = lambda cp: unicodedata.name(chr(cp), "")
+else:
+    sys.exit("usage: gen.py [UnicodeData.txt version]")
+
+
+def names():
+    lines = []
+    prev = 0
+    for cp in range(0x110000):
+        if cp < 0x20:
+            name = CONTROLS[cp]
+        elif cp == 0x7F:
+            name = "DELETE"
+        elif 0x80 <= cp <= 0x9F:
+            name = C1[cp - 0x80]
+        else:
+            name = name_of(cp)
+        if not name or name.startswith(ALGORITHMIC):
+            continue
+        lines.append("%x;%s\n" % (cp - prev, name))
+        prev = cp
+    with open("names.txt.gz", "wb") as f:
+        f.write(gzip.compress("".join(lines).encode(), 9, mtime=0))
+
+
+def table(codec):
+    out = []
+    for b in range(256):
+        if codec is None:
+            if b < 0x80:
+                out.append(b)
+            elif 0xA1 <= b <= 0xDF:
+                out.append(0xFF61 + b - 0xA1)
+            else:
+                out.append(0xFFFD)
+            continue
+        try:
+            out.append(ord(bytes([b]).decode(codec)))
+        except UnicodeDecodeError:
+            out.append(0xFFFD)
+    return out
+
+
+def codepages():
+    w = []
+    w.append("// Code generated by gen.py; DO NOT EDIT.\n\npackage textinfo\n\n")
+    w.append("// Unicode %s names; see names.txt.gz.\nconst namesVersion = %r\n\n"
+             % (VERSION, VERSION))
+    w.append("var codePages = []*CodePage{\n")
+    for name, codec, escpos in CODE_PAGES:
+        w.append("\t{Name: %r, EscPos: %d, table: [256]rune{\n" % (name, escpos))
+        t = table(codec)
+        for i in range(0, 256, 8):
+            w.append("\t\t" + " ".join("0x%04X," % r for r in t[i:i+8]) + "\n")
+        w.append("\t}},\n")
+    w.append("}\n")
+    src = "".join(w).replace("'", '"')
+    with open("codepages_gen.go", "w") as f:
+        f.write(src)
+
+
+names()
+codepages()
@@ -0,0 +1,174 @@
+package textinfo
+
+import (
+	"iter"
+	"unicode"
+	"unicode/utf8"
+)
+
+// gcb is a Grapheme_Cluster_Break property value (UAX #29).
+type gcb int
+
+const (
+	gbOther gcb = iota
+	gbCR
+	gbLF
+	gbControl
+	gbExtend
+	gbZWJ
+	gbRI
+	gbPrepend
+	gbSpacingMark
+	gbL
+	gbV
+	gbT
+	gbLV
+	gbLVT
+)
+
+// spacingMarkExceptions are Mc characters that are not SpacingMark.
+var spacingMarkExceptions = &unicode.RangeTable{R16: []unicode.Range16{
+	{0x102B, 0x102C, 1}, {0x1038, 0x1038, 1}, {0x1062, 0x1064, 1}, {0x1067, 0x106D, 1},
+	{0x1083, 0x1083, 1}, {0x1087, 0x108C, 1}, {0x108F, 0x108F, 1}, {0x109A, 0x109C, 1},
+	{0x1A61, 0x1A61, 1}, {0x1A63, 0x1A64, 1}, {0xAA7B, 0xAA7B, 1}, {0xAA7D, 0xAA7D, 1},
+}, R32: []unicode.Range32{{0x11720, 0x11721, 1}}}
+
+// prepend lists Prepend characters beyond the prepended concatenation marks.
+var prepend = &unicode.RangeTable{R16: []unicode.Range16{{0x0D4E, 0x0D4E, 1}}, R32: []unicode.Range32{
+	{0x111C2, 0x111C3, 1}, {0x1193F, 0x1193F, 1}, {0x11941, 0x11941, 1}, {0x11A3A, 0x11A3A, 1},
+	{0x11A84, 0x11A89, 1}, {0x11D46, 0x11D46, 1},
+}}
+
+// pictographic approximates Extended_Pictographic from emoji-data.txt, which
+// the unicode package does not carry.
+var pictographic = &unicode.RangeTable{R16: []unicode.Range16{
+	{0x00A9, 0x00AE, 5}, {0x203C, 0x2049, 13}, {0x2122, 0x2139, 23}, {0x2194, 0x2199, 1},
+	{0x21A9, 0x21AA, 1}, {0x231A, 0x231B, 1}, {0x2328, 0x2388, 96}, {0x23CF, 0x23CF, 1},
+	{0x23E9, 0x23F3, 1}, {0x23F8, 0x23FA, 1}, {0x24C2, 0x24C2, 1}, {0x25AA, 0x25AB, 1},
+	{0x25B6, 0x25C0, 10}, {0x25FB, 0x25FE, 1}, {0x2600, 0x2605, 1}, {0x2607, 0x2612, 1},
+	{0x2614, 0x2685, 1}, {0x2690, 0x2705, 1}, {0x2708, 0x2712, 1}, {0x2714, 0x2716, 2},
+	{0x271D, 0x2721, 4}, {0x2728, 0x2728, 1}, {0x2733, 0x2734, 1}, {0x2744, 0x2747, 3},
+	{0x274C, 0x274E, 2}, {0x2753, 0x2755, 1}, {0x2757, 0x2757, 1}, {0x2763, 0x2767, 1},
+	{0x2795, 0x2797, 1}, {0x27A1, 0x27B0, 15}, {0x27BF, 0x27BF, 1}, {0x2934, 0x2935, 1},
+	{0x2B05, 0x2B07, 1}, {0x2B1B, 0x2B1C, 1}, {0x2B50, 0x2B55, 5}, {0x3030, 0x303D, 13},
+	{0x3297, 0x3299, 2},
+}, R32: []unicode.Range32{
+	{0x1F000, 0x1F0FF, 1}, {0x1F10D, 0x1F10F, 1}, {0x1F12F, 0x1F12F, 1}, {0x1F16C, 0x1F171, 1},
+	{0x1F17E, 0x1F17F, 1}, {0x1F18E, 0x1F18E, 1}, {0x1F191, 0x1F19A, 1}, {0x1F1AD, 0x1F1E5, 1},
+	{0x1F201, 0x1F20F, 1}, {0x1F21A, 0x1F21A, 1}, {0x1F22F, 0x1F22F, 1}, {0x1F232, 0x1F23A, 1},
+	{0x1F23C, 0x1F23F, 1}, {0x1F249, 0x1F3FA, 1}, {0x1F400, 0x1F53D, 1}, {0x1F546, 0x1F64F, 1},
+	{0x1F680, 0x1F6FF, 1}, {0x1F774, 0x1F77F, 1}, {0x1F7D5, 0x1F7FF, 1}, {0x1F80C, 0x1F80F, 1},
+	{0x1F848, 0x1F84F, 1}, {0x1F85A, 0x1F85F, 1}, {0x1F888, 0x1F88F, 1}, {0x1F8AE, 0x1F8FF, 1},
+	{0x1F90C, 0x1F93A, 1}, {0x1F93C, 0x1F945, 1}, {0x1F947, 0x1FAFF, 1}, {0x1FC00, 0x1FFFD, 1},
+}}
+
+func breakProp(r rune) gcb {
+	switch {
+	case r == '\r':
+		return gbCR
+	case r == '\n':
+		return gbLF
+	case r == 0x200D:
+		return gbZWJ
+	case r >= 0x1100 && r <= 0x115F, r >= 0xA960 && r <= 0xA97C:
+		return gbL
+	case r >= 0x1160 && r <= 0x11A7, r >= 0xD7B0 && r <= 0xD7C6:
+		return gbV
+	case r >= 0x11A8 && r <= 0x11FF, r >= 0xD7CB && r <= 0xD7FB:
+		return gbT
+	case r >= 0xAC00 && r <= 0xD7A3:
+		if (r-0xAC00)%28 == 0 {
+			return gbLV
+		}
+		return gbLVT
+	case unicode.Is(unicode.Regional_Indicator, r):
+		return gbRI
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Other_Grapheme_Extend), r >= 0x1F3FB && r <= 0x1F3FF:
+		return gbExtend
+	case unicode.In(r, unicode.Prepended_Concatenation_Mark, prepend):
+		return gbPrepend
+	case unicode.In(r, unicode.Cc, unicode.Cf, unicode.Zl, unicode.Zp):
+		return gbControl
+	case r == 0x0E33 || r == 0x0EB3, unicode.Is(unicode.Mc, r) && !unicode.Is(spacingMarkExceptions, r):
+		return gbSpacingMark
+	}
+	return gbOther
+}
+
+// Graphemes yields the extended grapheme clusters of s with their byte
+// offsets: what a reader would call one character, such as e plus a
+// combining accent, a flag made of two regional indicators, or an emoji
+// family joined with ZWJ. It follows UAX #29 except for the Indic conjunct
+// rule (GB9c). Invalid bytes form clusters of their own.
+func Graphemes(s string) iter.Seq2[int, string] {
+	return func(yield func(int, string) bool) {
+		for off := 0; off < len(s); {
+			n := firstCluster(s[off:])
+			if !yield(off, s[off:off+n]) {
+				return
+			}
+			off += n
+		}
+	}
+}
+
+func firstCluster(s string) int {
+	r, i := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError && i == 1 {
+		return 1
+	}
+	prev := breakProp(r)
+	emoji := unicode.Is(pictographic, r) // the cluster so far ends in ExtPict Extend*
+	ris := 0
+	if prev == gbRI {
+		ris = 1
+	}
+	for i < len(s) {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && n == 1 {
+			break
+		}
+		p := breakProp(r)
+		pict := unicode.Is(pictographic, r)
+		join := false
+		switch {
+		case prev == gbCR && p == gbLF: // GB3
+			join = true
+		case prev == gbControl || prev == gbCR || prev == gbLF: // GB4
+		case p == gbControl || p == gbCR || p == gbLF: // GB5
+		case prev == gbL && (p == gbL || p == gbV || p == gbLV || p == gbLVT): // GB6
+			join = true
+		case (prev == gbLV || prev == gbV) && (p == gbV || p == gbT): // GB7
+			join = true
+		case (prev == gbLVT || prev == gbT) && p == gbT: // GB8
+			join = true
+		case p == gbExtend || p == gbZWJ || p == gbSpacingMark: // GB9, GB9a
+			join = true
+		case prev == gbPrepend: // GB9b
+			join = true
+		case prev == gbZWJ && emoji && pict: // GB11
+			join = true
+		case prev == gbRI && p == gbRI && ris%2 == 1: // GB12, GB13
+			join = true
+		}
+		if !join {
+			break
+		}
+		// GB11 looks back across Extend* and one ZWJ to an ExtPict.
+		switch {
+		case pict:
+			emoji = true
+		case (p == gbExtend || p == gbZWJ) && prev != gbZWJ:
+		default:
+			emoji = false
+		}
+		if p == gbRI {
+			ris++
+		} else {
+			ris = 0
+		}
+		prev = p
+		i += n
+	}
+	return i
+}
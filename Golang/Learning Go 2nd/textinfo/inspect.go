@@ -0,0 +1,205 @@
+package textinfo
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Summary counts what Inspect found.
+type Summary struct {
+	Bytes, Runes, Graphemes, Invalid int
+	Unencodable                      int // runes the chosen code page lacks
+}
+
+// Inspect prints one line per UTF-8 sequence of text: byte offset, bytes in
+// hex, the decoded rune with its category and name, and its byte in cp if
+// cp is not nil. Invalid sequences are explained. It ends with the three
+// ways of measuring length and the multi-rune grapheme clusters.
+func Inspect(w io.Writer, text []byte, cp *CodePage) (Summary, error) {
+	ew := &errWriter{w: w}
+	cpCol := ""
+	if cp != nil {
+		cpCol = cp.Name
+	}
+	ew.printf("%6s  %-12s %-8s %-5s %-3s %-6s %s\n", "offset", "bytes", "rune", "char", "cat", cpCol, "name")
+	var sum Summary
+	var missing []rune
+	for off := 0; off < len(text); {
+		r, n := utf8.DecodeRune(text[off:])
+		if r == utf8.RuneError && n <= 1 {
+			ew.printf("%6d  %-12s invalid: %s; decodes as U+FFFD\n", off, hexBytes(text[off:off+1]), invalidReason(text[off:]))
+			sum.Invalid++
+			sum.Runes++
+			off++
+			continue
+		}
+		enc := ""
+		if cp != nil {
+			if b, ok := cp.Encode(r); ok {
+				enc = fmt.Sprintf("%02X", b)
+			} else {
+				enc = "--"
+				sum.Unencodable++
+				if !slices.Contains(missing, r) {
+					missing = append(missing, r)
+				}
+			}
+		}
+		name := Name(r)
+		if name == "" {
+			name = "(no name in the Unicode " + NamesVersion + " table)"
+		}
+		ew.printf("%6d  %-12s U+%04X  %-5s %-3s %-6s %s\n", off, hexBytes(text[off:off+n]), r, glyph(r), Category(r), enc, name)
+		sum.Runes++
+		off += n
+	}
+	sum.Bytes = len(text)
+
+	type cluster struct {
+		off  int
+		text string
+	}
+	var multi []cluster
+	for off, g := range Graphemes(string(text)) {
+		sum.Graphemes++
+		if utf8.RuneCountInString(g) > 1 {
+			multi = append(multi, cluster{off, g})
+		}
+	}
+	ew.printf("\nlen = %d bytes, utf8.RuneCount = %d runes, %d grapheme clusters", sum.Bytes, sum.Runes, sum.Graphemes)
+	if sum.Invalid > 0 {
+		ew.printf(", %d invalid bytes", sum.Invalid)
+	}
+	ew.printf("\n")
+	for _, c := range multi {
+		var parts []string
+		for _, r := range c.text {
+			parts = append(parts, fmt.Sprintf("U+%04X", r))
+		}
+		ew.printf("  cluster at %d: %q = %s\n", c.off, c.text, strings.Join(parts, " + "))
+	}
+
+	if cp != nil {
+		esc := ""
+		if cp.EscPos >= 0 {
+			esc = fmt.Sprintf(" (ESC t %d)", cp.EscPos)
+		}
+		if len(missing) == 0 {
+			ew.printf("\nevery rune fits in %s%s\n", cp.Name, esc)
+		} else {
+			ew.printf("\n%d runes do not fit in %s%s; distinct: %s\n", sum.Unencodable, cp.Name, esc, quoteRunes(missing))
+		}
+		var fits []string
+		for _, other := range codePages {
+			if encodable(other, text) {
+				fits = append(fits, other.Name)
+			}
+		}
+		if len(fits) > 0 {
+			ew.printf("code pages that hold every rune: %s\n", strings.Join(fits, ", "))
+		} else {
+			ew.printf("no single code page holds every rune\n")
+		}
+	}
+	return sum, ew.err
+}
+
+// encodable reports whether cp holds every rune of text; invalid bytes are
+// not runes and do not count.
+func encodable(cp *CodePage, text []byte) bool {
+	for len(text) > 0 {
+		r, n := utf8.DecodeRune(text)
+		text = text[n:]
+		if r == utf8.RuneError && n == 1 {
+			continue
+		}
+		if _, ok := cp.Encode(r); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func quoteRunes(rs []rune) string {
+	parts := make([]string, len(rs))
+	for i, r := range rs {
+		parts[i] = fmt.Sprintf("%s U+%04X", strconv.QuoteRune(r), r)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func hexBytes(b []byte) string {
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = fmt.Sprintf("%02X", c)
+	}
+	return strings.Join(parts, " ")
+}
+
+// glyph is how r is shown in the char column: escaped when invisible, on a
+// dotted circle when it combines with what comes before.
+func glyph(r rune) string {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return "◌" + string(r)
+	case r == ' ' || unicode.IsGraphic(r) && !unicode.Is(unicode.Zs, r):
+		return string(r)
+	}
+	q := strconv.QuoteRuneToGraphic(r)
+	return q[1 : len(q)-1]
+}
+
+// invalidReason explains why b does not start with a valid UTF-8 sequence.
+func invalidReason(b []byte) string {
+	c := b[0]
+	var size int
+	switch {
+	case c < 0x80:
+		return "ASCII byte" // not reached: always valid
+	case c < 0xC0:
+		return fmt.Sprintf("continuation byte 0x%02X without a lead byte", c)
+	case c < 0xC2:
+		return fmt.Sprintf("0x%02X would start an overlong 2-byte encoding of ASCII", c)
+	case c < 0xE0:
+		size = 2
+	case c < 0xF0:
+		size = 3
+	case c < 0xF5:
+		size = 4
+	default:
+		return fmt.Sprintf("byte 0x%02X never appears in UTF-8", c)
+	}
+	for i := 1; i < size; i++ {
+		if i >= len(b) {
+			return fmt.Sprintf("%d-byte sequence truncated by the end of input", size)
+		}
+		if b[i] < 0x80 || b[i] > 0xBF {
+			return fmt.Sprintf("%d-byte sequence cut short by 0x%02X at byte %d", size, b[i], i)
+		}
+	}
+	switch {
+	case c == 0xE0 && b[1] < 0xA0, c == 0xF0 && b[1] < 0x90:
+		return "overlong encoding of a smaller code point"
+	case c == 0xED && b[1] >= 0xA0:
+		return "UTF-16 surrogate half, which UTF-8 forbids"
+	case c == 0xF4 && b[1] >= 0x90:
+		return "code point beyond U+10FFFF"
+	}
+	return "invalid sequence"
+}
+
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...any) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
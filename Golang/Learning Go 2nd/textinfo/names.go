@@ -0,0 +1,118 @@
+// Package textinfo explains what is inside a piece of text: the bytes of
+// each UTF-8 sequence, the runes they decode to with their Unicode names
+// and categories, grapheme clusters, and how every rune would be encoded in
+// the legacy single-byte code pages receipt printers use.
+//
+// Names and categories come from different Unicode versions. Categories,
+// scripts and grapheme break properties are read from Go's unicode package,
+// at unicode.Version. Names come from a table gen.py builds, at
+// NamesVersion, which is Python's unicodedata unless gen.py is given the
+// matching UnicodeData.txt. When NamesVersion is the older of the two, as
+// with the checked-in table, characters added in between have a category
+// but no name, and Inspect says so.
+package textinfo
+
+//go:generate python3 gen.py
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// names.txt.gz holds one "delta;NAME" line per named code point, the delta
+// being the hex distance from the previous line's code point.
+//
+//go:embed names.txt.gz
+var namesGz []byte
+
+var loadNames = sync.OnceValue(func() map[rune]string {
+	zr, err := gzip.NewReader(bytes.NewReader(namesGz))
+	if err != nil {
+		panic(fmt.Sprintf("textinfo: names table: %v", err))
+	}
+	m := make(map[rune]string, 40000)
+	sc := bufio.NewScanner(zr)
+	r := rune(0)
+	for sc.Scan() {
+		delta, name, _ := strings.Cut(sc.Text(), ";")
+		d, err := strconv.ParseUint(delta, 16, 32)
+		if err != nil {
+			panic(fmt.Sprintf("textinfo: names table: %v", err))
+		}
+		r += rune(d)
+		m[r] = name
+	}
+	return m
+})
+
+// Hangul syllables are named after their jamo (Unicode §3.12).
+var (
+	jamoL = strings.Fields("G GG N D DD R M B BB S SS _ J JJ C K T P H")
+	jamoV = strings.Fields("A AE YA YAE EO E YEO YE O WA WAE OE YO U WEO WE WI YU EU YI I")
+	jamoT = strings.Fields("_ G GG GS N NJ NH D L LG LM LB LS LT LP LH M B BS S SS NG J C K T P H")
+)
+
+// Name returns the Unicode name of r, or "" for unassigned code points and
+// those assigned after NamesVersion.
+func Name(r rune) string {
+	switch {
+	case r >= 0xAC00 && r <= 0xD7A3:
+		s := int(r - 0xAC00)
+		l, v, t := s/(21*28), s%(21*28)/28, s%28
+		return "HANGUL SYLLABLE " + strings.ReplaceAll(jamoL[l]+jamoV[v]+jamoT[t], "_", "")
+	case unicode.Is(unicode.Unified_Ideograph, r):
+		return fmt.Sprintf("CJK UNIFIED IDEOGRAPH-%04X", r)
+	case unicode.Is(unicode.Tangut, r) && (r >= 0x17000 && r <= 0x187FF || r >= 0x18D00 && r <= 0x18D7F) && unicode.IsLetter(r):
+		return fmt.Sprintf("TANGUT IDEOGRAPH-%04X", r)
+	case unicode.Is(unicode.Khitan_Small_Script, r) && unicode.IsLetter(r):
+		return fmt.Sprintf("KHITAN SMALL SCRIPT CHARACTER-%04X", r)
+	case unicode.Is(unicode.Nushu, r) && unicode.IsLetter(r) && r != 0x16FE1:
+		return fmt.Sprintf("NUSHU CHARACTER-%04X", r)
+	}
+	return loadNames()[r]
+}
+
+// NamesVersion is the Unicode version of the name table. It can be older
+// than unicode.Version, which categories come from; see the package
+// documentation.
+const NamesVersion = namesVersion
+
+// categories are the two-letter general categories, most common first.
+var categories = []string{
+	"Ll", "Lu", "Lo", "Lt", "Lm", "Mn", "Mc", "Me", "Nd", "Nl", "No",
+	"Pc", "Pd", "Ps", "Pe", "Pi", "Pf", "Po", "Sm", "Sc", "Sk", "So",
+	"Zs", "Zl", "Zp", "Cc", "Cf", "Co", "Cs",
+}
+
+// Category returns r's two-letter general category, "Cn" when unassigned.
+func Category(r rune) string {
+	for _, c := range categories {
+		if unicode.Is(unicode.Categories[c], r) {
+			return c
+		}
+	}
+	return "Cn"
+}
+
+var categoryNames = map[string]string{
+	"Ll": "lowercase letter", "Lu": "uppercase letter", "Lo": "other letter",
+	"Lt": "titlecase letter", "Lm": "modifier letter", "Mn": "nonspacing mark",
+	"Mc": "spacing mark", "Me": "enclosing mark", "Nd": "decimal digit",
+	"Nl": "letter number", "No": "other number", "Pc": "connector punctuation",
+	"Pd": "dash punctuation", "Ps": "open punctuation", "Pe": "close punctuation",
+	"Pi": "initial quote", "Pf": "final quote", "Po": "other punctuation",
+	"Sm": "math symbol", "Sc": "currency symbol", "Sk": "modifier symbol",
+	"So": "other symbol", "Zs": "space separator", "Zl": "line separator",
+	"Zp": "paragraph separator", "Cc": "control", "Cf": "format",
+	"Co": "private use", "Cs": "surrogate", "Cn": "unassigned",
+}
+
+// CategoryName spells out a two-letter category.
+func CategoryName(cat string) string { return categoryNames[cat] }
@@ -0,0 +1,159 @@
+package textinfo
+
+import (
+	"bufio"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func clusters(s string) []string {
+	var out []string
+	for off, c := range Graphemes(s) {
+		if off != len(strings.Join(out, "")) {
+			return append(out, "bad offset "+strconv.Itoa(off))
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func TestGraphemeBreakTest(t *testing.T) {
+	f, err := os.Open("testdata/GraphemeBreakTest.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		spec, _, _ := strings.Cut(sc.Text(), "#")
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		var want []string
+		var s strings.Builder
+		for _, field := range strings.Fields(spec) {
+			switch field {
+			case "÷":
+				want = append(want, "")
+			case "×":
+			default:
+				r, err := strconv.ParseUint(field, 16, 32)
+				if err != nil {
+					t.Fatalf("line %d: %v", line, err)
+				}
+				s.WriteRune(rune(r))
+				want[len(want)-1] += string(rune(r))
+			}
+		}
+		want = want[:len(want)-1] // the boundary at the end
+		if got := clusters(s.String()); !slices.Equal(got, want) {
+			t.Errorf("line %d: %s: Graphemes yields %+q, want %+q", line, strings.TrimSpace(spec), got, want)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGraphemesInvalid(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"e\xff\u0301", []string{"e", "\xff", "\u0301"}},
+		{"é\xff", []string{"é", "\xff"}},
+		{"\xe2\x82", []string{"\xe2", "\x82"}},
+		{"\ufffd\u0301", []string{"\ufffd\u0301"}}, // an encoded U+FFFD is a character
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := clusters(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("Graphemes(%+q) yields %+q, want %+q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want string
+	}{
+		{'A', "LATIN CAPITAL LETTER A"},
+		{'é', "LATIN SMALL LETTER E WITH ACUTE"},
+		{0x1F600, "GRINNING FACE"},
+		{0xAC00, "HANGUL SYLLABLE GA"},
+		{0xAC01, "HANGUL SYLLABLE GAG"},
+		{0xD55C, "HANGUL SYLLABLE HAN"},
+		{0xC544, "HANGUL SYLLABLE A"},
+		{0xD7A3, "HANGUL SYLLABLE HIH"},
+		{0x4E00, "CJK UNIFIED IDEOGRAPH-4E00"},
+		{0x20000, "CJK UNIFIED IDEOGRAPH-20000"},
+		{0x17000, "TANGUT IDEOGRAPH-17000"},
+		{0x18B00, "KHITAN SMALL SCRIPT CHARACTER-18B00"},
+		{0x1B170, "NUSHU CHARACTER-1B170"},
+		{0x0378, ""},
+		{0x10FFFF, ""},
+	}
+	for _, tt := range tests {
+		if got := Name(tt.r); got != tt.want {
+			t.Errorf("Name(%U) = %q, want %q", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestCodePages(t *testing.T) {
+	tests := []struct {
+		page string
+		r    rune
+		b    byte
+		ok   bool
+	}{
+		{"cp437", 'A', 'A', true},
+		{"cp437", 'é', 0x82, true},
+		{"cp437", '│', 0xB3, true},
+		{"cp437", '€', 0, false},
+		{"cp858", '€', 0xD5, true},
+		{"cp1252", '€', 0x80, true},
+		{"iso8859-15", '€', 0xA4, true},
+		{"iso8859-15", '¤', 0, false},
+		{"iso8859-1", '¤', 0xA4, true},
+		{"cp866", 'Ж', 0x86, true},
+		{"cp1252", 'Ж', 0, false},
+	}
+	for _, tt := range tests {
+		cp, err := LookupCodePage(tt.page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, ok := cp.Encode(tt.r); b != tt.b || ok != tt.ok {
+			t.Errorf("%s: Encode(%q) = %#x, %v; want %#x, %v", tt.page, tt.r, b, ok, tt.b, tt.ok)
+		}
+	}
+
+	// Encode inverts Decode on every assigned byte.
+	for _, cp := range CodePages() {
+		for b := range 256 {
+			r, ok := cp.Decode(byte(b))
+			if !ok {
+				continue
+			}
+			if e, ok := cp.Encode(r); !ok || cp.table[e] != r {
+				t.Errorf("%s: Decode(%#x) = %q, but Encode(%q) = %#x, %v", cp.Name, b, r, r, e, ok)
+			}
+		}
+	}
+
+	for name, want := range map[string]string{"CP437": "cp437", "escpos:16": "cp1252", "escpos:2": "cp850"} {
+		if cp, err := LookupCodePage(name); err != nil || cp.Name != want {
+			t.Errorf("LookupCodePage(%q) = %v, %v; want %s", name, cp, err, want)
+		}
+	}
+	for _, name := range []string{"cp1251", "escpos:-1"} {
+		if _, err := LookupCodePage(name); err == nil {
+			t.Errorf("LookupCodePage(%q) succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package trie
+
+import (
+	"iter"
+	"sort"
+	"strings"
+)
+
+// Radix is a compressed trie: every edge carries a whole substring and no
+// node without a value has a single child.
+type Radix[V any] struct {
+	root radixNode[V]
+	size int
+}
+
+type radixNode[V any] struct {
+	edges    []radixEdge[V] // sorted by label's first byte
+	value    V
+	hasValue bool
+}
+
+type radixEdge[V any] struct {
+	label string
+	node  *radixNode[V]
+}
+
+func (r *Radix[V]) Len() int { return r.size }
+
+// edge returns the index of the edge starting with b, or where it would go.
+func (n *radixNode[V]) edge(b byte) (int, bool) {
+	i := sort.Search(len(n.edges), func(i int) bool { return n.edges[i].label[0] >= b })
+	return i, i < len(n.edges) && n.edges[i].label[0] == b
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// Put stores v under key and reports whether the key was new.
+func (r *Radix[V]) Put(key string, v V) bool {
+	n := &r.root
+	for {
+		if key == "" {
+			added := !n.hasValue
+			n.value, n.hasValue = v, true
+			if added {
+				r.size++
+			}
+			return added
+		}
+		i, ok := n.edge(key[0])
+		if !ok {
+			leaf := &radixNode[V]{value: v, hasValue: true}
+			n.edges = append(n.edges, radixEdge[V]{})
+			copy(n.edges[i+1:], n.edges[i:])
+			n.edges[i] = radixEdge[V]{label: key, node: leaf}
+			r.size++
+			return true
+		}
+		e := &n.edges[i]
+		common := commonPrefix(key, e.label)
+		if common < len(e.label) {
+			// Split the edge: the shared part leads to a new middle node.
+			mid := &radixNode[V]{edges: []radixEdge[V]{{label: e.label[common:], node: e.node}}}
+			e.label, e.node = e.label[:common], mid
+		}
+		n, key = e.node, key[common:]
+	}
+}
+
+func (r *Radix[V]) Get(key string) (V, bool) {
+	n := &r.root
+	for key != "" {
+		i, ok := n.edge(key[0])
+		if !ok || !strings.HasPrefix(key, n.edges[i].label) {
+			var zero V
+			return zero, false
+		}
+		key = key[len(n.edges[i].label):]
+		n = n.edges[i].node
+	}
+	return n.value, n.hasValue
+}
+
+// Delete removes key, merging a node left with a single child back into its
+// parent edge.
+func (r *Radix[V]) Delete(key string) bool {
+	var deleted bool
+	var walk func(n *radixNode[V], key string)
+	walk = func(n *radixNode[V], key string) {
+		if key == "" {
+			if n.hasValue {
+				var zero V
+				n.value, n.hasValue, deleted = zero, false, true
+			}
+			return
+		}
+		i, ok := n.edge(key[0])
+		if !ok || !strings.HasPrefix(key, n.edges[i].label) {
+			return
+		}
+		e := &n.edges[i]
+		walk(e.node, key[len(e.label):])
+		child := e.node
+		switch {
+		case child.hasValue:
+		case len(child.edges) == 0:
+			n.edges = append(n.edges[:i], n.edges[i+1:]...)
+		case len(child.edges) == 1:
+			e.label += child.edges[0].label
+			e.node = child.edges[0].node
+		}
+	}
+	walk(&r.root, key)
+	if deleted {
+		r.size--
+	}
+	return deleted
+}
+
+// LongestPrefix returns the longest stored key that is a prefix of s.
+func (r *Radix[V]) LongestPrefix(s string) (string, V, bool) {
+	var (
+		bestLen = -1
+		best    V
+	)
+	n, depth := &r.root, 0
+	for {
+		if n.hasValue {
+			bestLen, best = depth, n.value
+		}
+		if depth == len(s) {
+			break
+		}
+		i, ok := n.edge(s[depth])
+		if !ok || !strings.HasPrefix(s[depth:], n.edges[i].label) {
+			break
+		}
+		depth += len(n.edges[i].label)
+		n = n.edges[i].node
+	}
+	if bestLen < 0 {
+		return "", best, false
+	}
+	return s[:bestLen], best, true
+}
+
+// WithPrefix yields every key starting with prefix, in byte order.
+func (r *Radix[V]) WithPrefix(prefix string) iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		// Descend until the remaining prefix ends inside or at an edge.
+		n, path, rest := &r.root, "", prefix
+		for rest != "" {
+			i, ok := n.edge(rest[0])
+			if !ok {
+				return
+			}
+			e := n.edges[i]
+			switch {
+			case strings.HasPrefix(rest, e.label):
+				rest = rest[len(e.label):]
+			case strings.HasPrefix(e.label, rest):
+				rest = ""
+			default:
+				return
+			}
+			path += e.label
+			n = e.node
+		}
+		var walk func(n *radixNode[V], path string) bool
+		walk = func(n *radixNode[V], path string) bool {
+			if n.hasValue && !yield(path, n.value) {
+				return false
+			}
+			for _, e := range n.edges {
+				if !walk(e.node, path+e.label) {
+					return false
+				}
+			}
+			return true
+		}
+		walk(n, path)
+	}
+}
+
+// All yields every key in byte order.
+func (r *Radix[V]) All() iter.Seq2[string, V] { return r.WithPrefix("") }
+
+// Nodes counts the nodes below the root, for comparing against Trie.
+func (r *Radix[V]) Nodes() int {
+	var count func(n *radixNode[V]) int
+	count = func(n *radixNode[V]) int {
+		c := 0
+		for _, e := range n.edges {
+			c += 1 + count(e.node)
+		}
+		return c
+	}
+	return count(&r.root)
+}
@@ -0,0 +1,153 @@
+// Package trie provides string-keyed prefix trees: Trie stores one byte per
+// edge, Radix compresses chains of single-child nodes into one edge. Both
+// support prefix iteration in key order and longest-prefix match.
+package trie
+
+import (
+	"iter"
+	"sort"
+)
+
+// Trie maps string keys to values with one node per key byte.
+type Trie[V any] struct {
+	root trieNode[V]
+	size int
+}
+
+type trieNode[V any] struct {
+	children map[byte]*trieNode[V]
+	value    V
+	hasValue bool
+}
+
+func (t *Trie[V]) Len() int { return t.size }
+
+// Put stores v under key and reports whether the key was new.
+func (t *Trie[V]) Put(key string, v V) bool {
+	n := &t.root
+	for i := 0; i < len(key); i++ {
+		if n.children == nil {
+			n.children = map[byte]*trieNode[V]{}
+		}
+		child, ok := n.children[key[i]]
+		if !ok {
+			child = &trieNode[V]{}
+			n.children[key[i]] = child
+		}
+		n = child
+	}
+	added := !n.hasValue
+	n.value, n.hasValue = v, true
+	if added {
+		t.size++
+	}
+	return added
+}
+
+func (t *Trie[V]) find(key string) *trieNode[V] {
+	n := &t.root
+	for i := 0; i < len(key) && n != nil; i++ {
+		n = n.children[key[i]]
+	}
+	return n
+}
+
+func (t *Trie[V]) Get(key string) (V, bool) {
+	if n := t.find(key); n != nil && n.hasValue {
+		return n.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Delete removes key and prunes nodes left without values or children.
+func (t *Trie[V]) Delete(key string) bool {
+	var deleted bool
+	var walk func(n *trieNode[V], depth int) bool // reports whether n can be pruned
+	walk = func(n *trieNode[V], depth int) bool {
+		if depth == len(key) {
+			if !n.hasValue {
+				return false
+			}
+			var zero V
+			n.value, n.hasValue, deleted = zero, false, true
+			return len(n.children) == 0
+		}
+		child, ok := n.children[key[depth]]
+		if !ok {
+			return false
+		}
+		if walk(child, depth+1) {
+			delete(n.children, key[depth])
+		}
+		return !n.hasValue && len(n.children) == 0
+	}
+	walk(&t.root, 0)
+	if deleted {
+		t.size--
+	}
+	return deleted
+}
+
+// LongestPrefix returns the longest stored key that is a prefix of s.
+func (t *Trie[V]) LongestPrefix(s string) (string, V, bool) {
+	var (
+		bestLen = -1
+		best    V
+	)
+	n := &t.root
+	for i := 0; ; i++ {
+		if n.hasValue {
+			bestLen, best = i, n.value
+		}
+		if i == len(s) {
+			break
+		}
+		if n = n.children[s[i]]; n == nil {
+			break
+		}
+	}
+	if bestLen < 0 {
+		return "", best, false
+	}
+	return s[:bestLen], best, true
+}
+
+// WithPrefix yields every key starting with prefix, in byte order.
+func (t *Trie[V]) WithPrefix(prefix string) iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		n := t.find(prefix)
+		if n == nil {
+			return
+		}
+		buf := []byte(prefix)
+		var walk func(n *trieNode[V]) bool
+		walk = func(n *trieNode[V]) bool {
+			if n.hasValue && !yield(string(buf), n.value) {
+				return false
+			}
+			for _, b := range sortedKeys(n.children) {
+				buf = append(buf, b)
+				ok := walk(n.children[b])
+				buf = buf[:len(buf)-1]
+				if !ok {
+					return false
+				}
+			}
+			return true
+		}
+		walk(n)
+	}
+}
+
+// All yields every key in byte order.
+func (t *Trie[V]) All() iter.Seq2[string, V] { return t.WithPrefix("") }
+
+func sortedKeys[V any](m map[byte]V) []byte {
+	keys := make([]byte, 0, len(m))
+	for b := range m {
+		keys = append(keys, b)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
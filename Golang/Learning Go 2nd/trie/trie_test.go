@@ -0,0 +1,173 @@
+package trie
+
+import (
+	"iter"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// store is what Trie and Radix have in common.
+type store interface {
+	Put(key string, v int) bool
+	Get(key string) (int, bool)
+	Delete(key string) bool
+	Len() int
+	LongestPrefix(s string) (string, int, bool)
+	WithPrefix(prefix string) iter.Seq2[string, int]
+}
+
+// randomKey draws short keys over a small alphabet so that keys share
+// prefixes, split edges and become prefixes of each other.
+func randomKey(rng *rand.Rand) string {
+	const alphabet = "abc"
+	b := make([]byte, rng.IntN(6))
+	for i := range b {
+		b[i] = alphabet[rng.IntN(len(alphabet))]
+	}
+	return string(b)
+}
+
+func naiveLongestPrefix(m map[string]int, s string) (string, int, bool) {
+	best, found := "", false
+	for k := range m {
+		if strings.HasPrefix(s, k) && (!found || len(k) > len(best)) {
+			best, found = k, true
+		}
+	}
+	return best, m[best], found
+}
+
+// checkRadix verifies the compressed shape: no edge is empty, edges are
+// sorted and start with distinct bytes, and below the root every node
+// without a value has at least two children.
+func checkRadix(t *testing.T, n *radixNode[int], root bool) {
+	t.Helper()
+	if !root && !n.hasValue && len(n.edges) < 2 {
+		t.Fatalf("valueless node with %d edges", len(n.edges))
+	}
+	for i, e := range n.edges {
+		if e.label == "" {
+			t.Fatal("empty edge label")
+		}
+		if i > 0 && n.edges[i-1].label[0] >= e.label[0] {
+			t.Fatalf("edges %q and %q out of order", n.edges[i-1].label, e.label)
+		}
+		checkRadix(t, e.node, false)
+	}
+}
+
+func TestAgainstMap(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for round := range 20 {
+		radix := &Radix[int]{}
+		stores := map[string]store{"trie": &Trie[int]{}, "radix": radix}
+		want := map[string]int{}
+		for op := range 2000 {
+			key := randomKey(rng)
+			if rng.IntN(3) == 0 {
+				_, had := want[key]
+				delete(want, key)
+				for name, s := range stores {
+					if got := s.Delete(key); got != had {
+						t.Fatalf("round %d op %d: %s.Delete(%q) = %v, want %v", round, op, name, key, got, had)
+					}
+				}
+			} else {
+				_, had := want[key]
+				want[key] = op
+				for name, s := range stores {
+					if got := s.Put(key, op); got != !had {
+						t.Fatalf("round %d op %d: %s.Put(%q) = %v, want %v", round, op, name, key, got, !had)
+					}
+				}
+			}
+			probe, long := randomKey(rng), randomKey(rng)+randomKey(rng)
+			wantV, wantOK := want[probe]
+			wantP, wantPV, wantPOK := naiveLongestPrefix(want, long)
+			for name, s := range stores {
+				if s.Len() != len(want) {
+					t.Fatalf("round %d op %d: %s.Len() = %d, want %d", round, op, name, s.Len(), len(want))
+				}
+				if v, ok := s.Get(probe); v != wantV || ok != wantOK {
+					t.Fatalf("round %d op %d: %s.Get(%q) = %d, %v; want %d, %v", round, op, name, probe, v, ok, wantV, wantOK)
+				}
+				if p, v, ok := s.LongestPrefix(long); p != wantP || v != wantPV || ok != wantPOK {
+					t.Fatalf("round %d op %d: %s.LongestPrefix(%q) = %q, %d, %v; want %q, %d, %v",
+						round, op, name, long, p, v, ok, wantP, wantPV, wantPOK)
+				}
+			}
+		}
+		checkRadix(t, &radix.root, true)
+
+		// A radix tree's shape depends only on its keys, so deleting must
+		// leave the same nodes as building from scratch.
+		fresh := &Radix[int]{}
+		for k, v := range want {
+			fresh.Put(k, v)
+		}
+		if radix.Nodes() != fresh.Nodes() {
+			t.Errorf("round %d: %d nodes after deletes, %d when built fresh", round, radix.Nodes(), fresh.Nodes())
+		}
+
+		for _, prefix := range []string{"", "a", "ab", "abc", "ca", "cccccc"} {
+			var wantKeys []string
+			for k := range want {
+				if strings.HasPrefix(k, prefix) {
+					wantKeys = append(wantKeys, k)
+				}
+			}
+			slices.Sort(wantKeys)
+			for name, s := range stores {
+				var got []string
+				for k, v := range s.WithPrefix(prefix) {
+					if v != want[k] {
+						t.Errorf("round %d: %s.WithPrefix(%q) yields %q=%d, want %d", round, name, prefix, k, v, want[k])
+					}
+					got = append(got, k)
+				}
+				if !slices.Equal(got, wantKeys) {
+					t.Errorf("round %d: %s.WithPrefix(%q) = %q, want %q", round, name, prefix, got, wantKeys)
+				}
+			}
+		}
+	}
+}
+
+func TestRadixSplitAndMerge(t *testing.T) {
+	var r Radix[int]
+	r.Put("romane", 1)
+	r.Put("romanus", 2)
+	r.Put("romulus", 3)
+	r.Put("rom", 4)
+	checkRadix(t, &r.root, true)
+	// rom → {an → {e, us}, ulus}: five nodes below the root.
+	if n := r.Nodes(); n != 5 {
+		t.Errorf("Nodes() = %d, want 5", n)
+	}
+	r.Delete("romanus")
+	// The "an" node keeps one child and merges into "ane".
+	if n := r.Nodes(); n != 3 {
+		t.Errorf("after deleting romanus, Nodes() = %d, want 3", n)
+	}
+	if v, ok := r.Get("romane"); !ok || v != 1 {
+		t.Errorf("Get(romane) = %d, %v after the merge", v, ok)
+	}
+	r.Delete("rom")
+	if n := r.Nodes(); n != 3 {
+		t.Errorf("after deleting rom, Nodes() = %d, want 3", n)
+	}
+	r.Delete("romulus")
+	// Only one key left: a single edge from the root.
+	if n := r.Nodes(); n != 1 || r.root.edges[0].label != "romane" {
+		t.Errorf("after deleting romulus, %d nodes, edges %+v", n, r.root.edges)
+	}
+	if r.Delete("roman") || r.Delete("romanes") || r.Len() != 1 {
+		t.Errorf("deleting keys that are not stored changed the tree")
+	}
+	if got := slices.Collect(maps.Keys(maps.Collect(r.All()))); !slices.Equal(got, []string{"romane"}) {
+		t.Errorf("All() = %v", got)
+	}
+}
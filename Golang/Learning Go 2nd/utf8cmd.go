@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/textinfo"
+)
+
+// runUTF8 inspects text given as arguments, in a file or on stdin.
+func runUTF8(ctx *cli.Context) error {
+	fs := ctx.Flags
+	cpName := fs.String("cp", "cp437", "code page to encode into (name or escpos:N); empty to skip")
+	file := fs.String("file", "", "read the text from this file")
+	list := fs.Bool("codepages", false, "list the supported code pages")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	if *list {
+		for _, cp := range textinfo.CodePages() {
+			esc := "-"
+			if cp.EscPos >= 0 {
+				esc = fmt.Sprintf("ESC t %d", cp.EscPos)
+			}
+			fmt.Printf("%-11s %s\n", cp.Name, esc)
+		}
+		return nil
+	}
+	var cp *textinfo.CodePage
+	if *cpName != "" {
+		var err error
+		if cp, err = textinfo.LookupCodePage(*cpName); err != nil {
+			return err
+		}
+	}
+	var text []byte
+	var err error
+	switch {
+	case *file != "":
+		text, err = os.ReadFile(*file)
+	case fs.NArg() > 0:
+		text = []byte(strings.Join(fs.Args(), " "))
+	default:
+		text, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = textinfo.Inspect(os.Stdout, text, cp)
+	return err
+}
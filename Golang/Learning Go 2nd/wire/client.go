@@ -0,0 +1,422 @@
+package wire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+var (
+	ErrConnBusy   = errors.New("wire: previous result still open on this connection")
+	ErrPoolClosed = errors.New("wire: pool closed")
+)
+
+// ServerError is an error reported by the server, such as a bad query. The
+// connection stays usable after one.
+type ServerError struct{ Message string }
+
+func (e *ServerError) Error() string { return e.Message }
+
+// Conn is one client connection. It is not safe for concurrent use; share
+// connections through a Pool instead.
+type Conn struct {
+	c      net.Conn
+	r      *bufio.Reader
+	w      *bufio.Writer
+	open   *Rows // unfinished result
+	broken error // set once the connection can no longer be trusted
+}
+
+func Dial(addr string) (*Conn, error) {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{c: c, r: bufio.NewReader(c), w: bufio.NewWriter(c)}, nil
+}
+
+func (c *Conn) Close() error { return c.c.Close() }
+
+// Broken reports why the connection is unusable, or nil if it is fine.
+func (c *Conn) Broken() error { return c.broken }
+
+func (c *Conn) send(typ byte, payload []byte) error {
+	if c.broken != nil {
+		return c.broken
+	}
+	if c.open != nil {
+		return ErrConnBusy
+	}
+	if err := writeFrame(c.w, typ, payload); err != nil {
+		return c.fail(err)
+	}
+	if err := c.w.Flush(); err != nil {
+		return c.fail(err)
+	}
+	return nil
+}
+
+func (c *Conn) recv() (byte, *decoder, error) {
+	typ, payload, err := readFrame(c.r)
+	if err != nil {
+		return 0, nil, c.fail(err)
+	}
+	return typ, &decoder{b: payload}, nil
+}
+
+// fail marks the connection broken: after an I/O or framing error the two
+// ends no longer agree on where the next message starts.
+func (c *Conn) fail(err error) error {
+	if c.broken == nil {
+		c.broken = err
+		c.c.Close()
+	}
+	return err
+}
+
+// Query sends sql with args for its ? placeholders and returns the streaming
+// result, which must be closed before the connection is used again.
+func (c *Conn) Query(sql string, args ...any) (*Rows, error) {
+	b, err := appendValues(appendString(nil, sql), args)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.send(msgQuery, b); err != nil {
+		return nil, err
+	}
+	return c.result()
+}
+
+// Exec runs a statement and returns the number of rows it inserted.
+func (c *Conn) Exec(sql string, args ...any) (int, error) {
+	rows, err := c.Query(sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return rows.drain()
+}
+
+// result reads the start of a response: column names for a row set, or an
+// immediate completion or error.
+func (c *Conn) result() (*Rows, error) {
+	typ, d, err := c.recv()
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case msgColumns:
+		// Every name takes at least its length byte, which bounds the count.
+		n := d.uvarint()
+		if n > uint64(len(d.b)) {
+			return nil, c.fail(errMalformed)
+		}
+		cols := make([]string, n)
+		for i := range cols {
+			cols[i] = d.string()
+		}
+		if err := d.done(); err != nil {
+			return nil, c.fail(err)
+		}
+		rows := &Rows{c: c, cols: cols}
+		c.open = rows
+		return rows, nil
+	case msgComplete:
+		n := d.uvarint()
+		if err := d.done(); err != nil {
+			return nil, c.fail(err)
+		}
+		return &Rows{c: c, done: true, affected: int(n)}, nil
+	case msgError:
+		return nil, c.serverError(d)
+	}
+	return nil, c.fail(fmt.Errorf("wire: unexpected message %q", typ))
+}
+
+func (c *Conn) serverError(d *decoder) error {
+	msg := d.string()
+	if err := d.done(); err != nil {
+		return c.fail(err)
+	}
+	return &ServerError{msg}
+}
+
+// Stmt is a statement prepared on one connection.
+type Stmt struct {
+	c         *Conn
+	id        uint64
+	numParams int
+}
+
+func (c *Conn) Prepare(sql string) (*Stmt, error) {
+	if err := c.send(msgPrepare, appendString(nil, sql)); err != nil {
+		return nil, err
+	}
+	typ, d, err := c.recv()
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case msgStmt:
+		st := &Stmt{c: c, id: d.uvarint(), numParams: int(d.uvarint())}
+		if err := d.done(); err != nil {
+			return nil, c.fail(err)
+		}
+		return st, nil
+	case msgError:
+		return nil, c.serverError(d)
+	}
+	return nil, c.fail(fmt.Errorf("wire: unexpected message %q", typ))
+}
+
+func (s *Stmt) NumParams() int { return s.numParams }
+
+func (s *Stmt) Query(args ...any) (*Rows, error) {
+	b, err := appendValues(binary.AppendUvarint(nil, s.id), args)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.c.send(msgExecute, b); err != nil {
+		return nil, err
+	}
+	return s.c.result()
+}
+
+func (s *Stmt) Exec(args ...any) (int, error) {
+	rows, err := s.Query(args...)
+	if err != nil {
+		return 0, err
+	}
+	return rows.drain()
+}
+
+// Close frees the statement on the server.
+func (s *Stmt) Close() error {
+	if err := s.c.send(msgFree, binary.AppendUvarint(nil, s.id)); err != nil {
+		return err
+	}
+	rows, err := s.c.result()
+	if err != nil {
+		return err
+	}
+	_, err = rows.drain()
+	return err
+}
+
+// Rows is a result streamed from the server.
+type Rows struct {
+	c        *Conn
+	cols     []string
+	cur      []any
+	err      error
+	done     bool
+	affected int
+	release  func(*Conn) // set by a pool
+}
+
+func (r *Rows) Columns() []string { return r.cols }
+
+// Next reads the next row. It returns false at the end of the result or on
+// error; check Err.
+func (r *Rows) Next() bool {
+	if r.done {
+		return false
+	}
+	typ, d, err := r.c.recv()
+	if err != nil {
+		r.finish(err)
+		return false
+	}
+	switch typ {
+	case msgRow:
+		r.cur = d.values()
+		if err := d.done(); err != nil {
+			r.finish(r.c.fail(err))
+			return false
+		}
+		if len(r.cur) != len(r.cols) {
+			r.finish(r.c.fail(errMalformed))
+			return false
+		}
+		return true
+	case msgComplete:
+		r.affected = int(d.uvarint())
+		r.finish(d.done())
+	case msgError:
+		r.finish(r.c.serverError(d))
+	default:
+		r.finish(r.c.fail(fmt.Errorf("wire: unexpected message %q", typ)))
+	}
+	return false
+}
+
+func (r *Rows) finish(err error) {
+	r.done, r.err, r.cur = true, err, nil
+	if r.c.open == r {
+		r.c.open = nil
+	}
+	if r.release != nil {
+		r.release(r.c)
+		r.release = nil
+	}
+}
+
+// Values returns the current row: int64 and string values.
+func (r *Rows) Values() []any { return r.cur }
+
+// Scan copies the current row into pointers to int, int64 or string.
+func (r *Rows) Scan(dest ...any) error {
+	if r.cur == nil {
+		return errors.New("wire: Scan called without a current row")
+	}
+	if len(dest) != len(r.cur) {
+		return fmt.Errorf("wire: Scan expects %d destinations, got %d", len(r.cur), len(dest))
+	}
+	for i, v := range r.cur {
+		ok := false
+		switch d := dest[i].(type) {
+		case *int64:
+			*d, ok = v.(int64)
+		case *int:
+			var n int64
+			n, ok = v.(int64)
+			*d = int(n)
+		case *string:
+			*d, ok = v.(string)
+		case *any:
+			*d, ok = v, true
+		}
+		if !ok {
+			return fmt.Errorf("wire: cannot scan %T into %T", v, dest[i])
+		}
+	}
+	return nil
+}
+
+func (r *Rows) Err() error { return r.err }
+
+// RowsAffected is the number of rows an INSERT added, known once the result
+// has been read to the end.
+func (r *Rows) RowsAffected() int { return r.affected }
+
+// Close reads and discards the rest of the result.
+func (r *Rows) Close() error {
+	for r.Next() {
+	}
+	return r.err
+}
+
+func (r *Rows) drain() (int, error) {
+	err := r.Close()
+	return r.affected, err
+}
+
+// Pool shares up to PoolConfig.Size connections to one server between
+// goroutines.
+type Pool struct {
+	addr   string
+	slots  chan struct{} // one token per connection that may exist
+	mu     sync.Mutex
+	idle   []*Conn
+	dials  int
+	closed bool
+}
+
+// NewPool returns a pool of connections to addr. Options are applied over
+// cfg; see PoolConfig.Resolve.
+func NewPool(addr string, cfg PoolConfig, opts ...PoolOption) (*Pool, error) {
+	cfg, err := cfg.Resolve(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Pool{addr: addr, slots: make(chan struct{}, cfg.Size)}, nil
+}
+
+// Get returns an idle connection, or dials a new one if the pool is below its
+// Size, or waits for one to be returned.
+func (p *Pool) Get() (*Conn, error) {
+	p.slots <- struct{}{}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.slots
+		return nil, ErrPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.dials++
+	p.mu.Unlock()
+	c, err := Dial(p.addr)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	return c, nil
+}
+
+// Put hands a connection back. Broken or busy connections are closed instead
+// of reused.
+func (p *Pool) Put(c *Conn) {
+	p.mu.Lock()
+	if p.closed || c.broken != nil || c.open != nil {
+		c.Close()
+	} else {
+		p.idle = append(p.idle, c)
+	}
+	p.mu.Unlock()
+	<-p.slots
+}
+
+// Query runs sql on a pooled connection that goes back to the pool when the
+// rows are read to the end or closed.
+func (p *Pool) Query(sql string, args ...any) (*Rows, error) {
+	c, err := p.Get()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.Query(sql, args...)
+	if err != nil {
+		p.Put(c)
+		return nil, err
+	}
+	if rows.done {
+		p.Put(c)
+	} else {
+		rows.release = p.Put
+	}
+	return rows, nil
+}
+
+func (p *Pool) Exec(sql string, args ...any) (int, error) {
+	rows, err := p.Query(sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return rows.drain()
+}
+
+// Dials is the number of connections the pool has opened.
+func (p *Pool) Dials() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dials
+}
+
+// Close closes the idle connections; connections still in use are closed as
+// they are returned.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, c := range p.idle {
+		c.Close()
+	}
+	p.idle = nil
+	return nil
+}
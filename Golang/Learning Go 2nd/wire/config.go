@@ -0,0 +1,79 @@
+package wire
+
+import (
+	"fmt"
+	"time"
+
+	"learning-go.adcon.dev/options"
+)
+
+// ServerConfig tunes a Server. Zero fields take the defaults in
+// DefaultServerConfig.
+type ServerConfig struct {
+	WriteTimeout time.Duration `json:"write_timeout"` // a client that reads nothing for this long is disconnected
+}
+
+// PoolConfig tunes a Pool. Zero fields take the defaults in
+// DefaultPoolConfig.
+type PoolConfig struct {
+	Size int `json:"size"` // connections that may be open at once
+}
+
+// Environment variable prefixes for the two configs, as in
+// WIRE_SERVER_WRITE_TIMEOUT and WIRE_POOL_SIZE.
+const (
+	ServerEnvPrefix = "WIRE_SERVER"
+	PoolEnvPrefix   = "WIRE_POOL"
+)
+
+// ServerOption and PoolOption set one field of the matching config; see
+// options.Apply.
+type (
+	ServerOption = options.Option[ServerConfig]
+	PoolOption   = options.Option[PoolConfig]
+)
+
+func WithWriteTimeout(d time.Duration) ServerOption {
+	return options.New[ServerConfig]("write_timeout", d)
+}
+
+func WithSize(n int) PoolOption { return options.New[PoolConfig]("size", n) }
+
+// DefaultServerConfig returns the settings used for zero ServerConfig
+// fields.
+func DefaultServerConfig() ServerConfig { return ServerConfig{WriteTimeout: DefaultWriteTimeout} }
+
+// DefaultPoolConfig returns the settings used for zero PoolConfig fields.
+func DefaultPoolConfig() PoolConfig { return PoolConfig{Size: 4} }
+
+// Resolve applies opts over c, fills in defaults and validates the result,
+// as NewServer does.
+func (c ServerConfig) Resolve(opts ...ServerOption) (ServerConfig, error) {
+	c, err := options.Apply(c, opts...)
+	if err != nil {
+		return c, fmt.Errorf("wire: %w", err)
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = DefaultServerConfig().WriteTimeout
+	}
+	if c.WriteTimeout < 0 {
+		return c, fmt.Errorf("wire: write timeout %v must be positive", c.WriteTimeout)
+	}
+	return c, nil
+}
+
+// Resolve applies opts over c, fills in defaults and validates the result,
+// as NewPool does.
+func (c PoolConfig) Resolve(opts ...PoolOption) (PoolConfig, error) {
+	c, err := options.Apply(c, opts...)
+	if err != nil {
+		return c, fmt.Errorf("wire: %w", err)
+	}
+	if c.Size == 0 {
+		c.Size = DefaultPoolConfig().Size
+	}
+	if c.Size < 0 {
+		return c, fmt.Errorf("wire: pool size %d must be positive", c.Size)
+	}
+	return c, nil
+}
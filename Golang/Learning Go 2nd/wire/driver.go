@@ -0,0 +1,215 @@
+package wire
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"time"
+)
+
+func init() { sql.Register("wire", Driver{}) }
+
+// Driver is the database/sql driver. The data source name is the server
+// address:
+//
+//	db, err := sql.Open("wire", "127.0.0.1:7070")
+//
+// database/sql pools its own connections, so each driver connection wraps a
+// single Conn.
+type Driver struct{}
+
+func (Driver) Open(addr string) (driver.Conn, error) {
+	c, err := Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &driverConn{c: c}, nil
+}
+
+type driverConn struct{ c *Conn }
+
+var (
+	_ driver.QueryerContext     = (*driverConn)(nil)
+	_ driver.ExecerContext      = (*driverConn)(nil)
+	_ driver.ConnPrepareContext = (*driverConn)(nil)
+	_ driver.Validator          = (*driverConn)(nil)
+)
+
+func (dc *driverConn) Prepare(query string) (driver.Stmt, error) {
+	return dc.PrepareContext(context.Background(), query)
+}
+
+func (dc *driverConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := dc.usable(); err != nil {
+		return nil, err
+	}
+	defer dc.deadline(ctx)()
+	st, err := dc.c.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &driverStmt{dc: dc, st: st}, nil
+}
+
+func (dc *driverConn) Close() error { return dc.c.Close() }
+
+func (dc *driverConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("wire: transactions are not supported")
+}
+
+// IsValid lets database/sql drop connections that broke mid-conversation.
+func (dc *driverConn) IsValid() bool { return dc.c.broken == nil }
+
+func (dc *driverConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	vals, err := positional(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := dc.usable(); err != nil {
+		return nil, err
+	}
+	undo := dc.deadline(ctx)
+	rows, err := dc.c.Query(query, vals...)
+	if err != nil {
+		undo()
+		return nil, err
+	}
+	return &driverRows{rows: rows, undo: undo}, nil
+}
+
+func (dc *driverConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	vals, err := positional(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := dc.usable(); err != nil {
+		return nil, err
+	}
+	defer dc.deadline(ctx)()
+	n, err := dc.c.Exec(query, vals...)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(n), nil
+}
+
+// deadline applies the context deadline to the socket and returns a func
+// that clears it.
+func (dc *driverConn) deadline(ctx context.Context) func() {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return func() {}
+	}
+	dc.c.c.SetDeadline(d)
+	return func() { dc.c.c.SetDeadline(time.Time{}) }
+}
+
+// usable reports driver.ErrBadConn for a connection that broke during an
+// earlier request, so database/sql retries on a fresh one. A request that
+// fails on its own is never retried, as it may have run on the server.
+func (dc *driverConn) usable() error {
+	if dc.c.broken != nil {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func positional(args []driver.NamedValue) ([]any, error) {
+	vals := make([]any, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("wire: named parameters are not supported")
+		}
+		vals[i] = a.Value
+	}
+	return vals, nil
+}
+
+type driverStmt struct {
+	dc *driverConn
+	st *Stmt
+}
+
+var (
+	_ driver.StmtQueryContext = (*driverStmt)(nil)
+	_ driver.StmtExecContext  = (*driverStmt)(nil)
+)
+
+func (s *driverStmt) Close() error  { return s.st.Close() }
+func (s *driverStmt) NumInput() int { return s.st.NumParams() }
+
+func (s *driverStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+func (s *driverStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+func (s *driverStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	vals, err := positional(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.dc.usable(); err != nil {
+		return nil, err
+	}
+	defer s.dc.deadline(ctx)()
+	n, err := s.st.Exec(vals...)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (s *driverStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	vals, err := positional(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.dc.usable(); err != nil {
+		return nil, err
+	}
+	undo := s.dc.deadline(ctx)
+	rows, err := s.st.Query(vals...)
+	if err != nil {
+		undo()
+		return nil, err
+	}
+	return &driverRows{rows: rows, undo: undo}, nil
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		nv[i] = driver.NamedValue{Ordinal: i + 1, Value: a}
+	}
+	return nv
+}
+
+type driverRows struct {
+	rows *Rows
+	undo func()
+}
+
+func (r *driverRows) Columns() []string { return r.rows.Columns() }
+
+func (r *driverRows) Close() error {
+	defer r.undo()
+	return r.rows.Close()
+}
+
+func (r *driverRows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	for i, v := range r.rows.Values() {
+		dest[i] = v
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Package wire serves a query.Catalog over TCP and talks to it: a
+// length-prefixed protocol, a server, a client with a connection pool, and a
+// database/sql driver registered as "wire".
+//
+// Every message is a frame: a type byte, a big-endian uint32 payload length
+// and the payload. Strings are a uvarint length and bytes; a value list is a
+// uvarint count followed by values tagged 'i' (varint int64) or 's' (string).
+//
+//	client                          server
+//	'Q' sql, values           →     'T' column names, then 'D' values per row,
+//	'X' stmt id, values       →         then 'C' rows affected;
+//	                                    or 'E' message at any point
+//	'P' sql                   →     'S' stmt id, parameter count | 'E'
+//	'F' stmt id               →     'C' 0
+//
+// An INSERT answers with 'C' straight away. Requests on one connection are
+// handled in order; a client must read a response to the end before sending
+// the next request.
+package wire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	msgQuery    = 'Q'
+	msgPrepare  = 'P'
+	msgExecute  = 'X'
+	msgFree     = 'F'
+	msgColumns  = 'T'
+	msgRow      = 'D'
+	msgComplete = 'C'
+	msgError    = 'E'
+	msgStmt     = 'S'
+
+	maxFrame = 16 << 20
+)
+
+var errMalformed = errors.New("wire: malformed message")
+
+func writeFrame(w io.Writer, typ byte, payload []byte) error {
+	if len(payload) > maxFrame {
+		return fmt.Errorf("wire: %d byte message exceeds the %d byte limit", len(payload), maxFrame)
+	}
+	var hdr [5]byte
+	hdr[0] = typ
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(payload)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxFrame {
+		return 0, nil, errMalformed
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return hdr[0], payload, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendValues encodes int, int64 and string values.
+func appendValues(b []byte, vals []any) ([]byte, error) {
+	b = binary.AppendUvarint(b, uint64(len(vals)))
+	for i, v := range vals {
+		switch v := v.(type) {
+		case int:
+			b = binary.AppendVarint(append(b, 'i'), int64(v))
+		case int64:
+			b = binary.AppendVarint(append(b, 'i'), v)
+		case string:
+			b = appendString(append(b, 's'), v)
+		default:
+			return nil, fmt.Errorf("wire: value %d: unsupported type %T", i+1, v)
+		}
+	}
+	return b, nil
+}
+
+// decoder reads fields from a payload, remembering the first error.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = errMalformed
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = errMalformed
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil || n > uint64(len(d.b)) {
+		d.err = errMalformed
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
+
+func (d *decoder) values() []any {
+	n := d.uvarint()
+	if d.err != nil || n > uint64(len(d.b)) {
+		d.err = errMalformed
+		return nil
+	}
+	vals := make([]any, n)
+	for i := range vals {
+		if len(d.b) == 0 {
+			d.err = errMalformed
+			return nil
+		}
+		tag := d.b[0]
+		d.b = d.b[1:]
+		switch tag {
+		case 'i':
+			vals[i] = d.varint()
+		case 's':
+			vals[i] = d.string()
+		default:
+			d.err = errMalformed
+		}
+	}
+	return vals
+}
+
+// done reports the first error, or errMalformed if bytes are left over.
+func (d *decoder) done() error {
+	if d.err == nil && len(d.b) > 0 {
+		d.err = errMalformed
+	}
+	return d.err
+}
@@ -0,0 +1,250 @@
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"learning-go.adcon.dev/query"
+)
+
+var ErrServerClosed = errors.New("wire: server closed")
+
+// DefaultWriteTimeout bounds every write to a client by default. A client that stops
+// reading a large result for longer is disconnected, so its open cursor
+// cannot hold the catalog's read lock and stall INSERTs indefinitely.
+const DefaultWriteTimeout = 30 * time.Second
+
+// maxBuffered is how much of a result run collects before writing any of
+// it. Results up to this size are complete, and their cursor closed, before
+// the client is asked to read them.
+const maxBuffered = 1 << 20
+
+// Server answers protocol requests against a catalog. Each connection is
+// handled by its own goroutine with its own prepared statements.
+type Server struct {
+	cat          *query.Catalog
+	writeTimeout time.Duration
+
+	mu        sync.Mutex
+	listeners map[net.Listener]bool
+	conns     map[net.Conn]bool
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewServer returns a server for cat. Options are applied over cfg; see
+// ServerConfig.Resolve.
+func NewServer(cat *query.Catalog, cfg ServerConfig, opts ...ServerOption) (*Server, error) {
+	cfg, err := cfg.Resolve(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{cat: cat, writeTimeout: cfg.WriteTimeout, listeners: map[net.Listener]bool{}, conns: map[net.Conn]bool{}}, nil
+}
+
+// Serve accepts connections on l until Close, then returns ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.listeners[l] = true
+	s.mu.Unlock()
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, l)
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return ErrServerClosed
+		}
+		s.conns[c] = true
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.wg.Done()
+			s.handle(c)
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Close stops the listeners, drops every connection and waits for their
+// handlers to finish.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// session is one connection's state.
+type session struct {
+	srv   *Server
+	r     *bufio.Reader
+	w     *bufio.Writer
+	stmts map[uint64]*query.Stmt
+	next  uint64
+}
+
+func (s *Server) handle(c net.Conn) {
+	defer c.Close()
+	w := bufio.NewWriter(deadlineWriter{c, s.writeTimeout})
+	ss := &session{srv: s, r: bufio.NewReader(c), w: w, stmts: map[uint64]*query.Stmt{}}
+	for {
+		typ, payload, err := readFrame(ss.r)
+		if err != nil {
+			return // closed by the client, or unreadable: nothing to answer
+		}
+		if err := ss.dispatch(typ, payload); err != nil {
+			return
+		}
+		if err := ss.w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// dispatch answers one request. Request errors go back to the client as 'E'
+// frames; only connection errors are returned.
+func (ss *session) dispatch(typ byte, payload []byte) error {
+	d := &decoder{b: payload}
+	switch typ {
+	case msgQuery:
+		sql, args := d.string(), d.values()
+		if err := d.done(); err != nil {
+			return ss.fail(err)
+		}
+		st, err := ss.srv.cat.Prepare(sql)
+		if err != nil {
+			return ss.fail(err)
+		}
+		return ss.run(st, args)
+	case msgPrepare:
+		sql := d.string()
+		if err := d.done(); err != nil {
+			return ss.fail(err)
+		}
+		st, err := ss.srv.cat.Prepare(sql)
+		if err != nil {
+			return ss.fail(err)
+		}
+		ss.next++
+		ss.stmts[ss.next] = st
+		b := binary.AppendUvarint(nil, ss.next)
+		return writeFrame(ss.w, msgStmt, binary.AppendUvarint(b, uint64(st.NumParams())))
+	case msgExecute:
+		id, args := d.uvarint(), d.values()
+		if err := d.done(); err != nil {
+			return ss.fail(err)
+		}
+		st, ok := ss.stmts[id]
+		if !ok {
+			return ss.fail(fmt.Errorf("wire: no prepared statement %d", id))
+		}
+		return ss.run(st, args)
+	case msgFree:
+		id := d.uvarint()
+		if err := d.done(); err != nil {
+			return ss.fail(err)
+		}
+		delete(ss.stmts, id)
+		return writeFrame(ss.w, msgComplete, binary.AppendUvarint(nil, 0))
+	}
+	return ss.fail(fmt.Errorf("wire: unknown message type %q", typ))
+}
+
+func (ss *session) fail(err error) error {
+	return writeFrame(ss.w, msgError, appendString(nil, err.Error()))
+}
+
+// deadlineWriter gives every write to the connection its own deadline.
+type deadlineWriter struct {
+	c       net.Conn
+	timeout time.Duration
+}
+
+func (d deadlineWriter) Write(p []byte) (int, error) {
+	if err := d.c.SetWriteDeadline(time.Now().Add(d.timeout)); err != nil {
+		return 0, err
+	}
+	return d.c.Write(p)
+}
+
+// run sends a statement's result. The cursor holds the catalog's read lock,
+// so rows collect in memory and reach the client only after the cursor is
+// closed, unless they outgrow maxBuffered; the rest then streams behind
+// them, each write bounded by the server's write timeout.
+func (ss *session) run(st *query.Stmt, args []any) error {
+	cur, err := st.Query(args...)
+	if err != nil {
+		return ss.fail(err)
+	}
+	defer cur.Close()
+	var pending bytes.Buffer
+	var out io.Writer = &pending
+	stream := func() error {
+		out = ss.w
+		_, err := pending.WriteTo(ss.w)
+		return err
+	}
+	if cur.Columns != nil {
+		b := binary.AppendUvarint(nil, uint64(len(cur.Columns)))
+		for _, c := range cur.Columns {
+			b = appendString(b, c)
+		}
+		if err := writeFrame(out, msgColumns, b); err != nil {
+			return err
+		}
+		var buf []byte
+		for r, ok := cur.Next(); ok; r, ok = cur.Next() {
+			if buf, err = appendValues(buf[:0], r); err != nil {
+				cur.Close()
+				if err := stream(); err != nil {
+					return err
+				}
+				return ss.fail(err)
+			}
+			if err := writeFrame(out, msgRow, buf); err != nil {
+				return err
+			}
+			if pending.Len() > maxBuffered {
+				if err := stream(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cur.Close()
+	if err := stream(); err != nil {
+		return err
+	}
+	return writeFrame(ss.w, msgComplete, binary.AppendUvarint(nil, uint64(cur.RowsAffected)))
+}
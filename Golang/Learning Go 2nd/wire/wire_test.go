@@ -0,0 +1,376 @@
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"learning-go.adcon.dev/query"
+)
+
+func TestValuesRoundTrip(t *testing.T) {
+	in := []any{int64(0), int64(-1), int64(1 << 62), "", "kid", "ñ\x00"}
+	b, err := appendValues(nil, append([]any{int(7)}, in...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &decoder{b: b}
+	got := d.values()
+	if err := d.done(); err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]any{int64(7)}, in...); !reflect.DeepEqual(got, want) {
+		t.Errorf("values = %#v, want %#v", got, want)
+	}
+	if _, err := appendValues(nil, []any{1.5}); err == nil {
+		t.Error("appendValues accepted a float64")
+	}
+}
+
+func TestDecoderMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"empty", nil},
+		{"count past the payload", []byte{5, 'i', 2}},
+		{"huge count", binary.AppendUvarint(nil, 1<<62)},
+		{"unknown tag", []byte{1, 'f', 0}},
+		{"truncated varint", []byte{1, 'i', 0x80}},
+		{"string past the payload", []byte{1, 's', 9, 'a'}},
+		{"trailing bytes", []byte{1, 'i', 2, 0}},
+	}
+	for _, tt := range tests {
+		d := &decoder{b: tt.b}
+		d.values()
+		if err := d.done(); !errors.Is(err, errMalformed) {
+			t.Errorf("%s: err = %v, want errMalformed", tt.name, err)
+		}
+	}
+}
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	if err := writeFrame(w, msgQuery, []byte("payload")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFrame(w, msgQuery, make([]byte, maxFrame+1)); err == nil {
+		t.Error("writeFrame accepted a frame over the limit")
+	}
+	w.Flush()
+	typ, payload, err := readFrame(bufio.NewReader(bytes.NewReader(buf.Bytes())))
+	if err != nil || typ != msgQuery || string(payload) != "payload" {
+		t.Errorf("readFrame = %q, %q, %v", typ, payload, err)
+	}
+
+	tooBig := []byte{msgQuery, 0xff, 0xff, 0xff, 0xff}
+	if _, _, err := readFrame(bufio.NewReader(bytes.NewReader(tooBig))); !errors.Is(err, errMalformed) {
+		t.Errorf("oversized length: err = %v, want errMalformed", err)
+	}
+	short := buf.Bytes()[:len(buf.Bytes())-2]
+	if _, _, err := readFrame(bufio.NewReader(bytes.NewReader(short))); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated payload: err = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+// TestBadServer answers a query with a column count no payload can hold,
+// which must break the connection rather than allocate.
+func TestBadServer(t *testing.T) {
+	client, server := net.Pipe()
+	go func() {
+		r, w := bufio.NewReader(server), bufio.NewWriter(server)
+		readFrame(r)
+		writeFrame(w, msgColumns, binary.AppendUvarint(nil, 1<<62))
+		w.Flush()
+		server.Close()
+	}()
+	c := &Conn{c: client, r: bufio.NewReader(client), w: bufio.NewWriter(client)}
+	if _, err := c.Query("SELECT 1"); !errors.Is(err, errMalformed) {
+		t.Fatalf("err = %v, want errMalformed", err)
+	}
+	if c.Broken() == nil {
+		t.Error("connection still usable after a malformed response")
+	}
+}
+
+// serve starts a server over a table of n kids, kid i aged 3 + i%10.
+func serve(t *testing.T, n int) string {
+	t.Helper()
+	srv, err := NewServer(kidsCatalog(t, n), ServerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listen(t, srv)
+}
+
+func kidsCatalog(t *testing.T, n int) *query.Catalog {
+	t.Helper()
+	kids := query.NewTable("kids", "id", "age", "candies")
+	for i := range n {
+		if err := kids.Insert(i, 3+i%10, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return query.NewCatalog(kids)
+}
+
+func listen(t *testing.T, srv *Server) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return l.Addr().String()
+}
+
+func TestConn(t *testing.T) {
+	c, err := Dial(serve(t, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	rows, err := c.Query("SELECT k.id, k.candies FROM kids k WHERE k.age = ?", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rows.Columns(); len(got) != 2 {
+		t.Errorf("columns %v, want 2", got)
+	}
+	// A second request before the first result is read must wait.
+	if _, err := c.Query("SELECT k.id FROM kids k"); !errors.Is(err, ErrConnBusy) {
+		t.Errorf("query with a result open: err = %v, want ErrConnBusy", err)
+	}
+	count := 0
+	for rows.Next() {
+		var id, candies int
+		if err := rows.Scan(&id, &candies); err != nil {
+			t.Fatal(err)
+		}
+		if id%10 != 2 || candies != id {
+			t.Errorf("row %d, %d does not have age 5", id, candies)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil || count != 10 {
+		t.Errorf("read %d rows, %v; want 10", count, err)
+	}
+
+	// A server error leaves the connection usable.
+	_, err = c.Query("SELECT nope FROM kids")
+	var se *ServerError
+	if !errors.As(err, &se) {
+		t.Fatalf("bad query: err = %v, want a ServerError", err)
+	}
+	if c.Broken() != nil {
+		t.Fatalf("connection broken after a server error: %v", c.Broken())
+	}
+
+	st, err := c.Prepare("SELECT k.id FROM kids k WHERE k.id = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.NumParams() != 1 {
+		t.Errorf("NumParams = %d, want 1", st.NumParams())
+	}
+	for _, id := range []int{0, 42, 99} {
+		rows, err := st.Query(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []any
+		for rows.Next() {
+			got = append(got, rows.Values()...)
+		}
+		if err := rows.Err(); err != nil || len(got) != 1 || got[0] != int64(id) {
+			t.Errorf("prepared lookup of %d = %v, %v", id, got, err)
+		}
+	}
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Query(1); !errors.As(err, &se) {
+		t.Errorf("closed statement: err = %v, want a ServerError", err)
+	}
+
+	n, err := c.Exec("INSERT INTO kids VALUES (?, ?, ?), (?, ?, ?)", 100, 4, 1, 101, 4, 2)
+	if err != nil || n != 2 {
+		t.Errorf("Exec = %d, %v; want 2", n, err)
+	}
+}
+
+func TestMalformedRequest(t *testing.T) {
+	c, err := Dial(serve(t, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// A query whose value list claims more values than it carries.
+	if err := c.send(msgQuery, append(appendString(nil, "SELECT k.id FROM kids k"), 3, 'i')); err != nil {
+		t.Fatal(err)
+	}
+	var se *ServerError
+	if _, err := c.result(); !errors.As(err, &se) {
+		t.Fatalf("malformed request: err = %v, want a ServerError", err)
+	}
+	if err := c.send('?', nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.result(); !errors.As(err, &se) {
+		t.Fatalf("unknown message: err = %v, want a ServerError", err)
+	}
+	if _, err := c.Exec("SELECT k.id FROM kids k"); err != nil {
+		t.Errorf("connection unusable after bad requests: %v", err)
+	}
+}
+
+func TestPool(t *testing.T) {
+	pool, err := NewPool(serve(t, 100), PoolConfig{}, WithSize(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 20 {
+				age := 3 + (i+j)%10
+				rows, err := pool.Query("SELECT k.id FROM kids k WHERE k.age = ?", age)
+				if err != nil {
+					errs <- err
+					return
+				}
+				n := 0
+				for rows.Next() {
+					n++
+				}
+				if err := rows.Err(); err != nil || n != 10 {
+					errs <- fmt.Errorf("age %d: %d rows, %v", age, n, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if d := pool.Dials(); d > 3 {
+		t.Errorf("pool of 3 dialed %d connections", d)
+	}
+	pool.Close()
+	if _, err := pool.Get(); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Get after Close: err = %v, want ErrPoolClosed", err)
+	}
+}
+
+func TestDriver(t *testing.T) {
+	db, err := sql.Open("wire", serve(t, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var age, candies int
+	if err := db.QueryRow("SELECT k.age, k.candies FROM kids k WHERE k.id = ?", 7).Scan(&age, &candies); err != nil {
+		t.Fatal(err)
+	}
+	if age != 10 || candies != 7 {
+		t.Errorf("kid 7: age %d, candies %d; want 10, 7", age, candies)
+	}
+	res, err := db.Exec("INSERT INTO kids VALUES (?, ?, ?)", 20, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Errorf("RowsAffected = %d, want 1", n)
+	}
+	var count int
+	if err := db.QueryRow("SELECT k.id FROM kids k WHERE k.id >= ?", 20).Scan(&count); err != nil || count != 20 {
+		t.Errorf("inserted kid reads back as %d, %v", count, err)
+	}
+	var se *ServerError
+	if _, err := db.Query("SELECT nope FROM kids"); !errors.As(err, &se) {
+		t.Errorf("bad query: err = %v, want a ServerError", err)
+	}
+}
+
+// TestStalledReader has a client read one row of a result and stop. Writes
+// from other connections wait for the catalog's write lock, which the open
+// cursor holds, so they must not wait on the stalled client for long.
+func TestStalledReader(t *testing.T) {
+	insert := func(addr string) error {
+		done := make(chan error, 1)
+		go func() {
+			c, err := Dial(addr)
+			if err != nil {
+				done <- err
+				return
+			}
+			defer c.Close()
+			_, err = c.Exec("INSERT INTO kids VALUES (?, ?, ?)", -1, 5, 0)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			return errors.New("INSERT still blocked after 5s")
+		}
+	}
+	stall := func(addr string) (*Conn, *Rows) {
+		c, err := Dial(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := c.Query("SELECT * FROM kids k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rows.Next() {
+			t.Fatal(rows.Err())
+		}
+		return c, rows
+	}
+
+	// A result that fits in the buffer is complete before it is written, so
+	// its cursor is already closed.
+	c, _ := stall(serve(t, 1000))
+	defer c.Close()
+	if err := insert(c.c.RemoteAddr().String()); err != nil {
+		t.Errorf("small result: %v", err)
+	}
+
+	// A larger one streams, and the client is dropped once a write times
+	// out.
+	srv, err := NewServer(kidsCatalog(t, 400_000), ServerConfig{WriteTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listen(t, srv)
+	c, rows := stall(addr)
+	defer c.Close()
+	if err := insert(addr); err != nil {
+		t.Errorf("large result: %v", err)
+	}
+	for rows.Next() {
+	}
+	if rows.Err() == nil {
+		t.Error("stalled client read the whole result")
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+
+	"learning-go.adcon.dev/cli"
+	"learning-go.adcon.dev/wire"
+)
+
+// runWire serves the candy shop tables over TCP. Without -listen it runs a
+// loopback session instead: pooled clients with prepared statements,
+// database/sql, inserts and errors, checked against the catalog in process.
+func runWire(ctx *cli.Context) error {
+	fs := ctx.Flags
+	listen := fs.String("listen", "", "address to serve on until interrupted (default: loopback demo)")
+	n := fs.Int("kids", 2000, "generated kids")
+	clients := fs.Int("clients", 16, "concurrent clients in the demo")
+	poolSize := fs.Int("pool", 4, "client pool size in the demo")
+	seed := fs.Uint64("seed", 1, "random seed")
+	if err := ctx.Parse(); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return fmt.Errorf("wire: need at least one kid")
+	}
+	cat, err := candyShop(*n, rand.New(rand.NewPCG(*seed, *seed)))
+	if err != nil {
+		return err
+	}
+	srv, err := wire.NewServer(cat, wire.ServerConfig{})
+	if err != nil {
+		return err
+	}
+	if *listen != "" {
+		l, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("serving %d kids on %s\n", *n, l.Addr())
+		return srv.Serve(l)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	go srv.Serve(l)
+	defer srv.Close()
+	addr := l.Addr().String()
+	fmt.Printf("server on %s\n", addr)
+
+	// Expected answers straight from the catalog.
+	want := map[int64]int{}
+	for age := int64(3); age <= 12; age++ {
+		res, err := cat.Exec(fmt.Sprintf("SELECT k.id FROM kids k WHERE k.age = %d", age))
+		if err != nil {
+			return err
+		}
+		want[age] = len(res.Rows)
+	}
+
+	pool, err := wire.NewPool(addr, wire.PoolConfig{Size: *poolSize})
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	var wg sync.WaitGroup
+	errs := make(chan error, *clients)
+	for i := range *clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- poolClient(pool, uint64(i), want)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%d clients ran 50 prepared queries each over %d pooled connections\n", *clients, pool.Dials())
+
+	db, err := sql.Open("wire", addr)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var age, candies int
+	if err := db.QueryRow("SELECT k.age, k.candies FROM kids k WHERE k.id = ?", 7).Scan(&age, &candies); err != nil {
+		return err
+	}
+	fmt.Printf("database/sql: kid 7 is %d and has %d candies\n", age, candies)
+	res, err := db.Exec("INSERT INTO kids VALUES (?, ?, ?), (?, ?, ?)", *n, 8, 30, *n+1, 9, 12)
+	if err != nil {
+		return err
+	}
+	added, _ := res.RowsAffected()
+	var count int
+	rows, err := db.Query("SELECT k.id FROM kids k WHERE k.id >= ?", *n)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	fmt.Printf("database/sql: inserted %d kids, read back %d\n", added, count)
+	_, err = db.Query("SELECT nope FROM kids")
+	var se *wire.ServerError
+	if !errors.As(err, &se) {
+		return fmt.Errorf("wire: expected a server error, got %v", err)
+	}
+	fmt.Printf("database/sql: bad query reported as %q\n", se.Message)
+
+	// EXPLAIN ANALYZE comes back as rows, one per plan line.
+	plan, err := pool.Query("EXPLAIN ANALYZE SELECT k.id, p.candy FROM kids k JOIN purchases p ON k.id = p.kid_id WHERE k.age = ?", 4)
+	if err != nil {
+		return err
+	}
+	for plan.Next() {
+		fmt.Println(plan.Values()[0])
+	}
+	return plan.Err()
+}
+
+// poolClient checks random prepared lookups against the expected counts.
+func poolClient(pool *wire.Pool, seed uint64, want map[int64]int) error {
+	c, err := pool.Get()
+	if err != nil {
+		return err
+	}
+	st, err := c.Prepare("SELECT k.id, k.age FROM kids k WHERE k.age = ?")
+	if err != nil {
+		pool.Put(c)
+		return err
+	}
+	defer pool.Put(c)
+	defer st.Close()
+	rng := rand.New(rand.NewPCG(seed, 7))
+	for range 50 {
+		age := int64(3 + rng.IntN(10))
+		rows, err := st.Query(age)
+		if err != nil {
+			return err
+		}
+		got := 0
+		for rows.Next() {
+			var id, a int64
+			if err := rows.Scan(&id, &a); err != nil {
+				rows.Close()
+				return err
+			}
+			if a != age {
+				rows.Close()
+				return fmt.Errorf("wire: kid %d has age %d, asked for %d", id, a, age)
+			}
+			got++
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if got != want[age] {
+			return fmt.Errorf("wire: %d kids aged %d, want %d", got, age, want[age])
+		}
+	}
+	return nil
+}